@@ -0,0 +1,304 @@
+// Package apply applies DynamoDB update expressions to items in memory.
+//
+// Expressions use the same syntax as dynamo's SetExpr and friends:
+// ? placeholders for values and $ placeholders for names.
+// Expression attribute names (#name) and values (:value) can be given with UpdateInput,
+// which is useful for implementing fakes of the DynamoDB API.
+//
+//	updated, err := apply.Update(item, "SET Count = Count + ?, Tags = list_append(Tags, ?) REMOVE Old", 1, []string{"new"})
+package apply
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo/internal/docpath"
+	"github.com/niltonkummer/dynamo/internal/exprs"
+)
+
+// Update applies the update expression expr to item, with args for its placeholders.
+// It returns an updated copy of item; the original item is not modified.
+func Update(item map[string]types.AttributeValue, expr string, args ...interface{}) (map[string]types.AttributeValue, error) {
+	return update(item, expr, args, nil, nil)
+}
+
+// UpdateInput applies the update expression expr to item,
+// using the given expression attribute names and values.
+// It returns an updated copy of item; the original item is not modified.
+func UpdateInput(item map[string]types.AttributeValue, expr string, names map[string]string, values map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	return update(item, expr, nil, names, values)
+}
+
+func update(item map[string]types.AttributeValue, expr string, args []interface{}, names map[string]string, values map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	tree, err := exprs.ParseUpdate(expr)
+	if err != nil {
+		return nil, err
+	}
+	b, err := docpath.Bind(tree, args, names, values)
+	if err != nil {
+		return nil, err
+	}
+	u := updater{item: item, b: b}
+	return u.apply(tree.Update)
+}
+
+type updater struct {
+	// item is the original item; every operand is evaluated against it
+	item map[string]types.AttributeValue
+	b    *docpath.Binding
+}
+
+type action struct {
+	path  docpath.Path
+	value types.AttributeValue
+}
+
+func (u updater) apply(expr *exprs.UpdateExpr) (map[string]types.AttributeValue, error) {
+	// evaluate everything first, so that every action sees the original item
+	var set, add, del []action
+	for _, act := range expr.Set {
+		path, err := u.b.Path(act.Path)
+		if err != nil {
+			return nil, err
+		}
+		value, err := u.operand(act.Value)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, action{path, value})
+	}
+	remove := make([]docpath.Path, 0, len(expr.Remove))
+	for _, p := range expr.Remove {
+		path, err := u.b.Path(p)
+		if err != nil {
+			return nil, err
+		}
+		remove = append(remove, path)
+	}
+	for _, act := range expr.Add {
+		path, value, err := u.pathValue(act)
+		if err != nil {
+			return nil, err
+		}
+		add = append(add, action{path, value})
+	}
+	for _, act := range expr.Delete {
+		path, value, err := u.pathValue(act)
+		if err != nil {
+			return nil, err
+		}
+		del = append(del, action{path, value})
+	}
+	if err := checkOverlap(set, remove, add, del); err != nil {
+		return nil, err
+	}
+
+	out := docpath.CloneItem(u.item)
+	for _, act := range set {
+		if err := docpath.Set(out, act.path, docpath.Clone(act.value)); err != nil {
+			return nil, err
+		}
+	}
+	// remove list elements from the end first, so indexes refer to the original list
+	sort.Slice(remove, func(i, j int) bool {
+		return removeBefore(remove[i], remove[j])
+	})
+	for _, path := range remove {
+		docpath.Remove(out, path)
+	}
+	for _, act := range add {
+		if err := addAction(out, act); err != nil {
+			return nil, err
+		}
+	}
+	for _, act := range del {
+		if err := deleteAction(out, act); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func addAction(item map[string]types.AttributeValue, act action) error {
+	current, exists := docpath.Get(item, act.path)
+	if !exists {
+		if _, ok := act.value.(*types.AttributeValueMemberN); !ok && !docpath.IsSet(act.value) {
+			return fmt.Errorf("dynamo: ADD %s: value must be a number or set (got %s)", act.path, docpath.TypeName(act.value))
+		}
+		return docpath.Set(item, act.path, docpath.Clone(act.value))
+	}
+	if n, ok := current.(*types.AttributeValueMemberN); ok {
+		delta, ok := act.value.(*types.AttributeValueMemberN)
+		if !ok {
+			return fmt.Errorf("dynamo: ADD %s: cannot add %s to N", act.path, docpath.TypeName(act.value))
+		}
+		sum, err := docpath.AddNumbers(n.Value, delta.Value, false)
+		if err != nil {
+			return err
+		}
+		return docpath.Set(item, act.path, &types.AttributeValueMemberN{Value: sum})
+	}
+	if !docpath.IsSet(current) {
+		return fmt.Errorf("dynamo: ADD %s: attribute must be a number or set (got %s)", act.path, docpath.TypeName(current))
+	}
+	union, err := docpath.Union(current, act.value)
+	if err != nil {
+		return err
+	}
+	return docpath.Set(item, act.path, union)
+}
+
+func deleteAction(item map[string]types.AttributeValue, act action) error {
+	if !docpath.IsSet(act.value) {
+		return fmt.Errorf("dynamo: DELETE %s: value must be a set (got %s)", act.path, docpath.TypeName(act.value))
+	}
+	current, exists := docpath.Get(item, act.path)
+	if !exists {
+		return nil
+	}
+	diff, ok, err := docpath.Difference(current, act.value)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// sets can't be empty
+		docpath.Remove(item, act.path)
+		return nil
+	}
+	return docpath.Set(item, act.path, diff)
+}
+
+func (u updater) pathValue(act *exprs.PathValue) (docpath.Path, types.AttributeValue, error) {
+	path, err := u.b.Path(act.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := u.operand(act.Value)
+	return path, value, err
+}
+
+func (u updater) operand(op exprs.Operand) (types.AttributeValue, error) {
+	switch x := op.(type) {
+	case *exprs.Value:
+		return u.b.Value(x)
+	case *exprs.Path:
+		path, err := u.b.Path(x)
+		if err != nil {
+			return nil, err
+		}
+		av, ok := docpath.Get(u.item, path)
+		if !ok {
+			return nil, fmt.Errorf("dynamo: the document path %s does not exist in the item", path)
+		}
+		return av, nil
+	case *exprs.Arithmetic:
+		left, err := u.operand(x.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := u.operand(x.Right)
+		if err != nil {
+			return nil, err
+		}
+		ln, lok := left.(*types.AttributeValueMemberN)
+		rn, rok := right.(*types.AttributeValueMemberN)
+		if !lok || !rok {
+			return nil, fmt.Errorf("dynamo: incorrect operand types for %s: %s and %s", x.Op, docpath.TypeName(left), docpath.TypeName(right))
+		}
+		n, err := docpath.AddNumbers(ln.Value, rn.Value, x.Op == "-")
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberN{Value: n}, nil
+	case *exprs.Func:
+		switch x.Name {
+		case "if_not_exists":
+			path, err := u.b.Path(x.Args[0].(*exprs.Path))
+			if err != nil {
+				return nil, err
+			}
+			if av, ok := docpath.Get(u.item, path); ok {
+				return av, nil
+			}
+			return u.operand(x.Args[1])
+		case "list_append":
+			first, err := u.operand(x.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			second, err := u.operand(x.Args[1])
+			if err != nil {
+				return nil, err
+			}
+			a, aok := first.(*types.AttributeValueMemberL)
+			b, bok := second.(*types.AttributeValueMemberL)
+			if !aok || !bok {
+				return nil, fmt.Errorf("dynamo: incorrect operand types for list_append: %s and %s", docpath.TypeName(first), docpath.TypeName(second))
+			}
+			list := make([]types.AttributeValue, 0, len(a.Value)+len(b.Value))
+			for _, v := range a.Value {
+				list = append(list, docpath.Clone(v))
+			}
+			for _, v := range b.Value {
+				list = append(list, docpath.Clone(v))
+			}
+			return &types.AttributeValueMemberL{Value: list}, nil
+		}
+	}
+	return nil, fmt.Errorf("dynamo: unexpected operand %T", op)
+}
+
+// removeBefore orders the paths of REMOVE actions by their first differing step:
+// names in order, and list indexes from highest to lowest, so removing an element
+// never shifts the index of another path.
+func removeBefore(a, b docpath.Path) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[i], b[i]
+		switch {
+		case x.IsIndex && y.IsIndex:
+			if x.Index != y.Index {
+				return x.Index > y.Index
+			}
+		case x.IsIndex != y.IsIndex:
+			return y.IsIndex
+		case x.Name != y.Name:
+			return x.Name < y.Name
+		}
+	}
+	return len(a) > len(b)
+}
+
+// checkOverlap returns an error if two actions refer to the same path,
+// or one's path is inside the other's, which DynamoDB doesn't allow.
+func checkOverlap(set []action, remove []docpath.Path, add, del []action) error {
+	var paths []docpath.Path
+	for _, acts := range [][]action{set, add, del} {
+		for _, act := range acts {
+			paths = append(paths, act.path)
+		}
+	}
+	paths = append(paths, remove...)
+	for i, a := range paths {
+		for _, b := range paths[i+1:] {
+			if hasPrefix(a, b) || hasPrefix(b, a) {
+				return fmt.Errorf("dynamo: two document paths overlap: %s and %s", a, b)
+			}
+		}
+	}
+	return nil
+}
+
+// hasPrefix reports whether path starts with prefix.
+func hasPrefix(path, prefix docpath.Path) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, step := range prefix {
+		if step != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package apply
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func testItem() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberN{Value: "42"},
+		"Count": &types.AttributeValueMemberN{Value: "1.5"},
+		"Tags":  &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"List": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "x"},
+			&types.AttributeValueMemberS{Value: "y"},
+			&types.AttributeValueMemberS{Value: "z"},
+		}},
+		"Map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"Inner": &types.AttributeValueMemberBOOL{Value: true},
+		}},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		args []interface{}
+		want func(item map[string]types.AttributeValue)
+	}{
+		{
+			name: "set arithmetic",
+			expr: "SET Count = Count + ?, ID = ID - ?",
+			args: []interface{}{1.25, 2},
+			want: func(item map[string]types.AttributeValue) {
+				item["Count"] = &types.AttributeValueMemberN{Value: "2.75"}
+				item["ID"] = &types.AttributeValueMemberN{Value: "40"}
+			},
+		},
+		{
+			name: "set nested",
+			expr: "SET Map.Other = ?, List[1] = ?, List[10] = ?",
+			args: []interface{}{"hi", "Y", "end"},
+			want: func(item map[string]types.AttributeValue) {
+				m := item["Map"].(*types.AttributeValueMemberM)
+				m.Value["Other"] = &types.AttributeValueMemberS{Value: "hi"}
+				item["List"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "x"},
+					&types.AttributeValueMemberS{Value: "Y"},
+					&types.AttributeValueMemberS{Value: "z"},
+					&types.AttributeValueMemberS{Value: "end"},
+				}}
+			},
+		},
+		{
+			name: "if_not_exists and list_append",
+			expr: "SET Created = if_not_exists(Created, ?), ID = if_not_exists(ID, ?), List = list_append(?, List)",
+			args: []interface{}{"now", 1, []string{"w"}},
+			want: func(item map[string]types.AttributeValue) {
+				item["Created"] = &types.AttributeValueMemberS{Value: "now"}
+				item["List"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "w"},
+					&types.AttributeValueMemberS{Value: "x"},
+					&types.AttributeValueMemberS{Value: "y"},
+					&types.AttributeValueMemberS{Value: "z"},
+				}}
+			},
+		},
+		{
+			name: "remove",
+			expr: "REMOVE List[0], List[2], Map.Inner, Missing",
+			want: func(item map[string]types.AttributeValue) {
+				item["List"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "y"},
+				}}
+				item["Map"] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}
+			},
+		},
+		{
+			name: "remove list elements around other paths",
+			expr: "REMOVE List[0], ID, List[2]",
+			want: func(item map[string]types.AttributeValue) {
+				delete(item, "ID")
+				item["List"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "y"},
+				}}
+			},
+		},
+		{
+			name: "add and delete",
+			expr: "ADD Count ?, New ?, Tags ? DELETE Gone ?",
+			args: []interface{}{-0.5, 3, &types.AttributeValueMemberSS{Value: []string{"c"}}, &types.AttributeValueMemberSS{Value: []string{"x"}}},
+			want: func(item map[string]types.AttributeValue) {
+				item["Count"] = &types.AttributeValueMemberN{Value: "1"}
+				item["New"] = &types.AttributeValueMemberN{Value: "3"}
+				item["Tags"] = &types.AttributeValueMemberSS{Value: []string{"a", "b", "c"}}
+			},
+		},
+		{
+			name: "delete from set",
+			expr: "delete Tags ? set Removed = ?",
+			args: []interface{}{&types.AttributeValueMemberSS{Value: []string{"b", "a"}}, true},
+			want: func(item map[string]types.AttributeValue) {
+				delete(item, "Tags")
+				item["Removed"] = &types.AttributeValueMemberBOOL{Value: true}
+			},
+		},
+	}
+	for _, tc := range tests {
+		item := testItem()
+		got, err := Update(item, tc.expr, tc.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(item, testItem()) {
+			t.Errorf("%s: original item was modified", tc.name)
+		}
+		want := testItem()
+		tc.want(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: bad result. want: %#v got: %#v", tc.name, want, got)
+		}
+	}
+}
+
+func TestUpdateInput(t *testing.T) {
+	got, err := UpdateInput(testItem(), "SET #c = #c + :one REMOVE #t",
+		map[string]string{"#c": "Count", "#t": "Tags"},
+		map[string]types.AttributeValue{":one": &types.AttributeValueMemberN{Value: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testItem()
+	want["Count"] = &types.AttributeValueMemberN{Value: "2.5"}
+	delete(want, "Tags")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad result. want: %#v got: %#v", want, got)
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		args []interface{}
+	}{
+		{"SET Count = Missing + ?", []interface{}{1}},
+		{"SET Count = ID + ?", []interface{}{"x"}},
+		{"SET Count = ?, Count = ?", []interface{}{1, 2}},
+		{"SET Map.Inner = ? REMOVE Map", []interface{}{false}},
+		{"SET List[0] = ? REMOVE List", []interface{}{"a"}},
+		{"SET Missing.Inner = ?", []interface{}{1}},
+		{"ADD Map ?", []interface{}{1}},
+		{"DELETE Tags ?", []interface{}{"a"}},
+		{"SET List = list_append(List, ?)", []interface{}{"a"}},
+	}
+	for _, tc := range tests {
+		if _, err := Update(testItem(), tc.expr, tc.args...); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.expr)
+		}
+	}
+}
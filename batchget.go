@@ -87,6 +87,18 @@ func (bg *BatchGet) add(keys []Keyed) {
 	}
 }
 
+// Items adds requests to get the items with the same hash and range keys as items.
+// Items must be structs whose key fields are tagged with hash and range, as in CreateTable.
+// The batch doesn't need to be given key names.
+func (bg *BatchGet) Items(items ...interface{}) *BatchGet {
+	for _, item := range items {
+		get := bg.batch.table.keyedGet(item)
+		bg.setError(get.err)
+		bg.reqs = append(bg.reqs, get)
+	}
+	return bg
+}
+
 // Consistent will, if on is true, make this batch use a strongly consistent read.
 // Reads are eventually consistent by default.
 // Strongly consistent reads are more resource-heavy than eventually consistent reads.
@@ -103,7 +115,7 @@ func (bg *BatchGet) ConsumedCapacity(cc *ConsumedCapacity) *BatchGet {
 
 // All executes this request and unmarshals all results to out, which must be a pointer to a slice.
 func (bg *BatchGet) All(out interface{}) error {
-	iter := newBGIter(bg, unmarshalAppend, bg.err)
+	iter := newBGIter(bg, bg.batch.table.db.decoder().unmarshalAppend, bg.err)
 	for iter.Next(out) {
 	}
 	return iter.Err()
@@ -111,7 +123,7 @@ func (bg *BatchGet) All(out interface{}) error {
 
 // AllWithContext executes this request and unmarshals all results to out, which must be a pointer to a slice.
 func (bg *BatchGet) AllWithContext(ctx context.Context, out interface{}) error {
-	iter := newBGIter(bg, unmarshalAppend, bg.err)
+	iter := newBGIter(bg, bg.batch.table.db.decoder().withContext(ctx).unmarshalAppend, bg.err)
 	for iter.NextWithContext(ctx, out) {
 	}
 	return iter.Err()
@@ -119,7 +131,7 @@ func (bg *BatchGet) AllWithContext(ctx context.Context, out interface{}) error {
 
 // Iter returns a results iterator for this batch.
 func (bg *BatchGet) Iter() Iter {
-	return newBGIter(bg, unmarshalItem, bg.err)
+	return newBGIter(bg, bg.batch.table.db.decoder().unmarshalItem, bg.err)
 }
 
 func (bg *BatchGet) input(start int) *dynamodb.BatchGetItemInput {
@@ -19,8 +19,10 @@ const maxWriteOps = 25
 type BatchWrite struct {
 	batch Batch
 	ops   []types.WriteRequest
-	err   error
-	cc    *ConsumedCapacity
+	// offloaded are the names of offloaded fields of put requests, by index in ops
+	offloaded map[int][]string
+	err       error
+	cc        *ConsumedCapacity
 }
 
 // Write creates a new batch write request, to which
@@ -35,8 +37,15 @@ func (b Batch) Write() *BatchWrite {
 // Put adds put operations for items to this batch.
 func (bw *BatchWrite) Put(items ...interface{}) *BatchWrite {
 	for _, item := range items {
-		encoded, err := marshalItem(item)
+		encoded, err := encodeItem(item, bw.batch.table.db.crypter())
 		bw.setError(err)
+		bw.batch.table.registry.discriminate(item, encoded)
+		if names := offloadedFields(item); len(names) > 0 {
+			if bw.offloaded == nil {
+				bw.offloaded = make(map[int][]string)
+			}
+			bw.offloaded[len(bw.ops)] = names
+		}
 		bw.ops = append(bw.ops, types.WriteRequest{PutRequest: &types.PutRequest{
 			Item: encoded,
 		}})
@@ -59,6 +68,20 @@ func (bw *BatchWrite) Delete(keys ...Keyed) *BatchWrite {
 	return bw
 }
 
+// DeleteItems adds delete operations for the items with the same hash and range keys as items.
+// Items must be structs whose key fields are tagged with hash and range, as in CreateTable.
+// The batch doesn't need to be given key names.
+func (bw *BatchWrite) DeleteItems(items ...interface{}) *BatchWrite {
+	for _, item := range items {
+		del := bw.batch.table.Remove(item)
+		bw.setError(del.err)
+		bw.ops = append(bw.ops, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: del.key(),
+		}})
+	}
+	return bw
+}
+
 // ConsumedCapacity will measure the throughput capacity consumed by this operation and add it to cc.
 func (bw *BatchWrite) ConsumedCapacity(cc *ConsumedCapacity) *BatchWrite {
 	bw.cc = cc
@@ -82,6 +105,18 @@ func (bw *BatchWrite) RunWithContext(ctx context.Context) (wrote int, err error)
 	if len(bw.ops) == 0 {
 		return 0, ErrNoInput
 	}
+	db := bw.batch.table.db
+	for i, op := range bw.ops {
+		if op.PutRequest == nil {
+			continue
+		}
+		if err := db.offloader().offloadItem(ctx, bw.batch.table.name, bw.offloaded[i], op.PutRequest.Item); err != nil {
+			return 0, err
+		}
+		if err := bw.batch.table.db.checkSize("item", itemSize(op.PutRequest.Item), MaxItemSize); err != nil {
+			return 0, err
+		}
+	}
 
 	// TODO: this could be made to be more efficient,
 	// by combining unprocessed items with the next request.
@@ -3,6 +3,8 @@ package dynamo
 import (
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 var (
@@ -102,6 +104,32 @@ func BenchmarkDecodeVeryComplexMap(b *testing.B) {
 	}
 }
 
+func BenchmarkDecodeScanPage(b *testing.B) {
+	page := make([]map[string]types.AttributeValue, 100)
+	for i := range page {
+		page[i], _ = marshalItem(veryComplexObject)
+	}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var out []fancyObject
+		for _, item := range page {
+			unmarshalAppend(item, &out)
+		}
+	}
+}
+
+func BenchmarkDecodeScanPageParallel(b *testing.B) {
+	item, _ := marshalItem(veryComplexObject)
+
+	b.RunParallel(func(pb *testing.PB) {
+		var out fancyObject
+		for pb.Next() {
+			unmarshalItem(item, &out)
+		}
+	})
+}
+
 type simpleObject struct {
 	User  int
 	Other string
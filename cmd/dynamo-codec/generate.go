@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is how a field is encoded by the generated code.
+type kind int
+
+const (
+	kindOther kind = iota // encoded with dynamo.Field
+	kindString
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindBytes
+)
+
+// basicKinds are the kinds of the predeclared types encoded directly.
+var basicKinds = map[string]kind{
+	"string": kindString,
+	"bool":   kindBool,
+	"int":    kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt, "rune": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint, "byte": kindUint,
+	"float32": kindFloat, "float64": kindFloat,
+}
+
+// model is a struct type to generate marshalers for.
+type model struct {
+	name   string
+	fields []*field
+}
+
+// field is an encoded struct field.
+type field struct {
+	name string // Go name
+	attr string
+	tag  string
+	// opts are the options of the dynamo struct tag, such as omitempty.
+	opts map[string]bool
+	kind kind
+	// typ is the Go type of basic kinds, or the element type of slices.
+	typ   string
+	ptr   bool
+	slice bool
+}
+
+// generate returns the source of a file declaring marshalers for the named struct types of the package in dir.
+// The file named skip, which holds the previously generated code, isn't read.
+func generate(dir string, names []string, skip string) ([]byte, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	structs := make(map[string]*ast.StructType)
+	found := make(map[string]bool)
+	for _, name := range pkg.GoFiles {
+		if name == skip {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				found[ts.Name.Name] = true
+				if st, ok := ts.Type.(*ast.StructType); ok && !ts.Assign.IsValid() {
+					structs[ts.Name.Name] = st
+				}
+			}
+		}
+	}
+
+	var models []*model
+	for _, name := range names {
+		st, ok := structs[name]
+		switch {
+		case !found[name]:
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg.Name)
+		case !ok:
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		fields, err := parseFields(st)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %v", name, err)
+		}
+		models = append(models, &model{name: name, fields: fields})
+	}
+	return writeFile(pkg.Name, models)
+}
+
+// parseFields returns the encoded fields of st, in the order the reflection-based encoder uses.
+func parseFields(st *ast.StructType) ([]*field, error) {
+	var fields []*field
+	pos := make(map[string]int)
+	for _, af := range st.Fields.List {
+		var tag string
+		if af.Tag != nil {
+			var err error
+			if tag, err = strconv.Unquote(af.Tag.Value); err != nil {
+				return nil, err
+			}
+		}
+		opts := strings.Split(reflect.StructTag(tag).Get("dynamo"), ",")
+		if opts[0] == "-" {
+			continue
+		}
+		if len(af.Names) == 0 {
+			return nil, fmt.Errorf("embedded field %s isn't supported", typeString(af.Type))
+		}
+		f := &field{tag: tag, opts: parseOptions(opts[1:])}
+		for _, opt := range []string{"inline", "compress", "encrypt", "offload"} {
+			if f.opts[opt] {
+				return nil, fmt.Errorf("field %s: %s isn't supported", af.Names[0].Name, opt)
+			}
+		}
+		f.classify(af.Type)
+		for _, ident := range af.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			f := *f
+			f.name = ident.Name
+			f.attr = opts[0]
+			if f.attr == "" {
+				f.attr = ident.Name
+			}
+			// like encoding/json, later fields with the same name win
+			if i, ok := pos[f.attr]; ok {
+				fields[i] = &f
+				continue
+			}
+			pos[f.attr] = len(fields)
+			fields = append(fields, &f)
+		}
+	}
+	return fields, nil
+}
+
+// parseOptions parses the options of a dynamo struct tag.
+// Like the reflection-based encoder, it stops at time=, whose layout may contain commas.
+func parseOptions(opts []string) map[string]bool {
+	m := make(map[string]bool, len(opts))
+	for _, opt := range opts {
+		if strings.HasPrefix(opt, "time=") {
+			break
+		}
+		m[opt] = true
+	}
+	return m
+}
+
+// classify sets the kind of f from its type expression.
+// Only predeclared types are encoded directly, because named types may have custom marshalers.
+func (f *field) classify(expr ast.Expr) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if k, ok := basicKinds[t.Name]; ok && t.Obj == nil {
+			f.kind, f.typ = k, t.Name
+		}
+	case *ast.StarExpr:
+		if id, ok := t.X.(*ast.Ident); ok && id.Obj == nil {
+			if k, ok := basicKinds[id.Name]; ok {
+				f.kind, f.typ, f.ptr = k, id.Name, true
+			}
+		}
+	case *ast.ArrayType:
+		id, ok := t.Elt.(*ast.Ident)
+		if t.Len != nil || !ok || id.Obj != nil {
+			return
+		}
+		switch k := basicKinds[id.Name]; {
+		case id.Name == "byte" || id.Name == "uint8":
+			f.kind = kindBytes
+		case k == kindString || isNumber(k):
+			f.kind, f.typ, f.slice = k, id.Name, true
+		}
+	}
+}
+
+func isNumber(k kind) bool {
+	return k == kindInt || k == kindUint || k == kindFloat
+}
+
+func typeString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// generator writes the marshalers of models.
+type generator struct {
+	buf bytes.Buffer
+	// strconv and math are true if the generated code uses those packages.
+	strconv bool
+	math    bool
+}
+
+func writeFile(pkg string, models []*model) ([]byte, error) {
+	g := new(generator)
+	for _, m := range models {
+		g.writeModel(m)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by dynamo-codec; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	if g.math {
+		fmt.Fprintf(&out, "%q\n", "math")
+	}
+	if g.strconv {
+		fmt.Fprintf(&out, "%q\n", "strconv")
+	}
+	if g.math || g.strconv {
+		fmt.Fprintf(&out, "\n")
+	}
+	fmt.Fprintf(&out, "%q\n\n%q\n)\n", "github.com/aws/aws-sdk-go-v2/service/dynamodb/types", "github.com/niltonkummer/dynamo")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) writeModel(m *model) {
+	fieldsVar := lowerFirst(m.name) + "Fields"
+
+	if len(m.fields) > 0 {
+		g.printf("\n// Attribute names of %s, for use in expressions.\nconst (\n", m.name)
+		for _, f := range m.fields {
+			g.printf("%s = %q\n", attrConst(m, f), f.attr)
+		}
+		g.printf(")\n")
+	}
+
+	g.printf("\n// %s are the encodings of the fields of %s, used for errors and fields of other types.\n", fieldsVar, m.name)
+	g.printf("var %s = struct {\n", fieldsVar)
+	for _, f := range m.fields {
+		g.printf("%s *dynamo.Field\n", f.name)
+	}
+	g.printf("}{\n")
+	for _, f := range m.fields {
+		g.printf("%s: dynamo.NewField(%q, %s),\n", f.name, f.name, tagLiteral(f.tag))
+	}
+	g.printf("}\n")
+
+	attrsVar := lowerFirst(m.name) + "Attrs"
+	g.printf("\n// %s are the attribute names of %s, passed to Decoder.Unknown.\n", attrsVar, m.name)
+	g.printf("var %s = []string{", attrsVar)
+	for i, f := range m.fields {
+		if i > 0 {
+			g.printf(", ")
+		}
+		g.printf("%s", attrConst(m, f))
+	}
+	g.printf("}\n")
+
+	g.printf("\n// MarshalDynamoItem implements dynamo.ItemMarshaler.\n")
+	g.printf("func (v %s) MarshalDynamoItem() (map[string]types.AttributeValue, error) {\n", m.name)
+	g.printf("item := make(map[string]types.AttributeValue, %d)\n", len(m.fields))
+	for _, f := range m.fields {
+		g.encode(m, f, fieldsVar)
+	}
+	g.printf("return item, nil\n}\n")
+
+	g.printf("\n// UnmarshalDynamoItem implements dynamo.ItemUnmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalDynamoItem(item map[string]types.AttributeValue) error {\n", m.name)
+	g.printf("return v.DecodeDynamoItem(item, nil)\n}\n")
+
+	g.printf("\n// DecodeDynamoItem implements dynamo.ItemDecoder.\n")
+	g.printf("func (v *%s) DecodeDynamoItem(item map[string]types.AttributeValue, d *dynamo.Decoder) error {\n", m.name)
+	g.printf("*v = %s{}\nvar errs []error\n", m.name)
+	for _, f := range m.fields {
+		g.decode(m, f, fieldsVar)
+	}
+	g.printf("errs = append(errs, d.Unknown(item, %s)...)\n", attrsVar)
+	g.printf("return dynamo.JoinDecodeErrors(errs)\n}\n")
+}
+
+const nullAV = "&types.AttributeValueMemberNULL{Value: true}"
+
+// encode writes the code adding f to item, which mirrors the rules of the reflection-based encoder.
+func (g *generator) encode(m *model, f *field, fieldsVar string) {
+	x := "v." + f.name
+	set := func(av string) string {
+		return fmt.Sprintf("item[%s] = %s\n", attrConst(m, f), av)
+	}
+	omit, null := f.opts["omitempty"], f.opts["null"]
+
+	switch {
+	case f.kind == kindOther:
+		g.printf("if av, err := %s.%s.Marshal(&%s); err != nil {\nreturn nil, err\n} else if av != nil {\n%s}\n",
+			fieldsVar, f.name, x, set("av"))
+	case f.kind == kindBytes:
+		g.printf("if len(%s) != 0 {\n%s}", x, set("&types.AttributeValueMemberB{Value: "+x+"}"))
+		if null && !omit {
+			g.printf(" else if %s == nil {\n%s}", x, set(nullAV))
+		}
+		if f.opts["allowempty"] {
+			if omit {
+				g.printf(" else if %s != nil {\n%s}", x, set("&types.AttributeValueMemberB{Value: []byte{}}"))
+			} else {
+				g.printf(" else {\n%s}", set("&types.AttributeValueMemberB{Value: []byte{}}"))
+			}
+		}
+		g.printf("\n")
+	case f.slice:
+		g.encodeSlice(f, x, set)
+	case f.ptr:
+		// omitempty only omits nil pointers, the value is encoded with the same options
+		g.printf("if %s != nil {\n", x)
+		g.encodeScalar(f, "*"+x, false, set)
+		g.printf("}")
+		if null && !omit {
+			g.printf(" else {\n%s}", set(nullAV))
+		}
+		g.printf("\n")
+	default:
+		g.encodeScalar(f, x, omit, set)
+	}
+}
+
+func (g *generator) encodeScalar(f *field, x string, omit bool, set func(string) string) {
+	av := g.scalarAV(f, x)
+	switch f.kind {
+	case kindString:
+		g.printf("if %s != \"\" {\n%s}", x, set(av))
+		switch {
+		case omit:
+		case f.opts["allowempty"]:
+			g.printf(" else {\n%s}", set(`&types.AttributeValueMemberS{Value: ""}`))
+		case f.opts["null"]:
+			g.printf(" else {\n%s}", set(nullAV))
+		}
+		g.printf("\n")
+	case kindBool:
+		if omit {
+			g.printf("if %s {\n%s}\n", x, set(av))
+		} else {
+			g.printf("%s", set(av))
+		}
+	default:
+		if omit {
+			g.printf("if %s != 0 {\n%s}\n", x, set(av))
+		} else {
+			g.printf("%s", set(av))
+		}
+	}
+}
+
+func (g *generator) encodeSlice(f *field, x string, set func(string) string) {
+	omit, null := f.opts["omitempty"], f.opts["null"]
+	g.printf("if len(%s) != 0 {\n", x)
+	switch {
+	case f.opts["set"] && f.kind == kindString && !f.opts["omitemptyelem"]:
+		g.printf("%s", set("&types.AttributeValueMemberSS{Value: append(make([]string, 0, len("+x+")), "+x+"...)}"))
+	case f.opts["set"]:
+		member := "&types.AttributeValueMemberNS{Value: s}"
+		if f.kind == kindString {
+			member = "&types.AttributeValueMemberSS{Value: s}"
+		}
+		g.printf("s := make([]string, 0, len(%s))\nfor _, e := range %s {\n", x, x)
+		if f.opts["omitemptyelem"] {
+			g.printf("if e == %s {\ncontinue\n}\n", zero(f.kind))
+		}
+		if f.kind == kindString {
+			g.printf("s = append(s, e)\n}\n")
+		} else {
+			g.printf("s = append(s, %s)\n}\n", g.numberString(f, "e"))
+		}
+		// sets can't be empty
+		if f.opts["omitemptyelem"] {
+			g.printf("if len(s) != 0 {\n%s}\n", set(member))
+		} else {
+			g.printf("%s", set(member))
+		}
+	default:
+		// elements of lists keep their position, so empty strings are kept unless omitemptyelem is set
+		g.printf("avs := make([]types.AttributeValue, 0, len(%s))\nfor _, e := range %s {\n", x, x)
+		if f.kind == kindString && f.opts["omitemptyelem"] {
+			g.printf("if e == \"\" {\ncontinue\n}\n")
+		}
+		g.printf("avs = append(avs, %s)\n}\n", g.scalarAV(f, "e"))
+		if omit {
+			g.printf("if len(avs) != 0 {\n%s}\n", set("&types.AttributeValueMemberL{Value: avs}"))
+		} else {
+			g.printf("%s", set("&types.AttributeValueMemberL{Value: avs}"))
+		}
+	}
+	g.printf("}")
+	if null && !omit {
+		g.printf(" else if %s == nil {\n%s}", x, set(nullAV))
+	}
+	if !f.opts["set"] && !omit {
+		g.printf(" else {\n%s}", set("&types.AttributeValueMemberL{Value: []types.AttributeValue{}}"))
+	}
+	g.printf("\n")
+}
+
+// scalarAV returns an expression encoding x, of a basic kind.
+func (g *generator) scalarAV(f *field, x string) string {
+	switch f.kind {
+	case kindString:
+		return "&types.AttributeValueMemberS{Value: " + x + "}"
+	case kindBool:
+		return "&types.AttributeValueMemberBOOL{Value: " + x + "}"
+	}
+	return "&types.AttributeValueMemberN{Value: " + g.numberString(f, x) + "}"
+}
+
+// numberString returns an expression formatting the number x.
+func (g *generator) numberString(f *field, x string) string {
+	g.strconv = true
+	switch f.kind {
+	case kindInt:
+		return "strconv.FormatInt(" + convert("int64", f.typ, x) + ", 10)"
+	case kindUint:
+		return "strconv.FormatUint(" + convert("uint64", f.typ, x) + ", 10)"
+	}
+	return "strconv.FormatFloat(" + convert("float64", f.typ, x) + ", 'f', -1, 64)"
+}
+
+// decode writes the code unmarshaling f from item.
+// Values of unexpected types, and numbers that don't fit their field, are left to dynamo.Field,
+// which decodes them with the caller's DecodeOptions and returns the same errors as the reflection-based decoder.
+func (g *generator) decode(m *model, f *field, fieldsVar string) {
+	x := "v." + f.name
+	fallback := fmt.Sprintf("if err := %s.%s.Decode(av, &%s, d); err != nil {\nerrs = append(errs, err)\n}\n", fieldsVar, f.name, x)
+	g.printf("if av, ok := item[%s]; ok {\n", attrConst(m, f))
+	if f.kind == kindOther {
+		g.printf("%s}\n", fallback)
+		return
+	}
+
+	g.printf("done := false\nswitch x := av.(type) {\n")
+	switch {
+	case f.kind == kindBytes:
+		g.printf("case *types.AttributeValueMemberB:\n%s, done = x.Value, true\n", x)
+	case f.slice:
+		g.decodeSlice(f, x)
+	case f.kind == kindString || f.kind == kindBool:
+		g.printf("case %s:\n", memberType(f.kind))
+		if f.ptr {
+			g.printf("s := x.Value\n%s, done = &s, true\n", x)
+		} else {
+			g.printf("%s, done = x.Value, true\n", x)
+		}
+	default:
+		g.printf("case *types.AttributeValueMemberN:\n")
+		g.printf("if n, err := %s; err == nil%s {\n", g.parseNumber(f, "x.Value"), g.fits(f, "n"))
+		if f.ptr {
+			g.printf("s := %s\n%s, done = &s, true\n", convert(f.typ, parsedType(f.kind), "n"), x)
+		} else {
+			g.printf("%s, done = %s, true\n", x, convert(f.typ, parsedType(f.kind), "n"))
+		}
+		g.printf("}\n")
+	}
+	g.printf("}\nif !done {\n%s}\n}\n", fallback)
+}
+
+func (g *generator) decodeSlice(f *field, x string) {
+	if f.kind == kindString {
+		g.printf("case *types.AttributeValueMemberSS:\n%s, done = append(make([]string, 0, len(x.Value)), x.Value...), true\n", x)
+	} else {
+		g.printf("case *types.AttributeValueMemberNS:\n")
+		g.printf("s := make([]%s, 0, len(x.Value))\nfor _, e := range x.Value {\n", f.typ)
+		g.printf("n, err := %s\nif err != nil%s {\ns = nil\nbreak\n}\n", g.parseNumber(f, "e"), g.overflows(f, "n"))
+		g.printf("s = append(s, %s)\n}\n%s, done = s, s != nil\n", convert(f.typ, parsedType(f.kind), "n"), x)
+	}
+
+	g.printf("case *types.AttributeValueMemberL:\n")
+	g.printf("s := make([]%s, 0, len(x.Value))\nfor _, e := range x.Value {\n", f.typ)
+	g.printf("ev, ok := e.(%s)\nif !ok {\ns = nil\nbreak\n}\n", memberType(f.kind))
+	if f.kind == kindString {
+		g.printf("s = append(s, ev.Value)\n")
+	} else {
+		g.printf("n, err := %s\nif err != nil%s {\ns = nil\nbreak\n}\n", g.parseNumber(f, "ev.Value"), g.overflows(f, "n"))
+		g.printf("s = append(s, %s)\n", convert(f.typ, parsedType(f.kind), "n"))
+	}
+	g.printf("}\n%s, done = s, s != nil\n", x)
+}
+
+// parseNumber returns an expression parsing the string s like the reflection-based decoder does.
+// It fails for integers that overflow the type of f, which are left to dynamo.Field.
+func (g *generator) parseNumber(f *field, s string) string {
+	g.strconv = true
+	switch f.kind {
+	case kindInt:
+		return "strconv.ParseInt(" + s + ", 10, " + bitSize(f.typ) + ")"
+	case kindUint:
+		return "strconv.ParseUint(" + s + ", 10, " + bitSize(f.typ) + ")"
+	}
+	return "strconv.ParseFloat(" + s + ", 64)"
+}
+
+// bitSize returns the bit size argument of strconv for the integer type typ.
+func bitSize(typ string) string {
+	switch typ {
+	case "int8", "uint8", "byte":
+		return "8"
+	case "int16", "uint16":
+		return "16"
+	case "int32", "uint32", "rune":
+		return "32"
+	case "int64", "uint64":
+		return "64"
+	}
+	return "0"
+}
+
+// fits returns a condition, starting with &&, that the parsed float32 n fits f.
+// Other numbers are checked by parseNumber.
+func (g *generator) fits(f *field, n string) string {
+	if f.typ != "float32" {
+		return ""
+	}
+	g.math = true
+	return " && math.Abs(" + n + ") <= math.MaxFloat32"
+}
+
+// overflows is the negation of fits, starting with ||.
+func (g *generator) overflows(f *field, n string) string {
+	if f.typ != "float32" {
+		return ""
+	}
+	g.math = true
+	return " || math.Abs(" + n + ") > math.MaxFloat32"
+}
+
+// parsedType is the type returned by parseNumber.
+func parsedType(k kind) string {
+	switch k {
+	case kindInt:
+		return "int64"
+	case kindUint:
+		return "uint64"
+	}
+	return "float64"
+}
+
+func memberType(k kind) string {
+	switch k {
+	case kindString:
+		return "*types.AttributeValueMemberS"
+	case kindBool:
+		return "*types.AttributeValueMemberBOOL"
+	}
+	return "*types.AttributeValueMemberN"
+}
+
+func zero(k kind) string {
+	if k == kindString {
+		return `""`
+	}
+	return "0"
+}
+
+// convert returns x, of type from, converted to type to.
+func convert(to, from, x string) string {
+	if to == from {
+		return x
+	}
+	return to + "(" + x + ")"
+}
+
+// attrConst returns the name of the constant holding the attribute name of f, such as OrderAttrUserID.
+func attrConst(m *model, f *field) string {
+	return m.name + "Attr" + f.name
+}
+
+// tagLiteral returns a Go string literal for tag, preferring raw strings like struct tags.
+func tagLiteral(tag string) string {
+	if strings.ContainsRune(tag, '`') {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// lowerFirst returns name with its leading upper case word in lower case, such as urlRecord for URLRecord.
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
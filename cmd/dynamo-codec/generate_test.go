@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the generated code of the example package")
+
+// TestGenerate checks that the generated code of the example package, which is tested against
+// the reflection-based encoder, is up to date.
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	path := filepath.Join(dir, "order_dynamo.go")
+	src, err := generate(dir, []string{"Order", "Line"}, filepath.Base(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(want) {
+		t.Errorf("%s is out of date, run go generate or go test -update", path)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"type Other struct{}", "type Order not found"},
+		{"type Order []string", "type Order is not a struct"},
+		{"type Base struct{}\ntype Order struct{ Base }", "embedded field Base isn't supported"},
+		{"type Order struct{ A struct{} `dynamo:\",inline\"` }", "field A: inline isn't supported"},
+		{"type Order struct{ A string `dynamo:\",encrypt\"` }", "field A: encrypt isn't supported"},
+	}
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "dynamo-codec")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		if err := ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte("package model\n\n"+test.src+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err = generate(dir, []string{"Order"}, "order_dynamo.go")
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: want error %q, got %v", test.src, test.want, err)
+		}
+	}
+}
+
+func TestLowerFirst(t *testing.T) {
+	for in, want := range map[string]string{
+		"Order":     "order",
+		"URLRecord": "urlRecord",
+		"ID":        "id",
+		"order":     "order",
+	} {
+		if got := lowerFirst(in); got != want {
+			t.Errorf("lowerFirst(%q): want %s, got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package example has models with marshalers generated by dynamo-codec,
+// which are tested against the reflection-based encoder.
+package example
+
+import (
+	"strings"
+	"time"
+)
+
+//go:generate go run github.com/niltonkummer/dynamo/cmd/dynamo-codec -type Order,Line
+
+// Order has fields of every kind dynamo-codec encodes directly, and some it doesn't.
+type Order struct {
+	UserID string `dynamo:"user_id,hash"`
+	ID     int64  `dynamo:",range"`
+
+	Name     string `dynamo:",omitempty"`
+	Note     string `dynamo:",allowempty"`
+	Comment  string `dynamo:",null"`
+	Count    int
+	Small    int8 `dynamo:",omitempty"`
+	Char     rune
+	Size     uint32
+	Big      uint64 `dynamo:",omitempty"`
+	Price    float64
+	Ratio    float32 `dynamo:",omitempty"`
+	Paid     bool
+	Gift     bool `dynamo:",omitempty"`
+	Data     []byte
+	Blob     []byte `dynamo:",allowempty"`
+	Raw      []byte `dynamo:",null"`
+	Packed   []byte `dynamo:",omitempty,allowempty"`
+	Ref      *string
+	RefEmpty *string `dynamo:",allowempty"`
+	RefNull  *int    `dynamo:",null"`
+	RefBool  *bool   `dynamo:",omitempty"`
+	RefRatio *float32
+
+	Tags    []string  `dynamo:",set"`
+	Labels  []string  `dynamo:",set,omitemptyelem"`
+	Scores  []int     `dynamo:",set,null"`
+	Weights []float32 `dynamo:",set,omitemptyelem"`
+	Notes   []string
+	Remarks []string  `dynamo:",omitemptyelem,omitempty"`
+	Counts  []uint16  `dynamo:",null"`
+	Sizes   []float64 `dynamo:",omitempty"`
+
+	Status  Status
+	Created time.Time         `dynamo:",unixtime"`
+	Shipped *time.Time        `dynamo:",omitempty,time=2006-01-02"`
+	Updated time.Time         `dynamo:",omitempty"`
+	Meta    map[string]string `dynamo:",omitempty"`
+	Flags   map[string]bool   `dynamo:",set"`
+	Lines   []Line
+	Extra   interface{}
+	Version int `dynamo:",version"`
+
+	A, B    string `dynamo:",omitempty"`
+	Dup     string `dynamo:"Name"`
+	Ignored string `dynamo:"-"`
+	private string
+}
+
+// Line is an item of an order.
+type Line struct {
+	SKU   string `dynamo:"sku"`
+	Qty   int    `dynamo:"qty,omitempty"`
+	Price float64
+}
+
+// Status has a custom text encoding.
+type Status int
+
+const (
+	Pending Status = iota
+	Shipped
+)
+
+func (s Status) MarshalText() ([]byte, error) {
+	if s == Shipped {
+		return []byte("shipped"), nil
+	}
+	return []byte("pending"), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	if strings.EqualFold(string(text), "shipped") {
+		*s = Shipped
+	} else {
+		*s = Pending
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package example
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// reflected has the fields of Order without its generated methods,
+// so it is encoded by reflection.
+type reflected Order
+
+func str(s string) *string { return &s }
+
+var orders = map[string]Order{
+	"zero": {},
+	"full": {
+		UserID: "u1", ID: 42, Name: "name", Note: "note", Comment: "comment",
+		Count: -3, Small: -8, Char: 'x', Size: 7, Big: math.MaxUint64, Price: 1.25, Ratio: 0.1,
+		Paid: true, Gift: true, Data: []byte{1}, Blob: []byte{2}, Raw: []byte{3}, Packed: []byte{4},
+		Ref: str("ref"), RefEmpty: str("x"), RefNull: new(int), RefBool: new(bool), RefRatio: new(float32),
+		Tags: []string{"a", "b"}, Labels: []string{"", "c"}, Scores: []int{1, -2}, Weights: []float32{0, 0.5},
+		Notes: []string{"", "n"}, Remarks: []string{"", "r"}, Counts: []uint16{0, 9}, Sizes: []float64{math.Inf(1)},
+		Status: Shipped, Created: time.Unix(1600000000, 0), Updated: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		Meta: map[string]string{"k": "v"}, Flags: map[string]bool{"on": true, "off": false},
+		Lines: []Line{{SKU: "s", Qty: 2, Price: 9.99}, {}}, Extra: []interface{}{"x", 1.5},
+		Version: 3, A: "a", B: "b", Dup: "dup", Ignored: "ignored", private: "private",
+	},
+	"empty": {
+		Data: []byte{}, Blob: []byte{}, Raw: []byte{}, Packed: []byte{},
+		Ref: str(""), RefEmpty: str(""),
+		Tags: []string{}, Labels: []string{""}, Scores: []int{}, Weights: []float32{0},
+		Notes: []string{}, Remarks: []string{""}, Counts: []uint16{}, Sizes: []float64{},
+		Shipped: new(time.Time), Meta: map[string]string{}, Lines: []Line{},
+	},
+	"odd": {
+		Price: math.NaN(), Ratio: float32(math.Copysign(0, -1)), Sizes: []float64{math.NaN()},
+		Shipped: func() *time.Time { t := time.Date(2021, 5, 6, 0, 0, 0, 0, time.UTC); return &t }(),
+	},
+}
+
+func TestMarshalEquivalence(t *testing.T) {
+	for name, order := range orders {
+		want, err := dynamo.MarshalItem(reflected(order))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		got, err := order.MarshalDynamoItem()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: bad item.\nwant: %#v\ngot:  %#v", name, want, got)
+		}
+	}
+}
+
+func TestUnmarshalEquivalence(t *testing.T) {
+	s := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	n := func(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+	null := &types.AttributeValueMemberNULL{Value: true}
+	items := map[string]map[string]types.AttributeValue{
+		"empty": {},
+		"null": {
+			"user_id": null, "ID": null, "Count": null, "Paid": null, "Data": null, "Ref": null,
+			"RefNull": null, "Tags": null, "Scores": null, "Notes": null, "Created": null, "Shipped": null,
+		},
+		"swapped": {
+			"Tags":    &types.AttributeValueMemberL{Value: []types.AttributeValue{s("a"), s("b")}},
+			"Notes":   &types.AttributeValueMemberSS{Value: []string{"x"}},
+			"Scores":  &types.AttributeValueMemberL{Value: []types.AttributeValue{n("1"), n("2")}},
+			"Counts":  &types.AttributeValueMemberNS{Value: []string{"3"}},
+			"Weights": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			"Data":    &types.AttributeValueMemberL{Value: []types.AttributeValue{n("1")}},
+			"Small":   n("300"),
+		},
+		"invalid": {
+			"user_id":  n("1"),
+			"ID":       n("1.5"),
+			"Price":    n("abc"),
+			"Paid":     s("true"),
+			"RefRatio": s("x"),
+			"Ref":      &types.AttributeValueMemberBOOL{Value: true},
+			"Size":     n("-1"),
+			"Tags":     &types.AttributeValueMemberL{Value: []types.AttributeValue{s("a"), null}},
+			"Scores":   &types.AttributeValueMemberNS{Value: []string{"1", "x"}},
+			"Sizes":    &types.AttributeValueMemberL{Value: []types.AttributeValue{s("1")}},
+			"Created":  s("now"),
+			"Lines":    &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"qty": s("1")}}}},
+			"Unknown":  s("x"),
+		},
+	}
+	for name, order := range orders {
+		item, err := dynamo.MarshalItem(reflected(order))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		items["order "+name] = item
+	}
+
+	for name, item := range items {
+		var want reflected
+		wantErr := dynamo.UnmarshalItem(item, &want)
+		got := Order{Ignored: "stale", Tags: []string{"stale"}}
+		gotErr := got.UnmarshalDynamoItem(item)
+		if (wantErr == nil) != (gotErr == nil) || wantErr != nil && wantErr.Error() != gotErr.Error() {
+			t.Errorf("%s: bad error. want: %v, got: %v", name, wantErr, gotErr)
+		}
+		if wantErr != nil && !reflect.DeepEqual(gotErr, wantErr) {
+			t.Errorf("%s: bad error.\nwant: %#v\ngot:  %#v", name, wantErr, gotErr)
+		}
+		// NaN never equals itself
+		if !reflect.DeepEqual(Order(want), got) && name != "order odd" {
+			t.Errorf("%s: bad result.\nwant: %#v\ngot:  %#v", name, want, got)
+		}
+	}
+}
+
+func TestDecodeOptionsEquivalence(t *testing.T) {
+	s := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	n := func(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+	items := map[string]map[string]types.AttributeValue{
+		"overflow": {
+			"Small":    n("300"),
+			"Char":     n("3000000000"),
+			"Size":     n("5000000000"),
+			"Ratio":    n("1e300"),
+			"RefRatio": n("-1e39"),
+			"Weights":  &types.AttributeValueMemberNS{Value: []string{"1", "1e300"}},
+			"Counts":   &types.AttributeValueMemberL{Value: []types.AttributeValue{n("70000")}},
+			"Lines":    &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"qty": n("1e3")}}}},
+		},
+		"implicit time": {
+			"Updated": n("1600000000"),
+		},
+		"unknown": {
+			"user_id": s("u1"),
+			"Unknown": s("x"),
+			"Lines":   &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"sku": s("s"), "Other": s("x")}}}},
+		},
+	}
+	for name, order := range orders {
+		item, err := dynamo.MarshalItem(reflected(order))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		items["order "+name] = item
+	}
+
+	options := map[string]dynamo.DecodeOptions{
+		"default": {},
+		"strict":  {Strict: true},
+		"unknown": {DisallowUnknown: true},
+		"all":     {Strict: true, DisallowUnknown: true},
+	}
+	for optName, opts := range options {
+		for name, item := range items {
+			var want reflected
+			wantErr := dynamo.UnmarshalItemWithOptions(item, &want, opts)
+			var got Order
+			gotErr := dynamo.UnmarshalItemWithOptions(item, &got, opts)
+			if !reflect.DeepEqual(gotErr, wantErr) {
+				t.Errorf("%s %s: bad error.\nwant: %v\ngot:  %v", optName, name, wantErr, gotErr)
+			}
+			if !reflect.DeepEqual(Order(want), got) && name != "order odd" {
+				t.Errorf("%s %s: bad result.\nwant: %#v\ngot:  %#v", optName, name, want, got)
+			}
+		}
+	}
+
+	// the options must have had an effect for the comparison to mean anything
+	var order Order
+	if err := dynamo.UnmarshalItemWithOptions(items["overflow"], &order, options["strict"]); err == nil {
+		t.Error("expected overflow errors with Strict")
+	} else if de, ok := err.(*dynamo.DecodeError); !ok || len(de.Errors) != 8 {
+		t.Errorf("want 8 overflow errors, got %v", err)
+	}
+	if err := dynamo.UnmarshalItemWithOptions(items["unknown"], &order, options["unknown"]); err == nil {
+		t.Error("expected unknown attribute errors with DisallowUnknown")
+	} else if de, ok := err.(*dynamo.DecodeError); !ok || len(de.Errors) != 2 {
+		t.Errorf("want 2 unknown attribute errors, got %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	order := orders["full"]
+	item, err := dynamo.MarshalItem(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Order
+	if err := dynamo.UnmarshalItem(item, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != order.UserID || got.Big != order.Big || got.Status != order.Status ||
+		!got.Created.Equal(order.Created) || len(got.Lines) != 2 || got.Lines[0] != order.Lines[0] {
+		t.Errorf("bad round trip: %#v", got)
+	}
+}
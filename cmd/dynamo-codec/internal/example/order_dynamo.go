@@ -0,0 +1,1074 @@
+// Code generated by dynamo-codec; DO NOT EDIT.
+
+package example
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// Attribute names of Order, for use in expressions.
+const (
+	OrderAttrUserID   = "user_id"
+	OrderAttrID       = "ID"
+	OrderAttrDup      = "Name"
+	OrderAttrNote     = "Note"
+	OrderAttrComment  = "Comment"
+	OrderAttrCount    = "Count"
+	OrderAttrSmall    = "Small"
+	OrderAttrChar     = "Char"
+	OrderAttrSize     = "Size"
+	OrderAttrBig      = "Big"
+	OrderAttrPrice    = "Price"
+	OrderAttrRatio    = "Ratio"
+	OrderAttrPaid     = "Paid"
+	OrderAttrGift     = "Gift"
+	OrderAttrData     = "Data"
+	OrderAttrBlob     = "Blob"
+	OrderAttrRaw      = "Raw"
+	OrderAttrPacked   = "Packed"
+	OrderAttrRef      = "Ref"
+	OrderAttrRefEmpty = "RefEmpty"
+	OrderAttrRefNull  = "RefNull"
+	OrderAttrRefBool  = "RefBool"
+	OrderAttrRefRatio = "RefRatio"
+	OrderAttrTags     = "Tags"
+	OrderAttrLabels   = "Labels"
+	OrderAttrScores   = "Scores"
+	OrderAttrWeights  = "Weights"
+	OrderAttrNotes    = "Notes"
+	OrderAttrRemarks  = "Remarks"
+	OrderAttrCounts   = "Counts"
+	OrderAttrSizes    = "Sizes"
+	OrderAttrStatus   = "Status"
+	OrderAttrCreated  = "Created"
+	OrderAttrShipped  = "Shipped"
+	OrderAttrUpdated  = "Updated"
+	OrderAttrMeta     = "Meta"
+	OrderAttrFlags    = "Flags"
+	OrderAttrLines    = "Lines"
+	OrderAttrExtra    = "Extra"
+	OrderAttrVersion  = "Version"
+	OrderAttrA        = "A"
+	OrderAttrB        = "B"
+)
+
+// orderFields are the encodings of the fields of Order, used for errors and fields of other types.
+var orderFields = struct {
+	UserID   *dynamo.Field
+	ID       *dynamo.Field
+	Dup      *dynamo.Field
+	Note     *dynamo.Field
+	Comment  *dynamo.Field
+	Count    *dynamo.Field
+	Small    *dynamo.Field
+	Char     *dynamo.Field
+	Size     *dynamo.Field
+	Big      *dynamo.Field
+	Price    *dynamo.Field
+	Ratio    *dynamo.Field
+	Paid     *dynamo.Field
+	Gift     *dynamo.Field
+	Data     *dynamo.Field
+	Blob     *dynamo.Field
+	Raw      *dynamo.Field
+	Packed   *dynamo.Field
+	Ref      *dynamo.Field
+	RefEmpty *dynamo.Field
+	RefNull  *dynamo.Field
+	RefBool  *dynamo.Field
+	RefRatio *dynamo.Field
+	Tags     *dynamo.Field
+	Labels   *dynamo.Field
+	Scores   *dynamo.Field
+	Weights  *dynamo.Field
+	Notes    *dynamo.Field
+	Remarks  *dynamo.Field
+	Counts   *dynamo.Field
+	Sizes    *dynamo.Field
+	Status   *dynamo.Field
+	Created  *dynamo.Field
+	Shipped  *dynamo.Field
+	Updated  *dynamo.Field
+	Meta     *dynamo.Field
+	Flags    *dynamo.Field
+	Lines    *dynamo.Field
+	Extra    *dynamo.Field
+	Version  *dynamo.Field
+	A        *dynamo.Field
+	B        *dynamo.Field
+}{
+	UserID:   dynamo.NewField("UserID", `dynamo:"user_id,hash"`),
+	ID:       dynamo.NewField("ID", `dynamo:",range"`),
+	Dup:      dynamo.NewField("Dup", `dynamo:"Name"`),
+	Note:     dynamo.NewField("Note", `dynamo:",allowempty"`),
+	Comment:  dynamo.NewField("Comment", `dynamo:",null"`),
+	Count:    dynamo.NewField("Count", ``),
+	Small:    dynamo.NewField("Small", `dynamo:",omitempty"`),
+	Char:     dynamo.NewField("Char", ``),
+	Size:     dynamo.NewField("Size", ``),
+	Big:      dynamo.NewField("Big", `dynamo:",omitempty"`),
+	Price:    dynamo.NewField("Price", ``),
+	Ratio:    dynamo.NewField("Ratio", `dynamo:",omitempty"`),
+	Paid:     dynamo.NewField("Paid", ``),
+	Gift:     dynamo.NewField("Gift", `dynamo:",omitempty"`),
+	Data:     dynamo.NewField("Data", ``),
+	Blob:     dynamo.NewField("Blob", `dynamo:",allowempty"`),
+	Raw:      dynamo.NewField("Raw", `dynamo:",null"`),
+	Packed:   dynamo.NewField("Packed", `dynamo:",omitempty,allowempty"`),
+	Ref:      dynamo.NewField("Ref", ``),
+	RefEmpty: dynamo.NewField("RefEmpty", `dynamo:",allowempty"`),
+	RefNull:  dynamo.NewField("RefNull", `dynamo:",null"`),
+	RefBool:  dynamo.NewField("RefBool", `dynamo:",omitempty"`),
+	RefRatio: dynamo.NewField("RefRatio", ``),
+	Tags:     dynamo.NewField("Tags", `dynamo:",set"`),
+	Labels:   dynamo.NewField("Labels", `dynamo:",set,omitemptyelem"`),
+	Scores:   dynamo.NewField("Scores", `dynamo:",set,null"`),
+	Weights:  dynamo.NewField("Weights", `dynamo:",set,omitemptyelem"`),
+	Notes:    dynamo.NewField("Notes", ``),
+	Remarks:  dynamo.NewField("Remarks", `dynamo:",omitemptyelem,omitempty"`),
+	Counts:   dynamo.NewField("Counts", `dynamo:",null"`),
+	Sizes:    dynamo.NewField("Sizes", `dynamo:",omitempty"`),
+	Status:   dynamo.NewField("Status", ``),
+	Created:  dynamo.NewField("Created", `dynamo:",unixtime"`),
+	Shipped:  dynamo.NewField("Shipped", `dynamo:",omitempty,time=2006-01-02"`),
+	Updated:  dynamo.NewField("Updated", `dynamo:",omitempty"`),
+	Meta:     dynamo.NewField("Meta", `dynamo:",omitempty"`),
+	Flags:    dynamo.NewField("Flags", `dynamo:",set"`),
+	Lines:    dynamo.NewField("Lines", ``),
+	Extra:    dynamo.NewField("Extra", ``),
+	Version:  dynamo.NewField("Version", `dynamo:",version"`),
+	A:        dynamo.NewField("A", `dynamo:",omitempty"`),
+	B:        dynamo.NewField("B", `dynamo:",omitempty"`),
+}
+
+// orderAttrs are the attribute names of Order, passed to Decoder.Unknown.
+var orderAttrs = []string{OrderAttrUserID, OrderAttrID, OrderAttrDup, OrderAttrNote, OrderAttrComment, OrderAttrCount, OrderAttrSmall, OrderAttrChar, OrderAttrSize, OrderAttrBig, OrderAttrPrice, OrderAttrRatio, OrderAttrPaid, OrderAttrGift, OrderAttrData, OrderAttrBlob, OrderAttrRaw, OrderAttrPacked, OrderAttrRef, OrderAttrRefEmpty, OrderAttrRefNull, OrderAttrRefBool, OrderAttrRefRatio, OrderAttrTags, OrderAttrLabels, OrderAttrScores, OrderAttrWeights, OrderAttrNotes, OrderAttrRemarks, OrderAttrCounts, OrderAttrSizes, OrderAttrStatus, OrderAttrCreated, OrderAttrShipped, OrderAttrUpdated, OrderAttrMeta, OrderAttrFlags, OrderAttrLines, OrderAttrExtra, OrderAttrVersion, OrderAttrA, OrderAttrB}
+
+// MarshalDynamoItem implements dynamo.ItemMarshaler.
+func (v Order) MarshalDynamoItem() (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, 42)
+	if v.UserID != "" {
+		item[OrderAttrUserID] = &types.AttributeValueMemberS{Value: v.UserID}
+	}
+	item[OrderAttrID] = &types.AttributeValueMemberN{Value: strconv.FormatInt(v.ID, 10)}
+	if v.Dup != "" {
+		item[OrderAttrDup] = &types.AttributeValueMemberS{Value: v.Dup}
+	}
+	if v.Note != "" {
+		item[OrderAttrNote] = &types.AttributeValueMemberS{Value: v.Note}
+	} else {
+		item[OrderAttrNote] = &types.AttributeValueMemberS{Value: ""}
+	}
+	if v.Comment != "" {
+		item[OrderAttrComment] = &types.AttributeValueMemberS{Value: v.Comment}
+	} else {
+		item[OrderAttrComment] = &types.AttributeValueMemberNULL{Value: true}
+	}
+	item[OrderAttrCount] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(v.Count), 10)}
+	if v.Small != 0 {
+		item[OrderAttrSmall] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(v.Small), 10)}
+	}
+	item[OrderAttrChar] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(v.Char), 10)}
+	item[OrderAttrSize] = &types.AttributeValueMemberN{Value: strconv.FormatUint(uint64(v.Size), 10)}
+	if v.Big != 0 {
+		item[OrderAttrBig] = &types.AttributeValueMemberN{Value: strconv.FormatUint(v.Big, 10)}
+	}
+	item[OrderAttrPrice] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(v.Price, 'f', -1, 64)}
+	if v.Ratio != 0 {
+		item[OrderAttrRatio] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(float64(v.Ratio), 'f', -1, 64)}
+	}
+	item[OrderAttrPaid] = &types.AttributeValueMemberBOOL{Value: v.Paid}
+	if v.Gift {
+		item[OrderAttrGift] = &types.AttributeValueMemberBOOL{Value: v.Gift}
+	}
+	if len(v.Data) != 0 {
+		item[OrderAttrData] = &types.AttributeValueMemberB{Value: v.Data}
+	}
+	if len(v.Blob) != 0 {
+		item[OrderAttrBlob] = &types.AttributeValueMemberB{Value: v.Blob}
+	} else {
+		item[OrderAttrBlob] = &types.AttributeValueMemberB{Value: []byte{}}
+	}
+	if len(v.Raw) != 0 {
+		item[OrderAttrRaw] = &types.AttributeValueMemberB{Value: v.Raw}
+	} else if v.Raw == nil {
+		item[OrderAttrRaw] = &types.AttributeValueMemberNULL{Value: true}
+	}
+	if len(v.Packed) != 0 {
+		item[OrderAttrPacked] = &types.AttributeValueMemberB{Value: v.Packed}
+	} else if v.Packed != nil {
+		item[OrderAttrPacked] = &types.AttributeValueMemberB{Value: []byte{}}
+	}
+	if v.Ref != nil {
+		if *v.Ref != "" {
+			item[OrderAttrRef] = &types.AttributeValueMemberS{Value: *v.Ref}
+		}
+	}
+	if v.RefEmpty != nil {
+		if *v.RefEmpty != "" {
+			item[OrderAttrRefEmpty] = &types.AttributeValueMemberS{Value: *v.RefEmpty}
+		} else {
+			item[OrderAttrRefEmpty] = &types.AttributeValueMemberS{Value: ""}
+		}
+	}
+	if v.RefNull != nil {
+		item[OrderAttrRefNull] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(*v.RefNull), 10)}
+	} else {
+		item[OrderAttrRefNull] = &types.AttributeValueMemberNULL{Value: true}
+	}
+	if v.RefBool != nil {
+		item[OrderAttrRefBool] = &types.AttributeValueMemberBOOL{Value: *v.RefBool}
+	}
+	if v.RefRatio != nil {
+		item[OrderAttrRefRatio] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(float64(*v.RefRatio), 'f', -1, 64)}
+	}
+	if len(v.Tags) != 0 {
+		item[OrderAttrTags] = &types.AttributeValueMemberSS{Value: append(make([]string, 0, len(v.Tags)), v.Tags...)}
+	}
+	if len(v.Labels) != 0 {
+		s := make([]string, 0, len(v.Labels))
+		for _, e := range v.Labels {
+			if e == "" {
+				continue
+			}
+			s = append(s, e)
+		}
+		if len(s) != 0 {
+			item[OrderAttrLabels] = &types.AttributeValueMemberSS{Value: s}
+		}
+	}
+	if len(v.Scores) != 0 {
+		s := make([]string, 0, len(v.Scores))
+		for _, e := range v.Scores {
+			s = append(s, strconv.FormatInt(int64(e), 10))
+		}
+		item[OrderAttrScores] = &types.AttributeValueMemberNS{Value: s}
+	} else if v.Scores == nil {
+		item[OrderAttrScores] = &types.AttributeValueMemberNULL{Value: true}
+	}
+	if len(v.Weights) != 0 {
+		s := make([]string, 0, len(v.Weights))
+		for _, e := range v.Weights {
+			if e == 0 {
+				continue
+			}
+			s = append(s, strconv.FormatFloat(float64(e), 'f', -1, 64))
+		}
+		if len(s) != 0 {
+			item[OrderAttrWeights] = &types.AttributeValueMemberNS{Value: s}
+		}
+	}
+	if len(v.Notes) != 0 {
+		avs := make([]types.AttributeValue, 0, len(v.Notes))
+		for _, e := range v.Notes {
+			avs = append(avs, &types.AttributeValueMemberS{Value: e})
+		}
+		item[OrderAttrNotes] = &types.AttributeValueMemberL{Value: avs}
+	} else {
+		item[OrderAttrNotes] = &types.AttributeValueMemberL{Value: []types.AttributeValue{}}
+	}
+	if len(v.Remarks) != 0 {
+		avs := make([]types.AttributeValue, 0, len(v.Remarks))
+		for _, e := range v.Remarks {
+			if e == "" {
+				continue
+			}
+			avs = append(avs, &types.AttributeValueMemberS{Value: e})
+		}
+		if len(avs) != 0 {
+			item[OrderAttrRemarks] = &types.AttributeValueMemberL{Value: avs}
+		}
+	}
+	if len(v.Counts) != 0 {
+		avs := make([]types.AttributeValue, 0, len(v.Counts))
+		for _, e := range v.Counts {
+			avs = append(avs, &types.AttributeValueMemberN{Value: strconv.FormatUint(uint64(e), 10)})
+		}
+		item[OrderAttrCounts] = &types.AttributeValueMemberL{Value: avs}
+	} else if v.Counts == nil {
+		item[OrderAttrCounts] = &types.AttributeValueMemberNULL{Value: true}
+	} else {
+		item[OrderAttrCounts] = &types.AttributeValueMemberL{Value: []types.AttributeValue{}}
+	}
+	if len(v.Sizes) != 0 {
+		avs := make([]types.AttributeValue, 0, len(v.Sizes))
+		for _, e := range v.Sizes {
+			avs = append(avs, &types.AttributeValueMemberN{Value: strconv.FormatFloat(e, 'f', -1, 64)})
+		}
+		if len(avs) != 0 {
+			item[OrderAttrSizes] = &types.AttributeValueMemberL{Value: avs}
+		}
+	}
+	if av, err := orderFields.Status.Marshal(&v.Status); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrStatus] = av
+	}
+	if av, err := orderFields.Created.Marshal(&v.Created); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrCreated] = av
+	}
+	if av, err := orderFields.Shipped.Marshal(&v.Shipped); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrShipped] = av
+	}
+	if av, err := orderFields.Updated.Marshal(&v.Updated); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrUpdated] = av
+	}
+	if av, err := orderFields.Meta.Marshal(&v.Meta); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrMeta] = av
+	}
+	if av, err := orderFields.Flags.Marshal(&v.Flags); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrFlags] = av
+	}
+	if av, err := orderFields.Lines.Marshal(&v.Lines); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrLines] = av
+	}
+	if av, err := orderFields.Extra.Marshal(&v.Extra); err != nil {
+		return nil, err
+	} else if av != nil {
+		item[OrderAttrExtra] = av
+	}
+	item[OrderAttrVersion] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(v.Version), 10)}
+	if v.A != "" {
+		item[OrderAttrA] = &types.AttributeValueMemberS{Value: v.A}
+	}
+	if v.B != "" {
+		item[OrderAttrB] = &types.AttributeValueMemberS{Value: v.B}
+	}
+	return item, nil
+}
+
+// UnmarshalDynamoItem implements dynamo.ItemUnmarshaler.
+func (v *Order) UnmarshalDynamoItem(item map[string]types.AttributeValue) error {
+	return v.DecodeDynamoItem(item, nil)
+}
+
+// DecodeDynamoItem implements dynamo.ItemDecoder.
+func (v *Order) DecodeDynamoItem(item map[string]types.AttributeValue, d *dynamo.Decoder) error {
+	*v = Order{}
+	var errs []error
+	if av, ok := item[OrderAttrUserID]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.UserID, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.UserID.Decode(av, &v.UserID, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrID]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 64); err == nil {
+				v.ID, done = n, true
+			}
+		}
+		if !done {
+			if err := orderFields.ID.Decode(av, &v.ID, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrDup]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.Dup, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Dup.Decode(av, &v.Dup, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrNote]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.Note, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Note.Decode(av, &v.Note, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrComment]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.Comment, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Comment.Decode(av, &v.Comment, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrCount]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 0); err == nil {
+				v.Count, done = int(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Count.Decode(av, &v.Count, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrSmall]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 8); err == nil {
+				v.Small, done = int8(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Small.Decode(av, &v.Small, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrChar]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 32); err == nil {
+				v.Char, done = rune(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Char.Decode(av, &v.Char, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrSize]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseUint(x.Value, 10, 32); err == nil {
+				v.Size, done = uint32(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Size.Decode(av, &v.Size, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrBig]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseUint(x.Value, 10, 64); err == nil {
+				v.Big, done = n, true
+			}
+		}
+		if !done {
+			if err := orderFields.Big.Decode(av, &v.Big, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrPrice]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseFloat(x.Value, 64); err == nil {
+				v.Price, done = n, true
+			}
+		}
+		if !done {
+			if err := orderFields.Price.Decode(av, &v.Price, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRatio]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseFloat(x.Value, 64); err == nil && math.Abs(n) <= math.MaxFloat32 {
+				v.Ratio, done = float32(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Ratio.Decode(av, &v.Ratio, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrPaid]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberBOOL:
+			v.Paid, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Paid.Decode(av, &v.Paid, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrGift]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberBOOL:
+			v.Gift, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Gift.Decode(av, &v.Gift, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrData]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberB:
+			v.Data, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Data.Decode(av, &v.Data, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrBlob]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberB:
+			v.Blob, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Blob.Decode(av, &v.Blob, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRaw]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberB:
+			v.Raw, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Raw.Decode(av, &v.Raw, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrPacked]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberB:
+			v.Packed, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.Packed.Decode(av, &v.Packed, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRef]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			s := x.Value
+			v.Ref, done = &s, true
+		}
+		if !done {
+			if err := orderFields.Ref.Decode(av, &v.Ref, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRefEmpty]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			s := x.Value
+			v.RefEmpty, done = &s, true
+		}
+		if !done {
+			if err := orderFields.RefEmpty.Decode(av, &v.RefEmpty, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRefNull]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 0); err == nil {
+				s := int(n)
+				v.RefNull, done = &s, true
+			}
+		}
+		if !done {
+			if err := orderFields.RefNull.Decode(av, &v.RefNull, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRefBool]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberBOOL:
+			s := x.Value
+			v.RefBool, done = &s, true
+		}
+		if !done {
+			if err := orderFields.RefBool.Decode(av, &v.RefBool, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRefRatio]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseFloat(x.Value, 64); err == nil && math.Abs(n) <= math.MaxFloat32 {
+				s := float32(n)
+				v.RefRatio, done = &s, true
+			}
+		}
+		if !done {
+			if err := orderFields.RefRatio.Decode(av, &v.RefRatio, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrTags]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberSS:
+			v.Tags, done = append(make([]string, 0, len(x.Value)), x.Value...), true
+		case *types.AttributeValueMemberL:
+			s := make([]string, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberS)
+				if !ok {
+					s = nil
+					break
+				}
+				s = append(s, ev.Value)
+			}
+			v.Tags, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Tags.Decode(av, &v.Tags, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrLabels]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberSS:
+			v.Labels, done = append(make([]string, 0, len(x.Value)), x.Value...), true
+		case *types.AttributeValueMemberL:
+			s := make([]string, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberS)
+				if !ok {
+					s = nil
+					break
+				}
+				s = append(s, ev.Value)
+			}
+			v.Labels, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Labels.Decode(av, &v.Labels, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrScores]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberNS:
+			s := make([]int, 0, len(x.Value))
+			for _, e := range x.Value {
+				n, err := strconv.ParseInt(e, 10, 0)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, int(n))
+			}
+			v.Scores, done = s, s != nil
+		case *types.AttributeValueMemberL:
+			s := make([]int, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberN)
+				if !ok {
+					s = nil
+					break
+				}
+				n, err := strconv.ParseInt(ev.Value, 10, 0)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, int(n))
+			}
+			v.Scores, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Scores.Decode(av, &v.Scores, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrWeights]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberNS:
+			s := make([]float32, 0, len(x.Value))
+			for _, e := range x.Value {
+				n, err := strconv.ParseFloat(e, 64)
+				if err != nil || math.Abs(n) > math.MaxFloat32 {
+					s = nil
+					break
+				}
+				s = append(s, float32(n))
+			}
+			v.Weights, done = s, s != nil
+		case *types.AttributeValueMemberL:
+			s := make([]float32, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberN)
+				if !ok {
+					s = nil
+					break
+				}
+				n, err := strconv.ParseFloat(ev.Value, 64)
+				if err != nil || math.Abs(n) > math.MaxFloat32 {
+					s = nil
+					break
+				}
+				s = append(s, float32(n))
+			}
+			v.Weights, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Weights.Decode(av, &v.Weights, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrNotes]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberSS:
+			v.Notes, done = append(make([]string, 0, len(x.Value)), x.Value...), true
+		case *types.AttributeValueMemberL:
+			s := make([]string, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberS)
+				if !ok {
+					s = nil
+					break
+				}
+				s = append(s, ev.Value)
+			}
+			v.Notes, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Notes.Decode(av, &v.Notes, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrRemarks]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberSS:
+			v.Remarks, done = append(make([]string, 0, len(x.Value)), x.Value...), true
+		case *types.AttributeValueMemberL:
+			s := make([]string, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberS)
+				if !ok {
+					s = nil
+					break
+				}
+				s = append(s, ev.Value)
+			}
+			v.Remarks, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Remarks.Decode(av, &v.Remarks, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrCounts]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberNS:
+			s := make([]uint16, 0, len(x.Value))
+			for _, e := range x.Value {
+				n, err := strconv.ParseUint(e, 10, 16)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, uint16(n))
+			}
+			v.Counts, done = s, s != nil
+		case *types.AttributeValueMemberL:
+			s := make([]uint16, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberN)
+				if !ok {
+					s = nil
+					break
+				}
+				n, err := strconv.ParseUint(ev.Value, 10, 16)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, uint16(n))
+			}
+			v.Counts, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Counts.Decode(av, &v.Counts, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrSizes]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberNS:
+			s := make([]float64, 0, len(x.Value))
+			for _, e := range x.Value {
+				n, err := strconv.ParseFloat(e, 64)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, n)
+			}
+			v.Sizes, done = s, s != nil
+		case *types.AttributeValueMemberL:
+			s := make([]float64, 0, len(x.Value))
+			for _, e := range x.Value {
+				ev, ok := e.(*types.AttributeValueMemberN)
+				if !ok {
+					s = nil
+					break
+				}
+				n, err := strconv.ParseFloat(ev.Value, 64)
+				if err != nil {
+					s = nil
+					break
+				}
+				s = append(s, n)
+			}
+			v.Sizes, done = s, s != nil
+		}
+		if !done {
+			if err := orderFields.Sizes.Decode(av, &v.Sizes, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrStatus]; ok {
+		if err := orderFields.Status.Decode(av, &v.Status, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrCreated]; ok {
+		if err := orderFields.Created.Decode(av, &v.Created, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrShipped]; ok {
+		if err := orderFields.Shipped.Decode(av, &v.Shipped, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrUpdated]; ok {
+		if err := orderFields.Updated.Decode(av, &v.Updated, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrMeta]; ok {
+		if err := orderFields.Meta.Decode(av, &v.Meta, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrFlags]; ok {
+		if err := orderFields.Flags.Decode(av, &v.Flags, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrLines]; ok {
+		if err := orderFields.Lines.Decode(av, &v.Lines, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrExtra]; ok {
+		if err := orderFields.Extra.Decode(av, &v.Extra, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if av, ok := item[OrderAttrVersion]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 0); err == nil {
+				v.Version, done = int(n), true
+			}
+		}
+		if !done {
+			if err := orderFields.Version.Decode(av, &v.Version, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrA]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.A, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.A.Decode(av, &v.A, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[OrderAttrB]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.B, done = x.Value, true
+		}
+		if !done {
+			if err := orderFields.B.Decode(av, &v.B, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	errs = append(errs, d.Unknown(item, orderAttrs)...)
+	return dynamo.JoinDecodeErrors(errs)
+}
+
+// Attribute names of Line, for use in expressions.
+const (
+	LineAttrSKU   = "sku"
+	LineAttrQty   = "qty"
+	LineAttrPrice = "Price"
+)
+
+// lineFields are the encodings of the fields of Line, used for errors and fields of other types.
+var lineFields = struct {
+	SKU   *dynamo.Field
+	Qty   *dynamo.Field
+	Price *dynamo.Field
+}{
+	SKU:   dynamo.NewField("SKU", `dynamo:"sku"`),
+	Qty:   dynamo.NewField("Qty", `dynamo:"qty,omitempty"`),
+	Price: dynamo.NewField("Price", ``),
+}
+
+// lineAttrs are the attribute names of Line, passed to Decoder.Unknown.
+var lineAttrs = []string{LineAttrSKU, LineAttrQty, LineAttrPrice}
+
+// MarshalDynamoItem implements dynamo.ItemMarshaler.
+func (v Line) MarshalDynamoItem() (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, 3)
+	if v.SKU != "" {
+		item[LineAttrSKU] = &types.AttributeValueMemberS{Value: v.SKU}
+	}
+	if v.Qty != 0 {
+		item[LineAttrQty] = &types.AttributeValueMemberN{Value: strconv.FormatInt(int64(v.Qty), 10)}
+	}
+	item[LineAttrPrice] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(v.Price, 'f', -1, 64)}
+	return item, nil
+}
+
+// UnmarshalDynamoItem implements dynamo.ItemUnmarshaler.
+func (v *Line) UnmarshalDynamoItem(item map[string]types.AttributeValue) error {
+	return v.DecodeDynamoItem(item, nil)
+}
+
+// DecodeDynamoItem implements dynamo.ItemDecoder.
+func (v *Line) DecodeDynamoItem(item map[string]types.AttributeValue, d *dynamo.Decoder) error {
+	*v = Line{}
+	var errs []error
+	if av, ok := item[LineAttrSKU]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberS:
+			v.SKU, done = x.Value, true
+		}
+		if !done {
+			if err := lineFields.SKU.Decode(av, &v.SKU, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[LineAttrQty]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseInt(x.Value, 10, 0); err == nil {
+				v.Qty, done = int(n), true
+			}
+		}
+		if !done {
+			if err := lineFields.Qty.Decode(av, &v.Qty, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if av, ok := item[LineAttrPrice]; ok {
+		done := false
+		switch x := av.(type) {
+		case *types.AttributeValueMemberN:
+			if n, err := strconv.ParseFloat(x.Value, 64); err == nil {
+				v.Price, done = n, true
+			}
+		}
+		if !done {
+			if err := lineFields.Price.Decode(av, &v.Price, d); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	errs = append(errs, d.Unknown(item, lineAttrs)...)
+	return dynamo.JoinDecodeErrors(errs)
+}
@@ -0,0 +1,64 @@
+// Command dynamo-codec generates reflection-free marshalers for model structs.
+//
+// For each named struct type, it writes MarshalDynamoItem and UnmarshalDynamoItem methods,
+// implementing dynamo.ItemMarshaler and dynamo.ItemUnmarshaler, and constants holding
+// the attribute names of its fields for use in expressions, such as OrderAttrUserID.
+// The generated methods produce exactly the same items and errors as the reflection-based encoder.
+//
+// Fields of strings, booleans, numbers and byte slices, pointers to them, and lists and sets
+// of strings and numbers are encoded directly. Fields of other types, such as time.Time,
+// maps and nested structs, are encoded with dynamo.Field, which uses reflection for that field only.
+// Embedded structs and fields tagged with inline, compress, encrypt or offload aren't supported,
+// because they need the reflection-based encoder.
+//
+// Usage, in the package declaring Order:
+//
+//	//go:generate go run github.com/niltonkummer/dynamo/cmd/dynamo-codec -type Order,Customer
+//
+// By default the code is written to <type>_dynamo.go, named after the first type.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma-separated list of struct type names (required)")
+		out       = flag.String("o", "", "output file (default: <dir>/<type>_dynamo.go)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dynamo-codec -type T[,T...] [-o file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	names := strings.Split(*typeNames, ",")
+	if *out == "" {
+		*out = filepath.Join(dir, strings.ToLower(names[0])+"_dynamo.go")
+	}
+	if err := run(dir, names, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "dynamo-codec:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, names []string, out string) error {
+	src, err := generate(dir, names, filepath.Base(out))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
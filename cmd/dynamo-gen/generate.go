@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// options configure the generated code.
+type options struct {
+	// pkg is the package name of the generated file.
+	pkg string
+	// typeName is the name of the item struct. If empty, it is derived from the table name.
+	typeName string
+}
+
+// generator writes Go code for the items of a table.
+type generator struct {
+	desc dynamo.Description
+	opts options
+	buf  bytes.Buffer
+	// pending are the nested struct types left to declare.
+	pending []nestedType
+	// declared are the names of the declared types.
+	declared map[string]bool
+}
+
+// nestedType is a struct type inferred from a map attribute.
+type nestedType struct {
+	name   string
+	attr   string
+	fields map[string]*attr
+	count  int
+}
+
+// field is a generated struct field.
+type field struct {
+	name string // Go name
+	attr *attr
+	typ  string
+	tags []string
+}
+
+// generate returns the source of a Go file declaring a struct for the items of the table described by desc,
+// with the types of its attributes inferred from items, and functions returning the keys of the table and its indexes.
+func generate(desc dynamo.Description, items []map[string]types.AttributeValue, opts options) ([]byte, error) {
+	if desc.HashKey == "" {
+		return nil, fmt.Errorf("table %s has no hash key", desc.Name)
+	}
+	if opts.pkg == "" {
+		opts.pkg = "main"
+	}
+	if opts.typeName == "" {
+		opts.typeName = goName(desc.Name)
+	}
+	g := &generator{
+		desc:     desc,
+		opts:     opts,
+		declared: map[string]bool{opts.typeName: true},
+	}
+	obj := newObject()
+	for _, item := range items {
+		obj.add(item)
+	}
+	g.addKeys(obj)
+
+	fmt.Fprintf(&g.buf, "// Code generated by dynamo-gen from the table %s and a sample of %d item(s).\n", desc.Name, len(items))
+	fmt.Fprintf(&g.buf, "// Attribute types are inferred from the sample, so review them before use.\n\n")
+	fmt.Fprintf(&g.buf, "package %s\n\n", opts.pkg)
+	fmt.Fprintf(&g.buf, "import %q\n\n", "github.com/niltonkummer/dynamo")
+
+	fields := g.fields(opts.typeName, obj.attrs, obj.count, true)
+	fmt.Fprintf(&g.buf, "// %s is an item of the table %s.\n", opts.typeName, desc.Name)
+	g.writeStruct(opts.typeName, fields)
+	for len(g.pending) > 0 {
+		nt := g.pending[0]
+		g.pending = g.pending[1:]
+		fmt.Fprintf(&g.buf, "\n// %s is the %s attribute of %s.\n", nt.name, nt.attr, opts.typeName)
+		g.writeStruct(nt.name, g.fields(nt.name, nt.fields, nt.count, false))
+	}
+	g.writeKeys(fields)
+
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, g.buf.Bytes())
+	}
+	return out, nil
+}
+
+// addKeys adds the key attributes of the table and its indexes, and projected attributes, if they weren't sampled.
+// Key attributes take their type from the description.
+func (g *generator) addKeys(obj *object) {
+	key := func(name string, typ dynamo.KeyType, always bool) {
+		if name == "" {
+			return
+		}
+		a := obj.attrs[name]
+		if a == nil {
+			a = &attr{name: name, shape: &shape{}}
+			obj.attrs[name] = a
+		}
+		if always {
+			a.seen = obj.count
+		}
+		switch typ {
+		case dynamo.StringType:
+			a.shape = &shape{kind: kindString}
+		case dynamo.BinaryType:
+			a.shape = &shape{kind: kindBytes}
+		case dynamo.NumberType:
+			if a.shape.kind != kindFloat {
+				a.shape = &shape{kind: kindInt}
+			}
+		}
+	}
+	key(g.desc.HashKey, g.desc.HashKeyType, true)
+	key(g.desc.RangeKey, g.desc.RangeKeyType, true)
+	for _, idx := range g.indexes() {
+		key(idx.HashKey, idx.HashKeyType, false)
+		key(idx.RangeKey, idx.RangeKeyType, false)
+		for _, name := range idx.ProjectionAttribs {
+			key(name, dynamo.NoneType, false)
+		}
+	}
+}
+
+// indexes returns the global indexes, then the local indexes, each sorted by name.
+func (g *generator) indexes() []dynamo.Index {
+	var indexes []dynamo.Index
+	for _, list := range [][]dynamo.Index{g.desc.GSI, g.desc.LSI} {
+		sorted := append([]dynamo.Index(nil), list...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+		indexes = append(indexes, sorted...)
+	}
+	return indexes
+}
+
+// fields returns the fields of a struct with the given attributes:
+// the table's keys first (for items), then the rest sorted by attribute name.
+func (g *generator) fields(typeName string, attrs map[string]*attr, count int, item bool) []field {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	rank := func(name string) int {
+		switch {
+		case !item:
+		case name == g.desc.HashKey:
+			return 0
+		case name == g.desc.RangeKey:
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	taken := make(map[string]bool, len(names))
+	fields := make([]field, 0, len(names))
+	for _, name := range names {
+		a := attrs[name]
+		f := field{name: uniqueName(goName(name), taken), attr: a}
+		f.typ = g.goType(typeName+f.name, name, a.shape)
+
+		var opts []string
+		if f.name != name {
+			opts = append(opts, name)
+		} else {
+			opts = append(opts, "")
+		}
+		switch {
+		case item && name == g.desc.HashKey:
+			opts = append(opts, "hash")
+		case item && name == g.desc.RangeKey:
+			opts = append(opts, "range")
+		}
+		switch a.shape.kind {
+		case kindStringSet, kindIntSet, kindFloatSet, kindBytesSet:
+			opts = append(opts, "set")
+		}
+		if a.seen < count && a.shape.kind != kindNull && a.shape.kind != kindAny {
+			opts = append(opts, "omitempty")
+		}
+		if len(opts) > 1 || opts[0] != "" {
+			f.tags = append(f.tags, fmt.Sprintf("dynamo:%q", strings.Join(opts, ",")))
+		}
+		if item {
+			f.tags = append(f.tags, g.indexTags(name)...)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// indexTags returns the index, localIndex and project struct tags of the attribute name.
+func (g *generator) indexTags(name string) []string {
+	var tags, project []string
+	for _, idx := range g.indexes() {
+		tagName := "index"
+		if idx.Local {
+			tagName = "localIndex"
+		}
+		var opts []string
+		switch {
+		case name == idx.HashKey && !idx.Local:
+			opts = append(opts, idx.Name, "hash")
+		case name == idx.RangeKey:
+			opts = append(opts, idx.Name, "range")
+		}
+		// options go on the field that declares the index: the hash key of global indexes and the range key of local indexes
+		if name == idx.HashKey && !idx.Local || name == idx.RangeKey && idx.Local {
+			switch idx.ProjectionType {
+			case dynamo.KeysOnlyProjection:
+				opts = append(opts, "projection=keys_only")
+			case dynamo.IncludeProjection:
+				opts = append(opts, "projection=include")
+			}
+			if !idx.Local && !g.desc.OnDemand && (idx.Throughput.Read != 0 || idx.Throughput.Write != 0) {
+				opts = append(opts,
+					"read="+strconv.FormatInt(idx.Throughput.Read, 10),
+					"write="+strconv.FormatInt(idx.Throughput.Write, 10))
+			}
+		}
+		if len(opts) > 0 {
+			tags = append(tags, fmt.Sprintf("%s:%q", tagName, strings.Join(opts, ",")))
+		}
+		if idx.ProjectionType == dynamo.IncludeProjection {
+			for _, attr := range idx.ProjectionAttribs {
+				if attr == name {
+					project = append(project, idx.Name)
+				}
+			}
+		}
+	}
+	if len(project) > 0 {
+		tags = append(tags, fmt.Sprintf("project:%q", strings.Join(project, ",")))
+	}
+	return tags
+}
+
+// goType returns the Go type of s, declaring a struct named typeName for maps with fixed keys.
+func (g *generator) goType(typeName, attrName string, s *shape) string {
+	switch s.kind {
+	case kindString:
+		return "string"
+	case kindInt:
+		return "int64"
+	case kindFloat:
+		return "float64"
+	case kindBool:
+		return "bool"
+	case kindBytes:
+		return "[]byte"
+	case kindStringSet:
+		return "[]string"
+	case kindIntSet:
+		return "[]int64"
+	case kindFloatSet:
+		return "[]float64"
+	case kindBytesSet:
+		return "[][]byte"
+	case kindList:
+		return "[]" + g.goType(typeName, attrName, s.elem)
+	case kindMap:
+		return "map[string]" + g.goType(typeName, attrName, s.elem)
+	case kindStruct:
+		name := typeName
+		for i := 2; g.declared[name]; i++ {
+			name = typeName + strconv.Itoa(i)
+		}
+		g.declared[name] = true
+		g.pending = append(g.pending, nestedType{name: name, attr: attrName, fields: s.fields, count: s.count})
+		return name
+	}
+	return "interface{}"
+}
+
+func (g *generator) writeStruct(name string, fields []field) {
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, f := range fields {
+		fmt.Fprintf(&g.buf, "\t%s %s", f.name, f.typ)
+		if len(f.tags) > 0 {
+			fmt.Fprintf(&g.buf, " `%s`", strings.Join(f.tags, " "))
+		}
+		g.buf.WriteString("\n")
+	}
+	g.buf.WriteString("}\n")
+}
+
+// writeKeys writes functions returning the primary key and the keys of each index.
+func (g *generator) writeKeys(fields []field) {
+	byAttr := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byAttr[f.attr.name] = f
+	}
+	write := func(funcName, doc, hashKey, rangeKey string) {
+		keys := []field{byAttr[hashKey]}
+		if rangeKey != "" {
+			keys = append(keys, byAttr[rangeKey])
+		}
+		var params, args []string
+		for _, key := range keys {
+			param := paramName(key.name)
+			params = append(params, param+" "+key.typ)
+			args = append(args, param)
+		}
+		fmt.Fprintf(&g.buf, "\n// %s %s\n", funcName, doc)
+		fmt.Fprintf(&g.buf, "func %s(%s) dynamo.Keys {\n", funcName, strings.Join(params, ", "))
+		fmt.Fprintf(&g.buf, "\treturn dynamo.Keys{%s}\n}\n", strings.Join(args, ", "))
+	}
+
+	typeName := g.opts.typeName
+	write(typeName+"Key", fmt.Sprintf("returns the primary key of an item of the table %s.", g.desc.Name), g.desc.HashKey, g.desc.RangeKey)
+	for _, idx := range g.indexes() {
+		write(typeName+goName(idx.Name)+"Key", fmt.Sprintf("returns the key of an item in the index %s.", idx.Name), idx.HashKey, idx.RangeKey)
+	}
+}
+
+// commonInitialisms are written in upper case in Go names.
+var commonInitialisms = map[string]bool{
+	"API": true, "ARN": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "SQL": true, "TTL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goName returns an exported Go identifier for name, such as UserID for user_id.
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "X" + id
+	}
+	return id
+}
+
+// paramName returns the Go field name with its leading upper case word in lower case, such as userID for UserID.
+func paramName(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	// keep the first letter of the next word in upper case, as in URLPath → urlPath
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	param := string(runes)
+	if token.IsKeyword(param) {
+		param += "Key"
+	}
+	return param
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+func n(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+
+func TestGenerate(t *testing.T) {
+	desc := dynamo.Description{
+		Name:         "Orders",
+		HashKey:      "user_id",
+		HashKeyType:  dynamo.StringType,
+		RangeKey:     "OrderID",
+		RangeKeyType: dynamo.NumberType,
+		Throughput:   dynamo.Throughput{Read: 5, Write: 5},
+		GSI: []dynamo.Index{{
+			Name:              "Status-index",
+			HashKey:           "Status",
+			HashKeyType:       dynamo.StringType,
+			RangeKey:          "Created",
+			RangeKeyType:      dynamo.StringType,
+			ProjectionType:    dynamo.IncludeProjection,
+			ProjectionAttribs: []string{"Total", "Note"},
+			Throughput:        dynamo.Throughput{Read: 2, Write: 1},
+		}},
+		LSI: []dynamo.Index{{
+			Name:           "Total-index",
+			Local:          true,
+			HashKey:        "user_id",
+			HashKeyType:    dynamo.StringType,
+			RangeKey:       "Total",
+			RangeKeyType:   dynamo.NumberType,
+			ProjectionType: dynamo.KeysOnlyProjection,
+		}},
+	}
+	items := []map[string]types.AttributeValue{
+		{
+			"user_id": s("u1"),
+			"OrderID": n("1"),
+			"Status":  s("paid"),
+			"Created": s("2020-01-01"),
+			"Total":   n("10"),
+			"Tags":    &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+			"Lines": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"SKU": s("x"), "qty": n("2")}},
+			}},
+			"Address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"street": s("Main St"),
+				"zip":    s("12345"),
+			}},
+			"Counts": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"2020-01": n("3"),
+			}},
+			"Gift":  &types.AttributeValueMemberBOOL{Value: true},
+			"Extra": &types.AttributeValueMemberNULL{Value: true},
+			"type":  s("order"),
+		},
+		{
+			"user_id": s("u2"),
+			"OrderID": n("2"),
+			"Total":   n("12.5"),
+			"Lines": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"SKU": s("y"), "qty": n("1"), "Gift": &types.AttributeValueMemberBOOL{Value: false}}},
+			}},
+			"Mixed": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("a"), n("1")}},
+			"Data":  &types.AttributeValueMemberB{Value: []byte{1}},
+			"type":  s("order"),
+		},
+	}
+
+	src, err := generate(desc, items, options{pkg: "model", typeName: "Order"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", "order.go.golden")
+	if *update {
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(want) {
+		t.Errorf("output differs from golden file.\nwant:\n%s\ngot:\n%s", want, src)
+	}
+}
+
+func TestInferMerge(t *testing.T) {
+	tests := []struct {
+		a, b types.AttributeValue
+		want kind
+	}{
+		{n("1"), n("2"), kindInt},
+		{n("1"), n("2.5"), kindFloat},
+		{s("x"), n("1"), kindAny},
+		{&types.AttributeValueMemberNULL{Value: true}, s("x"), kindString},
+		{&types.AttributeValueMemberNS{Value: []string{"1"}}, &types.AttributeValueMemberNS{Value: []string{"1.5"}}, kindFloatSet},
+		{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"a": s("x")}},
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"b c": s("y")}},
+			kindMap,
+		},
+	}
+	for i, test := range tests {
+		if got := merge(infer(test.a), infer(test.b)).kind; got != test.want {
+			t.Errorf("%d: want kind %d, got %d", i, test.want, got)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	for in, want := range map[string]string{
+		"user_id":      "UserID",
+		"UserID":       "UserID",
+		"created-at":   "CreatedAt",
+		"Status-index": "StatusIndex",
+		"2fa":          "X2fa",
+		"sku":          "SKU",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q): want %s, got %s", in, want, got)
+		}
+	}
+	for in, want := range map[string]string{
+		"UserID":  "userID",
+		"ID":      "id",
+		"URLPath": "urlPath",
+		"Type":    "typeKey",
+	} {
+		if got := paramName(in); got != want {
+			t.Errorf("paramName(%q): want %s, got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// kind is the inferred kind of an attribute.
+type kind int
+
+const (
+	kindNull kind = iota // only NULL (or nothing) seen
+	kindString
+	kindInt
+	kindFloat
+	kindBool
+	kindBytes
+	kindStringSet
+	kindIntSet
+	kindFloatSet
+	kindBytesSet
+	kindList
+	kindStruct // map with fixed keys
+	kindMap    // map with arbitrary keys
+	kindAny    // conflicting kinds
+)
+
+// maxStructFields is the number of distinct keys above which maps are inferred as Go maps instead of structs.
+const maxStructFields = 32
+
+// shape is the inferred type of an attribute, merged across all of its sampled values.
+type shape struct {
+	kind kind
+	// elem is the element shape of lists and maps.
+	elem *shape
+	// fields are the attributes of structs, by name.
+	fields map[string]*attr
+	// count is the number of maps merged into a struct.
+	count int
+}
+
+// attr is an attribute of an item or struct.
+type attr struct {
+	name  string
+	shape *shape
+	// seen is the number of values this attribute appeared in.
+	seen int
+}
+
+// object is the inferred shape of a set of items or maps.
+type object struct {
+	attrs map[string]*attr
+	// count is the number of items or maps merged.
+	count int
+}
+
+func newObject() *object {
+	return &object{attrs: make(map[string]*attr)}
+}
+
+// add merges an item or map into this object.
+func (o *object) add(item map[string]types.AttributeValue) {
+	o.count++
+	for name, av := range item {
+		a := o.attrs[name]
+		if a == nil {
+			a = &attr{name: name, shape: &shape{}}
+			o.attrs[name] = a
+		}
+		a.seen++
+		a.shape = merge(a.shape, infer(av))
+	}
+}
+
+// infer returns the shape of a single value.
+func infer(av types.AttributeValue) *shape {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &shape{kind: kindString}
+	case *types.AttributeValueMemberN:
+		if isInt(v.Value) {
+			return &shape{kind: kindInt}
+		}
+		return &shape{kind: kindFloat}
+	case *types.AttributeValueMemberB:
+		return &shape{kind: kindBytes}
+	case *types.AttributeValueMemberBOOL:
+		return &shape{kind: kindBool}
+	case *types.AttributeValueMemberSS:
+		return &shape{kind: kindStringSet}
+	case *types.AttributeValueMemberNS:
+		for _, n := range v.Value {
+			if !isInt(n) {
+				return &shape{kind: kindFloatSet}
+			}
+		}
+		return &shape{kind: kindIntSet}
+	case *types.AttributeValueMemberBS:
+		return &shape{kind: kindBytesSet}
+	case *types.AttributeValueMemberL:
+		elem := &shape{}
+		for _, item := range v.Value {
+			elem = merge(elem, infer(item))
+		}
+		return &shape{kind: kindList, elem: elem}
+	case *types.AttributeValueMemberM:
+		s := &shape{kind: kindStruct, fields: make(map[string]*attr, len(v.Value)), count: 1}
+		for name, item := range v.Value {
+			s.fields[name] = &attr{name: name, shape: infer(item), seen: 1}
+		}
+		return structOrMap(s)
+	}
+	return &shape{kind: kindNull}
+}
+
+// merge returns the shape of values that are either a or b.
+func merge(a, b *shape) *shape {
+	switch {
+	case a.kind == kindNull:
+		return b
+	case b.kind == kindNull:
+		return a
+	case a.kind == kindAny || b.kind == kindAny:
+		return &shape{kind: kindAny}
+	}
+	if a.kind == kindStruct && b.kind == kindMap || a.kind == kindMap && b.kind == kindStruct {
+		a, b = asMap(a), asMap(b)
+	}
+	if a.kind != b.kind {
+		switch {
+		case isNumber(a.kind) && isNumber(b.kind):
+			return &shape{kind: kindFloat}
+		case isNumberSet(a.kind) && isNumberSet(b.kind):
+			return &shape{kind: kindFloatSet}
+		}
+		return &shape{kind: kindAny}
+	}
+	switch a.kind {
+	case kindList, kindMap:
+		return &shape{kind: a.kind, elem: merge(a.elem, b.elem)}
+	case kindStruct:
+		s := &shape{kind: kindStruct, fields: make(map[string]*attr, len(a.fields)), count: a.count + b.count}
+		for _, fields := range []map[string]*attr{a.fields, b.fields} {
+			for name, f := range fields {
+				prev := s.fields[name]
+				if prev == nil {
+					prev = &attr{name: name, shape: &shape{}}
+					s.fields[name] = prev
+				}
+				prev.seen += f.seen
+				prev.shape = merge(prev.shape, f.shape)
+			}
+		}
+		return structOrMap(s)
+	}
+	return a
+}
+
+// structOrMap turns a struct shape into a map shape if its keys don't look like field names.
+func structOrMap(s *shape) *shape {
+	if len(s.fields) > maxStructFields {
+		return asMap(s)
+	}
+	for name := range s.fields {
+		if !fieldLike(name) {
+			return asMap(s)
+		}
+	}
+	return s
+}
+
+func asMap(s *shape) *shape {
+	if s.kind != kindStruct {
+		return s
+	}
+	elem := &shape{}
+	for _, f := range s.fields {
+		elem = merge(elem, f.shape)
+	}
+	return &shape{kind: kindMap, elem: elem}
+}
+
+// fieldLike returns true if name can be the name of a struct field: it starts
+// with a letter and contains only letters, digits, underscores, dashes and dots.
+func fieldLike(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+		case i > 0 && (unicode.IsDigit(r) || strings.ContainsRune("_-.", r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isInt(n string) bool {
+	_, err := strconv.ParseInt(n, 10, 64)
+	return err == nil
+}
+
+func isNumber(k kind) bool {
+	return k == kindInt || k == kindFloat
+}
+
+func isNumberSet(k kind) bool {
+	return k == kindIntSet || k == kindFloatSet
+}
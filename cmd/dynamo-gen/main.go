@@ -0,0 +1,81 @@
+// Command dynamo-gen generates a Go struct for the items of an existing DynamoDB table.
+//
+// It describes the table and scans a sample of its items, inferring the type of each attribute,
+// including sets, lists and maps (as nested structs or Go maps). The generated struct has
+// dynamo struct tags marking the table's keys, index and localIndex tags declaring its
+// secondary indexes, and is followed by functions returning the keys of the table and each index.
+//
+// Usage:
+//
+//	dynamo-gen -table Orders -type Order -package model -o order.go
+//
+// The region and credentials come from the default AWS configuration.
+// Use -endpoint to connect to DynamoDB Local.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo/internal/awscfg"
+)
+
+func main() {
+	var (
+		table    = flag.String("table", "", "name of the table (required)")
+		typeName = flag.String("type", "", "name of the generated struct (default: derived from the table name)")
+		pkg      = flag.String("package", "main", "package name of the generated file")
+		out      = flag.String("o", "", "output file (default: standard output)")
+		sample   = flag.Int64("sample", 100, "number of items to scan for attribute types")
+		region   = flag.String("region", "", "AWS region (default: from the AWS configuration)")
+		endpoint = flag.String("endpoint", "", "DynamoDB endpoint URL, such as http://localhost:8000")
+	)
+	flag.Parse()
+	if *table == "" || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*table, *region, *endpoint, *sample, *out, options{pkg: *pkg, typeName: *typeName}); err != nil {
+		fmt.Fprintln(os.Stderr, "dynamo-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(table, region, endpoint string, sample int64, out string, opts options) error {
+	ctx := context.Background()
+	db, err := awscfg.Open(ctx, region, endpoint)
+	if err != nil {
+		return err
+	}
+	desc, err := db.Table(table).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var items []map[string]types.AttributeValue
+	if sample > 0 {
+		iter := db.Table(table).Scan().Limit(sample).Iter()
+		var item map[string]types.AttributeValue
+		for iter.NextWithContext(ctx, &item) {
+			items = append(items, item)
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+	}
+
+	src, err := generate(desc, items, opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
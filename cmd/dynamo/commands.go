@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// maxLineSize is the size of the longest JSON line put reads from standard input.
+const maxLineSize = 4 << 20
+
+func (c *cli) tables(ctx context.Context, args []string) error {
+	names, err := c.db.ListTables().AllWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintln(c.stdout, name)
+	}
+	return nil
+}
+
+func (c *cli) describe(ctx context.Context, args []string) error {
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if c.opts.output == "json" {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(desc)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tHASH KEY\tRANGE KEY\tPROJECTION\tSTATUS\tITEMS\tSIZE")
+	fmt.Fprintf(tw, "%s\ttable\t%s\t%s\t\t%s\t%d\t%d\n", desc.Name,
+		keyText(desc.HashKey, desc.HashKeyType), keyText(desc.RangeKey, desc.RangeKeyType), desc.Status, desc.Items, desc.Size)
+	for _, idx := range append(append([]dynamo.Index{}, desc.GSI...), desc.LSI...) {
+		typ := "global"
+		if idx.Local {
+			typ = "local"
+		}
+		projection := string(idx.ProjectionType)
+		if len(idx.ProjectionAttribs) > 0 {
+			projection += " (" + strings.Join(idx.ProjectionAttribs, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n", idx.Name, typ,
+			keyText(idx.HashKey, idx.HashKeyType), keyText(idx.RangeKey, idx.RangeKeyType), projection, idx.Status, idx.Items, idx.Size)
+	}
+	return tw.Flush()
+}
+
+func keyText(name string, typ dynamo.KeyType) string {
+	if name == "" {
+		return ""
+	}
+	return name + " (" + string(typ) + ")"
+}
+
+func (c *cli) get(ctx context.Context, args []string) error {
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	hash, rangeKey, err := parseKey(desc, args[1])
+	if err != nil {
+		return err
+	}
+	q := c.db.Table(desc.Name).Get(desc.HashKey, hash).Consistent(c.opts.consistent)
+	if rangeKey != nil {
+		q.Range(desc.RangeKey, dynamo.Equal, rangeKey)
+	}
+	if paths := splitList(c.opts.project); len(paths) > 0 {
+		q.Project(paths...)
+	}
+	var item map[string]types.AttributeValue
+	if err := q.OneWithContext(ctx, &item); err != nil {
+		return err
+	}
+	keys, _ := keyNames(desc, "")
+	p := newPrinter(c.opts.output, c.stdout, keys)
+	if err := p.print(item); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func (c *cli) query(ctx context.Context, args []string) error {
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	hashName, rangeName, err := keySchema(desc, c.opts.index)
+	if err != nil {
+		return err
+	}
+	key, err := parseItem(args[1])
+	if err != nil {
+		return err
+	}
+	hash := key[hashName]
+	if hash == nil {
+		return fmt.Errorf("key is missing the hash key %s", hashName)
+	}
+	q := c.db.Table(desc.Name).Get(hashName, hash)
+	for name, av := range key {
+		switch {
+		case name == hashName:
+		case name == rangeName && len(args) == 2:
+			q.Range(rangeName, dynamo.Equal, av)
+		case name == rangeName:
+			return fmt.Errorf("range key %s is given in both the key and a condition", rangeName)
+		default:
+			return fmt.Errorf("%s is not a key of the table or index", name)
+		}
+	}
+	if len(args) > 2 {
+		if rangeName == "" {
+			return fmt.Errorf("table or index has no range key to compare with %s", args[2])
+		}
+		op, values, err := parseRange(args[2:])
+		if err != nil {
+			return err
+		}
+		q.Range(rangeName, op, values...)
+	}
+
+	if c.opts.index != "" {
+		q.Index(c.opts.index)
+	}
+	if c.opts.desc {
+		q.Order(dynamo.Descending)
+	}
+	if paths := splitList(c.opts.project); len(paths) > 0 {
+		q.Project(paths...)
+	}
+	if c.opts.startFrom != "" {
+		start, err := decodeToken(c.opts.startFrom)
+		if err != nil {
+			return err
+		}
+		q.StartFrom(start)
+	}
+	filterArgs, err := c.filterArgs()
+	if err != nil {
+		return err
+	}
+	if c.opts.filter != "" {
+		q.Filter(c.opts.filter, filterArgs...)
+	}
+	q.Consistent(c.opts.consistent).Limit(c.opts.limit)
+
+	keys, err := keyNames(desc, c.opts.index)
+	if err != nil {
+		return err
+	}
+	return c.printAll(ctx, q.Iter(), keys)
+}
+
+func (c *cli) scan(ctx context.Context, args []string) error {
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := keyNames(desc, c.opts.index)
+	if err != nil {
+		return err
+	}
+	s := c.db.Table(desc.Name).Scan()
+	if c.opts.index != "" {
+		s.Index(c.opts.index)
+	}
+	if paths := splitList(c.opts.project); len(paths) > 0 {
+		s.Project(paths...)
+	}
+	if c.opts.startFrom != "" {
+		start, err := decodeToken(c.opts.startFrom)
+		if err != nil {
+			return err
+		}
+		s.StartFrom(start)
+	}
+	filterArgs, err := c.filterArgs()
+	if err != nil {
+		return err
+	}
+	if c.opts.filter != "" {
+		s.Filter(c.opts.filter, filterArgs...)
+	}
+	s.Consistent(c.opts.consistent).Limit(c.opts.limit)
+	return c.printAll(ctx, s.Iter(), keys)
+}
+
+// filterArgs returns the arguments of -filter.
+func (c *cli) filterArgs() ([]interface{}, error) {
+	args, err := parseArgs(c.opts.args)
+	if err != nil {
+		return nil, err
+	}
+	q := &argQueue{args: args}
+	var filterArgs []interface{}
+	if c.opts.filter != "" {
+		if filterArgs, err = q.take(c.opts.filter); err != nil {
+			return nil, err
+		}
+	}
+	return filterArgs, q.done()
+}
+
+// printAll prints the results of iter. If -limit cut them short,
+// it prints the token of the next page to standard error.
+func (c *cli) printAll(ctx context.Context, iter dynamo.PagingIter, keys []string) error {
+	p := newPrinter(c.opts.output, c.stdout, keys)
+	var n int64
+	var item, last map[string]types.AttributeValue
+	for iter.NextWithContext(ctx, &item) {
+		if err := p.print(item); err != nil {
+			return err
+		}
+		last = item
+		n++
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	next := iter.LastEvaluatedKey()
+	if c.opts.limit > 0 && n == c.opts.limit {
+		// the last page may have been read past the limit, so continue from the last item
+		if key := itemKey(last, keys); key != nil {
+			next = key
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	token, err := encodeToken(next)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "next page: -start-from %s\n", token)
+	return nil
+}
+
+// itemKey returns the key attributes of item, or nil if some are missing, such as when they weren't projected.
+func itemKey(item map[string]types.AttributeValue, keys []string) dynamo.PagingKey {
+	key := make(dynamo.PagingKey, len(keys))
+	for _, name := range keys {
+		av, ok := item[name]
+		if !ok {
+			return nil
+		}
+		key[name] = av
+	}
+	return key
+}
+
+func (c *cli) put(ctx context.Context, args []string) error {
+	cond, err := c.condArgs()
+	if err != nil {
+		return err
+	}
+	put := func(data string) error {
+		item, err := parseItem(data)
+		if err != nil {
+			return err
+		}
+		p := c.db.Table(args[0]).Put(item)
+		if c.opts.cond != "" {
+			p.If(c.opts.cond, cond...)
+		}
+		return p.RunWithContext(ctx)
+	}
+	if args[1] != "-" {
+		return put(args[1])
+	}
+
+	sc := bufio.NewScanner(c.stdin)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	n := 0
+	for line := 1; sc.Scan(); line++ {
+		data := strings.TrimSpace(sc.Text())
+		if data == "" {
+			continue
+		}
+		if err := put(data); err != nil {
+			return fmt.Errorf("line %d: %v (put %d items before it)", line, err, n)
+		}
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "put %d items\n", n)
+	return nil
+}
+
+// condArgs returns the arguments of -if.
+func (c *cli) condArgs() ([]interface{}, error) {
+	args, err := parseArgs(c.opts.args)
+	if err != nil {
+		return nil, err
+	}
+	q := &argQueue{args: args}
+	var condArgs []interface{}
+	if c.opts.cond != "" {
+		if condArgs, err = q.take(c.opts.cond); err != nil {
+			return nil, err
+		}
+	}
+	return condArgs, q.done()
+}
+
+func (c *cli) update(ctx context.Context, args []string) error {
+	if len(c.opts.set) == 0 && len(c.opts.remove) == 0 {
+		return fmt.Errorf("nothing to update, use -set or -remove")
+	}
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	hash, rangeKey, err := parseKey(desc, args[1])
+	if err != nil {
+		return err
+	}
+	u := c.db.Table(desc.Name).Update(desc.HashKey, hash)
+	if rangeKey != nil {
+		u.Range(desc.RangeKey, rangeKey)
+	}
+
+	exprArgs, err := parseArgs(c.opts.args)
+	if err != nil {
+		return err
+	}
+	q := &argQueue{args: exprArgs}
+	for _, expr := range c.opts.set {
+		args, err := q.take(expr)
+		if err != nil {
+			return err
+		}
+		u.SetExpr(expr, args...)
+	}
+	for _, expr := range c.opts.remove {
+		args, err := q.take(expr)
+		if err != nil {
+			return err
+		}
+		u.RemoveExpr(expr, args...)
+	}
+	if c.opts.cond != "" {
+		args, err := q.take(c.opts.cond)
+		if err != nil {
+			return err
+		}
+		u.If(c.opts.cond, args...)
+	}
+	if err := q.done(); err != nil {
+		return err
+	}
+
+	var item map[string]types.AttributeValue
+	if err := u.ValueWithContext(ctx, &item); err != nil {
+		return err
+	}
+	keys, _ := keyNames(desc, "")
+	p := newPrinter(c.opts.output, c.stdout, keys)
+	if err := p.print(item); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func (c *cli) delete(ctx context.Context, args []string) error {
+	cond, err := c.condArgs()
+	if err != nil {
+		return err
+	}
+	desc, err := c.db.Table(args[0]).Describe().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	hash, rangeKey, err := parseKey(desc, args[1])
+	if err != nil {
+		return err
+	}
+	d := c.db.Table(desc.Name).Delete(desc.HashKey, hash)
+	if rangeKey != nil {
+		d.Range(desc.RangeKey, rangeKey)
+	}
+	if c.opts.cond != "" {
+		d.If(c.opts.cond, cond...)
+	}
+
+	// print the deleted item, if there was one
+	var old map[string]types.AttributeValue
+	switch err := d.OldValueWithContext(ctx, &old); err {
+	case nil:
+	case dynamo.ErrNotFound:
+		fmt.Fprintln(c.stderr, "no item deleted")
+		return nil
+	default:
+		return err
+	}
+	keys, _ := keyNames(desc, "")
+	p := newPrinter(c.opts.output, c.stdout, keys)
+	if err := p.print(old); err != nil {
+		return err
+	}
+	return p.flush()
+}
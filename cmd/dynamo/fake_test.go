@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo/dynamodbiface"
+)
+
+// fakeClient stands in for DynamoDB in this package's tests.
+// It serves the Orders table, keyed by UserID and ID with a Status-index,
+// and records the requests made to it.
+type fakeClient struct {
+	dynamodbiface.DynamoDBAPI
+	items []map[string]types.AttributeValue
+
+	get    *dynamodb.GetItemInput
+	put    []*dynamodb.PutItemInput
+	update *dynamodb.UpdateItemInput
+	delete *dynamodb.DeleteItemInput
+	query  *dynamodb.QueryInput
+	scan   *dynamodb.ScanInput
+}
+
+func (c *fakeClient) ListTables(ctx context.Context, params *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	return &dynamodb.ListTablesOutput{TableNames: []string{"Orders", "Users"}}, nil
+}
+
+func (c *fakeClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if *params.TableName != "Orders" {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName:   aws.String("Orders"),
+		TableStatus: types.TableStatusActive,
+		ItemCount:   2,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("UserID"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("Status"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("UserID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("ID"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{{
+			IndexName:   aws.String("Status-index"),
+			IndexArn:    aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/Orders/index/Status-index"),
+			IndexStatus: types.IndexStatusActive,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("Status"), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+	}}, nil
+}
+
+func (c *fakeClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.get = params
+	return &dynamodb.GetItemOutput{Item: c.items[0]}, nil
+}
+
+func (c *fakeClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.put = append(c.put, params)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (c *fakeClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	c.update = params
+	return &dynamodb.UpdateItemOutput{Attributes: c.items[0]}, nil
+}
+
+func (c *fakeClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.delete = params
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (c *fakeClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.query = params
+	return &dynamodb.QueryOutput{Items: c.items, Count: int32(len(c.items))}, nil
+}
+
+func (c *fakeClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	// each item is on its own page
+	c.scan = params
+	i := 0
+	if params.ExclusiveStartKey != nil {
+		i = 1
+	}
+	out := &dynamodb.ScanOutput{Items: c.items[i : i+1], Count: 1}
+	if i == 0 {
+		out.LastEvaluatedKey = map[string]types.AttributeValue{
+			"UserID": c.items[0]["UserID"],
+			"ID":     c.items[0]["ID"],
+			"Status": c.items[0]["Status"],
+		}
+	}
+	return out, nil
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{items: []map[string]types.AttributeValue{
+		{
+			"UserID": &types.AttributeValueMemberS{Value: "u1"},
+			"ID":     &types.AttributeValueMemberN{Value: "1"},
+			"Status": &types.AttributeValueMemberS{Value: "paid"},
+			"Total":  &types.AttributeValueMemberN{Value: "12.50"},
+		},
+		{
+			"UserID": &types.AttributeValueMemberS{Value: "u1"},
+			"ID":     &types.AttributeValueMemberN{Value: "2"},
+			"Tags":   &types.AttributeValueMemberSS{Value: []string{"gift"}},
+		},
+	}}
+}
@@ -0,0 +1,257 @@
+// Command dynamo is a command-line client for DynamoDB tables.
+//
+// Usage:
+//
+//	dynamo [-region R] [-endpoint URL] [-o json|table] <command> [flags] [args]
+//
+// The commands are:
+//
+//	tables                            list the tables
+//	describe TABLE                    describe a table and its indexes
+//	get TABLE KEY                     get an item by its primary key
+//	query TABLE KEY [OP VALUE...]     query a table or index by hash key, and optionally range key
+//	scan TABLE                        scan a table or index
+//	put TABLE ITEM|-                  put an item, or each JSON line of standard input with -
+//	update TABLE KEY                  update an item with -set and -remove expressions
+//	delete TABLE KEY                  delete an item
+//
+// Keys, items and values are JSON, such as '{"UserID": "u1", "ID": 42}'. Sets and binary data
+// are written as objects naming their type, such as {"SS": ["a", "b"]} and {"B": "aGk="}.
+// Range key operators are =, <, <=, >, >=, begins_with and between (followed by two values).
+//
+// Filters, conditions and update expressions use the library's syntax: ? is a placeholder for a value,
+// $ is a placeholder for an attribute name, and 'quoted' names are escaped. Their arguments are given
+// as a JSON array with -args, and are consumed in order: -set, -remove, then -filter or -if.
+//
+//	dynamo query -index Status-index -filter "Total > ?" -args '[100]' Orders '{"Status": "paid"}'
+//	dynamo update -set "Count = Count + ?" -if "attribute_exists($)" -args '[1, "ID"]' Orders '{"ID": 42}'
+//
+// Items are written as JSON lines, or aligned in columns with -o table. When -limit stops a query or scan
+// before its end, the token to pass to -start-from for the next page is written to standard error.
+//
+// The region and credentials come from the default AWS configuration. Use -endpoint, or set
+// DYNAMO_ENDPOINT, to connect to DynamoDB Local, such as http://localhost:8000.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/niltonkummer/dynamo"
+	"github.com/niltonkummer/dynamo/internal/awscfg"
+)
+
+// command is a subcommand.
+type command struct {
+	name string
+	args string
+	help string
+	// flags are the names of the flags it accepts, besides the connection and output flags.
+	flags   []string
+	minArgs int
+	maxArgs int
+	run     func(c *cli, ctx context.Context, args []string) error
+}
+
+var commands = []*command{
+	{name: "tables", help: "list the tables", run: (*cli).tables},
+	{name: "describe", args: "TABLE", help: "describe a table and its indexes", minArgs: 1, maxArgs: 1, run: (*cli).describe},
+	{name: "get", args: "TABLE KEY", help: "get an item by its primary key",
+		flags: []string{"consistent", "project"}, minArgs: 2, maxArgs: 2, run: (*cli).get},
+	{name: "query", args: "TABLE KEY [OP VALUE...]", help: "query a table or index by hash key, and optionally range key",
+		flags: []string{"index", "limit", "consistent", "start-from", "project", "filter", "args", "desc"}, minArgs: 2, maxArgs: 5, run: (*cli).query},
+	{name: "scan", args: "TABLE", help: "scan a table or index",
+		flags: []string{"index", "limit", "consistent", "start-from", "project", "filter", "args"}, minArgs: 1, maxArgs: 1, run: (*cli).scan},
+	{name: "put", args: "TABLE ITEM|-", help: "put an item, or each JSON line of standard input with -",
+		flags: []string{"if", "args"}, minArgs: 2, maxArgs: 2, run: (*cli).put},
+	{name: "update", args: "TABLE KEY", help: "update an item with -set and -remove expressions",
+		flags: []string{"set", "remove", "if", "args"}, minArgs: 2, maxArgs: 2, run: (*cli).update},
+	{name: "delete", args: "TABLE KEY", help: "delete an item",
+		flags: []string{"if", "args"}, minArgs: 2, maxArgs: 2, run: (*cli).delete},
+}
+
+// options are the values of the flags.
+type options struct {
+	region     string
+	endpoint   string
+	output     string
+	index      string
+	limit      int64
+	consistent bool
+	startFrom  string
+	project    string
+	filter     string
+	cond       string
+	args       string
+	desc       bool
+	set        exprList
+	remove     exprList
+}
+
+// exprList is a flag that can be given more than once.
+type exprList []string
+
+func (l *exprList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *exprList) Set(expr string) error {
+	*l = append(*l, expr)
+	return nil
+}
+
+// registerGlobal adds the connection and output flags to fs.
+func (o *options) registerGlobal(fs *flag.FlagSet) {
+	fs.StringVar(&o.region, "region", o.region, "AWS region (default: from the AWS configuration)")
+	fs.StringVar(&o.endpoint, "endpoint", o.endpoint, "DynamoDB endpoint URL, such as http://localhost:8000 (default: $DYNAMO_ENDPOINT)")
+	fs.StringVar(&o.output, "o", o.output, "output format: json or table")
+	fs.StringVar(&o.output, "output", o.output, "output format: json or table")
+}
+
+// register adds the named command flags to fs.
+func (o *options) register(fs *flag.FlagSet, names []string) {
+	for _, name := range names {
+		switch name {
+		case "index":
+			fs.StringVar(&o.index, name, "", "name of the secondary index to use")
+		case "limit":
+			fs.Int64Var(&o.limit, name, 0, "maximum number of items to return")
+		case "consistent":
+			fs.BoolVar(&o.consistent, name, false, "use strongly consistent reads")
+		case "start-from":
+			fs.StringVar(&o.startFrom, name, "", "token of the page to start from, as printed by a previous call with -limit")
+		case "project":
+			fs.StringVar(&o.project, name, "", "comma-separated list of attributes to return")
+		case "filter":
+			fs.StringVar(&o.filter, name, "", "filter expression, such as \"Total > ?\"")
+		case "if":
+			fs.StringVar(&o.cond, name, "", "condition expression, such as \"attribute_exists($)\"")
+		case "args":
+			fs.StringVar(&o.args, name, "", "JSON array of the arguments of the expressions' placeholders")
+		case "desc":
+			fs.BoolVar(&o.desc, name, false, "return items in descending order of range key")
+		case "set":
+			fs.Var(&o.set, name, "SET expression, such as \"Count = Count + ?\" (repeatable)")
+		case "remove":
+			fs.Var(&o.remove, name, "REMOVE expression, such as \"Note\" (repeatable)")
+		}
+	}
+}
+
+// cli runs commands.
+type cli struct {
+	opts   options
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// open connects to DynamoDB. See awscfg.Open.
+	open func(ctx context.Context, region, endpoint string) (*dynamo.DB, error)
+	db   *dynamo.DB
+}
+
+func main() {
+	c := &cli{
+		opts:   options{endpoint: os.Getenv("DYNAMO_ENDPOINT"), output: "json"},
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		open:   awscfg.Open,
+	}
+	os.Exit(c.main(os.Args[1:]))
+}
+
+// main runs the command line args and returns the exit code.
+func (c *cli) main(args []string) int {
+	global := flag.NewFlagSet("dynamo", flag.ContinueOnError)
+	global.SetOutput(c.stderr)
+	global.Usage = c.usage
+	c.opts.registerGlobal(global)
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	args = global.Args()
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+
+	var cmd *command
+	for _, command := range commands {
+		if command.name == args[0] {
+			cmd = command
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(c.stderr, "dynamo: unknown command %q\n", args[0])
+		c.usage()
+		return 2
+	}
+
+	fs := flag.NewFlagSet("dynamo "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: dynamo %s [flags] %s\n\n%s.\n\nflags:\n", cmd.name, cmd.args, strings.ToUpper(cmd.help[:1])+cmd.help[1:])
+		fs.PrintDefaults()
+	}
+	c.opts.registerGlobal(fs)
+	c.opts.register(fs, cmd.flags)
+	pos, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+	if len(pos) < cmd.minArgs || len(pos) > cmd.maxArgs {
+		fs.Usage()
+		return 2
+	}
+	if c.opts.output != "json" && c.opts.output != "table" {
+		fmt.Fprintf(c.stderr, "dynamo: unknown output format %q, must be json or table\n", c.opts.output)
+		return 2
+	}
+
+	ctx := context.Background()
+	c.db, err = c.open(ctx, c.opts.region, c.opts.endpoint)
+	if err == nil {
+		err = cmd.run(c, ctx, pos)
+	}
+	if err != nil {
+		msg := err.Error()
+		if !strings.HasPrefix(msg, "dynamo:") {
+			msg = "dynamo: " + msg
+		}
+		fmt.Fprintln(c.stderr, msg)
+		return 1
+	}
+	return 0
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.stderr, "usage: dynamo [-region R] [-endpoint URL] [-o json|table] <command> [flags] [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-10s %-24s %s\n", cmd.name, cmd.args, cmd.help)
+	}
+	fmt.Fprintf(c.stderr, "\nRun dynamo <command> -h for the flags of a command.\n")
+}
+
+// parseInterspersed parses the flags of fs, which may come before, between or after the positional arguments,
+// and returns the positional arguments. Arguments after -- are never flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(pos, rest...), nil
+		}
+		if len(rest) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// run runs the command line args against client.
+func run(client *fakeClient, stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	c := &cli{
+		opts:   options{output: "json"},
+		stdin:  strings.NewReader(stdin),
+		stdout: &out,
+		stderr: &errOut,
+		open: func(ctx context.Context, region, endpoint string) (*dynamo.DB, error) {
+			return dynamo.NewFromIface(client), nil
+		},
+	}
+	code = c.main(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestTablesAndDescribe(t *testing.T) {
+	code, stdout, stderr := run(newFakeClient(), "", "tables")
+	if code != 0 || stdout != "Orders\nUsers\n" {
+		t.Errorf("tables: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = run(newFakeClient(), "", "-o", "table", "describe", "Orders")
+	if code != 0 {
+		t.Fatalf("describe: exit %d, stderr %q", code, stderr)
+	}
+	want := "NAME          TYPE    HASH KEY    RANGE KEY  PROJECTION  STATUS  ITEMS  SIZE\n" +
+		"Orders        table   UserID (S)  ID (N)                 ACTIVE  2      0\n" +
+		"Status-index  global  Status (S)             ALL         ACTIVE  0      0\n"
+	if stdout != want {
+		t.Errorf("describe: got\n%s\nwant\n%s", stdout, want)
+	}
+
+	code, _, stderr = run(newFakeClient(), "", "describe", "Nope")
+	if code != 1 || !strings.HasPrefix(stderr, "dynamo: ") {
+		t.Errorf("describe of a missing table: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestGet(t *testing.T) {
+	client := newFakeClient()
+	code, stdout, stderr := run(client, "", "get", "-consistent", "Orders", `{"UserID": "u1", "ID": 1}`)
+	if code != 0 {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if want := `{"ID":1,"Status":"paid","Total":12.50,"UserID":"u1"}` + "\n"; stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+	wantKey := map[string]types.AttributeValue{
+		"UserID": &types.AttributeValueMemberS{Value: "u1"},
+		"ID":     &types.AttributeValueMemberN{Value: "1"},
+	}
+	if !reflect.DeepEqual(client.get.Key, wantKey) {
+		t.Errorf("key: got %#v, want %#v", client.get.Key, wantKey)
+	}
+	if client.get.ConsistentRead == nil || !*client.get.ConsistentRead {
+		t.Error("read isn't consistent")
+	}
+
+	for _, key := range []string{`{"UserID": "u1"}`, `{"UserID": "u1", "ID": 1, "Status": "paid"}`, `{`} {
+		if code, _, stderr := run(newFakeClient(), "", "get", "Orders", key); code != 1 {
+			t.Errorf("key %s: exit %d, stderr %q", key, code, stderr)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	client := newFakeClient()
+	code, stdout, stderr := run(client, "", "query", "Orders", `{"UserID": "u1"}`, "between", "1", "5",
+		"-filter", "$ > ?", "-args", `["Total", 10]`, "-limit", "1", "-desc", "-o", "table")
+	if code != 0 {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	want := "UserID  ID  Status  Total\n" +
+		"u1      1   paid    12.50\n"
+	if stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	in := client.query
+	wantRange := types.Condition{
+		AttributeValueList: []types.AttributeValue{
+			&types.AttributeValueMemberN{Value: "1"},
+			&types.AttributeValueMemberN{Value: "5"},
+		},
+		ComparisonOperator: types.ComparisonOperatorBetween,
+	}
+	if got := in.KeyConditions["ID"]; !reflect.DeepEqual(got, wantRange) {
+		t.Errorf("range key condition: got %#v, want %#v", got, wantRange)
+	}
+	if in.FilterExpression == nil || in.ExpressionAttributeNames[strings.Trim(strings.Fields(*in.FilterExpression)[0], "(")] != "Total" {
+		t.Errorf("filter: %v %v", aws.ToString(in.FilterExpression), in.ExpressionAttributeNames)
+	}
+	if in.ScanIndexForward == nil || *in.ScanIndexForward {
+		t.Error("query isn't descending")
+	}
+
+	// the limit was hit, so the next page starts after the last item
+	const prefix = "next page: -start-from "
+	if !strings.HasPrefix(stderr, prefix) {
+		t.Fatalf("stderr: %q", stderr)
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(stderr, prefix))
+	code, _, stderr = run(client, "", "query", "-start-from", token, "Orders", `{"UserID": "u1"}`)
+	if code != 0 {
+		t.Fatalf("start-from: exit %d, stderr %q", code, stderr)
+	}
+	wantStart := map[string]types.AttributeValue{
+		"UserID": &types.AttributeValueMemberS{Value: "u1"},
+		"ID":     &types.AttributeValueMemberN{Value: "1"},
+	}
+	if !reflect.DeepEqual(client.query.ExclusiveStartKey, wantStart) {
+		t.Errorf("start key: got %#v, want %#v", client.query.ExclusiveStartKey, wantStart)
+	}
+	if stderr != "" {
+		t.Errorf("no limit, but stderr: %q", stderr)
+	}
+
+	for _, args := range [][]string{
+		{"query", "Orders", `{"ID": 1}`},
+		{"query", "Orders", `{"UserID": "u1", "Total": 1}`},
+		{"query", "Orders", `{"UserID": "u1"}`, "~", "1"},
+		{"query", "Orders", `{"UserID": "u1"}`, ">", "1", "2"},
+		{"query", "-index", "Status-index", "Orders", `{"Status": "paid"}`, ">", "1"},
+		{"query", "-filter", "Total > ?", "Orders", `{"UserID": "u1"}`},
+		{"query", "-args", "[1]", "Orders", `{"UserID": "u1"}`},
+	} {
+		if code, _, stderr := run(newFakeClient(), "", args...); code != 1 {
+			t.Errorf("%q: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	client := newFakeClient()
+	code, stdout, stderr := run(client, "", "scan", "-index", "Status-index", "-project", "UserID, ID", "Orders")
+	if code != 0 {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if n := strings.Count(stdout, "\n"); n != 2 || stderr != "" {
+		t.Errorf("got %d items: %q, stderr %q", n, stdout, stderr)
+	}
+	if aws.ToString(client.scan.IndexName) != "Status-index" || client.scan.ProjectionExpression == nil {
+		t.Errorf("input: %+v", client.scan)
+	}
+
+	// the key of the next page includes the index key
+	code, stdout, stderr = run(client, "", "scan", "-index", "Status-index", "-limit", "1", "Orders")
+	if code != 0 || strings.Count(stdout, "\n") != 1 {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(stderr, "next page: -start-from "))
+	key, err := decodeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 3 || key["Status"] == nil {
+		t.Errorf("next page key: %#v", key)
+	}
+}
+
+func TestPut(t *testing.T) {
+	client := newFakeClient()
+	code, _, stderr := run(client, "", "put", "-if", "attribute_not_exists($)", "-args", `["UserID"]`,
+		"Orders", `{"UserID": "u2", "ID": 3, "Tags": {"SS": ["a"]}}`)
+	if code != 0 {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if _, ok := client.put[0].Item["Tags"].(*types.AttributeValueMemberSS); !ok {
+		t.Errorf("Tags isn't a string set: %#v", client.put[0].Item["Tags"])
+	}
+	if client.put[0].ConditionExpression == nil {
+		t.Error("no condition")
+	}
+
+	client = newFakeClient()
+	stdin := `{"UserID": "u2", "ID": 3}` + "\n\n" + `{"UserID": "u2", "ID": 4}` + "\n"
+	code, _, stderr = run(client, stdin, "put", "Orders", "-")
+	if code != 0 || len(client.put) != 2 || stderr != "put 2 items\n" {
+		t.Errorf("exit %d, %d puts, stderr %q", code, len(client.put), stderr)
+	}
+
+	code, _, stderr = run(newFakeClient(), `{"UserID": "u2", "ID": 3}`+"\n{\n", "put", "Orders", "-")
+	if code != 1 || !strings.Contains(stderr, "line 2") {
+		t.Errorf("invalid line: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	client := newFakeClient()
+	code, stdout, stderr := run(client, "", "update", "Orders", `{"UserID": "u1", "ID": 1}`,
+		"-set", "Total = Total + ?", "-set", "$ = ?", "-remove", "Note", "-if", "attribute_exists($)",
+		"-args", `[1, "Status", "shipped", "UserID"]`)
+	if code != 0 {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if !strings.Contains(stdout, `"Status":"paid"`) {
+		t.Errorf("stdout: %q", stdout)
+	}
+	in := client.update
+	expr := aws.ToString(in.UpdateExpression)
+	if !strings.HasPrefix(expr, "SET ") || !strings.HasSuffix(expr, " REMOVE Note") || in.ConditionExpression == nil {
+		t.Errorf("expressions: %q, %v", expr, aws.ToString(in.ConditionExpression))
+	}
+	var names []string
+	for _, name := range in.ExpressionAttributeNames {
+		names = append(names, name)
+	}
+	for _, want := range []string{"Total", "Status", "UserID"} {
+		found := false
+		for _, name := range names {
+			found = found || name == want
+		}
+		if !found {
+			t.Errorf("%s isn't in the attribute names %v", want, names)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"update", "Orders", `{"UserID": "u1", "ID": 1}`},
+		{"update", "-set", "A = ?", "Orders", `{"UserID": "u1", "ID": 1}`},
+		{"update", "-set", "A = ?", "-args", `[1, 2]`, "Orders", `{"UserID": "u1", "ID": 1}`},
+	} {
+		if code, _, stderr := run(newFakeClient(), "", args...); code != 1 {
+			t.Errorf("%q: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	client := newFakeClient()
+	code, stdout, stderr := run(client, "", "delete", "Orders", `{"UserID": "u1", "ID": 1}`)
+	if code != 0 || stdout != "" || stderr != "no item deleted\n" {
+		t.Errorf("exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if client.delete.ReturnValues != types.ReturnValueAllOld {
+		t.Errorf("return values: %v", client.delete.ReturnValues)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"nope"},
+		{"get", "Orders"},
+		{"scan", "-desc", "Orders"},
+		{"-o", "yaml", "tables"},
+	} {
+		if code, _, stderr := run(newFakeClient(), "", args...); code != 2 || stderr == "" {
+			t.Errorf("%q: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// printer writes items.
+type printer interface {
+	print(item map[string]types.AttributeValue) error
+	// flush writes buffered items.
+	flush() error
+}
+
+// newPrinter returns a printer for the output format.
+// Table columns start with keys, followed by the other attributes sorted by name.
+func newPrinter(format string, w io.Writer, keys []string) printer {
+	if format == "table" {
+		return &tablePrinter{w: w, keys: keys}
+	}
+	return jsonPrinter{w: w}
+}
+
+// jsonPrinter writes items as JSON lines.
+type jsonPrinter struct {
+	w io.Writer
+}
+
+func (p jsonPrinter) print(item map[string]types.AttributeValue) error {
+	data, err := dynamo.ItemToJSON(item, jsonOptions)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", data)
+	return err
+}
+
+func (p jsonPrinter) flush() error {
+	return nil
+}
+
+// tablePrinter writes items in aligned columns, once they've all been printed.
+type tablePrinter struct {
+	w     io.Writer
+	keys  []string
+	items []map[string]types.AttributeValue
+}
+
+func (p *tablePrinter) print(item map[string]types.AttributeValue) error {
+	p.items = append(p.items, item)
+	return nil
+}
+
+func (p *tablePrinter) flush() error {
+	if len(p.items) == 0 {
+		return nil
+	}
+	columns := p.columns()
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, item := range p.items {
+		cells := make([]string, len(columns))
+		for i, name := range columns {
+			if av, ok := item[name]; ok {
+				cell, err := cellText(av)
+				if err != nil {
+					return err
+				}
+				cells[i] = cell
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	p.items = nil
+	return tw.Flush()
+}
+
+// columns returns the names of the attributes of the items: keys first, then the rest sorted.
+func (p *tablePrinter) columns() []string {
+	seen := make(map[string]bool)
+	var columns, rest []string
+	for _, item := range p.items {
+		for name := range item {
+			if !seen[name] {
+				seen[name] = true
+				rest = append(rest, name)
+			}
+		}
+	}
+	isKey := make(map[string]bool, len(p.keys))
+	for _, key := range p.keys {
+		if seen[key] && !isKey[key] {
+			columns = append(columns, key)
+		}
+		isKey[key] = true
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		if !isKey[name] {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+var cellEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// cellText returns av as text: strings and numbers as they are, and other values as JSON.
+func cellText(av types.AttributeValue) (string, error) {
+	switch x := av.(type) {
+	case *types.AttributeValueMemberS:
+		return cellEscaper.Replace(x.Value), nil
+	case *types.AttributeValueMemberN:
+		return x.Value, nil
+	}
+	data, err := dynamo.ItemToJSON(map[string]types.AttributeValue{"v": av}, jsonOptions)
+	if err != nil {
+		return "", err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", err
+	}
+	return cellEscaper.Replace(string(obj["v"])), nil
+}
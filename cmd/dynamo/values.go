@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+	"github.com/niltonkummer/dynamo/internal/exprs"
+)
+
+// jsonOptions keep the types of sets and binary data, so output can be read back as input.
+var jsonOptions = dynamo.JSONOptions{TagSets: true, TagBinary: true}
+
+// parseItem parses a JSON object into an item.
+func parseItem(data string) (map[string]types.AttributeValue, error) {
+	item, err := dynamo.ItemFromJSON([]byte(data), jsonOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item %s: %v", data, err)
+	}
+	return item, nil
+}
+
+// parseValue parses a JSON value. Strings are returned as is,
+// so they can also be used as attribute names for $ placeholders.
+func parseValue(data []byte) (interface{}, error) {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("invalid value %s: %v", data, err)
+		}
+		return s, nil
+	}
+	item, err := dynamo.ItemFromJSON([]byte(`{"v":`+string(data)+`}`), jsonOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s: %v", data, err)
+	}
+	return item["v"], nil
+}
+
+// parseArgs parses a JSON array of placeholder arguments.
+func parseArgs(data string) ([]interface{}, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return nil, fmt.Errorf("-args must be a JSON array: %v", err)
+	}
+	args := make([]interface{}, 0, len(raw))
+	for _, r := range raw {
+		v, err := parseValue(r)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+// argQueue hands out the arguments of -args to expressions, in order.
+type argQueue struct {
+	args []interface{}
+}
+
+// take returns the arguments of the placeholders of expr.
+func (q *argQueue) take(expr string) ([]interface{}, error) {
+	parsed, err := exprs.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, item := range parsed.Items {
+		if item.Type == exprs.ItemNamePlaceholder || item.Type == exprs.ItemValuePlaceholder {
+			n++
+		}
+	}
+	if n > len(q.args) {
+		return nil, fmt.Errorf("expression %q has %d placeholders, but only %d arguments are left in -args", expr, n, len(q.args))
+	}
+	args := q.args[:n:n]
+	q.args = q.args[n:]
+	return args, nil
+}
+
+// done returns an error if some arguments weren't used.
+func (q *argQueue) done() error {
+	if len(q.args) > 0 {
+		return fmt.Errorf("-args has %d more arguments than the expressions have placeholders", len(q.args))
+	}
+	return nil
+}
+
+// keySchema returns the key attributes of the table or the named index.
+func keySchema(desc dynamo.Description, index string) (hash, rangeKey string, err error) {
+	if index == "" {
+		return desc.HashKey, desc.RangeKey, nil
+	}
+	for _, idx := range append(append([]dynamo.Index{}, desc.GSI...), desc.LSI...) {
+		if idx.Name == index {
+			return idx.HashKey, idx.RangeKey, nil
+		}
+	}
+	return "", "", fmt.Errorf("table %s has no index named %s", desc.Name, index)
+}
+
+// keyNames returns the key attributes of the table, then those of the index, if any.
+// They make up the keys of the pages of queries and scans.
+func keyNames(desc dynamo.Description, index string) ([]string, error) {
+	names := []string{desc.HashKey}
+	if desc.RangeKey != "" {
+		names = append(names, desc.RangeKey)
+	}
+	if index != "" {
+		hash, rangeKey, err := keySchema(desc, index)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range []string{hash, rangeKey} {
+			if name != "" && name != desc.HashKey && name != desc.RangeKey {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// parseKey parses the primary key of an item, which must have the hash key of the table and its range key, if any.
+func parseKey(desc dynamo.Description, data string) (hash, rangeKey types.AttributeValue, err error) {
+	key, err := parseItem(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name := range key {
+		if name != desc.HashKey && name != desc.RangeKey {
+			return nil, nil, fmt.Errorf("%s is not a key of table %s", name, desc.Name)
+		}
+	}
+	hash = key[desc.HashKey]
+	if hash == nil {
+		return nil, nil, fmt.Errorf("key is missing the hash key %s", desc.HashKey)
+	}
+	if desc.RangeKey != "" {
+		if rangeKey = key[desc.RangeKey]; rangeKey == nil {
+			return nil, nil, fmt.Errorf("key is missing the range key %s", desc.RangeKey)
+		}
+	}
+	return hash, rangeKey, nil
+}
+
+// operators are the range key operators of queries.
+var operators = map[string]dynamo.Operator{
+	"=":           dynamo.Equal,
+	"==":          dynamo.Equal,
+	"<":           dynamo.Less,
+	"<=":          dynamo.LessOrEqual,
+	">":           dynamo.Greater,
+	">=":          dynamo.GreaterOrEqual,
+	"begins_with": dynamo.BeginsWith,
+	"between":     dynamo.Between,
+}
+
+// parseRange parses a range key condition, such as [">=", "10"] or ["between", "1", "5"].
+func parseRange(args []string) (dynamo.Operator, []interface{}, error) {
+	op, ok := operators[strings.ToLower(args[0])]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown range key operator %q", args[0])
+	}
+	want := 1
+	if op == dynamo.Between {
+		want = 2
+	}
+	if len(args)-1 != want {
+		return "", nil, fmt.Errorf("range key operator %s takes %d values, got %d", args[0], want, len(args)-1)
+	}
+	values := make([]interface{}, 0, want)
+	for _, arg := range args[1:] {
+		v, err := parseValue([]byte(arg))
+		if err != nil {
+			return "", nil, err
+		}
+		values = append(values, v)
+	}
+	return op, values, nil
+}
+
+// encodeToken encodes the key of a page for -start-from.
+func encodeToken(key dynamo.PagingKey) (string, error) {
+	data, err := dynamo.ItemToDynamoJSON(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeToken decodes a token printed by encodeToken.
+func decodeToken(token string) (dynamo.PagingKey, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -start-from token: %v", err)
+	}
+	key, err := dynamo.ItemFromDynamoJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -start-from token: %v", err)
+	}
+	return key, nil
+}
+
+// splitList splits a comma-separated list, ignoring blanks.
+func splitList(s string) []string {
+	var list []string
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+func TestParseArgs(t *testing.T) {
+	args, err := parseArgs(`["Name", 1.50, {"SS": ["a"]}, {"B": "aGk="}, null]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		"Name",
+		&types.AttributeValueMemberN{Value: "1.50"},
+		&types.AttributeValueMemberSS{Value: []string{"a"}},
+		&types.AttributeValueMemberB{Value: []byte("hi")},
+		&types.AttributeValueMemberNULL{Value: true},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("got %#v, want %#v", args, want)
+	}
+
+	for _, bad := range []string{`{"a": 1}`, `[1`, `[nope]`, `["a]`} {
+		if _, err := parseArgs(bad); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+func TestArgQueue(t *testing.T) {
+	q := &argQueue{args: []interface{}{1, 2, 3}}
+	got, err := q.take("$ = ? AND 'size' > ?")
+	if err != nil || !reflect.DeepEqual(got, []interface{}{1, 2, 3}) {
+		t.Errorf("take: %v, %v", got, err)
+	}
+	if err := q.done(); err != nil {
+		t.Error(err)
+	}
+
+	q = &argQueue{args: []interface{}{1, 2}}
+	if _, err := q.take("A = ?"); err != nil {
+		t.Error(err)
+	}
+	if err := q.done(); err == nil {
+		t.Error("done: no error with an argument left")
+	}
+	if _, err := q.take("A = ? AND B = ?"); err == nil {
+		t.Error("take: no error with too few arguments")
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	op, values, err := parseRange([]string{"BETWEEN", "1", `"b"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{&types.AttributeValueMemberN{Value: "1"}, "b"}
+	if op != dynamo.Between || !reflect.DeepEqual(values, want) {
+		t.Errorf("got %v %#v", op, values)
+	}
+
+	for _, bad := range [][]string{{"~", "1"}, {"between", "1"}, {"<", "1", "2"}, {"=", "x"}} {
+		if _, _, err := parseRange(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	key := dynamo.PagingKey{
+		"UserID": &types.AttributeValueMemberS{Value: "u1"},
+		"ID":     &types.AttributeValueMemberN{Value: "1"},
+	}
+	token, err := encodeToken(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, key) {
+		t.Errorf("got %#v, want %#v", got, key)
+	}
+	if _, err := decodeToken("!"); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "")
+	desc := fs.Bool("desc", false, "")
+	pos, err := parseInterspersed(fs, []string{"Orders", "-limit", "5", "{}", "-desc", "--", "-x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Orders", "{}", "-x"}; !reflect.DeepEqual(pos, want) || *limit != 5 || !*desc {
+		t.Errorf("got %q, limit %d, desc %v", pos, *limit, *desc)
+	}
+}
+
+func TestCellText(t *testing.T) {
+	tests := []struct {
+		av   types.AttributeValue
+		want string
+	}{
+		{&types.AttributeValueMemberS{Value: "a\tb\nc"}, `a\tb\nc`},
+		{&types.AttributeValueMemberN{Value: "1.50"}, "1.50"},
+		{&types.AttributeValueMemberBOOL{Value: true}, "true"},
+		{&types.AttributeValueMemberNS{Value: []string{"1"}}, `{"NS":[1]}`},
+		{&types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "x"}}}, `["x"]`},
+	}
+	for _, test := range tests {
+		got, err := cellText(test.av)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%#v: got %s, want %s", test.av, got, test.want)
+		}
+	}
+}
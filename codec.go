@@ -0,0 +1,503 @@
+package dynamo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// structCodec is the compiled encoding of a struct type.
+// It is built once per type and cached, so that struct tags are
+// only parsed and fields only discovered the first time a type is seen.
+type structCodec struct {
+	fields []*fieldCodec
+	names  map[string]bool
+	// extra is the inline map field that holds unclaimed attributes, if any.
+	extra *fieldCodec
+	// encrypted are the names of the fields tagged with encrypt.
+	encrypted []string
+	// offloaded are the names of the fields tagged with offload.
+	offloaded []string
+	// embedPtrs are the indexes of embedded struct pointers
+	// that are allocated when decoding, parents first.
+	embedPtrs [][]int
+	// err is set if the struct's tags are invalid.
+	err error
+}
+
+// fieldCodec is a compiled struct field.
+type fieldCodec struct {
+	name  string
+	flags encodeFlags
+	index []int
+	// settable is false for fields only reachable through unexported
+	// embedded pointers, which can be encoded but not decoded.
+	settable bool
+	// plain is true for basic kinds without custom (un)marshalers,
+	// which can skip the interface checks in marshal and unmarshalReflect.
+	plain bool
+	// time is the encoding of time.Time fields given by their struct tag, if any.
+	time *timeFormat
+}
+
+// structCodecs caches compiled codecs by type.
+var structCodecs sync.Map // reflect.Type → *structCodec
+
+// codecOf returns the compiled codec of rt, which must be a struct type.
+func codecOf(rt reflect.Type) *structCodec {
+	if codec, ok := structCodecs.Load(rt); ok {
+		return codec.(*structCodec)
+	}
+	codec, _ := structCodecs.LoadOrStore(rt, compileStruct(rt))
+	return codec.(*structCodec)
+}
+
+func compileStruct(rt reflect.Type) *structCodec {
+	codec := new(structCodec)
+	codec.fields = codec.compile(rt, nil, "", true, map[reflect.Type]bool{rt: true})
+	codec.names = make(map[string]bool, len(codec.fields))
+	for _, f := range codec.fields {
+		codec.names[f.name] = true
+		if f.flags&flagEncrypt != 0 {
+			codec.encrypted = append(codec.encrypted, f.name)
+			// keys must stay readable to find items by, and are signed instead
+			if keyTypeFromTag(rt.FieldByIndex(f.index).Tag.Get("dynamo")) != "" {
+				codec.setError(fmt.Errorf("dynamo: %s: key field %s can't be encrypted", rt, f.name))
+			}
+		}
+		if f.flags&flagOffload != 0 {
+			codec.offloaded = append(codec.offloaded, f.name)
+			if f.flags&(flagCompress|flagEncrypt) != 0 && rt.FieldByIndex(f.index).Type == blobType {
+				codec.setError(fmt.Errorf("dynamo: %s: Blob field %s can't be compressed or encrypted", rt, f.name))
+			}
+		}
+	}
+	if codec.extra != nil && codec.extra.flags&flagEncrypt != 0 {
+		codec.setError(fmt.Errorf("dynamo: %s: inline maps can't be encrypted", rt))
+	}
+	if codec.extra != nil && codec.extra.flags&flagOffload != 0 {
+		codec.setError(fmt.Errorf("dynamo: %s: inline maps can't be offloaded", rt))
+	}
+	return codec
+}
+
+// compile returns the fields of rt, flattening embedded structs and inline fields.
+// Fields of the outer struct take precedence over embedded ones with the same name,
+// and the fields of earlier embedded structs take precedence over later ones.
+// Names are prefixed with the prefixes of the inline fields containing them.
+func (codec *structCodec) compile(rt reflect.Type, index []int, prefix string, settable bool, visiting map[reflect.Type]bool) []*fieldCodec {
+	var fields []*fieldCodec
+	pos := make(map[string]int)
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, flags := fieldInfo(field)
+		if name == "-" {
+			continue
+		}
+		idx := append(append([]int{}, index...), i)
+
+		// embed anonymous structs and inline fields, they could be pointers so test that too
+		ft := field.Type
+		isPtr := ft.Kind() == reflect.Ptr
+		isStruct := ft.Kind() == reflect.Struct || isPtr && ft.Elem().Kind() == reflect.Struct
+		inline := flags&flagInline != 0
+		if inline && field.PkgPath != "" {
+			continue
+		}
+		if inline && ft.Kind() == reflect.Map {
+			if ft.Key().Kind() != reflect.String {
+				codec.setError(fmt.Errorf("dynamo: inline map field %s must have string keys (got %s)", field.Name, ft))
+				continue
+			}
+			if codec.extra != nil {
+				codec.setError(fmt.Errorf("dynamo: %s has more than one inline map field", rt))
+				continue
+			}
+			codec.extra = &fieldCodec{
+				name:     prefix + inlinePrefix(field),
+				flags:    flags &^ flagInline,
+				index:    idx,
+				settable: settable,
+			}
+			continue
+		}
+		if inline && !isStruct {
+			codec.setError(fmt.Errorf("dynamo: inline field %s must be a struct or map (got %s)", field.Name, ft))
+			continue
+		}
+		if field.Anonymous && isStruct || inline {
+			inner, innerSettable, innerPrefix := ft, settable, prefix
+			if isPtr {
+				inner = ft.Elem()
+				// unexported pointers can't be allocated
+				innerSettable = settable && field.PkgPath == ""
+			}
+			if inline {
+				innerPrefix += inlinePrefix(field)
+			}
+			if visiting[inner] {
+				continue
+			}
+			if isPtr && innerSettable && !inline {
+				codec.embedPtrs = append(codec.embedPtrs, idx)
+			}
+			visiting[inner] = true
+			for _, f := range codec.compile(inner, idx, innerPrefix, innerSettable, visiting) {
+				// don't clobber top-level fields
+				if _, exists := pos[f.name]; exists {
+					continue
+				}
+				pos[f.name] = len(fields)
+				fields = append(fields, f)
+			}
+			delete(visiting, inner)
+			continue
+		}
+
+		// skip unexported fields
+		if field.PkgPath != "" {
+			continue
+		}
+
+		f := &fieldCodec{
+			name:     prefix + name,
+			flags:    flags,
+			index:    idx,
+			settable: settable,
+			plain:    isPlain(ft),
+		}
+		if isTimeType(ft) {
+			f.time = timeFormatFor(flags, timeLayout(field))
+		}
+		if p, exists := pos[f.name]; exists {
+			fields[p] = f
+			continue
+		}
+		pos[f.name] = len(fields)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// inlinePrefix returns the attribute name prefix of an inline field,
+// which is given in place of the name in its tag.
+func inlinePrefix(field reflect.StructField) string {
+	tag := field.Tag.Get("dynamo")
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+func (codec *structCodec) setError(err error) {
+	if codec.err == nil {
+		codec.err = err
+	}
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType   = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	avMarshalerType   = reflect.TypeOf((*attributevalue.Marshaler)(nil)).Elem()
+	avUnmarshalerType = reflect.TypeOf((*attributevalue.Unmarshaler)(nil)).Elem()
+)
+
+// isPlain returns true if rt is a basic kind that doesn't implement
+// any of the interfaces that customize encoding.
+func isPlain(rt reflect.Type) bool {
+	if isNumberType(rt) {
+		return false
+	}
+	switch rt.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8,
+		reflect.Float32, reflect.Float64:
+	default:
+		return false
+	}
+	// pointer method sets include value methods
+	pt := reflect.PtrTo(rt)
+	for _, iface := range []reflect.Type{marshalerType, unmarshalerType, avMarshalerType, avUnmarshalerType, tmType, tumType} {
+		if pt.Implements(iface) {
+			return false
+		}
+	}
+	return true
+}
+
+// encode marshals the struct rv into an item.
+// Fields tagged with encrypt are encrypted with c, and so are those of nested structs.
+func (codec *structCodec) encode(rv reflect.Value, c *crypter) (map[string]types.AttributeValue, error) {
+	if codec.err != nil {
+		return nil, codec.err
+	}
+	c = c.encodingItem(rv)
+	item := make(map[string]types.AttributeValue, len(codec.fields))
+	for _, f := range codec.fields {
+		fv, ok := fieldForEncode(rv, f.index)
+		if !ok {
+			continue
+		}
+		if f.flags&flagOmitEmpty != 0 && isZero(fv) {
+			continue
+		}
+
+		var av types.AttributeValue
+		var err error
+		switch {
+		case f.time != nil:
+			av = f.time.encodeField(fv, f.flags)
+		case f.plain:
+			av, err = encodeReflect(fv, f.flags, c)
+		default:
+			av, err = encodeValue(fv.Interface(), f.flags, c.child(f.name))
+		}
+		if err == nil && av != nil && f.flags&flagCompress != 0 {
+			av, err = compressAV(av)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if av != nil {
+			item[f.name] = av
+		}
+	}
+	if codec.extra != nil {
+		if err := codec.encodeExtra(rv, item, c.inline(codec.extra.name)); err != nil {
+			return nil, err
+		}
+	}
+	if len(codec.encrypted) > 0 {
+		if err := c.encryptItem(codec, item); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// encodeExtra adds the entries of the inline map field to item,
+// except for those whose names are claimed by other fields.
+func (codec *structCodec) encodeExtra(rv reflect.Value, item map[string]types.AttributeValue, c *crypter) error {
+	fv, ok := fieldForEncode(rv, codec.extra.index)
+	if !ok || fv.Len() == 0 {
+		return nil
+	}
+	av, err := encodeValue(fv.Interface(), codec.extra.flags, c)
+	if err != nil {
+		return err
+	}
+	m, ok := av.(*types.AttributeValueMemberM)
+	if !ok {
+		return nil
+	}
+	for k, v := range m.Value {
+		name := codec.extra.name + k
+		if codec.names[name] {
+			continue
+		}
+		item[name] = v
+	}
+	return nil
+}
+
+// flagsAt returns the flags of the fields along path, a document path in items of struct type rt.
+// Map entries and list elements have no flags.
+// Elements past what the types describe, such as the entries of inline maps or interface values, are left out.
+func flagsAt(rt reflect.Type, path docPath) []encodeFlags {
+	flags := make([]encodeFlags, 0, len(path))
+	for _, elem := range path {
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		switch rt.Kind() {
+		case reflect.Struct:
+			f := codecOf(rt).field(elem)
+			if f == nil {
+				return flags
+			}
+			flags = append(flags, f.flags)
+			rt = rt.FieldByIndex(f.index).Type
+			continue
+		case reflect.Slice, reflect.Array:
+			if elem != "[]" {
+				return flags
+			}
+		case reflect.Map:
+			if elem == "[]" {
+				return flags
+			}
+		default:
+			return flags
+		}
+		flags = append(flags, flagNone)
+		rt = rt.Elem()
+	}
+	return flags
+}
+
+// field returns the field encoded as name, or nil if there is none.
+func (codec *structCodec) field(name string) *fieldCodec {
+	for _, f := range codec.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldForEncode returns the field of rv at index,
+// or false if it is inside of a nil embedded pointer.
+func fieldForEncode(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// decode unmarshals item into the struct rv, which must be settable.
+// Decoding continues past errors, which are returned together as a *DecodeError.
+func (codec *structCodec) decode(d decoder, item map[string]types.AttributeValue, rv reflect.Value) error {
+	if codec.err != nil {
+		return codec.err
+	}
+	rv.Set(reflect.Zero(rv.Type()))
+	for _, idx := range codec.embedPtrs {
+		fv := rv.FieldByIndex(idx)
+		fv.Set(reflect.New(fv.Type().Elem()))
+	}
+
+	crypt := d.crypt.decodingItem(rv.Type(), item)
+	var errs []*FieldError
+	for _, f := range codec.fields {
+		if !f.settable {
+			continue
+		}
+		av, ok := item[f.name]
+		if !ok {
+			continue
+		}
+		fv := fieldForDecode(rv, f.index)
+		d.crypt = crypt.child(f.name)
+		var err error
+		if f.flags&flagOffload != 0 {
+			if key, ok := blobKey(av); ok {
+				if fv.Type() == blobType {
+					fv.Set(reflect.ValueOf(Blob{key: key, blobs: d.blobs}))
+					continue
+				}
+				if av, err = d.blobs.load(d.context(), key); err != nil {
+					errs = append(errs, fieldErrors(pathError(err, f.name, fv.Type(), item[f.name]))...)
+					continue
+				}
+			}
+		}
+		if f.flags&flagEncrypt != 0 {
+			if av, err = crypt.decryptField(f.name, av); err != nil {
+				errs = append(errs, fieldErrors(pathError(err, f.name, fv.Type(), item[f.name]))...)
+				continue
+			}
+		}
+		if f.flags&flagCompress != 0 {
+			if av, err = decompressAV(av, d.opts.MaxDecompressedSize); err != nil {
+				errs = append(errs, fieldErrors(pathError(err, f.name, fv.Type(), item[f.name]))...)
+				continue
+			}
+		}
+		switch {
+		case f.time != nil:
+			err = f.time.decodeField(av, fv)
+		case f.plain:
+			err = d.unmarshalKind(av, fv)
+		default:
+			err = d.unmarshalReflect(av, fv)
+		}
+		if err != nil {
+			errs = append(errs, fieldErrors(pathError(err, f.name, fv.Type(), av))...)
+		}
+	}
+	if codec.extra != nil && codec.extra.settable {
+		d.crypt = crypt
+		if err := codec.decodeExtra(d, item, rv); err != nil {
+			errs = append(errs, fieldErrors(err)...)
+		}
+	}
+	if d.opts.DisallowUnknown {
+		errs = append(errs, codec.unknown(item)...)
+	}
+	if len(errs) > 0 {
+		return &DecodeError{Errors: errs}
+	}
+	return nil
+}
+
+// unknown returns errors for the attributes of item that no field claims, sorted by name.
+func (codec *structCodec) unknown(item map[string]types.AttributeValue) []*FieldError {
+	return unknownAttributes(item, func(name string) bool {
+		return codec.names[name] || codec.extra != nil && codec.extra.settable && strings.HasPrefix(name, codec.extra.name)
+	})
+}
+
+// unknownAttributes returns errors for the attributes of item that aren't claimed, sorted by name.
+func unknownAttributes(item map[string]types.AttributeValue, claimed func(name string) bool) []*FieldError {
+	var names []string
+	for name := range item {
+		if !claimed(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	errs := make([]*FieldError, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, &FieldError{Path: name, AttributeType: avTypeName(item[name]), Err: ErrUnknownAttribute})
+	}
+	return errs
+}
+
+// decodeExtra puts the attributes of item that aren't claimed by any field into the inline map field.
+func (codec *structCodec) decodeExtra(d decoder, item map[string]types.AttributeValue, rv reflect.Value) error {
+	var mapv reflect.Value
+	for name, av := range item {
+		if codec.names[name] || !strings.HasPrefix(name, codec.extra.name) {
+			continue
+		}
+		if !mapv.IsValid() {
+			mapv = fieldForDecode(rv, codec.extra.index)
+			if mapv.IsNil() {
+				mapv.Set(reflect.MakeMap(mapv.Type()))
+			}
+		}
+		innerRV := reflect.New(mapv.Type().Elem()).Elem()
+		if err := d.withCrypter(d.crypt.child(name)).unmarshalReflect(av, innerRV); err != nil {
+			return pathError(err, name, innerRV.Type(), av)
+		}
+		key := reflect.ValueOf(strings.TrimPrefix(name, codec.extra.name)).Convert(mapv.Type().Key())
+		mapv.SetMapIndex(key, innerRV)
+	}
+	return nil
+}
+
+// fieldForDecode returns the field of rv at index, allocating nil pointers along the way.
+func fieldForDecode(rv reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv
+}
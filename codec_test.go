@@ -0,0 +1,203 @@
+package dynamo
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type codecInner struct {
+	Name  string
+	Inner int
+}
+
+type codecOther struct {
+	Name  string
+	Other int
+}
+
+type codecOuter struct {
+	*codecInner
+	codecOther
+	Name string `dynamo:"Name"`
+	Skip int    `dynamo:"-"`
+	priv int
+}
+
+type codecRecursive struct {
+	*codecRecursive
+	ID int
+}
+
+func TestStructCodecFields(t *testing.T) {
+	codec := codecOf(reflect.TypeOf(codecOuter{}))
+	var names []string
+	settable := make(map[string]bool)
+	for _, f := range codec.fields {
+		names = append(names, f.name)
+		settable[f.name] = f.settable
+	}
+	if want := []string{"Name", "Inner", "Other"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("bad fields. want: %v got: %v", want, names)
+	}
+	if settable["Inner"] || !settable["Other"] || !settable["Name"] {
+		t.Errorf("bad settable fields: %v", settable)
+	}
+	if len(codec.embedPtrs) != 0 {
+		t.Errorf("unexported embedded pointer should not be allocated: %v", codec.embedPtrs)
+	}
+
+	// the outer field wins
+	item, err := marshalItem(codecOuter{
+		codecInner: &codecInner{Name: "inner", Inner: 1},
+		codecOther: codecOther{Name: "other", Other: 2},
+		Name:       "outer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]types.AttributeValue{
+		"Name":  &types.AttributeValueMemberS{Value: "outer"},
+		"Inner": &types.AttributeValueMemberN{Value: "1"},
+		"Other": &types.AttributeValueMemberN{Value: "2"},
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, item)
+	}
+
+	// nil embedded pointers are skipped
+	item, err = marshalItem(codecOuter{Name: "outer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := item["Inner"]; ok {
+		t.Errorf("field of nil embedded pointer was encoded: %#v", item)
+	}
+}
+
+func TestStructCodecRecursive(t *testing.T) {
+	codec := codecOf(reflect.TypeOf(codecRecursive{}))
+	if len(codec.fields) != 1 || codec.fields[0].name != "ID" {
+		t.Errorf("bad fields: %#v", codec.fields)
+	}
+
+	var out codecRecursive
+	if err := unmarshalItem(map[string]types.AttributeValue{
+		"ID": &types.AttributeValueMemberN{Value: "1"},
+	}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 1 || out.codecRecursive != nil {
+		t.Errorf("bad result: %#v", out)
+	}
+}
+
+func TestStructCodecConcurrent(t *testing.T) {
+	type concurrent struct {
+		A string
+		B int `dynamo:",omitempty"`
+	}
+	rt := reflect.TypeOf(concurrent{})
+	structCodecs.Delete(rt)
+
+	var wg sync.WaitGroup
+	codecs := make([]*structCodec, 16)
+	for i := range codecs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codecs[i] = codecOf(rt)
+			item, err := marshalItem(concurrent{A: "a"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var out concurrent
+			if err := unmarshalItem(item, &out); err != nil || out.A != "a" {
+				t.Errorf("bad round trip: %#v %v", out, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, codec := range codecs[1:] {
+		if codec != codecs[0] {
+			t.Error("codec compiled more than once")
+			break
+		}
+	}
+}
+
+func TestInlineMap(t *testing.T) {
+	type item struct {
+		ID    int
+		Name  string            `dynamo:",omitempty"`
+		Extra map[string]string `dynamo:",inline"`
+	}
+
+	// fields win over map entries with the same name
+	av, err := marshalItem(item{ID: 1, Extra: map[string]string{"Name": "extra", "Color": "blue"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberN{Value: "1"},
+		"Color": &types.AttributeValueMemberS{Value: "blue"},
+	}
+	if !reflect.DeepEqual(av, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, av)
+	}
+
+	var out item
+	if err := unmarshalItem(map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberN{Value: "1"},
+		"Name":  &types.AttributeValueMemberS{Value: "Alice"},
+		"Color": &types.AttributeValueMemberS{Value: "blue"},
+	}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if wantOut := (item{ID: 1, Name: "Alice", Extra: map[string]string{"Color": "blue"}}); !reflect.DeepEqual(out, wantOut) {
+		t.Errorf("bad result. want: %#v got: %#v", wantOut, out)
+	}
+
+	// prefixed maps only take matching attributes
+	type prefixed struct {
+		ID    int
+		Attrs map[string]string `dynamo:"attr_,inline"`
+	}
+	var pout prefixed
+	if err := unmarshalItem(map[string]types.AttributeValue{
+		"ID":         &types.AttributeValueMemberN{Value: "1"},
+		"attr_color": &types.AttributeValueMemberS{Value: "blue"},
+		"Other":      &types.AttributeValueMemberS{Value: "x"},
+	}, &pout); err != nil {
+		t.Fatal(err)
+	}
+	if wantOut := (prefixed{ID: 1, Attrs: map[string]string{"color": "blue"}}); !reflect.DeepEqual(pout, wantOut) {
+		t.Errorf("bad result. want: %#v got: %#v", wantOut, pout)
+	}
+}
+
+func TestInlineInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+	}{
+		{"two maps", struct {
+			A map[string]string `dynamo:",inline"`
+			B map[string]string `dynamo:"b_,inline"`
+		}{}},
+		{"non-string keys", struct {
+			A map[int]string `dynamo:",inline"`
+		}{}},
+		{"not a struct or map", struct {
+			A string `dynamo:",inline"`
+		}{}},
+	}
+	for _, tc := range tests {
+		if _, err := marshalItem(tc.in); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dynamo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// compressHeader starts every compressed attribute: a magic number, the format version,
+// and the compression algorithm (1 for gzip).
+// The rest of the attribute is the field's encoded value in DynamoDB JSON, compressed.
+var compressHeader = []byte{'d', 'z', 1, 1}
+
+// DefaultMaxDecompressedSize is the size limit of decompressed values,
+// unless changed with DecodeOptions.MaxDecompressedSize.
+const DefaultMaxDecompressedSize = 4 << 20
+
+// compressAV compresses av into a B attribute.
+// NULL values, and values that compression wouldn't make smaller, are left as they are.
+func compressAV(av types.AttributeValue) (types.AttributeValue, error) {
+	if _, ok := av.(*types.AttributeValueMemberNULL); ok {
+		return av, nil
+	}
+	obj, err := av2dynamoJSON(av)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(compressHeader)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	// binary data with the header must be compressed, so it isn't mistaken for a compressed value
+	if b, ok := av.(*types.AttributeValueMemberB); !ok || !bytes.HasPrefix(b.Value, compressHeader) {
+		if buf.Len() >= avSize(av) {
+			return av, nil
+		}
+	}
+	return &types.AttributeValueMemberB{Value: buf.Bytes()}, nil
+}
+
+// decompressAV returns the value compressed into av by compressAV.
+// Values that weren't compressed, such as those written before
+// the compress option was added to a field, are returned as they are.
+// It fails if the value decompresses to more than limit bytes,
+// or DefaultMaxDecompressedSize if limit is zero.
+func decompressAV(av types.AttributeValue, limit int64) (types.AttributeValue, error) {
+	b, ok := av.(*types.AttributeValueMemberB)
+	if !ok || !bytes.HasPrefix(b.Value, compressHeader) {
+		return av, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(b.Value[len(compressHeader):]))
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: decompress: %v", err)
+	}
+	if limit <= 0 {
+		limit = DefaultMaxDecompressedSize
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: decompress: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("dynamo: decompress: value is larger than %d bytes", limit)
+	}
+	return dynamoJSON2av(data)
+}
@@ -0,0 +1,147 @@
+package dynamo
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type compressed struct {
+	ID    int
+	Doc   map[string]interface{} `dynamo:",compress"`
+	Text  string                 `dynamo:",compress"`
+	Tags  []string               `dynamo:",set,compress"`
+	Empty string                 `dynamo:",compress"`
+	Nil   *string                `dynamo:",null,compress"`
+}
+
+func TestCompress(t *testing.T) {
+	long := strings.Repeat("hello world ", 1000)
+	in := compressed{
+		ID:   1,
+		Doc:  map[string]interface{}{"Body": long, "Count": 42.0},
+		Text: long,
+		Tags: []string{"a", "b"},
+	}
+	item, err := marshalItem(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Doc", "Text"} {
+		b, ok := item[name].(*types.AttributeValueMemberB)
+		if !ok {
+			t.Fatalf("%s: expected B, got %#v", name, item[name])
+		}
+		if !bytes.HasPrefix(b.Value, compressHeader) {
+			t.Errorf("%s: missing header: %v", name, b.Value[:4])
+		}
+	}
+	if n := len(item["Text"].(*types.AttributeValueMemberB).Value); n >= len(long) {
+		t.Errorf("value not compressed: %d bytes", n)
+	}
+	// compression would make small values larger, so they're stored as they are
+	if !reflect.DeepEqual(item["Tags"], &types.AttributeValueMemberSS{Value: []string{"a", "b"}}) {
+		t.Errorf("small value should not be compressed: %#v", item["Tags"])
+	}
+	if _, ok := item["Empty"]; ok {
+		t.Error("empty value should be omitted")
+	}
+	if !reflect.DeepEqual(item["Nil"], &types.AttributeValueMemberNULL{Value: true}) {
+		t.Errorf("NULL should not be compressed: %#v", item["Nil"])
+	}
+
+	var out compressed
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("bad round trip. want: %#v got: %#v", in, out)
+	}
+}
+
+func TestCompressLegacy(t *testing.T) {
+	// values written before the compress option was added
+	item := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberN{Value: "1"},
+		"Text": &types.AttributeValueMemberS{Value: "plain"},
+		"Tags": &types.AttributeValueMemberSS{Value: []string{"a"}},
+		"Doc": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"Body": &types.AttributeValueMemberS{Value: "old"},
+		}},
+	}
+	var out compressed
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := compressed{ID: 1, Text: "plain", Tags: []string{"a"}, Doc: map[string]interface{}{"Body": "old"}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("bad result. want: %#v got: %#v", want, out)
+	}
+
+	// uncompressed binary data is left alone
+	type blob struct {
+		Data []byte `dynamo:",compress"`
+	}
+	var bout blob
+	if err := unmarshalItem(map[string]types.AttributeValue{
+		"Data": &types.AttributeValueMemberB{Value: []byte("dz")},
+	}, &bout); err != nil {
+		t.Fatal(err)
+	}
+	if string(bout.Data) != "dz" {
+		t.Errorf("bad binary data: %q", bout.Data)
+	}
+
+	// corrupt compressed data is an error
+	corrupt := append(append([]byte{}, compressHeader...), "garbage"...)
+	err := unmarshalItem(map[string]types.AttributeValue{
+		"Data": &types.AttributeValueMemberB{Value: corrupt},
+	}, &bout)
+	if err == nil {
+		t.Error("expected error for corrupt data, got nil")
+	}
+}
+
+func TestCompressBinaryHeader(t *testing.T) {
+	// small binary data starting with the header is compressed anyway, so it reads back correctly
+	type blob struct {
+		Data []byte `dynamo:",compress"`
+	}
+	in := blob{Data: append(append([]byte{}, compressHeader...), 'x')}
+	item, err := marshalItem(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out blob
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Data, in.Data) {
+		t.Errorf("bad round trip. want: %v got: %v", in.Data, out.Data)
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	type doc struct {
+		Text string `dynamo:",compress"`
+	}
+	item, err := marshalItem(doc{Text: strings.Repeat("a", 10000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out doc
+	err = UnmarshalItemWithOptions(item, &out, DecodeOptions{MaxDecompressedSize: 1000})
+	if err == nil || !strings.Contains(err.Error(), "larger than 1000 bytes") {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+	if err := UnmarshalItemWithOptions(item, &out, DecodeOptions{MaxDecompressedSize: 20000}); err != nil {
+		t.Error(err)
+	}
+	if len(out.Text) != 10000 {
+		t.Errorf("bad decompressed length: %d", len(out.Text))
+	}
+}
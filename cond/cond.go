@@ -0,0 +1,317 @@
+// Package cond provides composable builders for DynamoDB condition and filter expressions.
+//
+// Conditions built with this package can be passed to dynamo's IfExpr and FilterExpr methods,
+// such as Put.IfExpr, Delete.IfExpr, Query.FilterExpr, and Scan.FilterExpr.
+// Attribute names are always substituted, so reserved words are never a problem.
+//
+//	table.Delete("ID", 42).
+//		IfExpr(cond.Name("Score").LE(cutoff).And(cond.BeginsWith("Name", "G"))).
+//		Run()
+package cond
+
+import (
+	"strings"
+)
+
+// Cond is a condition expression.
+// The zero value is an empty condition, which is ignored when combined with others.
+type Cond struct {
+	expr string
+	args []interface{}
+}
+
+// Expr returns this condition using dynamo's placeholder syntax, along with the
+// arguments for its placeholders. Names are given as $ placeholders and values as ? placeholders.
+func (c Cond) Expr() (string, []interface{}) {
+	return c.expr, c.args
+}
+
+// String returns this condition's expression with unsubstituted placeholders.
+func (c Cond) String() string {
+	return c.expr
+}
+
+// IsZero returns true if this is an empty condition.
+func (c Cond) IsZero() bool {
+	return c.expr == ""
+}
+
+// And returns a condition that is true when this condition and all of the others are true.
+func (c Cond) And(others ...Cond) Cond {
+	return join("AND", append([]Cond{c}, others...))
+}
+
+// Or returns a condition that is true when this condition or any of the others are true.
+func (c Cond) Or(others ...Cond) Cond {
+	return join("OR", append([]Cond{c}, others...))
+}
+
+// And returns a condition that is true when all of the given conditions are true.
+func And(conds ...Cond) Cond {
+	return join("AND", conds)
+}
+
+// Or returns a condition that is true when any of the given conditions are true.
+func Or(conds ...Cond) Cond {
+	return join("OR", conds)
+}
+
+// Not returns a condition that negates c.
+func Not(c Cond) Cond {
+	if c.IsZero() {
+		return c
+	}
+	return Cond{
+		expr: "NOT (" + c.expr + ")",
+		args: c.args,
+	}
+}
+
+func join(op string, conds []Cond) Cond {
+	var exprs []string
+	var args []interface{}
+	for _, c := range conds {
+		if c.IsZero() {
+			continue
+		}
+		exprs = append(exprs, "("+c.expr+")")
+		args = append(args, c.args...)
+	}
+	switch len(exprs) {
+	case 0:
+		return Cond{}
+	case 1:
+		// no need for extra parens
+		return Cond{expr: exprs[0][1 : len(exprs[0])-1], args: args}
+	}
+	return Cond{
+		expr: strings.Join(exprs, " "+op+" "),
+		args: args,
+	}
+}
+
+// Operand is one side of a comparison: an attribute path, the size of an attribute, or a value.
+type Operand struct {
+	expr string
+	args []interface{}
+}
+
+// EQ returns a condition that is true when this operand equals v.
+// If v is a Path or Operand, it will be compared as such, otherwise it is treated as a value.
+func (o Operand) EQ(v interface{}) Cond { return o.compare("=", v) }
+
+// NE returns a condition that is true when this operand does not equal v.
+func (o Operand) NE(v interface{}) Cond { return o.compare("<>", v) }
+
+// LT returns a condition that is true when this operand is less than v.
+func (o Operand) LT(v interface{}) Cond { return o.compare("<", v) }
+
+// LE returns a condition that is true when this operand is less than or equal to v.
+func (o Operand) LE(v interface{}) Cond { return o.compare("<=", v) }
+
+// GT returns a condition that is true when this operand is greater than v.
+func (o Operand) GT(v interface{}) Cond { return o.compare(">", v) }
+
+// GE returns a condition that is true when this operand is greater than or equal to v.
+func (o Operand) GE(v interface{}) Cond { return o.compare(">=", v) }
+
+// Between returns a condition that is true when this operand is greater than or equal to lower,
+// and less than or equal to upper.
+func (o Operand) Between(lower, upper interface{}) Cond {
+	lo := operandOf(lower)
+	hi := operandOf(upper)
+	return Cond{
+		expr: o.expr + " BETWEEN " + lo.expr + " AND " + hi.expr,
+		args: concat(o.args, lo.args, hi.args),
+	}
+}
+
+// In returns a condition that is true when this operand is equal to any of the given values.
+// DynamoDB allows up to 100 values.
+func (o Operand) In(values ...interface{}) Cond {
+	subs := make([]string, 0, len(values))
+	args := concat(o.args)
+	for _, v := range values {
+		op := operandOf(v)
+		subs = append(subs, op.expr)
+		args = append(args, op.args...)
+	}
+	return Cond{
+		expr: o.expr + " IN (" + strings.Join(subs, ", ") + ")",
+		args: args,
+	}
+}
+
+func (o Operand) compare(op string, v interface{}) Cond {
+	other := operandOf(v)
+	return Cond{
+		expr: o.expr + " " + op + " " + other.expr,
+		args: concat(o.args, other.args),
+	}
+}
+
+// Value returns an operand for the literal value v.
+// This is only necessary when v would otherwise be interpreted as a Path or Operand.
+func Value(v interface{}) Operand {
+	return Operand{expr: "?", args: []interface{}{v}}
+}
+
+func operandOf(v interface{}) Operand {
+	switch x := v.(type) {
+	case Path:
+		return x.Operand
+	case *Path:
+		return x.Operand
+	case Operand:
+		return x
+	case *Operand:
+		return *x
+	}
+	return Value(v)
+}
+
+// Path is a document path to an attribute.
+type Path struct {
+	Operand
+}
+
+// Name returns a path to the top-level attribute called name.
+// Use Field and Index to refer to nested attributes.
+// The name is always substituted, so it can be any string including reserved words.
+func Name(name string) Path {
+	return Path{Operand{expr: "$", args: []interface{}{name}}}
+}
+
+// Field returns a path to the map key called name inside of this path.
+func (p Path) Field(name string) Path {
+	return Path{Operand{
+		expr: p.expr + ".$",
+		args: concat(p.args, []interface{}{name}),
+	}}
+}
+
+// Index returns a path to the list element at index i inside of this path.
+func (p Path) Index(i int) Path {
+	return Path{Operand{
+		expr: p.expr + "[$]",
+		args: concat(p.args, []interface{}{i}),
+	}}
+}
+
+// Exists returns a condition that is true when this path exists.
+func (p Path) Exists() Cond {
+	return p.fn("attribute_exists")
+}
+
+// NotExists returns a condition that is true when this path does not exist.
+func (p Path) NotExists() Cond {
+	return p.fn("attribute_not_exists")
+}
+
+// Type returns a condition that is true when this path is of the given type.
+func (p Path) Type(typ Type) Cond {
+	return p.fn("attribute_type", string(typ))
+}
+
+// BeginsWith returns a condition that is true when this path begins with prefix.
+func (p Path) BeginsWith(prefix interface{}) Cond {
+	return p.fn("begins_with", prefix)
+}
+
+// Contains returns a condition that is true when this path contains v.
+// This path can be a string, in which case v must be a substring,
+// or a set or list, in which case v must be an element of it.
+func (p Path) Contains(v interface{}) Cond {
+	return p.fn("contains", v)
+}
+
+// Size returns an operand representing the size of this path.
+func (p Path) Size() Operand {
+	return Operand{
+		expr: "size(" + p.expr + ")",
+		args: p.args,
+	}
+}
+
+func (p Path) fn(name string, params ...interface{}) Cond {
+	expr := name + "(" + p.expr
+	args := concat(p.args)
+	for _, param := range params {
+		op := operandOf(param)
+		expr += ", " + op.expr
+		args = append(args, op.args...)
+	}
+	return Cond{
+		expr: expr + ")",
+		args: args,
+	}
+}
+
+// AttributeExists returns a condition that is true when the attribute called name exists.
+func AttributeExists(name string) Cond {
+	return Name(name).Exists()
+}
+
+// AttributeNotExists returns a condition that is true when the attribute called name does not exist.
+func AttributeNotExists(name string) Cond {
+	return Name(name).NotExists()
+}
+
+// AttributeType returns a condition that is true when the attribute called name is of the given type.
+func AttributeType(name string, typ Type) Cond {
+	return Name(name).Type(typ)
+}
+
+// BeginsWith returns a condition that is true when the attribute called name begins with prefix.
+func BeginsWith(name string, prefix interface{}) Cond {
+	return Name(name).BeginsWith(prefix)
+}
+
+// Contains returns a condition that is true when the attribute called name contains v.
+func Contains(name string, v interface{}) Cond {
+	return Name(name).Contains(v)
+}
+
+// In returns a condition that is true when the attribute called name is equal to any of the given values.
+func In(name string, values ...interface{}) Cond {
+	return Name(name).In(values...)
+}
+
+// Between returns a condition that is true when the attribute called name is between lower and upper, inclusive.
+func Between(name string, lower, upper interface{}) Cond {
+	return Name(name).Between(lower, upper)
+}
+
+// Size returns an operand representing the size of the attribute called name.
+func Size(name string) Operand {
+	return Name(name).Size()
+}
+
+// Type is a DynamoDB attribute type, for use with AttributeType.
+type Type string
+
+// Attribute types.
+const (
+	String    Type = "S"
+	StringSet Type = "SS"
+	Number    Type = "N"
+	NumberSet Type = "NS"
+	Binary    Type = "B"
+	BinarySet Type = "BS"
+	Boolean   Type = "BOOL"
+	Null      Type = "NULL"
+	List      Type = "L"
+	Map       Type = "M"
+)
+
+func concat(args ...[]interface{}) []interface{} {
+	var n int
+	for _, a := range args {
+		n += len(a)
+	}
+	all := make([]interface{}, 0, n)
+	for _, a := range args {
+		all = append(all, a...)
+	}
+	return all
+}
@@ -0,0 +1,86 @@
+package cond
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCond(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Cond
+		expr string
+		args []interface{}
+	}{
+		{
+			name: "compare",
+			in:   Name("Score").LE(10),
+			expr: "$ <= ?",
+			args: []interface{}{"Score", 10},
+		},
+		{
+			name: "and",
+			in:   Name("Score").LE(10).And(BeginsWith("Name", "G")),
+			expr: "($ <= ?) AND (begins_with($, ?))",
+			args: []interface{}{"Score", 10, "Name", "G"},
+		},
+		{
+			name: "or + not",
+			in:   Or(AttributeNotExists("ID"), Not(Name("Count").EQ(0))),
+			expr: "(attribute_not_exists($)) OR (NOT ($ = ?))",
+			args: []interface{}{"ID", "Count", 0},
+		},
+		{
+			name: "empty conds are skipped",
+			in:   And(Cond{}, AttributeExists("ID"), Cond{}),
+			expr: "attribute_exists($)",
+			args: []interface{}{"ID"},
+		},
+		{
+			name: "nested path",
+			in:   Name("Map").Field("Key").Index(2).GT(Name("Other")),
+			expr: "$.$[$] > $",
+			args: []interface{}{"Map", "Key", 2, "Other"},
+		},
+		{
+			name: "size",
+			in:   Size("Tags").GE(3),
+			expr: "size($) >= ?",
+			args: []interface{}{"Tags", 3},
+		},
+		{
+			name: "in",
+			in:   In("Status", "A", "B", "C"),
+			expr: "$ IN (?, ?, ?)",
+			args: []interface{}{"Status", "A", "B", "C"},
+		},
+		{
+			name: "between",
+			in:   Between("Time", 1, 2),
+			expr: "$ BETWEEN ? AND ?",
+			args: []interface{}{"Time", 1, 2},
+		},
+		{
+			name: "type + contains",
+			in:   AttributeType("Tags", StringSet).And(Contains("Tags", "cool")),
+			expr: "(attribute_type($, ?)) AND (contains($, ?))",
+			args: []interface{}{"Tags", "SS", "Tags", "cool"},
+		},
+		{
+			name: "value that looks like a path",
+			in:   Name("A").EQ(Value(Name("B"))),
+			expr: "$ = ?",
+			args: []interface{}{"A", Name("B")},
+		},
+	}
+
+	for _, tc := range tests {
+		expr, args := tc.in.Expr()
+		if expr != tc.expr {
+			t.Errorf("%s: bad expr. want: %s got: %s", tc.name, tc.expr, expr)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%s: bad args. want: %v got: %v", tc.name, tc.args, args)
+		}
+	}
+}
@@ -44,12 +44,13 @@ func (check *ConditionCheck) Range(rangeKey string, value interface{}) *Conditio
 // If specifies a conditional expression for this coniditon check to succeed.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 // Multiple calls to If will be combined with AND.
 func (check *ConditionCheck) If(expr string, args ...interface{}) *ConditionCheck {
-	expr = wrapExpr(expr)
-	cond, err := check.subExprN(expr, args...)
+	cond, err := check.subCond(expr, args)
 	check.setError(err)
+	if cond == "" {
+		return check
+	}
 	if check.condition != "" {
 		check.condition += " AND "
 	}
@@ -57,6 +58,16 @@ func (check *ConditionCheck) If(expr string, args ...interface{}) *ConditionChec
 	return check
 }
 
+// IfExpr is like If, but takes an Expression, such as a condition built with the cond package.
+func (check *ConditionCheck) IfExpr(expr Expression) *ConditionCheck {
+	if expr == nil {
+		check.setError(errNilExpression)
+		return check
+	}
+	str, args := expr.Expr()
+	return check.If(str, args...)
+}
+
 // IfExists sets this check to succeed if the item exists.
 func (check *ConditionCheck) IfExists() *ConditionCheck {
 	return check.If("attribute_exists($)", check.hashKey)
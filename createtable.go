@@ -74,6 +74,11 @@ type CreateTable struct {
 // This creates a table with the primary hash key ID and range key Time.
 // It creates two global secondary indices called UUID-index and Seq-ID-index,
 // and a local secondary index called ID-Seq-index.
+//
+// Index tags may also declare the index's projection and provisioned throughput,
+// as in `index:"UUID-index,hash,projection=keys_only,read=5,write=5"`,
+// and fields tagged with `project:"UUID-index"` are included in that index's projection.
+// Indexes can also be declared by from's DynamoIndexes method; see IndexDeclarer.
 func (db *DB) CreateTable(name string, from interface{}) *CreateTable {
 	ct := &CreateTable{
 		db:            db,
@@ -87,6 +92,9 @@ func (db *DB) CreateTable(name string, from interface{}) *CreateTable {
 	}
 	rv := reflect.ValueOf(from)
 	ct.setError(ct.from(rv))
+	if ct.err == nil {
+		ct.setError(ct.declare(indexesOf(rv.Type())))
+	}
 	return ct
 }
 
@@ -279,8 +287,7 @@ func (ct *CreateTable) from(rv reflect.Value) error {
 		if gsi, ok := tagLookup(string(field.Tag), "index"); ok {
 			for _, index := range gsi {
 				ct.add(name, typeOf(fv, field.Tag.Get("dynamo")))
-				keyType := keyTypeFromTag(index)
-				indexName := index[:len(index)-len(keyType)-1]
+				indexName, keyType, _ := parseIndexTag(index)
 				idx := ct.globalIndices[indexName]
 				idx.KeySchema = append(idx.KeySchema, types.KeySchemaElement{
 					AttributeName: &name,
@@ -294,8 +301,7 @@ func (ct *CreateTable) from(rv reflect.Value) error {
 		if lsi, ok := tagLookup(string(field.Tag), "localIndex"); ok {
 			for _, localIndex := range lsi {
 				ct.add(name, typeOf(fv, field.Tag.Get("dynamo")))
-				keyType := keyTypeFromTag(localIndex)
-				indexName := localIndex[:len(localIndex)-len(keyType)-1]
+				indexName, keyType, _ := parseIndexTag(localIndex)
 				idx := ct.localIndices[indexName]
 				idx.KeySchema = append(idx.KeySchema, types.KeySchemaElement{
 					AttributeName: &name,
@@ -309,6 +315,52 @@ func (ct *CreateTable) from(rv reflect.Value) error {
 	return nil
 }
 
+// declare applies the projections and throughput declared by struct tags and DynamoIndexes,
+// and adds the indexes that only DynamoIndexes declares.
+func (ct *CreateTable) declare(set *indexSet) error {
+	if set.err != nil {
+		return set.err
+	}
+	for _, idx := range set.list() {
+		_, global := ct.globalIndices[idx.Name]
+		_, local := ct.localIndices[idx.Name]
+		if !global && !local {
+			if idx.HashKeyType == "" {
+				idx.HashKeyType = ct.attribType(idx.HashKey)
+			}
+			if idx.RangeKey != "" && idx.RangeKeyType == "" {
+				idx.RangeKeyType = ct.attribType(idx.RangeKey)
+			}
+			ct.Index(idx)
+			continue
+		}
+		if idx.ProjectionType != "" {
+			ct.Project(idx.Name, idx.ProjectionType, idx.ProjectionAttribs...)
+		}
+		if global && (idx.Throughput.Read != 0 || idx.Throughput.Write != 0) {
+			read, write := idx.Throughput.Read, idx.Throughput.Write
+			if read == 0 {
+				read = 1
+			}
+			if write == 0 {
+				write = 1
+			}
+			ct.ProvisionIndex(idx.Name, read, write)
+		}
+	}
+	return ct.err
+}
+
+// attribType returns the type of the key attribute name, if it has been defined.
+func (ct *CreateTable) attribType(name string) KeyType {
+	for _, attr := range ct.attribs {
+		if *attr.AttributeName == name {
+			return KeyType(attr.AttributeType)
+		}
+	}
+	return NoneType
+}
+
 func (ct *CreateTable) input() *dynamodb.CreateTableInput {
 	sortKeySchemas(ct.schema)
 	input := &dynamodb.CreateTableInput{
@@ -406,11 +458,17 @@ func typeOf(rv reflect.Value, tag string) string {
 	split := strings.Split(tag, ",")
 	if len(split) > 1 {
 		for _, v := range split[1:] {
-			if v == "unixtime" {
+			if v == "unixtime" || v == "unixmilli" || v == "unixnano" {
 				return "N"
 			}
+			if v == "compress" || v == "encrypt" {
+				return "B"
+			}
 		}
 	}
+	if isNumberType(rv.Type()) {
+		return "N"
+	}
 	if rv.CanInterface() {
 		switch x := rv.Interface().(type) {
 		case Marshaler:
@@ -1,9 +1,15 @@
 // Package dynamo offers a rich DynamoDB client.
+//
+// Conditions, filters and projections use DynamoDB's expression syntax, with ? as a placeholder
+// for a value and $ as a placeholder for an attribute name. Names that are reserved words
+// in DynamoDB (like Count) are automatically escaped. Conditions and filters can also be built
+// with the cond package and given to methods such as Put.IfExpr and Query.FilterExpr.
 package dynamo
 
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
@@ -14,7 +20,13 @@ import (
 
 // DB is a DynamoDB client.
 type DB struct {
-	client dynamodbiface.DynamoDBAPI
+	client     dynamodbiface.DynamoDBAPI
+	clock      func() time.Time
+	decodeOpts DecodeOptions
+	crypt      *crypter
+	blobs      *offloader
+
+	validateSizes bool
 }
 
 // New creates a new client with the given configuration.
@@ -27,7 +39,7 @@ func New(cfg aws.Config) *DB {
 
 // NewFromIface creates a new client with the given interface.
 func NewFromIface(client dynamodbiface.DynamoDBAPI) *DB {
-	return &DB{client}
+	return &DB{client: client}
 }
 
 // Client returns this DB's internal client used to make API requests.
@@ -35,6 +47,50 @@ func (db *DB) Client() dynamodbiface.DynamoDBAPI {
 	return db.client
 }
 
+// SetClock sets the function used to get the current time for created and updated timestamp fields.
+// The default clock is time.Now in UTC. This is useful for tests.
+func (db *DB) SetClock(clock func() time.Time) {
+	db.clock = clock
+}
+
+// SetDecodeOptions sets the options used to unmarshal the results of every request.
+// Queries and scans can override them with DecodeOptions.
+func (db *DB) SetDecodeOptions(opts DecodeOptions) {
+	db.decodeOpts = opts
+}
+
+func (db *DB) decoder() decoder {
+	if db == nil {
+		return decoder{}
+	}
+	return decoder{opts: db.decodeOpts, crypt: db.crypt, blobs: db.blobs}
+}
+
+// SetEncryptor sets the Encryptor used to encrypt and decrypt fields tagged with encrypt.
+// Encrypted fields are stored as binary attributes holding the key ID, nonce and ciphertext.
+// Without an Encryptor, marshaling or unmarshaling a struct with encrypted fields fails.
+func (db *DB) SetEncryptor(enc Encryptor, opts EncryptOptions) {
+	if enc == nil {
+		db.crypt = nil
+		return
+	}
+	db.crypt = &crypter{enc: enc, opts: opts}
+}
+
+func (db *DB) crypter() *crypter {
+	if db == nil {
+		return nil
+	}
+	return db.crypt
+}
+
+func (db *DB) now() time.Time {
+	if db.clock != nil {
+		return db.clock()
+	}
+	return time.Now().UTC()
+}
+
 // ListTables is a request to list tables.
 // See: http://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_ListTables.html
 type ListTables struct {
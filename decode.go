@@ -1,10 +1,13 @@
 package dynamo
 
 import (
+	"context"
 	"encoding"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -23,15 +26,130 @@ type ItemUnmarshaler interface {
 	UnmarshalDynamoItem(item map[string]types.AttributeValue) error
 }
 
+// ItemDecoder is the interface implemented by objects that can unmarshal
+// an Item into themselves with the settings of the caller, such as its DecodeOptions.
+// It takes precedence over ItemUnmarshaler.
+// Unmarshalers generated by dynamo-codec implement both.
+type ItemDecoder interface {
+	DecodeDynamoItem(item map[string]types.AttributeValue, d *Decoder) error
+}
+
+// Decoder holds the settings an item is unmarshaled with: the DecodeOptions
+// of the DB or query, its Encryptor and its blob store.
+// ItemDecoders pass it to Field.Decode for the fields they don't decode themselves.
+// A nil *Decoder decodes like UnmarshalItem.
+type Decoder struct {
+	d decoder
+}
+
+func (dec *Decoder) decoder() decoder {
+	if dec == nil {
+		return decoder{}
+	}
+	return dec.d
+}
+
+// Unknown returns errors for the attributes of item that aren't in names,
+// sorted by name, if DecodeOptions.DisallowUnknown is set. Otherwise, it returns nil.
+func (dec *Decoder) Unknown(item map[string]types.AttributeValue, names []string) []error {
+	if !dec.decoder().opts.DisallowUnknown {
+		return nil
+	}
+	unknown := unknownAttributes(item, func(name string) bool {
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	})
+	errs := make([]error, 0, len(unknown))
+	for _, err := range unknown {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // Unmarshal decodes a DynamoDB item into out, which must be a pointer.
 func UnmarshalItem(item map[string]types.AttributeValue, out interface{}) error {
 	return unmarshalItem(item, out)
 }
 
+// UnmarshalItem decodes a DynamoDB item into out, which must be a pointer,
+// with the DB's decoding options and Encryptor.
+func (db *DB) UnmarshalItem(item map[string]types.AttributeValue, out interface{}) error {
+	return db.decoder().unmarshalItem(item, out)
+}
+
+// UnmarshalItemWithOptions decodes a DynamoDB item into out, which must be a pointer,
+// according to opts.
+func UnmarshalItemWithOptions(item map[string]types.AttributeValue, out interface{}, opts DecodeOptions) error {
+	return decoder{opts: opts}.unmarshalItem(item, out)
+}
+
 // Unmarshal decodes a DynamoDB value into out, which must be a pointer.
 func Unmarshal(av types.AttributeValue, out interface{}) error {
 	rv := reflect.ValueOf(out)
-	return unmarshalReflect(av, rv)
+	return decoder{}.unmarshalReflect(av, rv)
+}
+
+// DecodeOptions control how items are unmarshaled.
+// Set them for every request with DB.SetDecodeOptions,
+// or for a single query or scan with DecodeOptions.
+type DecodeOptions struct {
+	// DisallowUnknown makes unmarshaling into a struct fail
+	// if the item has an attribute that no field claims.
+	DisallowUnknown bool
+	// Strict makes unmarshaling fail on lossy or implicit conversions:
+	// numbers that overflow their field's type, and numbers unmarshaled into
+	// a time.Time without a time encoding (such as unixtime) in its struct tag.
+	Strict bool
+	// MaxDecompressedSize is the size limit, in bytes, of values decompressed
+	// for fields tagged with compress. Zero means DefaultMaxDecompressedSize.
+	MaxDecompressedSize int64
+}
+
+// decoder unmarshals items according to its options.
+type decoder struct {
+	opts  DecodeOptions
+	crypt *crypter
+	blobs *offloader
+	// ctx is used to load offloaded attributes, if set.
+	ctx context.Context
+}
+
+// withContext returns a decoder that loads offloaded attributes with ctx.
+func (d decoder) withContext(ctx context.Context) decoder {
+	d.ctx = ctx
+	return d
+}
+
+func (d decoder) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// withCrypter returns a decoder that decrypts with c, the crypter of the value being decoded.
+func (d decoder) withCrypter(c *crypter) decoder {
+	d.crypt = c
+	return d
+}
+
+// unmarshalItem unmarshals item into out with the default options.
+func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error {
+	return decoder{}.unmarshalItem(item, out)
+}
+
+// unmarshalAppend unmarshals item and appends it to out with the default options.
+func unmarshalAppend(item map[string]types.AttributeValue, out interface{}) error {
+	return decoder{}.unmarshalAppend(item, out)
+}
+
+// unmarshalReflect unmarshals av into rv with the default options.
+func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
+	return decoder{}.unmarshalReflect(av, rv)
 }
 
 // used in iterators for unmarshaling one item
@@ -41,7 +159,7 @@ var nilTum encoding.TextUnmarshaler
 var tumType = reflect.TypeOf(&nilTum).Elem()
 
 // unmarshals one value
-func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
+func (d decoder) unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 	// first try interface unmarshal stuff
 	if rv.CanInterface() {
 		var iface interface{}
@@ -51,11 +169,18 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 			iface = rv.Interface()
 		}
 
+		if ok, err := unmarshalNumber(av, iface); ok {
+			return err
+		}
+
 		if x, ok := iface.(*time.Time); ok {
 			if t, ok := av.(*types.AttributeValueMemberN); ok {
+				if d.opts.Strict {
+					return fmt.Errorf("dynamo: cannot unmarshal N data into time.Time without a time encoding such as unixtime")
+				}
 
-				// implicit unixtime
-				// TODO(guregu): have unixtime unmarshal explicitly check struct tags
+				// implicit unixtime, for backwards compatibility
+				// fields with a time encoding in their struct tag are decoded by their timeFormat instead
 				ts, err := strconv.ParseInt(t.Value, 10, 64)
 				if err != nil {
 					return err
@@ -122,6 +247,12 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		}
 	}
 
+	return d.unmarshalKind(av, rv)
+}
+
+// unmarshalKind unmarshals one value based on rv's kind,
+// without checking for custom unmarshalers.
+func (d decoder) unmarshalKind(av types.AttributeValue, rv reflect.Value) error {
 	if !rv.CanSet() {
 		return nil
 	}
@@ -136,13 +267,13 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		pt := reflect.New(rv.Type().Elem())
 		rv.Set(pt)
 		if !valueIsNull || (valueIsNull && !nullValue.Value) {
-			return unmarshalReflect(av, rv.Elem())
+			return d.unmarshalReflect(av, rv.Elem())
 		}
 		return nil
 	case reflect.Bool:
 		boolValue, valueIsBool := av.(*types.AttributeValueMemberBOOL)
 		if !valueIsBool {
-			return fmt.Errorf("dynamo: cannot unmarshal %s data into bool", avTypeName(boolValue))
+			return fmt.Errorf("dynamo: cannot unmarshal %s data into bool", avTypeName(av))
 		}
 		rv.SetBool(boolValue.Value)
 		return nil
@@ -155,6 +286,9 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		if err != nil {
 			return err
 		}
+		if d.opts.Strict && rv.OverflowInt(n) {
+			return fmt.Errorf("dynamo: cannot unmarshal %s into %s: overflow", nValue.Value, rv.Type())
+		}
 		rv.SetInt(n)
 		return nil
 	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
@@ -166,6 +300,9 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		if err != nil {
 			return err
 		}
+		if d.opts.Strict && rv.OverflowUint(n) {
+			return fmt.Errorf("dynamo: cannot unmarshal %s into %s: overflow", nValue.Value, rv.Type())
+		}
 		rv.SetUint(n)
 		return nil
 	case reflect.Float64, reflect.Float32:
@@ -177,6 +314,9 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		if err != nil {
 			return err
 		}
+		if d.opts.Strict && rv.OverflowFloat(n) {
+			return fmt.Errorf("dynamo: cannot unmarshal %s into %s: overflow", nValue.Value, rv.Type())
+		}
 		rv.SetFloat(n)
 		return nil
 	case reflect.String:
@@ -191,7 +331,7 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		if !valueIsM {
 			return fmt.Errorf("dynamo: cannot unmarshal %s data into struct", avTypeName(av))
 		}
-		if err := unmarshalItem(mValue.Value, rv.Addr().Interface()); err != nil {
+		if err := d.unmarshalItem(mValue.Value, rv.Addr().Interface()); err != nil {
 			return err
 		}
 		return nil
@@ -228,8 +368,8 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 			kv := kp.Elem()
 			for k, v := range item.Value {
 				innerRV := reflect.New(rv.Type().Elem())
-				if err := unmarshalReflect(v, innerRV.Elem()); err != nil {
-					return err
+				if err := d.withCrypter(d.crypt.child(k)).unmarshalReflect(v, innerRV.Elem()); err != nil {
+					return pathError(err, k, innerRV.Type().Elem(), v)
 				}
 				if kp.Type().Implements(tumType) {
 					tm := kp.Interface().(encoding.TextUnmarshaler)
@@ -259,9 +399,9 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 			return nil
 		case *types.AttributeValueMemberNS:
 			kv := reflect.New(rv.Type().Key()).Elem()
-			for _, n := range item.Value {
-				if err := unmarshalReflect(&types.AttributeValueMemberN{Value: n}, kv); err != nil {
-					return err
+			for i, n := range item.Value {
+				if err := d.unmarshalReflect(&types.AttributeValueMemberN{Value: n}, kv); err != nil {
+					return pathError(err, indexPath(i), kv.Type(), &types.AttributeValueMemberN{Value: n})
 				}
 				rv.SetMapIndex(kv, truthy)
 			}
@@ -276,7 +416,7 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 		}
 		return fmt.Errorf("dynamo: cannot unmarshal %s data into map", avTypeName(av))
 	case reflect.Slice:
-		return unmarshalSlice(av, rv)
+		return d.unmarshalSlice(av, rv)
 	case reflect.Array:
 		arr := reflect.New(rv.Type()).Elem()
 		elemtype := arr.Type().Elem()
@@ -292,10 +432,11 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 			if len(t.Value) > arr.Len() {
 				return fmt.Errorf("dynamo: cannot marshal %s into %s; too small (dst len: %d, src len: %d)", avTypeName(av), arr.Type().String(), arr.Len(), len(t.Value))
 			}
+			elem := d.withCrypter(d.crypt.elem())
 			for i, innerAV := range t.Value {
 				innerRV := reflect.New(elemtype).Elem()
-				if err := unmarshalReflect(innerAV, innerRV); err != nil {
-					return err
+				if err := elem.unmarshalReflect(innerAV, innerRV); err != nil {
+					return pathError(err, indexPath(i), elemtype, innerAV)
 				}
 				arr.Index(i).Set(innerRV)
 			}
@@ -323,7 +464,7 @@ func unmarshalReflect(av types.AttributeValue, rv reflect.Value) error {
 }
 
 // unmarshal for when rv's Kind is Slice
-func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
+func (d decoder) unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 	switch t := av.(type) {
 	case *types.AttributeValueMemberB:
 		rv.SetBytes(t.Value)
@@ -331,10 +472,11 @@ func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 
 	case *types.AttributeValueMemberL:
 		slicev := reflect.MakeSlice(rv.Type(), 0, len(t.Value))
-		for _, innerAV := range t.Value {
+		elem := d.withCrypter(d.crypt.elem())
+		for i, innerAV := range t.Value {
 			innerRV := reflect.New(rv.Type().Elem()).Elem()
-			if err := unmarshalReflect(innerAV, innerRV); err != nil {
-				return err
+			if err := elem.unmarshalReflect(innerAV, innerRV); err != nil {
+				return pathError(err, indexPath(i), innerRV.Type(), innerAV)
 			}
 			slicev = reflect.Append(slicev, innerRV)
 		}
@@ -344,10 +486,11 @@ func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 	// there's probably a better way to do these
 	case *types.AttributeValueMemberBS:
 		slicev := reflect.MakeSlice(rv.Type(), 0, len(t.Value))
-		for _, b := range t.Value {
+		for i, b := range t.Value {
 			innerRV := reflect.New(rv.Type().Elem()).Elem()
-			if err := unmarshalReflect(&types.AttributeValueMemberB{Value: b}, innerRV); err != nil {
-				return err
+			elem := &types.AttributeValueMemberB{Value: b}
+			if err := d.unmarshalReflect(elem, innerRV); err != nil {
+				return pathError(err, indexPath(i), innerRV.Type(), elem)
 			}
 			slicev = reflect.Append(slicev, innerRV)
 		}
@@ -355,10 +498,11 @@ func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 		return nil
 	case *types.AttributeValueMemberSS:
 		slicev := reflect.MakeSlice(rv.Type(), 0, len(t.Value))
-		for _, str := range t.Value {
+		for i, str := range t.Value {
 			innerRV := reflect.New(rv.Type().Elem()).Elem()
-			if err := unmarshalReflect(&types.AttributeValueMemberS{Value: str}, innerRV); err != nil {
-				return err
+			elem := &types.AttributeValueMemberS{Value: str}
+			if err := d.unmarshalReflect(elem, innerRV); err != nil {
+				return pathError(err, indexPath(i), innerRV.Type(), elem)
 			}
 			slicev = reflect.Append(slicev, innerRV)
 		}
@@ -366,10 +510,11 @@ func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 		return nil
 	case *types.AttributeValueMemberNS:
 		slicev := reflect.MakeSlice(rv.Type(), 0, len(t.Value))
-		for _, n := range t.Value {
+		for i, n := range t.Value {
 			innerRV := reflect.New(rv.Type().Elem()).Elem()
-			if err := unmarshalReflect(&types.AttributeValueMemberN{Value: n}, innerRV); err != nil {
-				return err
+			elem := &types.AttributeValueMemberN{Value: n}
+			if err := d.unmarshalReflect(elem, innerRV); err != nil {
+				return pathError(err, indexPath(i), innerRV.Type(), elem)
 			}
 			slicev = reflect.Append(slicev, innerRV)
 		}
@@ -379,54 +524,8 @@ func unmarshalSlice(av types.AttributeValue, rv reflect.Value) error {
 	return fmt.Errorf("dynamo: cannot unmarshal %s data into slice", avTypeName(av))
 }
 
-func fieldsInStruct(rv reflect.Value) map[string]reflect.Value {
-	if rv.Kind() == reflect.Ptr {
-		return fieldsInStruct(rv.Elem())
-	}
-
-	fields := make(map[string]reflect.Value)
-	for i := 0; i < rv.Type().NumField(); i++ {
-		field := rv.Type().Field(i)
-		fv := rv.Field(i)
-		isPtr := fv.Type().Kind() == reflect.Ptr
-
-		name, _ := fieldInfo(field)
-		if name == "-" {
-			// skip
-			continue
-		}
-
-		// embed anonymous structs, they could be pointers so test that too
-		if (fv.Type().Kind() == reflect.Struct || isPtr && fv.Type().Elem().Kind() == reflect.Struct) && field.Anonymous {
-			if isPtr {
-				// need to protect from setting unexported pointers because it will panic
-				if !fv.CanSet() {
-					continue
-				}
-				// set zero value for pointer
-				zero := reflect.New(fv.Type().Elem())
-				fv.Set(zero)
-				fv = zero
-			}
-
-			innerFields := fieldsInStruct(fv)
-			for k, v := range innerFields {
-				// don't clobber top-level fields
-				if _, exists := fields[k]; exists {
-					continue
-				}
-				fields[k] = v
-			}
-			continue
-		}
-
-		fields[name] = fv
-	}
-	return fields
-}
-
 // unmarshals a struct
-func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error {
+func (d decoder) unmarshalItem(item map[string]types.AttributeValue, out interface{}) error {
 	switch x := out.(type) {
 	case *map[string]types.AttributeValue:
 		*x = item
@@ -434,6 +533,12 @@ func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error
 	case awsEncoder:
 		// special case for AWSEncoding
 		return attributevalue.UnmarshalMap(item, x.iface)
+	case ItemDecoder:
+		rt := reflect.TypeOf(out)
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		return x.DecodeDynamoItem(item, &Decoder{d: d.withCrypter(d.crypt.decodingItem(rt, item))})
 	case ItemUnmarshaler:
 		return x.UnmarshalDynamoItem(item)
 	}
@@ -446,19 +551,9 @@ func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error
 	switch rv.Elem().Kind() {
 	case reflect.Ptr:
 		rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
-		return unmarshalItem(item, rv.Elem().Interface())
+		return d.unmarshalItem(item, rv.Elem().Interface())
 	case reflect.Struct:
-		var err error
-		rv.Elem().Set(reflect.Zero(rv.Type().Elem()))
-		fields := fieldsInStruct(rv.Elem())
-		for name, fv := range fields {
-			if av, ok := item[name]; ok {
-				if innerErr := unmarshalReflect(av, fv); innerErr != nil {
-					err = innerErr
-				}
-			}
-		}
-		return err
+		return codecOf(rv.Elem().Type()).decode(d, item, rv.Elem())
 	case reflect.Map:
 		mapv := rv.Elem()
 		if mapv.Type().Key().Kind() != reflect.String {
@@ -470,8 +565,8 @@ func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error
 
 		for k, av := range item {
 			innerRV := reflect.New(mapv.Type().Elem()).Elem()
-			if err := unmarshalReflect(av, innerRV); err != nil {
-				return err
+			if err := d.withCrypter(d.crypt.child(k)).unmarshalReflect(av, innerRV); err != nil {
+				return &DecodeError{Errors: fieldErrors(pathError(err, k, innerRV.Type(), av))}
 			}
 			mapv.SetMapIndex(reflect.ValueOf(k), innerRV)
 		}
@@ -480,7 +575,7 @@ func unmarshalItem(item map[string]types.AttributeValue, out interface{}) error
 	return fmt.Errorf("dynamo: unmarshal: unsupported type: %T", out)
 }
 
-func unmarshalAppend(item map[string]types.AttributeValue, out interface{}) error {
+func (d decoder) unmarshalAppend(item map[string]types.AttributeValue, out interface{}) error {
 	rv := reflect.ValueOf(out)
 	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
 		return fmt.Errorf("dynamo: unmarshal append: result argument must be a slice pointer")
@@ -488,7 +583,7 @@ func unmarshalAppend(item map[string]types.AttributeValue, out interface{}) erro
 
 	slicev := rv.Elem()
 	innerRV := reflect.New(slicev.Type().Elem())
-	if err := unmarshalItem(item, innerRV.Interface()); err != nil {
+	if err := d.unmarshalItem(item, innerRV.Interface()); err != nil {
 		return err
 	}
 	slicev = reflect.Append(slicev, innerRV.Elem())
@@ -552,6 +647,121 @@ func av2iface(av types.AttributeValue) (interface{}, error) {
 	return nil, fmt.Errorf("dynamo: unsupported AV: %#v", av)
 }
 
+// avTypeName returns the DynamoDB type of av, such as S or N.
 func avTypeName(av types.AttributeValue) string {
+	switch av.(type) {
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case nil:
+		return "<nil>"
+	}
 	return fmt.Sprintf("%T", av)
 }
+
+// ErrUnknownAttribute is the error of a FieldError for an attribute that no struct field claims.
+// It is only returned when DecodeOptions.DisallowUnknown is set.
+var ErrUnknownAttribute = errors.New("unknown attribute")
+
+// FieldError is an error unmarshaling one attribute of an item.
+type FieldError struct {
+	// Path is the path of the attribute in the item, such as Children[3].Msg.
+	Path string
+	// Type is the Go type the attribute was unmarshaled into.
+	// It is nil for unknown attributes.
+	Type reflect.Type
+	// AttributeType is the DynamoDB type of the attribute, such as S or N.
+	AttributeType string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("dynamo: unmarshal %s (%s): %v", e.Path, e.AttributeType, e.Err)
+	}
+	return fmt.Sprintf("dynamo: unmarshal %s (%s into %s): %v", e.Path, e.AttributeType, e.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when unmarshaling an item fails.
+// It holds an error for each attribute that couldn't be unmarshaled.
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more errors)", e.Errors[0], len(e.Errors)-1)
+}
+
+// Unwrap returns the first error.
+func (e *DecodeError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// pathError adds seg to the front of the path of err,
+// which came from unmarshaling av into a value of type rt.
+func pathError(err error, seg string, rt reflect.Type, av types.AttributeValue) error {
+	switch x := err.(type) {
+	case *DecodeError:
+		for _, fe := range x.Errors {
+			fe.Path = joinPath(seg, fe.Path)
+		}
+		return x
+	case *FieldError:
+		x.Path = joinPath(seg, x.Path)
+		return x
+	}
+	return &FieldError{Path: seg, Type: rt, AttributeType: avTypeName(av), Err: err}
+}
+
+// fieldErrors returns the field errors of an error returned by pathError.
+func fieldErrors(err error) []*FieldError {
+	switch x := err.(type) {
+	case *DecodeError:
+		return x.Errors
+	case *FieldError:
+		return []*FieldError{x}
+	}
+	return []*FieldError{{Err: err}}
+}
+
+func joinPath(parent, child string) string {
+	switch {
+	case child == "":
+		return parent
+	case parent == "":
+		return child
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+func indexPath(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
@@ -1,10 +1,12 @@
 package dynamo
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -139,3 +141,121 @@ func TestUnmarshalItem(t *testing.T) {
 
 	}
 }
+
+type decodeChild struct {
+	Msg string
+}
+
+type decodeParent struct {
+	ID       int8
+	Name     string
+	Children []decodeChild
+}
+
+func TestDecodeErrorPaths(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberS{Value: "x"},
+		"Name": &types.AttributeValueMemberN{Value: "1"},
+		"Children": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Msg": &types.AttributeValueMemberS{Value: "ok"}}},
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Msg": &types.AttributeValueMemberN{Value: "3"}}},
+		}},
+	}
+	var out decodeParent
+	err := unmarshalItem(item, &out)
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+	}
+	type result struct {
+		path, goType, avType string
+	}
+	var got []result
+	for _, fe := range derr.Errors {
+		got = append(got, result{fe.Path, fe.Type.String(), fe.AttributeType})
+	}
+	want := []result{
+		{"ID", "int8", "S"},
+		{"Name", "string", "N"},
+		{"Children[1].Msg", "string", "N"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad errors. want: %v got: %v", want, got)
+	}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberN{Value: "300"},
+		"Name":  &types.AttributeValueMemberS{Value: "a"},
+		"Color": &types.AttributeValueMemberS{Value: "blue"},
+		"Children": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Size": &types.AttributeValueMemberN{Value: "1"}}},
+		}},
+	}
+
+	var out decodeParent
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Errorf("unexpected error with default options: %v", err)
+	}
+
+	err := UnmarshalItemWithOptions(item, &out, DecodeOptions{DisallowUnknown: true})
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+	}
+	var paths []string
+	for _, fe := range derr.Errors {
+		if fe.Err != ErrUnknownAttribute {
+			t.Errorf("%s: expected ErrUnknownAttribute, got %v", fe.Path, fe.Err)
+		}
+		paths = append(paths, fe.Path)
+	}
+	if want := []string{"Children[0].Size", "Color"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("bad unknown attributes. want: %v got: %v", want, paths)
+	}
+	if !errors.Is(err, ErrUnknownAttribute) {
+		t.Error("DecodeError doesn't unwrap to ErrUnknownAttribute")
+	}
+
+	err = UnmarshalItemWithOptions(item, &out, DecodeOptions{Strict: true})
+	if !errors.As(err, &derr) || len(derr.Errors) != 1 || derr.Errors[0].Path != "ID" {
+		t.Errorf("expected overflow error for ID, got %v", err)
+	}
+
+	var timed struct {
+		T time.Time
+	}
+	err = UnmarshalItemWithOptions(map[string]types.AttributeValue{
+		"T": &types.AttributeValueMemberN{Value: "1577934245"},
+	}, &timed, DecodeOptions{Strict: true})
+	if err == nil {
+		t.Error("expected error for implicit unixtime in strict mode, got nil")
+	}
+}
+
+func TestDecodeOptionsRequests(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]types.AttributeValue{{
+		{
+			"ID":    &types.AttributeValueMemberN{Value: "1"},
+			"Color": &types.AttributeValueMemberS{Value: "blue"},
+		},
+	}}}
+	db := NewFromIface(client)
+	db.SetDecodeOptions(DecodeOptions{DisallowUnknown: true})
+	table := db.Table("Decode")
+
+	var out []decodeParent
+	if err := table.Scan().All(&out); !errors.Is(err, ErrUnknownAttribute) {
+		t.Errorf("expected ErrUnknownAttribute, got %v", err)
+	}
+
+	client.calls = 0
+	out = nil
+	if err := table.Scan().DecodeOptions(DecodeOptions{}).All(&out); err != nil {
+		t.Errorf("unexpected error with overridden options: %v", err)
+	}
+	if len(out) != 1 || out[0].ID != 1 {
+		t.Errorf("bad result: %#v", out)
+	}
+}
@@ -21,6 +21,8 @@ type Delete struct {
 
 	subber
 	condition string
+	// offloaded are the names of the item's fields tagged with offload
+	offloaded []string
 
 	err error
 	cc  *ConsumedCapacity
@@ -31,13 +33,31 @@ type Delete struct {
 // Value is the value of the hash key.
 func (table Table) Delete(name string, value interface{}) *Delete {
 	d := &Delete{
-		table:   table,
-		hashKey: name,
+		table:     table,
+		hashKey:   name,
+		offloaded: table.offloaded(),
 	}
 	d.hashValue, d.err = marshal(value, flagNone)
 	return d
 }
 
+// Remove creates a new request to delete the item with the same hash and range keys as item.
+// Item must be a struct whose key fields are tagged with hash and range, as in CreateTable.
+func (table Table) Remove(item interface{}) *Delete {
+	desc, hashValue, rangeValue, err := itemKeys(item)
+	if err != nil {
+		return &Delete{table: table, err: err}
+	}
+	d := table.Delete(desc.hashKey, hashValue)
+	if desc.rangeKey != "" {
+		d.Range(desc.rangeKey, rangeValue)
+	}
+	if names := offloadedFields(item); len(names) > 0 {
+		d.offloaded = names
+	}
+	return d
+}
+
 // Range specifies the range key (a.k.a. sort key) to delete.
 // Name is the name of the range key.
 // Value is the value of the range key.
@@ -52,19 +72,30 @@ func (d *Delete) Range(name string, value interface{}) *Delete {
 // If specifies a conditional expression for this delete to succeed.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 // Multiple calls to If will be combined with AND.
 func (d *Delete) If(expr string, args ...interface{}) *Delete {
-	expr = wrapExpr(expr)
-	expr, err := d.subExprN(expr, args...)
+	cond, err := d.subCond(expr, args)
 	d.setError(err)
+	if cond == "" {
+		return d
+	}
 	if d.condition != "" {
 		d.condition += " AND "
 	}
-	d.condition += expr
+	d.condition += cond
 	return d
 }
 
+// IfExpr is like If, but takes an Expression, such as a condition built with the cond package.
+func (d *Delete) IfExpr(expr Expression) *Delete {
+	if expr == nil {
+		d.setError(errNilExpression)
+		return d
+	}
+	str, args := expr.Expr()
+	return d.If(str, args...)
+}
+
 // ConsumedCapacity will measure the throughput capacity consumed by this operation and add it to cc.
 func (d *Delete) ConsumedCapacity(cc *ConsumedCapacity) *Delete {
 	d.cc = cc
@@ -72,6 +103,9 @@ func (d *Delete) ConsumedCapacity(cc *ConsumedCapacity) *Delete {
 }
 
 // Run executes this delete request.
+// If the DB has a BlobStore, the deleted item's offloaded attributes are cleaned up
+// afterwards when their fields are known, from the item given to Remove or the table's model
+// or registry; see OffloadOptions.OnOrphan.
 func (d *Delete) Run() error {
 	ctx, cancel := defaultContext()
 	defer cancel()
@@ -80,8 +114,11 @@ func (d *Delete) Run() error {
 
 func (d *Delete) RunWithContext(ctx context.Context) error {
 	d.returnType = "NONE"
-	_, err := d.run(ctx)
-	return err
+	output, err := d.run(ctx)
+	if err != nil {
+		return err
+	}
+	return d.table.db.offloader().orphan(ctx, d.offloaded, output.Attributes, nil)
 }
 
 // OldValue executes this delete request, unmarshaling the previous value to out.
@@ -101,7 +138,12 @@ func (d *Delete) OldValueWithContext(ctx context.Context, out interface{}) error
 	case output.Attributes == nil:
 		return ErrNotFound
 	}
-	return unmarshalItem(output.Attributes, out)
+	// resolve offloaded attributes before cleaning them up
+	err = d.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
+	if oerr := d.table.db.offloader().orphan(ctx, d.offloaded, output.Attributes, nil); err == nil {
+		err = oerr
+	}
+	return err
 }
 
 func (d *Delete) run(ctx context.Context) (*dynamodb.DeleteItemOutput, error) {
@@ -110,6 +152,10 @@ func (d *Delete) run(ctx context.Context) (*dynamodb.DeleteItemOutput, error) {
 	}
 
 	input := d.deleteInput()
+	if d.table.db.offloader() != nil && len(d.offloaded) > 0 {
+		// the old item is needed to clean up its offloaded attributes
+		input.ReturnValues = types.ReturnValueAllOld
+	}
 	var output *dynamodb.DeleteItemOutput
 	err := retry(ctx, func() error {
 		var err error
@@ -2,7 +2,9 @@ package dynamo
 
 import (
 	"encoding"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -25,11 +27,24 @@ type ItemMarshaler interface {
 }
 
 // MarshalItem converts the given struct into a DynamoDB item.
+// Structs with fields tagged with encrypt can't be marshaled without an Encryptor:
+// use DB.MarshalItem for those.
 func MarshalItem(v interface{}) (map[string]types.AttributeValue, error) {
 	return marshalItem(v)
 }
 
+// MarshalItem converts the given struct into a DynamoDB item,
+// encrypting fields tagged with encrypt with the DB's Encryptor.
+func (db *DB) MarshalItem(v interface{}) (map[string]types.AttributeValue, error) {
+	return encodeItem(v, db.crypter())
+}
+
 func marshalItem(v interface{}) (map[string]types.AttributeValue, error) {
+	return encodeItem(v, nil)
+}
+
+// encodeItem is like marshalItem, but encrypts fields tagged with encrypt with c.
+func encodeItem(v interface{}, c *crypter) (map[string]types.AttributeValue, error) {
 	switch x := v.(type) {
 	case map[string]types.AttributeValue:
 		return x, nil
@@ -44,18 +59,18 @@ func marshalItem(v interface{}) (map[string]types.AttributeValue, error) {
 
 	switch rv.Type().Kind() {
 	case reflect.Ptr:
-		return marshalItem(rv.Elem().Interface())
+		return encodeItem(rv.Elem().Interface(), c)
 	case reflect.Struct:
-		return marshalStruct(rv)
+		return codecOf(rv.Type()).encode(rv, c)
 	case reflect.Map:
-		return marshalItemMap(rv.Interface())
+		return encodeItemMap(rv.Interface(), c)
 	}
 	return nil, fmt.Errorf("dynamo: marshal item: unsupported type %T: %v", rv.Interface(), rv.Interface())
 }
 
-func marshalItemMap(v interface{}) (map[string]types.AttributeValue, error) {
+func encodeItemMap(v interface{}, c *crypter) (map[string]types.AttributeValue, error) {
 	// TODO: maybe unify this with the map stuff in marshal
-	av, err := marshal(v, flagNone)
+	av, err := encodeValue(v, flagNone, c)
 	if err != nil {
 		return nil, err
 	}
@@ -66,90 +81,35 @@ func marshalItemMap(v interface{}) (map[string]types.AttributeValue, error) {
 	return m.Value, nil
 }
 
-func marshalStruct(rv reflect.Value) (map[string]types.AttributeValue, error) {
-	item := make(map[string]types.AttributeValue)
-	var err error
-
-	for i := 0; i < rv.Type().NumField(); i++ {
-		field := rv.Type().Field(i)
-		fv := rv.Field(i)
-
-		name, flags := fieldInfo(field)
-		omitempty := flags&flagOmitEmpty != 0
-		anonStruct := fv.Type().Kind() == reflect.Struct && field.Anonymous
-		pointerAnonStruct := fv.Type().Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct && field.Anonymous
-		switch {
-		case !fv.CanInterface():
-			// skip unexported unembedded fields
-			if !anonStruct && !pointerAnonStruct {
-				continue
-			}
-		case name == "-":
-			continue
-		case omitempty:
-			if isZero(fv) {
-				continue
-			}
-		}
-
-		// embed anonymous structs
-		if anonStruct || pointerAnonStruct {
-			if pointerAnonStruct {
-				if fv.IsNil() {
-					continue
-				}
-				fv = fv.Elem()
-			}
-
-			avs, err := marshalStruct(fv)
-			if err != nil {
-				return nil, err
-			}
-			for k, v := range avs {
-				// don't clobber pre-existing fields
-				if _, exists := item[k]; exists {
-					continue
-				}
-				item[k] = v
-			}
-			continue
-		}
-
-		av, err := marshal(fv.Interface(), flags)
-		if err != nil {
-			return nil, err
-		}
-		if av != nil {
-			item[name] = av
-		}
-	}
-	return item, err
-}
-
 // Marshal converts the given value into a DynamoDB attribute value.
 func Marshal(v interface{}) (types.AttributeValue, error) {
 	return marshal(v, flagNone)
 }
 
 func marshal(v interface{}, flags encodeFlags) (types.AttributeValue, error) {
+	return encodeValue(v, flags, nil)
+}
+
+// encodeValue is like marshal, but encrypts the fields of structs tagged with encrypt with c.
+func encodeValue(v interface{}, flags encodeFlags, c *crypter) (types.AttributeValue, error) {
 	// encoders with precedence over interfaces
-	if flags&flagUnixTime != 0 {
+	if tf := timeFormatFor(flags, ""); tf != nil {
 		switch x := v.(type) {
 		case *time.Time:
 			if x != nil {
-				return marshal(*x, flags)
+				return tf.encode(*x), nil
 			}
 		case time.Time:
-			if x.IsZero() {
-				// omitempty behaviour
-				return nil, nil
-			}
-
-			ts := strconv.FormatInt(x.Unix(), 10)
-			return &types.AttributeValueMemberN{Value: ts}, nil
+			return tf.encode(x), nil
 		}
 	}
 
+	// arbitrary-precision numbers, some of which are also TextMarshalers
+	switch v.(type) {
+	case Number, json.Number, big.Int, *big.Int, big.Float, *big.Float, big.Rat, *big.Rat:
+		return marshalNumber(v, flags)
+	}
+
 	rv := reflect.ValueOf(v)
 
 	switch x := v.(type) {
@@ -205,13 +165,17 @@ func marshal(v interface{}, flags encodeFlags) (types.AttributeValue, error) {
 		}
 		return nil, nil
 	}
-	return marshalReflect(rv, flags)
+	return encodeReflect(rv, flags, c)
 }
 
 var nilTm encoding.TextMarshaler
 var tmType = reflect.TypeOf(&nilTm).Elem()
 
 func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue, error) {
+	return encodeReflect(rv, flags, nil)
+}
+
+func encodeReflect(rv reflect.Value, flags encodeFlags, c *crypter) (types.AttributeValue, error) {
 	switch rv.Kind() {
 	case reflect.Ptr:
 		if rv.IsNil() {
@@ -220,7 +184,7 @@ func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue,
 			}
 			return nil, nil
 		}
-		return marshal(rv.Elem().Interface(), flags)
+		return encodeValue(rv.Elem().Interface(), flags, c)
 	case reflect.Bool:
 		return &types.AttributeValueMemberBOOL{Value: rv.Bool()}, nil
 	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
@@ -290,11 +254,11 @@ func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue,
 			subflags |= flagOmitEmpty
 		}
 		for _, key := range rv.MapKeys() {
-			v, err := marshal(rv.MapIndex(key).Interface(), subflags)
+			kstr, err := keyString(key)
 			if err != nil {
 				return nil, err
 			}
-			kstr, err := keyString(key)
+			v, err := encodeValue(rv.MapIndex(key).Interface(), subflags, c.child(kstr))
 			if err != nil {
 				return nil, err
 			}
@@ -307,7 +271,7 @@ func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue,
 		}
 		return &types.AttributeValueMemberM{Value: avs}, nil
 	case reflect.Struct:
-		avs, err := marshalStruct(rv)
+		avs, err := codecOf(rv.Type()).encode(rv, c)
 		if err != nil {
 			return nil, err
 		}
@@ -355,9 +319,10 @@ func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue,
 			// this will preserve the position of items in the list
 			subflags |= flagAllowEmpty | flagNull
 		}
+		elem := c.elem()
 		for i := 0; i < rv.Len(); i++ {
 			innerVal := rv.Index(i)
-			av, err := marshal(innerVal.Interface(), subflags)
+			av, err := encodeValue(innerVal.Interface(), subflags, elem)
 			if err != nil {
 				return nil, err
 			}
@@ -375,6 +340,14 @@ func marshalReflect(rv reflect.Value, flags encodeFlags) (types.AttributeValue,
 }
 
 func marshalSet(rv reflect.Value, flags encodeFlags) (types.AttributeValue, error) {
+	if rv.Kind() == reflect.Slice && isNumberType(rv.Type().Elem()) {
+		values := make([]reflect.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i))
+		}
+		return marshalNumberSet(values)
+	}
+
 	iface := reflect.Zero(rv.Type().Elem()).Interface()
 	switch iface.(type) {
 	case encoding.TextMarshaler:
@@ -473,6 +446,16 @@ func marshalSet(rv reflect.Value, flags encodeFlags) (types.AttributeValue, erro
 			return nil, fmt.Errorf("dynamo: cannot marshal type %v into a set", rv.Type())
 		}
 
+		if isNumberType(rv.Type().Key()) {
+			values := make([]reflect.Value, 0, rv.Len())
+			for _, k := range rv.MapKeys() {
+				if !useBool || rv.MapIndex(k).Bool() {
+					values = append(values, k)
+				}
+			}
+			return marshalNumberSet(values)
+		}
+
 		if rv.Type().Key().Implements(tmType) {
 			ss := make([]string, 0, rv.Len())
 			for _, k := range rv.MapKeys() {
@@ -602,6 +585,17 @@ const (
 	flagAllowEmptyElem
 	flagNull
 	flagUnixTime
+	flagVersion
+	flagCreated
+	flagUpdated
+	flagInline
+	flagUnixMilli
+	flagUnixNano
+	flagRFC3339Nano
+	flagTimeLayout
+	flagCompress
+	flagEncrypt
+	flagOffload
 
 	flagNone encodeFlags = 0
 )
@@ -633,6 +627,32 @@ func fieldInfo(field reflect.StructField) (name string, flags encodeFlags) {
 			flags |= flagNull
 		case "unixtime":
 			flags |= flagUnixTime
+		case "version":
+			flags |= flagVersion
+		case "created":
+			flags |= flagCreated
+		case "updated":
+			flags |= flagUpdated
+		case "inline":
+			flags |= flagInline
+		case "unixmilli":
+			flags |= flagUnixMilli
+		case "unixnano":
+			flags |= flagUnixNano
+		case "rfc3339nano":
+			flags |= flagRFC3339Nano
+		case "compress":
+			flags |= flagCompress
+		case "encrypt":
+			flags |= flagEncrypt
+		case "offload":
+			flags |= flagOffload
+		default:
+			if strings.HasPrefix(t, "time=") {
+				// the layout may contain commas, so it's the last option
+				flags |= flagTimeLayout
+				return
+			}
 		}
 	}
 
@@ -639,12 +639,91 @@ var itemEncodingTests = []struct {
 			"thing": &types.AttributeValueMemberN{Value: "52"},
 		},
 	},
+	{
+		name: "inline struct",
+		in: struct {
+			Name string
+			Addr inlineAddress `dynamo:",inline"`
+		}{
+			Name: "Alice",
+			Addr: inlineAddress{Street: "1 Main St", City: "Springfield"},
+		},
+		out: map[string]types.AttributeValue{
+			"Name":   &types.AttributeValueMemberS{Value: "Alice"},
+			"Street": &types.AttributeValueMemberS{Value: "1 Main St"},
+			"City":   &types.AttributeValueMemberS{Value: "Springfield"},
+		},
+	},
+	{
+		name: "inline struct with prefix",
+		in: struct {
+			Home inlineAddress  `dynamo:"home_,inline"`
+			Work *inlineAddress `dynamo:"work_,inline"`
+		}{
+			Home: inlineAddress{Street: "1 Main St", City: "Springfield"},
+			Work: &inlineAddress{City: "Shelbyville"},
+		},
+		out: map[string]types.AttributeValue{
+			"home_Street": &types.AttributeValueMemberS{Value: "1 Main St"},
+			"home_City":   &types.AttributeValueMemberS{Value: "Springfield"},
+			"work_City":   &types.AttributeValueMemberS{Value: "Shelbyville"},
+		},
+	},
+	{
+		name: "nil inline struct pointer",
+		in: struct {
+			Name string
+			Work *inlineAddress `dynamo:"work_,inline"`
+		}{
+			Name: "Bob",
+		},
+		out: map[string]types.AttributeValue{
+			"Name": &types.AttributeValueMemberS{Value: "Bob"},
+		},
+	},
+	{
+		name: "inline map",
+		in: struct {
+			Name  string
+			Extra map[string]interface{} `dynamo:",inline"`
+		}{
+			Name: "Alice",
+			Extra: map[string]interface{}{
+				"Color": "blue",
+				"Size":  12.0,
+			},
+		},
+		out: map[string]types.AttributeValue{
+			"Name":  &types.AttributeValueMemberS{Value: "Alice"},
+			"Color": &types.AttributeValueMemberS{Value: "blue"},
+			"Size":  &types.AttributeValueMemberN{Value: "12"},
+		},
+	},
+	{
+		name: "inline map with prefix",
+		in: struct {
+			Name  string
+			Attrs map[string]string `dynamo:"attr_,inline"`
+		}{
+			Name:  "Alice",
+			Attrs: map[string]string{"color": "blue"},
+		},
+		out: map[string]types.AttributeValue{
+			"Name":       &types.AttributeValueMemberS{Value: "Alice"},
+			"attr_color": &types.AttributeValueMemberS{Value: "blue"},
+		},
+	},
 }
 
 type embedded struct {
 	Embedded bool
 }
 
+type inlineAddress struct {
+	Street string `dynamo:",omitempty"`
+	City   string
+}
+
 type ExportedEmbedded struct {
 	Embedded bool
 }
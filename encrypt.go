@@ -0,0 +1,370 @@
+package dynamo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Encryptor encrypts and decrypts the values of fields tagged with encrypt.
+// Implementations can use a local keyring, such as Keyring, or a key management service.
+// See DB.SetEncryptor.
+type Encryptor interface {
+	// Encrypt encrypts plaintext and authenticates aad along with it.
+	// It returns the ID of the key used, the nonce, and the ciphertext.
+	Encrypt(plaintext, aad []byte) (keyID string, nonce, ciphertext []byte, err error)
+	// Decrypt decrypts ciphertext that was encrypted with the given key and nonce,
+	// and verifies aad.
+	Decrypt(keyID string, nonce, ciphertext, aad []byte) ([]byte, error)
+}
+
+// EncryptOptions control how fields tagged with encrypt are encrypted.
+type EncryptOptions struct {
+	// SignKeys includes the values of the item's hash and range keys
+	// in the authenticated data of every encrypted attribute, including those of nested structs,
+	// so that ciphertext copied from another item fails to decrypt.
+	// Keys are found by the hash and range struct tags of the item's type,
+	// or the keys given to Update.
+	// Changing this option makes previously encrypted values unreadable.
+	SignKeys bool
+}
+
+// ErrNoEncryptor is returned when encoding or decoding a field tagged with encrypt
+// without an Encryptor.
+var ErrNoEncryptor = errors.New("dynamo: encrypted field requires an Encryptor (see DB.SetEncryptor)")
+
+// encryptHeader starts every encrypted attribute: a magic number and the format version.
+// It is followed by the key ID and the nonce, each prefixed with its length in one byte,
+// and then the ciphertext of the attribute's value in DynamoDB JSON.
+var encryptHeader = []byte{'d', 'e', 1}
+
+// crypter encrypts and decrypts attributes with an Encryptor.
+// The authenticated data of an attribute is its document path from the top of the item,
+// so crypters are derived for each struct field, map entry and list element being encoded or decoded.
+type crypter struct {
+	enc  Encryptor
+	opts EncryptOptions
+	// path is the document path of the value being encoded or decoded, empty for the item itself.
+	// List elements are given as [], because their indexes change as lists are updated.
+	path string
+	// prefix is added to the names of the map entries below path, for inline maps
+	prefix string
+	// keys are the signed keys of the item, set for the item and everything in it if keys are signed
+	keys *signedKeys
+}
+
+// child returns the crypter for the struct field or map entry name of the current value.
+func (c *crypter) child(name string) *crypter {
+	if c == nil {
+		return nil
+	}
+	child := *c
+	child.path = joinPath(c.path, c.prefix+name)
+	child.prefix = ""
+	return &child
+}
+
+// elem returns the crypter for the elements of the current list.
+func (c *crypter) elem() *crypter {
+	if c == nil {
+		return nil
+	}
+	elem := *c
+	elem.path = joinPath(c.path, "[]")
+	elem.prefix = ""
+	return &elem
+}
+
+// inline returns the crypter for the entries of an inline map, whose attribute names are prefixed.
+func (c *crypter) inline(prefix string) *crypter {
+	if c == nil {
+		return nil
+	}
+	inline := *c
+	inline.prefix = prefix
+	return &inline
+}
+
+// at returns the crypter for the value at path in an item, whose keys are given by withKeys.
+func (c *crypter) at(path string) *crypter {
+	if c == nil {
+		return nil
+	}
+	return &crypter{enc: c.enc, opts: c.opts, path: path}
+}
+
+// signed reports whether the item's keys have been signed by this crypter, or those derived from it.
+func (c *crypter) signed() bool {
+	return c != nil && c.keys != nil && c.keys.find == nil
+}
+
+// signedKeys finds the keys of an item when an encrypted attribute in it first needs them.
+type signedKeys struct {
+	find   func() ([]string, map[string]types.AttributeValue, error)
+	names  []string
+	values map[string]types.AttributeValue
+	err    error
+}
+
+func (k *signedKeys) get() ([]string, map[string]types.AttributeValue, error) {
+	if k.find != nil {
+		k.names, k.values, k.err = k.find()
+		k.find = nil
+	}
+	return k.names, k.values, k.err
+}
+
+// withKeys returns a crypter that signs names, whose values are in key, if keys are signed.
+func (c *crypter) withKeys(names []string, key map[string]types.AttributeValue) *crypter {
+	if c == nil || !c.opts.SignKeys {
+		return c
+	}
+	return c.findKeys(func() ([]string, map[string]types.AttributeValue, error) {
+		return names, key, nil
+	})
+}
+
+// encodingItem returns the crypter for encoding rv, a struct, if it is the item itself,
+// whose keys are encoded from its hash and range key fields.
+func (c *crypter) encodingItem(rv reflect.Value) *crypter {
+	if c == nil || !c.opts.SignKeys || c.keys != nil {
+		return c
+	}
+	return c.findKeys(func() ([]string, map[string]types.AttributeValue, error) {
+		desc, err := keyDescOf(rv.Type())
+		if err != nil {
+			return nil, nil, err
+		}
+		names := []string{desc.hashKey}
+		key := make(map[string]types.AttributeValue, 2)
+		if key[desc.hashKey], err = encodeKey(rv, desc.hashIdx, desc.hashField); err != nil {
+			return nil, nil, err
+		}
+		if desc.rangeKey != "" {
+			names = append(names, desc.rangeKey)
+			if key[desc.rangeKey], err = encodeKey(rv, desc.rangeIdx, desc.rangeField); err != nil {
+				return nil, nil, err
+			}
+		}
+		return names, key, nil
+	})
+}
+
+// decodingItem returns the crypter for decoding item into a struct of type rt, if it is the item itself,
+// whose keys are found by the hash and range key fields of rt.
+func (c *crypter) decodingItem(rt reflect.Type, item map[string]types.AttributeValue) *crypter {
+	if c == nil || !c.opts.SignKeys || c.keys != nil {
+		return c
+	}
+	return c.findKeys(func() ([]string, map[string]types.AttributeValue, error) {
+		desc, err := keyDescOf(rt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if desc.rangeKey == "" {
+			return []string{desc.hashKey}, item, nil
+		}
+		return []string{desc.hashKey, desc.rangeKey}, item, nil
+	})
+}
+
+func (c *crypter) findKeys(find func() ([]string, map[string]types.AttributeValue, error)) *crypter {
+	item := *c
+	item.keys = &signedKeys{find: find}
+	return &item
+}
+
+// encrypt encrypts av into a B attribute.
+func (c *crypter) encrypt(av types.AttributeValue, aad []byte) (types.AttributeValue, error) {
+	if c == nil {
+		return nil, ErrNoEncryptor
+	}
+	obj, err := av2dynamoJSON(av)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	keyID, nonce, ciphertext, err := c.enc.Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: encrypt: %v", err)
+	}
+	if len(keyID) > 255 || len(nonce) > 255 {
+		return nil, fmt.Errorf("dynamo: encrypt: key ID and nonce must be at most 255 bytes")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encryptHeader)
+	buf.WriteByte(byte(len(keyID)))
+	buf.WriteString(keyID)
+	buf.WriteByte(byte(len(nonce)))
+	buf.Write(nonce)
+	buf.Write(ciphertext)
+	return &types.AttributeValueMemberB{Value: buf.Bytes()}, nil
+}
+
+// decrypt decrypts av, which must have been encrypted by encrypt.
+func (c *crypter) decrypt(av types.AttributeValue, aad []byte) (types.AttributeValue, error) {
+	if c == nil {
+		return nil, ErrNoEncryptor
+	}
+	b, ok := av.(*types.AttributeValueMemberB)
+	if !ok || !bytes.HasPrefix(b.Value, encryptHeader) {
+		return nil, fmt.Errorf("dynamo: decrypt: attribute is not encrypted")
+	}
+	data := b.Value[len(encryptHeader):]
+	keyID, data, ok := cutField(data)
+	if !ok {
+		return nil, fmt.Errorf("dynamo: decrypt: truncated key ID")
+	}
+	nonce, ciphertext, ok := cutField(data)
+	if !ok {
+		return nil, fmt.Errorf("dynamo: decrypt: truncated nonce")
+	}
+	plaintext, err := c.enc.Decrypt(string(keyID), nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: decrypt: %v", err)
+	}
+	return dynamoJSON2av(plaintext)
+}
+
+// cutField splits a field prefixed with its length from the front of data.
+func cutField(data []byte) (field, rest []byte, ok bool) {
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return nil, nil, false
+	}
+	n := 1 + int(data[0])
+	return data[1:n], data[n:], true
+}
+
+// aad returns the authenticated data of the struct field or map entry name of the current value:
+// its document path and, if keys are signed, the names and values of the item's keys.
+func (c *crypter) aad(name string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrNoEncryptor
+	}
+	var buf bytes.Buffer
+	path := joinPath(c.path, c.prefix+name)
+	buf.WriteString(path)
+	if !c.opts.SignKeys {
+		return buf.Bytes(), nil
+	}
+	if c.keys == nil {
+		return nil, fmt.Errorf("dynamo: cannot sign encrypted attribute %s: unknown keys", path)
+	}
+	keys, item, err := c.keys.get()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		av, ok := item[key]
+		if !ok {
+			return nil, fmt.Errorf("dynamo: cannot sign encrypted attribute %s: missing key %s", path, key)
+		}
+		obj, err := av2dynamoJSON(av)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(0)
+		buf.WriteString(key)
+		buf.WriteByte(0)
+		buf.Write(value)
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptField decrypts av, the struct field or map entry name of the current value.
+func (c *crypter) decryptField(name string, av types.AttributeValue) (types.AttributeValue, error) {
+	aad, err := c.aad(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.decrypt(av, aad)
+}
+
+// encryptItem encrypts the attributes of item that belong to the encrypted fields of codec.
+func (c *crypter) encryptItem(codec *structCodec, item map[string]types.AttributeValue) error {
+	if c == nil {
+		return ErrNoEncryptor
+	}
+	for _, name := range codec.encrypted {
+		av, ok := item[name]
+		if !ok {
+			continue
+		}
+		aad, err := c.aad(name)
+		if err != nil {
+			return err
+		}
+		if item[name], err = c.encrypt(av, aad); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keyring is an Encryptor that uses AES-GCM with keys held in memory.
+// New values are encrypted with the current key, and values encrypted with
+// any of the keyring's keys can be decrypted, so keys can be rotated by
+// adding a new key and making it current.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring from keys, a map of key IDs to AES keys of 16, 24 or 32 bytes.
+// Values are encrypted with the key whose ID is current.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("dynamo: keyring: no key with current ID %q", current)
+	}
+	kr := &Keyring{current: current, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: keyring: key %q: %v", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: keyring: key %q: %v", id, err)
+		}
+		kr.keys[id] = aead
+	}
+	return kr, nil
+}
+
+// Encrypt encrypts plaintext with the current key and a random nonce.
+func (kr *Keyring) Encrypt(plaintext, aad []byte) (keyID string, nonce, ciphertext []byte, err error) {
+	aead := kr.keys[kr.current]
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", nil, nil, err
+	}
+	return kr.current, nonce, aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// Decrypt decrypts ciphertext with the key keyID.
+func (kr *Keyring) Decrypt(keyID string, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("bad nonce size %d", len(nonce))
+	}
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
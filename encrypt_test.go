@@ -0,0 +1,441 @@
+package dynamo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type secretUser struct {
+	ID    string `dynamo:",hash"`
+	Name  string
+	SSN   string            `dynamo:",encrypt"`
+	Notes map[string]string `dynamo:",encrypt,compress"`
+}
+
+func testKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	kr, err := NewKeyring("k2", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 16),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func TestEncryptPut(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+	table := db.Table("Users")
+
+	in := secretUser{ID: "u1", Name: "Alice", SSN: "123-45-6789", Notes: map[string]string{"a": "b"}}
+	if err := table.Put(in).Run(); err != nil {
+		t.Fatal(err)
+	}
+	item := client.put.Item
+	if !reflect.DeepEqual(item["Name"], &types.AttributeValueMemberS{Value: "Alice"}) {
+		t.Errorf("unencrypted field changed: %#v", item["Name"])
+	}
+	for _, name := range []string{"SSN", "Notes"} {
+		b, ok := item[name].(*types.AttributeValueMemberB)
+		if !ok || !bytes.HasPrefix(b.Value, encryptHeader) {
+			t.Fatalf("%s: not encrypted: %#v", name, item[name])
+		}
+		if bytes.Contains(b.Value, []byte("123-45-6789")) {
+			t.Errorf("%s: plaintext leaked", name)
+		}
+		if keyID := string(b.Value[len(encryptHeader)+1 : len(encryptHeader)+3]); keyID != "k2" {
+			t.Errorf("%s: bad key ID: %q", name, keyID)
+		}
+	}
+
+	var out secretUser
+	if err := db.decoder().unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("bad round trip. want: %#v got: %#v", in, out)
+	}
+
+	// ciphertext moved to another item is detected
+	moved := make(map[string]types.AttributeValue)
+	for k, v := range item {
+		moved[k] = v
+	}
+	moved["ID"] = &types.AttributeValueMemberS{Value: "u2"}
+	err := db.decoder().unmarshalItem(moved, &out)
+	var derr *DecodeError
+	if !errors.As(err, &derr) || len(derr.Errors) != 2 || derr.Errors[0].Path != "SSN" {
+		t.Errorf("expected decrypt errors for SSN and Notes, got %v", err)
+	}
+
+	// and so is ciphertext moved to another attribute
+	swapped := map[string]types.AttributeValue{
+		"ID":  item["ID"],
+		"SSN": item["Notes"],
+	}
+	if err := db.decoder().unmarshalItem(swapped, &out); err == nil {
+		t.Error("expected error for swapped attribute, got nil")
+	}
+
+	// batch writes are encrypted too
+	bw := table.Batch().Write().Put(in)
+	if _, ok := bw.ops[0].PutRequest.Item["SSN"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("batch put not encrypted: %#v", bw.ops[0].PutRequest.Item["SSN"])
+	}
+}
+
+func TestEncryptUpdate(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+
+	u := db.Table("Users").Update("ID", "u1").
+		Set("SSN", "987-65-4321").
+		Set("Name", "Bob").
+		Model(secretUser{})
+	if err := u.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var ssn types.AttributeValue
+	for _, v := range client.update.ExpressionAttributeValues {
+		switch x := v.(type) {
+		case *types.AttributeValueMemberS:
+			if x.Value != "Bob" {
+				t.Errorf("unexpected plaintext value: %q", x.Value)
+			}
+		case *types.AttributeValueMemberB:
+			ssn = x
+		}
+	}
+	if ssn == nil {
+		t.Fatal("SSN not encrypted")
+	}
+
+	// decrypts like a put item with the same key
+	var out secretUser
+	item := map[string]types.AttributeValue{
+		"ID":  &types.AttributeValueMemberS{Value: "u1"},
+		"SSN": ssn,
+	}
+	if err := db.decoder().unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SSN != "987-65-4321" {
+		t.Errorf("bad SSN: %q", out.SSN)
+	}
+
+	// running again doesn't encrypt twice
+	if err := u.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range client.update.ExpressionAttributeValues {
+		if v != ssn {
+			if _, ok := v.(*types.AttributeValueMemberB); ok {
+				t.Error("value encrypted again")
+			}
+		}
+	}
+}
+
+func TestEncryptNested(t *testing.T) {
+	db := NewFromIface(&fakeClient{})
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+
+	in := secretOuter{
+		ID:      "o1",
+		User:    secretUser{ID: "u1", SSN: "123-45-6789"},
+		Boss:    &secretUser{ID: "u3", SSN: "555-55-5555"},
+		Friends: []secretUser{{ID: "u2", SSN: "987-65-4321"}},
+	}
+	item, err := db.MarshalItem(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := item["User"].(*types.AttributeValueMemberM).Value
+	if b, ok := user["SSN"].(*types.AttributeValueMemberB); !ok || !bytes.HasPrefix(b.Value, encryptHeader) {
+		t.Errorf("nested field not encrypted: %#v", user["SSN"])
+	}
+	friend := item["Friends"].(*types.AttributeValueMemberL).Value[0].(*types.AttributeValueMemberM).Value
+	if _, ok := friend["SSN"].(*types.AttributeValueMemberB); !ok {
+		t.Errorf("field of nested list not encrypted: %#v", friend["SSN"])
+	}
+
+	var out secretOuter
+	if err := db.UnmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("bad round trip. want: %#v got: %#v", in, out)
+	}
+
+	// nested ciphertext is signed with the item's keys, so it can't be copied to another item
+	other, err := db.MarshalItem(secretOuter{ID: "o2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other["User"] = item["User"]
+	var derr *DecodeError
+	if err := db.UnmarshalItem(other, &out); !errors.As(err, &derr) || derr.Errors[0].Path != "User.SSN" {
+		t.Errorf("expected decrypt error for User.SSN, got %v", err)
+	}
+	// nor to another path of the same item
+	swapped := map[string]types.AttributeValue{
+		"ID":   item["ID"],
+		"User": item["Boss"],
+	}
+	if err := db.UnmarshalItem(swapped, &out); err == nil {
+		t.Error("expected error for ciphertext moved to another path, got nil")
+	}
+
+	size, err := db.ItemSize(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := itemSize(item); size != want {
+		t.Errorf("bad size: %d, want %d", size, want)
+	}
+	if _, err := ItemSize(in); !errors.Is(err, ErrNoEncryptor) {
+		t.Errorf("expected ErrNoEncryptor without a DB, got %v", err)
+	}
+}
+
+type secretOuter struct {
+	ID      string `dynamo:",hash"`
+	User    secretUser
+	Boss    *secretUser
+	Friends []secretUser
+}
+
+func TestEncryptUpdateNested(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+	table := db.Table("Outer").WithModel(secretOuter{})
+
+	// values are encrypted as they would be at their path in a put item
+	value := func(i int) types.AttributeValue {
+		return client.update.ExpressionAttributeValues[":v"+strconv.Itoa(i)]
+	}
+	decode := func(item map[string]types.AttributeValue) secretOuter {
+		t.Helper()
+		item["ID"] = &types.AttributeValueMemberS{Value: "o1"}
+		var out secretOuter
+		if err := db.UnmarshalItem(item, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	err := table.Update("ID", "o1").
+		Set("User", secretUser{ID: "u1", SSN: "123-45-6789"}).
+		Set("Boss.SSN", "555-55-5555").
+		Append("Friends", []secretUser{{ID: "u2", SSN: "987-65-4321"}}).
+		Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := decode(map[string]types.AttributeValue{
+		"User":    value(0),
+		"Boss":    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"SSN": value(1)}},
+		"Friends": value(2),
+	})
+	if out.User.SSN != "123-45-6789" || out.Boss.SSN != "555-55-5555" || out.Friends[0].SSN != "987-65-4321" {
+		t.Errorf("bad decrypted values: %#v", out)
+	}
+	if _, ok := value(1).(*types.AttributeValueMemberB); !ok {
+		t.Errorf("nested path not encrypted: %#v", value(1))
+	}
+
+	// values are signed with the range key, even if it's given after them
+	type rangedOuter struct {
+		ID    string `dynamo:",hash"`
+		Seq   int    `dynamo:",range"`
+		Inner secretUser
+	}
+	err = db.Table("Ranged").Update("ID", "o1").
+		Set("Inner", secretUser{SSN: "123-45-6789"}).
+		Range("Seq", 2).
+		Model(rangedOuter{}).
+		Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranged rangedOuter
+	err = db.UnmarshalItem(map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberS{Value: "o1"},
+		"Seq":   &types.AttributeValueMemberN{Value: "2"},
+		"Inner": value(0),
+	}, &ranged)
+	if err != nil || ranged.Inner.SSN != "123-45-6789" {
+		t.Errorf("bad decrypted value with range key: %#v %v", ranged, err)
+	}
+
+	if err := table.Update("ID", "o1").Set("User.SSN.X", "1").Run(); err == nil {
+		t.Error("expected error for path inside encrypted field, got nil")
+	}
+}
+
+func TestEncryptUpdateExpr(t *testing.T) {
+	client := newFakeStore()
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+	table := db.Table("Users").WithModel(secretUser{})
+	if err := table.Put(secretUser{ID: "u1", SSN: "123-45-6789"}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	raw := func() types.AttributeValue {
+		return client.items["Users"][0]["SSN"]
+	}
+	stored := raw()
+
+	// actions that can't encrypt their values fail instead of writing plaintext
+	for name, u := range map[string]*Update{
+		"SetExpr": table.Update("ID", "u1").SetExpr("SSN = ?", "987-65-4321"),
+		"$":       table.Update("ID", "u1").SetExpr("$ = ?", "SSN", "987-65-4321"),
+		"Append":  table.Update("ID", "u1").Append("SSN", []string{"987-65-4321"}),
+		"Add":     table.Update("ID", "u1").AddStringsToSet("SSN", "987-65-4321"),
+	} {
+		if err := u.Run(); err == nil {
+			t.Errorf("%s: expected error for encrypted field, got nil", name)
+		}
+	}
+	if raw() != stored {
+		t.Fatalf("attribute changed: %#v", raw())
+	}
+
+	if err := table.Update("ID", "u1").Set("SSN", "987-65-4321").Run(); err != nil {
+		t.Fatal(err)
+	}
+	b, ok := raw().(*types.AttributeValueMemberB)
+	if !ok || !bytes.HasPrefix(b.Value, encryptHeader) || bytes.Contains(b.Value, []byte("987-65-4321")) {
+		t.Fatalf("stored attribute not encrypted: %#v", raw())
+	}
+	var out secretUser
+	if err := db.UnmarshalItem(client.items["Users"][0], &out); err != nil || out.SSN != "987-65-4321" {
+		t.Errorf("bad stored value: %#v %v", out, err)
+	}
+}
+
+func TestEncryptUpdateTable(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{})
+
+	encrypted := func() bool {
+		for _, v := range client.update.ExpressionAttributeValues {
+			if s, ok := v.(*types.AttributeValueMemberS); ok && s.Value == "987-65-4321" {
+				return false
+			}
+		}
+		return true
+	}
+
+	// the table's model
+	table := db.Table("Users").WithModel(secretUser{})
+	if err := table.Update("ID", "u1").Set("SSN", "987-65-4321").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !encrypted() {
+		t.Error("Set with table model: SSN not encrypted")
+	}
+
+	// the types of the table's registry
+	reg := NewRegistry("Type").Register("user", secretUser{})
+	table = db.Table("Users").WithRegistry(reg)
+	if err := table.Update("ID", "u1").SetIfNotExists("SSN", "987-65-4321").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !encrypted() {
+		t.Error("SetIfNotExists with registry: SSN not encrypted")
+	}
+
+	// without an encryptor, setting an encrypted field fails instead of sending plaintext
+	db.SetEncryptor(nil, EncryptOptions{})
+	err := db.Table("Users").WithModel(secretUser{}).Update("ID", "u1").Set("SSN", "987-65-4321").Run()
+	if !errors.Is(err, ErrNoEncryptor) {
+		t.Errorf("expected ErrNoEncryptor, got %v", err)
+	}
+}
+
+func TestEncryptErrors(t *testing.T) {
+	in := secretUser{ID: "u1", SSN: "123-45-6789"}
+
+	// no encryptor
+	if _, err := marshalItem(in); !errors.Is(err, ErrNoEncryptor) {
+		t.Errorf("expected ErrNoEncryptor, got %v", err)
+	}
+	client := &fakeClient{}
+	if err := NewFromIface(client).Table("Users").Put(in).Run(); !errors.Is(err, ErrNoEncryptor) {
+		t.Errorf("expected ErrNoEncryptor, got %v", err)
+	}
+	err := unmarshalItem(map[string]types.AttributeValue{
+		"SSN": &types.AttributeValueMemberB{Value: []byte("de\x01")},
+	}, &secretUser{})
+	if !errors.Is(err, ErrNoEncryptor) {
+		t.Errorf("expected ErrNoEncryptor, got %v", err)
+	}
+
+	// plaintext isn't accepted in place of ciphertext
+	db := NewFromIface(client)
+	db.SetEncryptor(testKeyring(t), EncryptOptions{})
+	err = db.decoder().unmarshalItem(map[string]types.AttributeValue{
+		"SSN": &types.AttributeValueMemberS{Value: "123-45-6789"},
+	}, &secretUser{})
+	if err == nil {
+		t.Error("expected error for plaintext, got nil")
+	}
+
+	// signing keys needs hash key tags
+	db.SetEncryptor(testKeyring(t), EncryptOptions{SignKeys: true})
+	type untagged struct {
+		Secret string `dynamo:",encrypt"`
+	}
+	if _, err := encodeItem(untagged{Secret: "x"}, db.crypter()); err == nil {
+		t.Error("expected error for signing without hash key, got nil")
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	kr := testKeyring(t)
+	keyID, nonce, ciphertext, err := kr.Encrypt([]byte("hello"), []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID != "k2" {
+		t.Errorf("bad key ID: %s", keyID)
+	}
+	plaintext, err := kr.Decrypt(keyID, nonce, ciphertext, []byte("aad"))
+	if err != nil || string(plaintext) != "hello" {
+		t.Errorf("bad decrypt: %q %v", plaintext, err)
+	}
+	if _, err := kr.Decrypt(keyID, nonce, ciphertext, []byte("other")); err == nil {
+		t.Error("expected error for wrong aad, got nil")
+	}
+	if _, err := kr.Decrypt("k3", nonce, ciphertext, []byte("aad")); err == nil {
+		t.Error("expected error for unknown key, got nil")
+	}
+
+	// rotated keys can still decrypt old values
+	old, err := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, nonce, ciphertext, _ = old.Encrypt([]byte("old"), nil)
+	if plaintext, err := kr.Decrypt(keyID, nonce, ciphertext, nil); err != nil || string(plaintext) != "old" {
+		t.Errorf("bad decrypt with rotated keyring: %q %v", plaintext, err)
+	}
+
+	if _, err := NewKeyring("missing", map[string][]byte{"k1": make([]byte, 16)}); err == nil {
+		t.Error("expected error for missing current key, got nil")
+	}
+	if _, err := NewKeyring("k1", map[string][]byte{"k1": make([]byte, 5)}); err == nil {
+		t.Error("expected error for bad key size, got nil")
+	}
+}
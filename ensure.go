@@ -0,0 +1,587 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// EnsureOptions describe the desired settings of a table for EnsureTable,
+// in addition to the keys and indexes declared by its example struct.
+type EnsureOptions struct {
+	// OnDemand selects on-demand (pay per request) billing.
+	// Otherwise, the table uses provisioned throughput.
+	OnDemand bool
+	// Throughput is the provisioned throughput of the table; only Read and Write are used.
+	// If zero, new tables are created with 1 unit of each and existing tables keep theirs.
+	// The throughput of global indexes is declared with struct tags or DynamoIndexes.
+	Throughput Throughput
+
+	// Stream enables the table's stream with the given view. If empty, the stream is left as is.
+	Stream StreamView
+	// DisableStream disables the table's stream.
+	DisableStream bool
+
+	// TTL enables time to live with the given attribute. If empty, time to live is left as is.
+	TTL string
+	// DisableTTL disables time to live.
+	DisableTTL bool
+
+	// DeleteIndexes deletes global indexes that the example doesn't declare,
+	// and recreates those whose keys or projection changed.
+	// Otherwise, extra indexes are kept and changed indexes are an error.
+	DeleteIndexes bool
+
+	// DryRun makes Run return the plan without changing anything.
+	DryRun bool
+	// PollInterval is how often the table is described while waiting for a change to finish.
+	// The default is 5 seconds.
+	PollInterval time.Duration
+}
+
+// EnsureTable is a request to bring a table in line with a schema,
+// creating it or updating its settings and indexes as needed.
+type EnsureTable struct {
+	table Table
+	ct    *CreateTable
+	opts  EnsureOptions
+	// declared are the global indexes with throughput declared by the example
+	declared map[string]bool
+	err      error
+}
+
+// Plan is the list of changes that EnsureTable makes to a table, in the order they are applied.
+type Plan struct {
+	Table string
+	Steps []PlanStep
+}
+
+// PlanStep is a single change of a Plan.
+type PlanStep struct {
+	// Description describes the change, such as "create global index Status-index".
+	Description string
+
+	run func(ctx context.Context) error
+	// wait is true if the table must be active again before the next step.
+	wait bool
+}
+
+// Empty returns true if the table is already up to date.
+func (p Plan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// String returns the plan in human-readable form, one step per line.
+func (p Plan) String() string {
+	if p.Empty() {
+		return fmt.Sprintf("table %s: up to date\n", p.Table)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s: %d change(s)\n", p.Table, len(p.Steps))
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, step.Description)
+	}
+	return b.String()
+}
+
+func (p *Plan) add(desc string, wait bool, run func(ctx context.Context) error) {
+	p.Steps = append(p.Steps, PlanStep{Description: desc, run: run, wait: wait})
+}
+
+// EnsureTable begins a new request to create or update the table name to match
+// the keys and indexes of example, as in CreateTable, and the settings of opts.
+// The table's current schema is described and compared to the desired one,
+// and the differences are applied in order, one at a time,
+// waiting for the table and its indexes to become active between steps.
+// Keys and local indexes can't be changed after a table is created, so differences in them are an error.
+func (db *DB) EnsureTable(name string, example interface{}, opts EnsureOptions) *EnsureTable {
+	ct := db.CreateTable(name, example)
+	declared := make(map[string]bool)
+	if rt := reflect.TypeOf(example); ct.err == nil && rt != nil {
+		for _, idx := range indexesOf(rt).list() {
+			if !idx.Local && (idx.Throughput.Read != 0 || idx.Throughput.Write != 0) {
+				declared[idx.Name] = true
+			}
+		}
+	}
+	return db.ensureTable(ct, opts, declared)
+}
+
+// ensureTable creates a request to bring a table in line with ct,
+// managing the throughput of the global indexes in declared.
+func (db *DB) ensureTable(ct *CreateTable, opts EnsureOptions, declared map[string]bool) *EnsureTable {
+	ct.OnDemand(opts.OnDemand)
+	if opts.Throughput.Read != 0 || opts.Throughput.Write != 0 {
+		ct.Provision(opts.Throughput.Read, opts.Throughput.Write)
+	}
+	if opts.Stream != "" && !opts.DisableStream {
+		ct.Stream(opts.Stream)
+	}
+	return &EnsureTable{
+		table:    db.Table(ct.tableName),
+		ct:       ct,
+		opts:     opts,
+		declared: declared,
+		err:      ct.err,
+	}
+}
+
+// Plan describes the table and returns the changes needed, without applying them.
+func (et *EnsureTable) Plan() (Plan, error) {
+	ctx, cancel := defaultContext()
+	defer cancel()
+	return et.PlanWithContext(ctx)
+}
+
+// PlanWithContext describes the table and returns the changes needed, without applying them.
+func (et *EnsureTable) PlanWithContext(ctx context.Context) (Plan, error) {
+	if et.err != nil {
+		return Plan{Table: et.table.name}, et.err
+	}
+	want := et.ct.description()
+	have, err := et.table.Describe().RunWithContext(ctx)
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return et.createPlan(), nil
+	}
+	if err != nil {
+		return Plan{Table: et.table.name}, err
+	}
+	plan := Plan{Table: et.table.name}
+	if err := et.planKeys(want, have); err != nil {
+		return plan, err
+	}
+	et.planThroughput(&plan, want, have)
+	if err := et.planIndexes(&plan, want, have); err != nil {
+		return plan, err
+	}
+	et.planStream(&plan, have)
+	if err := et.planTTL(ctx, &plan); err != nil {
+		return plan, err
+	}
+	if err := et.planTags(ctx, &plan, have); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+// Run applies the changes needed and returns them.
+// If DryRun is set, nothing is changed.
+// Creating indexes can take much longer than RetryTimeout, so Run waits for each step without a deadline;
+// use RunWithContext to limit it.
+func (et *EnsureTable) Run() (Plan, error) {
+	return et.RunWithContext(context.Background())
+}
+
+// RunWithContext applies the changes needed and returns them.
+// If DryRun is set, nothing is changed.
+func (et *EnsureTable) RunWithContext(ctx context.Context) (Plan, error) {
+	plan, err := et.PlanWithContext(ctx)
+	if err != nil || et.opts.DryRun {
+		return plan, err
+	}
+	for i, step := range plan.Steps {
+		if err := step.run(ctx); err != nil {
+			return plan, fmt.Errorf("dynamo: ensure table %s: %s: %v", plan.Table, step.Description, err)
+		}
+		if step.wait && i < len(plan.Steps)-1 {
+			if err := et.wait(ctx); err != nil {
+				return plan, err
+			}
+		}
+	}
+	return plan, nil
+}
+
+// wait describes the table until it and its global indexes are active.
+func (et *EnsureTable) wait(ctx context.Context) error {
+	interval := et.opts.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		desc, err := et.table.Describe().RunWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		if tableReady(desc) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func tableReady(desc Description) bool {
+	if !desc.Active() {
+		return false
+	}
+	for _, idx := range desc.GSI {
+		if idx.Status != ActiveStatus || idx.Backfilling {
+			return false
+		}
+	}
+	return true
+}
+
+func (et *EnsureTable) createPlan() Plan {
+	plan := Plan{Table: et.table.name}
+	want := et.ct.description()
+	desc := fmt.Sprintf("create table with hash key %s (%s)", want.HashKey, want.HashKeyType)
+	if want.RangeKey != "" {
+		desc += fmt.Sprintf(", range key %s (%s)", want.RangeKey, want.RangeKeyType)
+	}
+	desc += ", " + billingString(want)
+	for _, idx := range want.GSI {
+		desc += ", global index " + indexString(idx)
+	}
+	for _, idx := range want.LSI {
+		desc += ", local index " + indexString(idx)
+	}
+	if want.StreamEnabled {
+		desc += ", stream " + string(want.StreamView)
+	}
+	plan.add(desc, true, et.ct.RunWithContext)
+	if et.opts.TTL != "" && !et.opts.DisableTTL {
+		plan.add("enable TTL on "+et.opts.TTL, false, et.table.UpdateTTL(et.opts.TTL, true).RunWithContext)
+	}
+	return plan
+}
+
+func (et *EnsureTable) planKeys(want, have Description) error {
+	if want.HashKey != have.HashKey || want.HashKeyType != have.HashKeyType ||
+		want.RangeKey != have.RangeKey || want.RangeKeyType != have.RangeKeyType {
+		return fmt.Errorf("dynamo: ensure table %s: primary key is %s, want %s; keys can't be changed",
+			et.table.name, keyString(have.HashKey, have.HashKeyType, have.RangeKey, have.RangeKeyType),
+			keyString(want.HashKey, want.HashKeyType, want.RangeKey, want.RangeKeyType))
+	}
+	haveLSI := make(map[string]Index, len(have.LSI))
+	for _, idx := range have.LSI {
+		haveLSI[idx.Name] = idx
+	}
+	for _, idx := range want.LSI {
+		live, ok := haveLSI[idx.Name]
+		if !ok || !sameIndex(idx, live) {
+			return fmt.Errorf("dynamo: ensure table %s: local index %s differs; local indexes can't be changed", et.table.name, idx.Name)
+		}
+		delete(haveLSI, idx.Name)
+	}
+	for name := range haveLSI {
+		return fmt.Errorf("dynamo: ensure table %s: local index %s isn't declared; local indexes can't be deleted", et.table.name, name)
+	}
+	return nil
+}
+
+func (et *EnsureTable) planThroughput(plan *Plan, want, have Description) {
+	ut := et.table.UpdateTable()
+	var changes []string
+	if want.OnDemand != have.OnDemand {
+		ut.OnDemand(want.OnDemand)
+		changes = append(changes, "switch to "+billingString(want))
+		if !want.OnDemand {
+			// switching to provisioned requires the throughput of the table and every existing index,
+			// including those that aren't declared, which get the table's throughput if they have none
+			ut.Provision(want.Throughput.Read, want.Throughput.Write)
+			for _, live := range have.GSI {
+				tp := live.Throughput
+				if idx, ok := findIndex(want.GSI, live.Name); ok {
+					tp = idx.Throughput
+				}
+				if tp.Read == 0 || tp.Write == 0 {
+					tp = want.Throughput
+				}
+				ut.ProvisionIndex(live.Name, tp.Read, tp.Write)
+			}
+		}
+	} else if !want.OnDemand {
+		if et.opts.Throughput.Read != 0 || et.opts.Throughput.Write != 0 {
+			if want.Throughput.Read != have.Throughput.Read || want.Throughput.Write != have.Throughput.Write {
+				ut.Provision(want.Throughput.Read, want.Throughput.Write)
+				changes = append(changes, fmt.Sprintf("change throughput from %s to %s",
+					throughputString(have.Throughput), throughputString(want.Throughput)))
+			}
+		}
+		for _, idx := range want.GSI {
+			live, ok := findIndex(have.GSI, idx.Name)
+			if !ok || !et.declared[idx.Name] || !sameIndex(idx, live) {
+				continue
+			}
+			if idx.Throughput.Read != live.Throughput.Read || idx.Throughput.Write != live.Throughput.Write {
+				ut.ProvisionIndex(idx.Name, idx.Throughput.Read, idx.Throughput.Write)
+				changes = append(changes, fmt.Sprintf("change throughput of index %s from %s to %s",
+					idx.Name, throughputString(live.Throughput), throughputString(idx.Throughput)))
+			}
+		}
+	}
+	if len(changes) > 0 {
+		plan.add(strings.Join(changes, ", "), true, func(ctx context.Context) error {
+			_, err := ut.RunWithContext(ctx)
+			return err
+		})
+	}
+}
+
+func (et *EnsureTable) planIndexes(plan *Plan, want, have Description) error {
+	var deletes, creates []Index
+	for _, live := range have.GSI {
+		idx, ok := findIndex(want.GSI, live.Name)
+		switch {
+		case ok && sameIndex(idx, live):
+		case !et.opts.DeleteIndexes && ok:
+			return fmt.Errorf("dynamo: ensure table %s: global index %s is %s, want %s; set DeleteIndexes to recreate it",
+				et.table.name, live.Name, indexString(live), indexString(idx))
+		case et.opts.DeleteIndexes:
+			deletes = append(deletes, live)
+		}
+	}
+	for _, idx := range want.GSI {
+		if live, ok := findIndex(have.GSI, idx.Name); !ok || !sameIndex(idx, live) {
+			creates = append(creates, idx)
+		}
+	}
+	for _, idx := range deletes {
+		name := idx.Name
+		plan.add("delete global index "+name, true, func(ctx context.Context) error {
+			_, err := et.table.UpdateTable().DeleteIndex(name).RunWithContext(ctx)
+			return err
+		})
+	}
+	for _, idx := range creates {
+		idx := idx
+		if want.OnDemand {
+			idx.Throughput = Throughput{}
+		}
+		plan.add("create global index "+indexString(idx), true, func(ctx context.Context) error {
+			_, err := et.table.UpdateTable().CreateIndex(idx).RunWithContext(ctx)
+			return err
+		})
+	}
+	return nil
+}
+
+func (et *EnsureTable) planStream(plan *Plan, have Description) {
+	disable := func(ctx context.Context) error {
+		_, err := et.table.UpdateTable().DisableStream().RunWithContext(ctx)
+		return err
+	}
+	switch {
+	case et.opts.DisableStream:
+		if have.StreamEnabled {
+			plan.add("disable stream", true, disable)
+		}
+	case et.opts.Stream != "":
+		if have.StreamEnabled && have.StreamView == et.opts.Stream {
+			return
+		}
+		if have.StreamEnabled {
+			// the view of an enabled stream can't be changed
+			plan.add("disable stream "+string(have.StreamView), true, disable)
+		}
+		view := et.opts.Stream
+		plan.add("enable stream "+string(view), true, func(ctx context.Context) error {
+			_, err := et.table.UpdateTable().Stream(view).RunWithContext(ctx)
+			return err
+		})
+	}
+}
+
+func (et *EnsureTable) planTTL(ctx context.Context, plan *Plan) error {
+	if et.opts.TTL == "" && !et.opts.DisableTTL {
+		return nil
+	}
+	ttl, err := et.table.DescribeTTL().RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	on := ttl.Status == TTLEnabled || ttl.Status == TTLEnabling
+	switch {
+	case et.opts.DisableTTL:
+		if on {
+			plan.add("disable TTL on "+ttl.Attribute, false, et.table.UpdateTTL(ttl.Attribute, false).RunWithContext)
+		}
+	case !on:
+		plan.add("enable TTL on "+et.opts.TTL, false, et.table.UpdateTTL(et.opts.TTL, true).RunWithContext)
+	case ttl.Attribute != et.opts.TTL:
+		plan.add("disable TTL on "+ttl.Attribute, false, et.table.UpdateTTL(ttl.Attribute, false).RunWithContext)
+		plan.add("enable TTL on "+et.opts.TTL, false, et.table.UpdateTTL(et.opts.TTL, true).RunWithContext)
+	}
+	return nil
+}
+
+// tagger is implemented by clients that can tag tables, such as *dynamodb.Client.
+// It isn't part of dynamodbiface.DynamoDBAPI, so that existing implementations of it keep working.
+type tagger interface {
+	ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error)
+	TagResource(ctx context.Context, params *dynamodb.TagResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error)
+}
+
+// planTags adds or changes the tags declared for the table.
+// Tags that aren't declared are left as is.
+// Declaring tags fails if the DB's client can't tag tables.
+func (et *EnsureTable) planTags(ctx context.Context, plan *Plan, have Description) error {
+	if len(et.ct.tags) == 0 {
+		return nil
+	}
+	client, ok := et.table.db.client.(tagger)
+	if !ok {
+		return fmt.Errorf("dynamo: ensure table %s: tags are declared, but the client can't tag tables (it needs ListTagsOfResource and TagResource)", et.table.name)
+	}
+	live := make(map[string]string)
+	input := &dynamodb.ListTagsOfResourceInput{ResourceArn: aws.String(have.ARN)}
+	for {
+		var out *dynamodb.ListTagsOfResourceOutput
+		err := retry(ctx, func() error {
+			var err error
+			out, err = client.ListTagsOfResource(ctx, input)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, tag := range out.Tags {
+			live[*tag.Key] = *tag.Value
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	var tags []types.Tag
+	var changes []string
+	for _, tag := range et.ct.tags {
+		if value, ok := live[*tag.Key]; ok && value == *tag.Value {
+			continue
+		}
+		tags = append(tags, tag)
+		changes = append(changes, *tag.Key+"="+*tag.Value)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	arn := have.ARN
+	plan.add("tag "+strings.Join(changes, ", "), false, func(ctx context.Context) error {
+		return retry(ctx, func() error {
+			_, err := client.TagResource(ctx, &dynamodb.TagResourceInput{
+				ResourceArn: aws.String(arn),
+				Tags:        tags,
+			})
+			return err
+		})
+	})
+	return nil
+}
+
+// description returns the description of the table this request would create.
+func (ct *CreateTable) description() Description {
+	input := ct.input()
+	desc := Description{
+		Name:     ct.tableName,
+		OnDemand: ct.ondemand,
+	}
+	desc.HashKey, desc.RangeKey = schemaKeys(input.KeySchema)
+	desc.HashKeyType = lookupADType(input.AttributeDefinitions, desc.HashKey)
+	desc.RangeKeyType = lookupADType(input.AttributeDefinitions, desc.RangeKey)
+	if !ct.ondemand {
+		desc.Throughput = Throughput{Read: ct.readUnits, Write: ct.writeUnits}
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		idx := Index{Name: *gsi.IndexName}
+		idx.HashKey, idx.RangeKey = schemaKeys(gsi.KeySchema)
+		idx.HashKeyType = lookupADType(input.AttributeDefinitions, idx.HashKey)
+		idx.RangeKeyType = lookupADType(input.AttributeDefinitions, idx.RangeKey)
+		idx.ProjectionType = IndexProjection(gsi.Projection.ProjectionType)
+		idx.ProjectionAttribs = gsi.Projection.NonKeyAttributes
+		if pt := gsi.ProvisionedThroughput; pt != nil {
+			idx.Throughput = Throughput{Read: *pt.ReadCapacityUnits, Write: *pt.WriteCapacityUnits}
+		}
+		desc.GSI = append(desc.GSI, idx)
+	}
+	for _, lsi := range input.LocalSecondaryIndexes {
+		idx := Index{Name: *lsi.IndexName, Local: true, Throughput: desc.Throughput}
+		idx.HashKey, idx.RangeKey = schemaKeys(lsi.KeySchema)
+		idx.HashKeyType = lookupADType(input.AttributeDefinitions, idx.HashKey)
+		idx.RangeKeyType = lookupADType(input.AttributeDefinitions, idx.RangeKey)
+		idx.ProjectionType = IndexProjection(lsi.Projection.ProjectionType)
+		idx.ProjectionAttribs = lsi.Projection.NonKeyAttributes
+		desc.LSI = append(desc.LSI, idx)
+	}
+	sortIndexes(desc.GSI)
+	sortIndexes(desc.LSI)
+	if input.StreamSpecification != nil {
+		desc.StreamEnabled = true
+		desc.StreamView = StreamView(input.StreamSpecification.StreamViewType)
+	}
+	return desc
+}
+
+func sortIndexes(indexes []Index) {
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+}
+
+func findIndex(indexes []Index, name string) (Index, bool) {
+	for _, idx := range indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return Index{}, false
+}
+
+// sameIndex returns true if a and b have the same keys and projection.
+func sameIndex(a, b Index) bool {
+	if a.HashKey != b.HashKey || a.HashKeyType != b.HashKeyType ||
+		a.RangeKey != b.RangeKey || a.RangeKeyType != b.RangeKeyType ||
+		a.ProjectionType != b.ProjectionType || len(a.ProjectionAttribs) != len(b.ProjectionAttribs) {
+		return false
+	}
+	attribs := make(map[string]bool, len(a.ProjectionAttribs))
+	for _, attr := range a.ProjectionAttribs {
+		attribs[attr] = true
+	}
+	for _, attr := range b.ProjectionAttribs {
+		if !attribs[attr] {
+			return false
+		}
+	}
+	return true
+}
+
+func keyString(hashKey string, hashType KeyType, rangeKey string, rangeType KeyType) string {
+	s := fmt.Sprintf("%s (%s)", hashKey, hashType)
+	if rangeKey != "" {
+		s += fmt.Sprintf(", %s (%s)", rangeKey, rangeType)
+	}
+	return s
+}
+
+func indexString(idx Index) string {
+	s := fmt.Sprintf("%s on %s, projection %s", idx.Name, keyString(idx.HashKey, idx.HashKeyType, idx.RangeKey, idx.RangeKeyType), idx.ProjectionType)
+	if len(idx.ProjectionAttribs) > 0 {
+		s += " [" + strings.Join(idx.ProjectionAttribs, ", ") + "]"
+	}
+	return s
+}
+
+func billingString(desc Description) string {
+	if desc.OnDemand {
+		return "on-demand billing"
+	}
+	return "provisioned throughput " + throughputString(desc.Throughput)
+}
+
+func throughputString(thru Throughput) string {
+	return fmt.Sprintf("%d read/%d write", thru.Read, thru.Write)
+}
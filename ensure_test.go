@@ -0,0 +1,204 @@
+package dynamo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type ensuredOrder struct {
+	UserID  string `dynamo:",hash"`
+	OrderID string `dynamo:",range"`
+	Status  string `index:"Status-index,hash,projection=keys_only,read=2,write=2"`
+	Expires int64
+}
+
+func liveOrders() *types.TableDescription {
+	return &types.TableDescription{
+		TableName:   aws.String("Orders"),
+		TableStatus: types.TableStatusActive,
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("UserID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("OrderID"), KeyType: types.KeyTypeRange},
+		},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("UserID"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("OrderID"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("Legacy"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(10),
+			WriteCapacityUnits: aws.Int64(10),
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{{
+			IndexName:   aws.String("Legacy-index"),
+			IndexArn:    aws.String("arn"),
+			IndexStatus: types.IndexStatusActive,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("Legacy"), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(1),
+				WriteCapacityUnits: aws.Int64(1),
+			},
+		}},
+		StreamSpecification: &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeKeysOnly,
+		},
+	}
+}
+
+func TestEnsureTableCreate(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	et := db.EnsureTable("Orders", ensuredOrder{}, EnsureOptions{
+		TTL:          "Expires",
+		PollInterval: time.Millisecond,
+	})
+	plan, err := et.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("want 2 steps, got:\n%s", plan)
+	}
+	if !strings.HasPrefix(plan.Steps[0].Description, "create table with hash key UserID (S), range key OrderID (S)") ||
+		!strings.Contains(plan.Steps[0].Description, "global index Status-index") {
+		t.Errorf("bad create step: %s", plan.Steps[0].Description)
+	}
+	if plan.Steps[1].Description != "enable TTL on Expires" {
+		t.Errorf("bad TTL step: %s", plan.Steps[1].Description)
+	}
+	if len(client.created) != 1 || len(client.ttlUpdate) != 1 {
+		t.Errorf("bad calls: %d creates, %d TTL updates", len(client.created), len(client.ttlUpdate))
+	}
+}
+
+func TestEnsureTableUpdate(t *testing.T) {
+	client := &fakeClient{table: liveOrders()}
+	db := NewFromIface(client)
+	opts := EnsureOptions{
+		Throughput:    Throughput{Read: 5, Write: 5},
+		Stream:        NewAndOldImagesView,
+		DeleteIndexes: true,
+		DryRun:        true,
+		PollInterval:  time.Millisecond,
+	}
+
+	plan, err := db.EnsureTable("Orders", ensuredOrder{}, opts).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `table Orders: 5 change(s)
+  1. change throughput from 10 read/10 write to 5 read/5 write
+  2. delete global index Legacy-index
+  3. create global index Status-index on Status (S), projection KEYS_ONLY
+  4. disable stream KEYS_ONLY
+  5. enable stream NEW_AND_OLD_IMAGES
+`
+	if plan.String() != want {
+		t.Errorf("bad plan.\nwant:\n%s\ngot:\n%s", want, plan)
+	}
+	if len(client.updated) != 0 {
+		t.Error("dry run changed the table")
+	}
+
+	opts.DryRun = false
+	client.describes = 0
+	if _, err := db.EnsureTable("Orders", ensuredOrder{}, opts).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.updated) != 5 {
+		t.Fatalf("want 5 updates, got %d", len(client.updated))
+	}
+	create := client.updated[2].GlobalSecondaryIndexUpdates[0].Create
+	if create == nil || *create.IndexName != "Status-index" || *create.ProvisionedThroughput.ReadCapacityUnits != 2 {
+		t.Errorf("bad index creation: %#v", client.updated[2].GlobalSecondaryIndexUpdates)
+	}
+	// initial describe, then one per step but the last
+	if client.describes != 5 {
+		t.Errorf("want 5 describes, got %d", client.describes)
+	}
+}
+
+func TestEnsureTableNoChanges(t *testing.T) {
+	live := liveOrders()
+	live.StreamSpecification = nil
+	client := &fakeClient{table: live}
+	// extra indexes are kept without DeleteIndexes
+	type order struct {
+		UserID  string `dynamo:",hash"`
+		OrderID string `dynamo:",range"`
+	}
+	plan, err := NewFromIface(client).EnsureTable("Orders", order{}, EnsureOptions{}).Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected empty plan, got:\n%s", plan)
+	}
+	if plan.String() != "table Orders: up to date\n" {
+		t.Errorf("bad string: %q", plan.String())
+	}
+}
+
+func TestEnsureTableProvision(t *testing.T) {
+	live := liveOrders()
+	live.StreamSpecification = nil
+	live.BillingModeSummary = &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest}
+	live.ProvisionedThroughput = nil
+	live.GlobalSecondaryIndexes[0].ProvisionedThroughput = nil
+	client := &fakeClient{table: live}
+	type order struct {
+		UserID  string `dynamo:",hash"`
+		OrderID string `dynamo:",range"`
+	}
+	opts := EnsureOptions{Throughput: Throughput{Read: 4, Write: 3}}
+	if _, err := NewFromIface(client).EnsureTable("Orders", order{}, opts).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.updated) != 1 {
+		t.Fatalf("want 1 update, got %d", len(client.updated))
+	}
+	// the undeclared index is kept, so it needs throughput too
+	updates := client.updated[0].GlobalSecondaryIndexUpdates
+	if len(updates) != 1 || updates[0].Update == nil || *updates[0].Update.IndexName != "Legacy-index" ||
+		*updates[0].Update.ProvisionedThroughput.ReadCapacityUnits != 4 || *updates[0].Update.ProvisionedThroughput.WriteCapacityUnits != 3 {
+		t.Errorf("bad index updates: %#v", updates)
+	}
+}
+
+func TestEnsureTableErrors(t *testing.T) {
+	client := &fakeClient{table: liveOrders()}
+	db := NewFromIface(client)
+
+	type wrongKeys struct {
+		ID string `dynamo:",hash"`
+	}
+	if _, err := db.EnsureTable("Orders", wrongKeys{}, EnsureOptions{}).Plan(); err == nil || !strings.Contains(err.Error(), "keys can't be changed") {
+		t.Errorf("expected key error, got %v", err)
+	}
+
+	type changedIndex struct {
+		UserID  string `dynamo:",hash"`
+		OrderID string `dynamo:",range"`
+		Legacy  string `index:"Legacy-index,hash,projection=keys_only"`
+	}
+	if _, err := db.EnsureTable("Orders", changedIndex{}, EnsureOptions{}).Plan(); err == nil || !strings.Contains(err.Error(), "DeleteIndexes") {
+		t.Errorf("expected changed index error, got %v", err)
+	}
+
+	type newLocal struct {
+		UserID  string `dynamo:",hash"`
+		OrderID string `dynamo:",range"`
+		Total   int    `localIndex:"Total-index,range"`
+	}
+	if _, err := db.EnsureTable("Orders", newLocal{}, EnsureOptions{}).Plan(); err == nil || !strings.Contains(err.Error(), "local index") {
+		t.Errorf("expected local index error, got %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Package eval evaluates DynamoDB condition and filter expressions against items in memory.
+//
+// Expressions use the same syntax as dynamo's If and Filter methods:
+// ? placeholders for values and $ placeholders for names.
+// Expression attribute names (#name) and values (:value) can be given with MatchInput,
+// which is useful for implementing fakes of the DynamoDB API.
+//
+//	ok, err := eval.Match(item, "Score <= ? AND begins_with($, ?)", cutoff, "Name", "G")
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+	"github.com/niltonkummer/dynamo/internal/docpath"
+	"github.com/niltonkummer/dynamo/internal/exprs"
+)
+
+// Match returns true if item satisfies the condition expression expr.
+// Expr may be a string with args for its placeholders, or a dynamo.Expression
+// such as a condition built with the cond package.
+func Match(item map[string]types.AttributeValue, expr interface{}, args ...interface{}) (bool, error) {
+	var str string
+	switch x := expr.(type) {
+	case string:
+		str = x
+	case dynamo.Expression:
+		if len(args) > 0 {
+			return false, fmt.Errorf("dynamo: unexpected arguments for expression %T: %v", expr, args)
+		}
+		str, args = x.Expr()
+	default:
+		return false, fmt.Errorf("dynamo: expression must be a string or Expression (got %T)", expr)
+	}
+	return match(item, str, args, nil, nil)
+}
+
+// MatchInput returns true if item satisfies the condition expression expr,
+// using the given expression attribute names and values.
+func MatchInput(item map[string]types.AttributeValue, expr string, names map[string]string, values map[string]types.AttributeValue) (bool, error) {
+	return match(item, expr, nil, names, values)
+}
+
+func match(item map[string]types.AttributeValue, expr string, args []interface{}, names map[string]string, values map[string]types.AttributeValue) (bool, error) {
+	tree, err := exprs.ParseCondition(expr)
+	if err != nil {
+		return false, err
+	}
+	b, err := docpath.Bind(tree, args, names, values)
+	if err != nil {
+		return false, err
+	}
+	e := evaluator{item: item, b: b}
+	return e.cond(tree.Cond)
+}
+
+type evaluator struct {
+	item map[string]types.AttributeValue
+	b    *docpath.Binding
+}
+
+func (e evaluator) cond(node exprs.CondNode) (bool, error) {
+	switch x := node.(type) {
+	case *exprs.Logical:
+		left, err := e.cond(x.Left)
+		if err != nil {
+			return false, err
+		}
+		// no short circuit, so that errors on either side are always reported
+		right, err := e.cond(x.Right)
+		if err != nil {
+			return false, err
+		}
+		if x.Op == "AND" {
+			return left && right, nil
+		}
+		return left || right, nil
+	case *exprs.Not:
+		ok, err := e.cond(x.Cond)
+		return !ok, err
+	case *exprs.Comparison:
+		return e.compare(x)
+	case *exprs.Between:
+		v, ok, err := e.operand(x.Operand)
+		if err != nil || !ok {
+			return false, err
+		}
+		lo, _, err := e.operand(x.Lower)
+		if err != nil {
+			return false, err
+		}
+		hi, _, err := e.operand(x.Upper)
+		if err != nil {
+			return false, err
+		}
+		if cmp, ok := docpath.Compare(lo, hi); ok && cmp > 0 {
+			return false, fmt.Errorf("dynamo: invalid BETWEEN: lower bound is greater than upper bound")
+		}
+		lower, lok := docpath.Compare(v, lo)
+		upper, uok := docpath.Compare(v, hi)
+		return lok && uok && lower >= 0 && upper <= 0, nil
+	case *exprs.In:
+		v, ok, err := e.operand(x.Operand)
+		if err != nil {
+			return false, err
+		}
+		var found bool
+		for _, op := range x.List {
+			other, otherOK, err := e.operand(op)
+			if err != nil {
+				return false, err
+			}
+			if ok && otherOK && docpath.Equal(v, other) {
+				found = true
+			}
+		}
+		return found, nil
+	case *exprs.Func:
+		return e.fn(x)
+	}
+	return false, fmt.Errorf("dynamo: unexpected condition node %T", node)
+}
+
+func (e evaluator) compare(x *exprs.Comparison) (bool, error) {
+	left, lok, err := e.operand(x.Left)
+	if err != nil {
+		return false, err
+	}
+	right, rok, err := e.operand(x.Right)
+	if err != nil {
+		return false, err
+	}
+	if !lok || !rok {
+		// comparisons with missing attributes are always false,
+		// except for inequality
+		return x.Op == "<>" && lok != rok, nil
+	}
+	switch x.Op {
+	case "=":
+		return docpath.Equal(left, right), nil
+	case "<>":
+		return !docpath.Equal(left, right), nil
+	}
+	cmp, ok := docpath.Compare(left, right)
+	if !ok {
+		return false, nil
+	}
+	switch x.Op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("dynamo: unknown comparator %s", x.Op)
+}
+
+func (e evaluator) fn(x *exprs.Func) (bool, error) {
+	path, err := e.b.Path(x.Args[0].(*exprs.Path))
+	if err != nil {
+		return false, err
+	}
+	av, exists := docpath.Get(e.item, path)
+	if x.Name == "attribute_exists" {
+		return exists, nil
+	}
+	if x.Name == "attribute_not_exists" {
+		return !exists, nil
+	}
+
+	arg, argOK, err := e.operand(x.Args[1])
+	if err != nil {
+		return false, err
+	}
+	switch x.Name {
+	case "attribute_type":
+		typ, ok := arg.(*types.AttributeValueMemberS)
+		if !ok || !validType(typ.Value) {
+			return false, fmt.Errorf("dynamo: invalid type for attribute_type: %v", arg)
+		}
+		return exists && docpath.TypeName(av) == typ.Value, nil
+	case "begins_with":
+		if !exists || !argOK {
+			return false, nil
+		}
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			prefix, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(v.Value, prefix.Value), nil
+		case *types.AttributeValueMemberB:
+			prefix, ok := arg.(*types.AttributeValueMemberB)
+			return ok && bytes.HasPrefix(v.Value, prefix.Value), nil
+		}
+		return false, nil
+	case "contains":
+		if !exists || !argOK {
+			return false, nil
+		}
+		if s, ok := av.(*types.AttributeValueMemberS); ok {
+			sub, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.Contains(s.Value, sub.Value), nil
+		}
+		if b, ok := av.(*types.AttributeValueMemberB); ok {
+			sub, ok := arg.(*types.AttributeValueMemberB)
+			return ok && bytes.Contains(b.Value, sub.Value), nil
+		}
+		return docpath.Contains(av, arg), nil
+	}
+	return false, fmt.Errorf("dynamo: unknown function %s", x.Name)
+}
+
+// operand evaluates op, returning false if it refers to an attribute that doesn't exist.
+func (e evaluator) operand(op exprs.Operand) (types.AttributeValue, bool, error) {
+	switch x := op.(type) {
+	case *exprs.Value:
+		av, err := e.b.Value(x)
+		return av, err == nil, err
+	case *exprs.Path:
+		path, err := e.b.Path(x)
+		if err != nil {
+			return nil, false, err
+		}
+		av, ok := docpath.Get(e.item, path)
+		return av, ok, nil
+	case *exprs.Func:
+		if x.Name != "size" {
+			break
+		}
+		av, ok, err := e.operand(x.Args[0])
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		size, ok := docpath.Size(av)
+		if !ok {
+			return nil, false, nil
+		}
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(size)}, true, nil
+	}
+	return nil, false, fmt.Errorf("dynamo: unexpected operand %T", op)
+}
+
+func validType(typ string) bool {
+	switch typ {
+	case "S", "SS", "N", "NS", "B", "BS", "BOOL", "NULL", "L", "M":
+		return true
+	}
+	return false
+}
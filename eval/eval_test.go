@@ -0,0 +1,119 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo/cond"
+)
+
+var testItem = map[string]types.AttributeValue{
+	"ID":    &types.AttributeValueMemberN{Value: "42"},
+	"Name":  &types.AttributeValueMemberS{Value: "Gopher"},
+	"Count": &types.AttributeValueMemberN{Value: "10.5"},
+	"Tags":  &types.AttributeValueMemberSS{Value: []string{"cool", "blue"}},
+	"Data":  &types.AttributeValueMemberB{Value: []byte("hello")},
+	"List": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: "a"},
+		&types.AttributeValueMemberN{Value: "1"},
+	}},
+	"Map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"Inner": &types.AttributeValueMemberBOOL{Value: true},
+		"Date":  &types.AttributeValueMemberS{Value: "2020-01-01"},
+	}},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		args []interface{}
+		want bool
+	}{
+		{"ID = ?", []interface{}{42}, true},
+		{"ID = ?", []interface{}{"42"}, false},
+		{"ID <> ?", []interface{}{41}, true},
+		{"Count > ? AND Count < ?", []interface{}{10, 11}, true},
+		{"Count >= ?", []interface{}{10.5}, true},
+		{"Name < ?", []interface{}{"Goat"}, false},
+		{"Missing = ?", []interface{}{1}, false},
+		{"Missing <> ?", []interface{}{1}, true},
+		{"ID BETWEEN ? AND ?", []interface{}{40, 42}, true},
+		{"Name BETWEEN ? AND ?", []interface{}{"A", "F"}, false},
+		{"Name IN (?, ?)", []interface{}{"Gopher", "Rust"}, true},
+		{"NOT ID IN (?, ?)", []interface{}{1, 2}, true},
+		{"attribute_exists(Map.Inner) AND attribute_not_exists(Map.Other)", nil, true},
+		{"attribute_exists(List[1]) AND attribute_not_exists(List[2])", nil, true},
+		{"attribute_type(Tags, ?)", []interface{}{"SS"}, true},
+		{"attribute_type(List[0], ?)", []interface{}{"N"}, false},
+		{"begins_with(Name, ?)", []interface{}{"Go"}, true},
+		{"begins_with(Data, ?)", []interface{}{[]byte("he")}, true},
+		{"begins_with(ID, ?)", []interface{}{"4"}, false},
+		{"contains(Name, ?)", []interface{}{"ph"}, true},
+		{"contains(Tags, ?)", []interface{}{"blue"}, true},
+		{"contains(Tags, ?)", []interface{}{"red"}, false},
+		{"contains(List, ?)", []interface{}{1}, true},
+		{"size(Tags) = ? AND size(Name) > ?", []interface{}{2, 5}, true},
+		{"size(Missing) < ?", []interface{}{100}, false},
+		{"$.$ = ? OR $[$] = ?", []interface{}{"Map", "Date", "2020-01-01", "List", 0, "z"}, true},
+		{"'Map'.Inner = ?", []interface{}{true}, true},
+		{"(ID = ? OR ID = ?) AND NOT Name = ?", []interface{}{1, 42, "Rust"}, true},
+	}
+	for _, tc := range tests {
+		got, err := Match(testItem, tc.expr, tc.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestMatchCond(t *testing.T) {
+	c := cond.Name("Count").LE(11).And(cond.BeginsWith("Name", "G"), cond.Size("Tags").EQ(2))
+	got, err := Match(testItem, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Error("condition didn't match")
+	}
+}
+
+func TestMatchInput(t *testing.T) {
+	got, err := MatchInput(testItem, "#n = :v AND #c > :zero",
+		map[string]string{"#n": "Name", "#c": "Count"},
+		map[string]types.AttributeValue{
+			":v":    &types.AttributeValueMemberS{Value: "Gopher"},
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Error("condition didn't match")
+	}
+
+	if _, err := MatchInput(testItem, "#missing = :v", nil, nil); err == nil {
+		t.Error("expected error for undefined name, got nil")
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		args []interface{}
+	}{
+		{"ID = ", nil},
+		{"ID = ?", nil},
+		{"attribute_type(ID, ?)", []interface{}{"X"}},
+		{"ID BETWEEN ? AND ?", []interface{}{10, 1}},
+	}
+	for _, tc := range tests {
+		if _, err := Match(testItem, tc.expr, tc.args...); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.expr)
+		}
+	}
+}
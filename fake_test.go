@@ -0,0 +1,275 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/niltonkummer/dynamo/dynamodbiface"
+)
+
+// fakeClient stands in for DynamoDB in tests that don't need DynamoDB Local (see testDB).
+// It records the last request of each kind and returns canned results:
+// writes return old as the old attributes and fail with err,
+// GetItem returns item, and Query and Scan return pages, one per call.
+//
+// Table operations describe table, which is missing if nil, and record changes.
+// A fake made by newFakeStore instead keeps a fakeClient per table for table operations,
+// and stores items by their ID attribute, checking write conditions.
+type fakeClient struct {
+	dynamodbiface.DynamoDBAPI
+	err   error
+	old   map[string]types.AttributeValue
+	item  map[string]types.AttributeValue
+	pages [][]map[string]types.AttributeValue
+	calls int
+
+	put    *dynamodb.PutItemInput
+	update *dynamodb.UpdateItemInput
+	del    *dynamodb.DeleteItemInput
+	get    *dynamodb.GetItemInput
+	tx     *dynamodb.TransactWriteItemsInput
+
+	table *types.TableDescription
+	ttl   *types.TimeToLiveDescription
+	tags  []types.Tag
+
+	describes int
+	created   []*dynamodb.CreateTableInput
+	updated   []*dynamodb.UpdateTableInput
+	ttlUpdate []*dynamodb.UpdateTimeToLiveInput
+	tagged    []*dynamodb.TagResourceInput
+
+	// set by newFakeStore
+	tables map[string]*fakeClient
+	mu     sync.Mutex
+	items  map[string][]map[string]types.AttributeValue
+}
+
+// newFakeStore returns a fake that manages any number of tables and keeps their items in memory.
+func newFakeStore() *fakeClient {
+	return &fakeClient{
+		tables: make(map[string]*fakeClient),
+		items:  make(map[string][]map[string]types.AttributeValue),
+	}
+}
+
+// tableClient returns the fake that handles table operations on name.
+func (c *fakeClient) tableClient(name *string) *fakeClient {
+	if c.tables == nil {
+		return c
+	}
+	if c.tables[*name] == nil {
+		c.tables[*name] = &fakeClient{}
+	}
+	return c.tables[*name]
+}
+
+func (c *fakeClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	c = c.tableClient(params.TableName)
+	c.describes++
+	if c.table == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &dynamodb.DescribeTableOutput{Table: c.table}, nil
+}
+
+func (c *fakeClient) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	c = c.tableClient(params.TableName)
+	c.created = append(c.created, params)
+	c.table = &types.TableDescription{
+		TableName:            params.TableName,
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            params.KeySchema,
+		AttributeDefinitions: params.AttributeDefinitions,
+	}
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (c *fakeClient) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	c = c.tableClient(params.TableName)
+	c.updated = append(c.updated, params)
+	return &dynamodb.UpdateTableOutput{TableDescription: c.table}, nil
+}
+
+func (c *fakeClient) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	c = c.tableClient(params.TableName)
+	ttl := c.ttl
+	if ttl == nil {
+		ttl = &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: ttl}, nil
+}
+
+func (c *fakeClient) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	c = c.tableClient(params.TableName)
+	c.ttlUpdate = append(c.ttlUpdate, params)
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+// ListTagsOfResource returns one tag per page, to exercise pagination.
+func (c *fakeClient) ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	var start int
+	if params.NextToken != nil {
+		start = int((*params.NextToken)[0] - '0')
+	}
+	out := &dynamodb.ListTagsOfResourceOutput{}
+	if start < len(c.tags) {
+		out.Tags = c.tags[start : start+1]
+		if start+1 < len(c.tags) {
+			out.NextToken = aws.String(string(rune('0' + start + 1)))
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeClient) TagResource(ctx context.Context, params *dynamodb.TagResourceInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	c.tagged = append(c.tagged, params)
+	return &dynamodb.TagResourceOutput{}, nil
+}
+
+func (c *fakeClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.get = params
+	return &dynamodb.GetItemOutput{Item: c.item}, nil
+}
+
+func (c *fakeClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put = params
+	if c.items == nil {
+		return &dynamodb.PutItemOutput{Attributes: c.old}, c.err
+	}
+	i, err := c.check(*params.TableName, params.Item, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		c.items[*params.TableName][i] = params.Item
+	} else {
+		c.items[*params.TableName] = append(c.items[*params.TableName], params.Item)
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// UpdateItem only supports update expressions of the form "SET name = :value" for stored items.
+func (c *fakeClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.update = params
+	if c.items == nil {
+		return &dynamodb.UpdateItemOutput{Attributes: c.old}, c.err
+	}
+	i, err := c.check(*params.TableName, params.Key, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 {
+		return nil, errors.New("fake: update of missing item")
+	}
+	set := strings.Fields(strings.TrimPrefix(*params.UpdateExpression, "SET "))
+	c.items[*params.TableName][i][set[0]] = params.ExpressionAttributeValues[set[2]]
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (c *fakeClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.del = params
+	if c.items == nil {
+		return &dynamodb.DeleteItemOutput{Attributes: c.old}, c.err
+	}
+	i, err := c.check(*params.TableName, params.Key, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		items := c.items[*params.TableName]
+		c.items[*params.TableName] = append(items[:i:i], items[i+1:]...)
+	}
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (c *fakeClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.tx = params
+	return &dynamodb.TransactWriteItemsOutput{}, c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	items, lek := c.page()
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: lek}, nil
+}
+
+// Scan returns every stored item of the table at once, or the next page.
+func (c *fakeClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items != nil {
+		items := c.items[*params.TableName]
+		return &dynamodb.ScanOutput{Items: items, Count: int32(len(items))}, nil
+	}
+	items, lek := c.page()
+	return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: lek}, nil
+}
+
+func (c *fakeClient) page() ([]map[string]types.AttributeValue, map[string]types.AttributeValue) {
+	items := c.pages[c.calls]
+	c.calls++
+	var lek map[string]types.AttributeValue
+	if c.calls < len(c.pages) {
+		lek = map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "next"}}
+	}
+	return items, lek
+}
+
+var fakeCondTerm = regexp.MustCompile(`^attribute_not_exists\((#\w+)\)$|^(#\w+) (<|=) (:\w+)$`)
+
+// check returns the index of the stored item with the same ID as key, or -1 if there is none,
+// and fails if the item doesn't satisfy cond.
+// Only conditions joined by OR, of the form attribute_not_exists(#name), #name < :value and #name = :value, are supported.
+func (c *fakeClient) check(table string, key map[string]types.AttributeValue, cond *string, names map[string]string, values map[string]types.AttributeValue) (int, error) {
+	id := key["ID"].(*types.AttributeValueMemberS).Value
+	idx := -1
+	var item map[string]types.AttributeValue
+	for i, it := range c.items[table] {
+		if it["ID"].(*types.AttributeValueMemberS).Value == id {
+			idx, item = i, it
+		}
+	}
+	if cond == nil {
+		return idx, nil
+	}
+	expr := strings.TrimSuffix(strings.TrimPrefix(*cond, "("), ")")
+	for _, term := range strings.Split(expr, " OR ") {
+		m := fakeCondTerm.FindStringSubmatch(term)
+		switch {
+		case m == nil:
+			return idx, fmt.Errorf("fake: unsupported condition %q", *cond)
+		case m[1] != "":
+			if item == nil || item[names[m[1]]] == nil {
+				return idx, nil
+			}
+		case item != nil && item[names[m[2]]] != nil:
+			have, want := item[names[m[2]]], values[m[4]]
+			if m[3] == "=" && reflect.DeepEqual(have, want) {
+				return idx, nil
+			}
+			if m[3] == "<" {
+				a, _ := strconv.ParseInt(have.(*types.AttributeValueMemberN).Value, 10, 64)
+				b, _ := strconv.ParseInt(want.(*types.AttributeValueMemberN).Value, 10, 64)
+				if a < b {
+					return idx, nil
+				}
+			}
+		}
+	}
+	return idx, &types.ConditionalCheckFailedException{Message: aws.String("condition failed")}
+}
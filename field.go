@@ -0,0 +1,128 @@
+package dynamo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Field is the encoding of a single struct field, as given by its dynamo struct tag.
+// Marshalers generated by dynamo-codec use it for fields of types they don't
+// encode themselves, so that every field is encoded exactly like the reflection-based encoder does.
+type Field struct {
+	name   string
+	flags  encodeFlags
+	layout string
+	err    error
+
+	once  sync.Once
+	plain bool
+	time  *timeFormat
+}
+
+// NewField returns the encoding of the struct field named goName with the given struct tag,
+// such as `dynamo:"Created,unixtime"`.
+// Fields tagged with inline, compress, encrypt or offload aren't supported, and always fail to encode.
+func NewField(goName string, tag reflect.StructTag) *Field {
+	sf := reflect.StructField{Name: goName, Tag: tag}
+	name, flags := fieldInfo(sf)
+	f := &Field{name: name, flags: flags, layout: timeLayout(sf)}
+	if flags&(flagInline|flagCompress|flagEncrypt|flagOffload) != 0 {
+		f.err = fmt.Errorf("dynamo: field %s: inline, compress, encrypt and offload aren't supported outside of the reflection-based encoder", goName)
+	}
+	return f
+}
+
+// Name returns the attribute name of this field.
+func (f *Field) Name() string {
+	return f.name
+}
+
+// value returns the field pointed to by ptr, compiling its encoding the first time it's seen.
+func (f *Field) value(ptr interface{}) reflect.Value {
+	rv := reflect.ValueOf(ptr).Elem()
+	f.compile(rv.Type())
+	return rv
+}
+
+func (f *Field) compile(rt reflect.Type) {
+	f.once.Do(func() {
+		f.plain = isPlain(rt)
+		if isTimeType(rt) {
+			f.time = timeFormatFor(f.flags, f.layout)
+		}
+	})
+}
+
+// Marshal encodes the field pointed to by ptr.
+// It returns a nil AttributeValue if the field should be omitted from the item.
+func (f *Field) Marshal(ptr interface{}) (types.AttributeValue, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.marshal(f.value(ptr))
+}
+
+// marshal encodes the field value fv, which needn't be addressable.
+func (f *Field) marshal(fv reflect.Value) (types.AttributeValue, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.compile(fv.Type())
+	if f.flags&flagOmitEmpty != 0 && isZero(fv) {
+		return nil, nil
+	}
+	switch {
+	case f.time != nil:
+		return f.time.encodeField(fv, f.flags), nil
+	case f.plain:
+		return marshalReflect(fv, f.flags)
+	}
+	return marshal(fv.Interface(), f.flags)
+}
+
+// Unmarshal decodes av into the field pointed to by ptr with the default DecodeOptions.
+// Errors are a *FieldError or *DecodeError whose paths start with the field's attribute name.
+// Pass them to JoinDecodeErrors to return them from an item unmarshaler.
+func (f *Field) Unmarshal(av types.AttributeValue, ptr interface{}) error {
+	return f.Decode(av, ptr, nil)
+}
+
+// Decode is like Unmarshal, but decodes with the settings of d,
+// the Decoder passed to an ItemDecoder, such as its DecodeOptions.
+func (f *Field) Decode(av types.AttributeValue, ptr interface{}, d *Decoder) error {
+	if f.err != nil {
+		return f.err
+	}
+	fv := f.value(ptr)
+	dec := d.decoder()
+	dec = dec.withCrypter(dec.crypt.child(f.name))
+	var err error
+	switch {
+	case f.time != nil:
+		err = f.time.decodeField(av, fv)
+	case f.plain:
+		err = dec.unmarshalKind(av, fv)
+	default:
+		err = dec.unmarshalReflect(av, fv)
+	}
+	if err != nil {
+		return pathError(err, f.name, fv.Type(), av)
+	}
+	return nil
+}
+
+// JoinDecodeErrors returns the errors returned by Field.Unmarshal as a single *DecodeError,
+// like the one returned when unmarshaling an item fails, or nil if errs is empty.
+func JoinDecodeErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	de := new(DecodeError)
+	for _, err := range errs {
+		de.Errors = append(de.Errors, fieldErrors(err)...)
+	}
+	return de
+}
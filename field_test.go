@@ -0,0 +1,53 @@
+package dynamo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestField(t *testing.T) {
+	day := NewField("Day", `dynamo:"day,omitempty,time=Mon, 02 Jan 2006"`)
+	if day.Name() != "day" {
+		t.Error("bad name:", day.Name())
+	}
+	when := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	av, err := day.Marshal(&when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&types.AttributeValueMemberS{Value: "Thu, 02 Jan 2020"}); !reflect.DeepEqual(av, want) {
+		t.Errorf("bad marshal. want: %#v, got: %#v", want, av)
+	}
+	var zero time.Time
+	if av, err := day.Marshal(&zero); av != nil || err != nil {
+		t.Errorf("zero time not omitted: %v %v", av, err)
+	}
+	var got time.Time
+	if err := day.Unmarshal(av, &got); err != nil || !got.Equal(when) {
+		t.Errorf("bad unmarshal: %v %v", got, err)
+	}
+
+	count := NewField("Count", ``)
+	var n int
+	err = count.Unmarshal(&types.AttributeValueMemberS{Value: "x"}, &n)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "Count" || fe.Type != reflect.TypeOf(n) || fe.AttributeType != "S" {
+		t.Errorf("bad error: %#v", err)
+	}
+	joined := JoinDecodeErrors([]error{err, err})
+	if de, ok := joined.(*DecodeError); !ok || len(de.Errors) != 2 {
+		t.Errorf("bad joined error: %#v", joined)
+	}
+	if err := JoinDecodeErrors(nil); err != nil {
+		t.Error("unexpected error:", err)
+	}
+
+	secret := NewField("Secret", `dynamo:",encrypt"`)
+	if _, err := secret.Marshal(new(string)); err == nil {
+		t.Error("expected error for encrypted field")
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/google/go-cmp v0.5.6
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 go 1.12
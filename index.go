@@ -0,0 +1,344 @@
+package dynamo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// IndexDeclarer is implemented by item types that declare secondary indexes in code,
+// in addition to or instead of the index and localIndex struct tags.
+// DynamoIndexes is called on a zero value of the type.
+// Indexes are merged with those declared by struct tags of the same name,
+// and their non-empty fields take precedence.
+// Local indexes without a HashKey use the table's hash key.
+// Key types can be omitted for attributes that are also keys of the table
+// or of indexes declared with struct tags.
+type IndexDeclarer interface {
+	DynamoIndexes() []Index
+}
+
+// indexSet is the set of secondary indexes declared by a struct type.
+type indexSet struct {
+	hashKey  string
+	rangeKey string
+	indexes  map[string]*Index
+	// project are the fields tagged with project, by index name
+	project map[string][]string
+	err     error
+}
+
+// indexSets caches declared indexes by type.
+var indexSets sync.Map // reflect.Type → *indexSet
+
+// indexesOf returns the secondary indexes declared by rt, which must be a struct or pointer to a struct.
+//
+// Indexes are declared with struct tags on their key fields, such as:
+//
+//	index:"Name,hash,projection=include,read=5,write=5"
+//	localIndex:"Name,range,projection=keys_only"
+//
+// Projection and throughput options may be given on either key field of an index.
+// Fields tagged with project:"Name" are included in the projection of that index.
+func indexesOf(rt reflect.Type) *indexSet {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if set, ok := indexSets.Load(rt); ok {
+		return set.(*indexSet)
+	}
+	set := &indexSet{
+		indexes: make(map[string]*Index),
+		project: make(map[string][]string),
+	}
+	if rt.Kind() != reflect.Struct {
+		set.err = fmt.Errorf("dynamo: model must be a struct (got %s)", rt)
+	} else {
+		set.err = set.compile(rt)
+	}
+	if set.err == nil {
+		set.err = set.declare(rt)
+	}
+	if set.err == nil {
+		set.err = set.validate()
+	}
+	actual, _ := indexSets.LoadOrStore(rt, set)
+	return actual.(*indexSet)
+}
+
+func (set *indexSet) compile(rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, _ := fieldInfo(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := set.compile(ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		switch keyTypeFromTag(field.Tag.Get("dynamo")) {
+		case types.KeyTypeHash:
+			if set.hashKey == "" {
+				set.hashKey = name
+			}
+		case types.KeyTypeRange:
+			if set.rangeKey == "" {
+				set.rangeKey = name
+			}
+		}
+
+		for _, tagName := range []string{"index", "localIndex"} {
+			tags, _ := tagLookup(string(field.Tag), tagName)
+			for _, tag := range tags {
+				if err := set.addKey(name, tag, tagName == "localIndex"); err != nil {
+					return fmt.Errorf("dynamo: %s: field %s: %v", rt, field.Name, err)
+				}
+			}
+		}
+
+		if project, ok := tagLookup(string(field.Tag), "project"); ok {
+			for _, list := range project {
+				for _, index := range strings.Split(list, ",") {
+					set.project[index] = append(set.project[index], name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addKey adds the key attribute name to the index declared by tag.
+func (set *indexSet) addKey(name, tag string, local bool) error {
+	indexName, keyType, opts := parseIndexTag(tag)
+	if indexName == "" || keyType == "" {
+		return fmt.Errorf("bad index tag %q: want name and hash or range", tag)
+	}
+	idx := set.indexes[indexName]
+	if idx == nil {
+		idx = &Index{Name: indexName, Local: local}
+		set.indexes[indexName] = idx
+	}
+	if idx.Local != local {
+		return fmt.Errorf("index %s is declared as both global and local", indexName)
+	}
+	if keyType == types.KeyTypeHash {
+		idx.HashKey = name
+	} else {
+		idx.RangeKey = name
+	}
+	for _, opt := range opts {
+		key, value := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			key, value = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case "projection":
+			var proj IndexProjection
+			switch strings.ToLower(value) {
+			case "all":
+				proj = AllProjection
+			case "keys_only", "keys":
+				proj = KeysOnlyProjection
+			case "include":
+				proj = IncludeProjection
+			default:
+				return fmt.Errorf("index %s: unknown projection %q", indexName, value)
+			}
+			if idx.ProjectionType != "" && idx.ProjectionType != proj {
+				return fmt.Errorf("index %s: conflicting projections %s and %s", indexName, idx.ProjectionType, proj)
+			}
+			idx.ProjectionType = proj
+		case "read", "write":
+			units, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || units <= 0 {
+				return fmt.Errorf("index %s: bad %s capacity %q", indexName, key, value)
+			}
+			if key == "read" {
+				idx.Throughput.Read = units
+			} else {
+				idx.Throughput.Write = units
+			}
+		default:
+			return fmt.Errorf("index %s: unknown option %q", indexName, opt)
+		}
+	}
+	return nil
+}
+
+// parseIndexTag splits an index or localIndex tag into the index name, key type, and other options.
+func parseIndexTag(tag string) (name string, keyType types.KeyType, opts []string) {
+	split := strings.Split(tag, ",")
+	name = split[0]
+	for _, v := range split[1:] {
+		switch v {
+		case "hash", "partition":
+			keyType = types.KeyTypeHash
+		case "range", "sort":
+			keyType = types.KeyTypeRange
+		case "":
+		default:
+			opts = append(opts, v)
+		}
+	}
+	return
+}
+
+// declare merges the indexes returned by rt's DynamoIndexes method, if any.
+func (set *indexSet) declare(rt reflect.Type) error {
+	declarer, ok := reflect.New(rt).Interface().(IndexDeclarer)
+	if !ok {
+		return nil
+	}
+	for _, decl := range declarer.DynamoIndexes() {
+		if decl.Name == "" {
+			return fmt.Errorf("dynamo: %s: DynamoIndexes: index without a name", rt)
+		}
+		idx := set.indexes[decl.Name]
+		if idx == nil {
+			decl := decl
+			decl.ProjectionAttribs = append([]string(nil), decl.ProjectionAttribs...)
+			set.indexes[decl.Name] = &decl
+			continue
+		}
+		if decl.Local != idx.Local {
+			return fmt.Errorf("dynamo: %s: DynamoIndexes: index %s is declared as both global and local", rt, decl.Name)
+		}
+		if decl.HashKey != "" {
+			idx.HashKey, idx.HashKeyType = decl.HashKey, decl.HashKeyType
+		}
+		if decl.RangeKey != "" {
+			idx.RangeKey, idx.RangeKeyType = decl.RangeKey, decl.RangeKeyType
+		}
+		if decl.ProjectionType != "" {
+			idx.ProjectionType = decl.ProjectionType
+		}
+		idx.ProjectionAttribs = append(idx.ProjectionAttribs, decl.ProjectionAttribs...)
+		if decl.Throughput.Read != 0 {
+			idx.Throughput.Read = decl.Throughput.Read
+		}
+		if decl.Throughput.Write != 0 {
+			idx.Throughput.Write = decl.Throughput.Write
+		}
+	}
+	return nil
+}
+
+// validate applies project tags and defaults, and checks that every index is complete.
+func (set *indexSet) validate() error {
+	for name, attribs := range set.project {
+		idx := set.indexes[name]
+		if idx == nil {
+			return fmt.Errorf("dynamo: project tag refers to unknown index %s", name)
+		}
+		if idx.ProjectionType == "" {
+			idx.ProjectionType = IncludeProjection
+		}
+		idx.ProjectionAttribs = append(idx.ProjectionAttribs, attribs...)
+	}
+	for _, idx := range set.indexes {
+		if idx.Local && idx.HashKey == "" {
+			idx.HashKey = set.hashKey
+		}
+		idx.ProjectionAttribs = uniqueStrings(idx.ProjectionAttribs)
+		switch {
+		case idx.HashKey == "":
+			return fmt.Errorf("dynamo: index %s has no hash key", idx.Name)
+		case idx.Local && idx.RangeKey == "":
+			return fmt.Errorf("dynamo: local index %s has no range key", idx.Name)
+		case idx.Local && (idx.Throughput.Read != 0 || idx.Throughput.Write != 0):
+			return fmt.Errorf("dynamo: local index %s can't have its own throughput", idx.Name)
+		case idx.ProjectionType == IncludeProjection && len(idx.ProjectionAttribs) == 0:
+			return fmt.Errorf("dynamo: index %s: include projection without attributes", idx.Name)
+		case idx.ProjectionType != IncludeProjection && len(idx.ProjectionAttribs) > 0:
+			return fmt.Errorf("dynamo: index %s: projected attributes require the include projection", idx.Name)
+		}
+	}
+	return nil
+}
+
+// list returns the indexes sorted by name.
+func (set *indexSet) list() []Index {
+	list := make([]Index, 0, len(set.indexes))
+	for _, idx := range set.indexes {
+		list = append(list, *idx)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// checkQuery returns an error if a query of the index name
+// with the given hash and range keys can't succeed.
+func (set *indexSet) checkQuery(name, hashKey, rangeKey string, consistent bool) error {
+	if set.err != nil {
+		return set.err
+	}
+	idx := set.indexes[name]
+	switch {
+	case idx == nil:
+		return fmt.Errorf("dynamo: no such index: %s", name)
+	case hashKey != idx.HashKey:
+		return fmt.Errorf("dynamo: index %s has hash key %s, not %s", name, idx.HashKey, hashKey)
+	case rangeKey != "" && rangeKey != idx.RangeKey:
+		if idx.RangeKey == "" {
+			return fmt.Errorf("dynamo: index %s has no range key, but range key %s was given", name, rangeKey)
+		}
+		return fmt.Errorf("dynamo: index %s has range key %s, not %s", name, idx.RangeKey, rangeKey)
+	case consistent && !idx.Local:
+		return fmt.Errorf("dynamo: global index %s doesn't support consistent reads", name)
+	}
+	return nil
+}
+
+func uniqueStrings(strs []string) []string {
+	if len(strs) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(strs))
+	out := make([]string, 0, len(strs))
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// WithModel returns a copy of this table handle that checks queries against the
+// secondary indexes declared by model, a struct or pointer to a struct,
+// with index and localIndex struct tags and its DynamoIndexes method, as in CreateTable.
+// Querying an index that model doesn't declare, with the wrong key names,
+// or with consistent reads on a global index fails without calling DynamoDB.
+// Values set by Update for model's fields tagged with encrypt or offload are encrypted or offloaded,
+// as with Update.Model, and deletes clean up the blobs of its offloaded fields.
+func (table Table) WithModel(model interface{}) Table {
+	table.indexes = nil
+	table.model = nil
+	if rt := reflect.TypeOf(model); rt != nil {
+		table.indexes = indexesOf(rt)
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt.Kind() == reflect.Struct {
+			table.model = rt
+		}
+	}
+	return table
+}
@@ -0,0 +1,153 @@
+package dynamo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type indexedOrder struct {
+	UserID  string `dynamo:",hash" index:"Status-index,range"`
+	OrderID string `dynamo:",range"`
+	Status  string `index:"Status-index,hash,projection=include,read=5,write=3"`
+	Total   int    `localIndex:"Total-index,range,projection=keys_only" project:"Status-index"`
+	Email   string `project:"Status-index"`
+	SKU     string
+}
+
+func (indexedOrder) DynamoIndexes() []Index {
+	return []Index{
+		{Name: "SKU-index", HashKey: "SKU", HashKeyType: StringType, ProjectionType: AllProjection},
+		{Name: "Status-index", Throughput: Throughput{Write: 4}},
+	}
+}
+
+func TestDeclaredIndexes(t *testing.T) {
+	set := indexesOf(reflect.TypeOf(&indexedOrder{}))
+	if set.err != nil {
+		t.Fatal(set.err)
+	}
+	want := []Index{
+		{
+			Name:           "SKU-index",
+			HashKey:        "SKU",
+			HashKeyType:    StringType,
+			ProjectionType: AllProjection,
+		},
+		{
+			Name:              "Status-index",
+			HashKey:           "Status",
+			RangeKey:          "UserID",
+			ProjectionType:    IncludeProjection,
+			ProjectionAttribs: []string{"Total", "Email"},
+			Throughput:        Throughput{Read: 5, Write: 4},
+		},
+		{
+			Name:           "Total-index",
+			Local:          true,
+			HashKey:        "UserID",
+			RangeKey:       "Total",
+			ProjectionType: KeysOnlyProjection,
+		},
+	}
+	if got := set.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("bad indexes.\nwant: %#v\ngot:  %#v", want, got)
+	}
+}
+
+func TestCreateTableDeclaredIndexes(t *testing.T) {
+	input := testDB.CreateTable("Orders", indexedOrder{}).input()
+	sort.Slice(input.GlobalSecondaryIndexes, func(i, j int) bool {
+		return *input.GlobalSecondaryIndexes[i].IndexName < *input.GlobalSecondaryIndexes[j].IndexName
+	})
+	gsis := input.GlobalSecondaryIndexes
+	if len(gsis) != 2 {
+		t.Fatalf("want 2 global indexes, got %d", len(gsis))
+	}
+	if *gsis[0].IndexName != "SKU-index" || gsis[0].Projection.ProjectionType != types.ProjectionTypeAll {
+		t.Errorf("bad SKU-index: %#v", gsis[0])
+	}
+	status := gsis[1]
+	if !reflect.DeepEqual(status.Projection, &types.Projection{
+		ProjectionType:   types.ProjectionTypeInclude,
+		NonKeyAttributes: []string{"Total", "Email"},
+	}) {
+		t.Errorf("bad Status-index projection: %#v", status.Projection)
+	}
+	if !reflect.DeepEqual(status.ProvisionedThroughput, &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(5),
+		WriteCapacityUnits: aws.Int64(4),
+	}) {
+		t.Errorf("bad Status-index throughput: %#v", status.ProvisionedThroughput)
+	}
+	if len(input.LocalSecondaryIndexes) != 1 || input.LocalSecondaryIndexes[0].Projection.ProjectionType != types.ProjectionTypeKeysOnly {
+		t.Errorf("bad local indexes: %#v", input.LocalSecondaryIndexes)
+	}
+	var sku bool
+	for _, attr := range input.AttributeDefinitions {
+		if *attr.AttributeName == "SKU" && attr.AttributeType == types.ScalarAttributeTypeS {
+			sku = true
+		}
+	}
+	if !sku {
+		t.Errorf("SKU attribute not defined: %#v", input.AttributeDefinitions)
+	}
+}
+
+func TestDeclaredIndexErrors(t *testing.T) {
+	type badProjection struct {
+		ID string `dynamo:",hash" index:"A,hash,projection=some"`
+	}
+	type emptyInclude struct {
+		ID string `dynamo:",hash" index:"A,hash,projection=include"`
+	}
+	type localThroughput struct {
+		ID  string `dynamo:",hash"`
+		Seq int    `localIndex:"A,range,read=1"`
+	}
+	type unknownProject struct {
+		ID   string `dynamo:",hash"`
+		Name string `project:"B"`
+	}
+	for _, model := range []interface{}{badProjection{}, emptyInclude{}, localThroughput{}, unknownProject{}} {
+		if err := indexesOf(reflect.TypeOf(model)).err; err == nil {
+			t.Errorf("%T: expected error, got nil", model)
+		}
+		if err := testDB.CreateTable("Bad", model).err; err == nil {
+			t.Errorf("%T: expected CreateTable error, got nil", model)
+		}
+	}
+}
+
+func TestQueryIndexValidation(t *testing.T) {
+	table := testDB.Table("Orders").WithModel(indexedOrder{})
+	tests := []struct {
+		q   *Query
+		err string
+	}{
+		{table.Get("Status", "paid").Index("Status-index").Range("UserID", Equal, "u1"), ""},
+		{table.Get("UserID", "u1").Range("Total", Greater, 10).Index("Total-index").Consistent(true), ""},
+		{table.Get("Status", "paid").Index("Nope-index"), "no such index"},
+		{table.Get("UserID", "u1").Index("Status-index"), "hash key Status, not UserID"},
+		{table.Get("Status", "paid").Range("OrderID", Equal, "o1").Index("Status-index"), "range key UserID, not OrderID"},
+		{table.Get("SKU", "x").Index("SKU-index").Range("Status", Equal, "paid"), "no range key"},
+		{table.Get("Status", "paid").Index("Status-index").Consistent(true), "consistent reads"},
+	}
+	for i, test := range tests {
+		switch {
+		case test.err == "" && test.q.err != nil:
+			t.Errorf("%d: unexpected error: %v", i, test.q.err)
+		case test.err != "" && (test.q.err == nil || !strings.Contains(test.q.err.Error(), test.err)):
+			t.Errorf("%d: want error containing %q, got %v", i, test.err, test.q.err)
+		}
+	}
+
+	// without a model, anything goes
+	if q := testDB.Table("Orders").Get("Foo", "x").Index("Nope"); q.err != nil {
+		t.Errorf("unexpected error without model: %v", q.err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package awscfg connects the command-line tools to DynamoDB,
+// using the default AWS configuration with an optional region and endpoint.
+package awscfg
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/niltonkummer/dynamo"
+)
+
+// Open returns a DB using the default AWS configuration (environment, shared config and credentials files).
+// If region isn't empty, it overrides the configured region.
+// If endpoint isn't empty, all requests are sent to it, such as http://localhost:8000 for DynamoDB Local.
+func Open(ctx context.Context, region, endpoint string) (*dynamo.DB, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if endpoint != "" {
+		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: endpoint, SigningRegion: region}, nil
+		})
+		opts = append(opts, config.WithEndpointResolverWithOptions(resolver))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dynamo.New(cfg), nil
+}
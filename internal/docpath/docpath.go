@@ -0,0 +1,610 @@
+// Package docpath resolves the document paths and values of parsed expressions
+// and reads and writes them in items, for evaluating expressions in memory.
+package docpath
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/niltonkummer/dynamo"
+	"github.com/niltonkummer/dynamo/internal/exprs"
+)
+
+// Step is one element of a resolved document path: either a map key or a list index.
+type Step struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// Path is a resolved document path.
+type Path []Step
+
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, step := range p {
+		switch {
+		case step.IsIndex:
+			sb.WriteString("[" + strconv.Itoa(step.Index) + "]")
+		case i > 0:
+			sb.WriteString("." + step.Name)
+		default:
+			sb.WriteString(step.Name)
+		}
+	}
+	return sb.String()
+}
+
+// Binding resolves the names and values of a parsed expression.
+// Names and values can be given as dynamo-style arguments for $ and ? placeholders,
+// or as expression attribute names (#name) and values (:value).
+type Binding struct {
+	tree   *exprs.Tree
+	args   map[int]interface{} // by token index; string or int for $, types.AttributeValue for ?
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+// Bind returns a binding for tree, with args for its placeholders in order,
+// and names and values for its references.
+func Bind(tree *exprs.Tree, args []interface{}, names map[string]string, values map[string]types.AttributeValue) (*Binding, error) {
+	if err := tree.CheckArgs(len(args)); err != nil {
+		return nil, err
+	}
+	b := &Binding{
+		tree:   tree,
+		args:   make(map[int]interface{}, len(args)),
+		names:  names,
+		values: values,
+	}
+	var idx int
+	for i, tok := range tree.Tokens {
+		if !tok.IsPlaceholder() {
+			continue
+		}
+		arg := args[idx]
+		idx++
+		switch tok.Type {
+		case exprs.TokenNamePlaceholder:
+			name, err := nameArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			b.args[i] = name
+		case exprs.TokenValuePlaceholder:
+			av, err := marshal(arg)
+			if err != nil {
+				return nil, err
+			}
+			b.args[i] = av
+		default:
+			return nil, fmt.Errorf("dynamo: unsupported placeholder %s in %q", tok, tree.Input)
+		}
+	}
+	return b, nil
+}
+
+func nameArg(arg interface{}) (interface{}, error) {
+	switch x := arg.(type) {
+	case encoding.TextMarshaler:
+		txt, err := x.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(txt), nil
+	case string:
+		return x, nil
+	case int:
+		return x, nil
+	case int64:
+		return int(x), nil
+	}
+	return nil, fmt.Errorf("dynamo: type of argument for $ must be string, int, or int64 (got %T)", arg)
+}
+
+// marshal encodes a value argument the same way placeholders are substituted,
+// allowing empty and null values.
+func marshal(v interface{}) (types.AttributeValue, error) {
+	// list elements are encoded with empty and null values allowed
+	av, err := dynamo.Marshal([]interface{}{v})
+	if err != nil {
+		return nil, err
+	}
+	list, ok := av.(*types.AttributeValueMemberL)
+	if !ok || len(list.Value) != 1 {
+		return nil, fmt.Errorf("dynamo: invalid value argument: %v", v)
+	}
+	return list.Value[0], nil
+}
+
+// Path resolves p.
+func (b *Binding) Path(p *exprs.Path) (Path, error) {
+	path := make(Path, 0, len(p.Elems))
+	for _, elem := range p.Elems {
+		var step Step
+		switch elem.Type {
+		case exprs.TokenIdent, exprs.TokenQuotedName:
+			step.Name = elem.Name
+		case exprs.TokenNameRef:
+			name, ok := b.names[elem.Name]
+			if !ok {
+				return nil, fmt.Errorf("dynamo: expression attribute name %s is not defined", elem.Name)
+			}
+			step.Name = name
+		case exprs.TokenNumber:
+			i, err := strconv.Atoi(elem.Name)
+			if err != nil {
+				return nil, err
+			}
+			step.Index = i
+		case exprs.TokenNamePlaceholder:
+			switch x := b.args[elem.Token].(type) {
+			case string:
+				if elem.Index {
+					return nil, fmt.Errorf("dynamo: list index argument must be an int (got %q)", x)
+				}
+				step.Name = x
+			case int:
+				if !elem.Index {
+					return nil, fmt.Errorf("dynamo: name argument must be a string (got %d)", x)
+				}
+				step.Index = x
+			}
+		default:
+			return nil, fmt.Errorf("dynamo: unsupported path element %q", elem.Name)
+		}
+		step.IsIndex = elem.Index
+		if step.IsIndex && step.Index < 0 {
+			return nil, fmt.Errorf("dynamo: invalid list index %d", step.Index)
+		}
+		path = append(path, step)
+	}
+	return path, nil
+}
+
+// Value resolves v.
+func (b *Binding) Value(v *exprs.Value) (types.AttributeValue, error) {
+	if v.Ref == "" {
+		return b.args[v.Token].(types.AttributeValue), nil
+	}
+	av, ok := b.values[v.Ref]
+	if !ok {
+		return nil, fmt.Errorf("dynamo: expression attribute value %s is not defined", v.Ref)
+	}
+	return av, nil
+}
+
+// Get returns the value at path in item, or false if it doesn't exist.
+func Get(item map[string]types.AttributeValue, path Path) (types.AttributeValue, bool) {
+	if len(path) == 0 || path[0].IsIndex {
+		return nil, false
+	}
+	av, ok := item[path[0].Name]
+	if !ok {
+		return nil, false
+	}
+	for _, step := range path[1:] {
+		av, ok = child(av, step)
+		if !ok {
+			return nil, false
+		}
+	}
+	return av, true
+}
+
+func child(av types.AttributeValue, step Step) (types.AttributeValue, bool) {
+	switch x := av.(type) {
+	case *types.AttributeValueMemberM:
+		if step.IsIndex {
+			return nil, false
+		}
+		v, ok := x.Value[step.Name]
+		return v, ok
+	case *types.AttributeValueMemberL:
+		if !step.IsIndex || step.Index >= len(x.Value) {
+			return nil, false
+		}
+		return x.Value[step.Index], true
+	}
+	return nil, false
+}
+
+// Set sets the value at path in item.
+// The parent of path must already exist. Setting a list index past the end of a list appends to it.
+// Set modifies maps and lists in place, so item should be a copy made with CloneItem.
+func Set(item map[string]types.AttributeValue, path Path, av types.AttributeValue) error {
+	if len(path) == 0 || path[0].IsIndex {
+		return fmt.Errorf("dynamo: invalid document path %s", path)
+	}
+	if len(path) == 1 {
+		item[path[0].Name] = av
+		return nil
+	}
+	parent, ok := Get(item, path[:len(path)-1])
+	if !ok {
+		return fmt.Errorf("dynamo: document path %s is invalid for update: %s does not exist", path, path[:len(path)-1])
+	}
+	last := path[len(path)-1]
+	switch x := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if !last.IsIndex {
+			x.Value[last.Name] = av
+			return nil
+		}
+	case *types.AttributeValueMemberL:
+		if last.IsIndex {
+			if last.Index < len(x.Value) {
+				x.Value[last.Index] = av
+			} else {
+				x.Value = append(x.Value, av)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("dynamo: document path %s is invalid for update: %s is a %s", path, path[:len(path)-1], TypeName(parent))
+}
+
+// Remove deletes the value at path in item, if it exists.
+// Removing a list element shifts the elements after it.
+// Remove modifies maps and lists in place, so item should be a copy made with CloneItem.
+func Remove(item map[string]types.AttributeValue, path Path) {
+	if len(path) == 0 || path[0].IsIndex {
+		return
+	}
+	if len(path) == 1 {
+		delete(item, path[0].Name)
+		return
+	}
+	parent, ok := Get(item, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	last := path[len(path)-1]
+	switch x := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if !last.IsIndex {
+			delete(x.Value, last.Name)
+		}
+	case *types.AttributeValueMemberL:
+		if last.IsIndex && last.Index < len(x.Value) {
+			x.Value = append(x.Value[:last.Index:last.Index], x.Value[last.Index+1:]...)
+		}
+	}
+}
+
+// CloneItem returns a deep copy of item.
+func CloneItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	clone := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		clone[k] = Clone(v)
+	}
+	return clone
+}
+
+// Clone returns a deep copy of av.
+func Clone(av types.AttributeValue) types.AttributeValue {
+	switch x := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: x.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: x.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, x.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: x.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: x.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, x.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, x.Value...)}
+	case *types.AttributeValueMemberBS:
+		bs := make([][]byte, len(x.Value))
+		for i, b := range x.Value {
+			bs[i] = append([]byte{}, b...)
+		}
+		return &types.AttributeValueMemberBS{Value: bs}
+	case *types.AttributeValueMemberL:
+		list := make([]types.AttributeValue, len(x.Value))
+		for i, v := range x.Value {
+			list[i] = Clone(v)
+		}
+		return &types.AttributeValueMemberL{Value: list}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: CloneItem(x.Value)}
+	}
+	return av
+}
+
+// TypeName returns the DynamoDB type of av, such as S or NS.
+func TypeName(av types.AttributeValue) string {
+	switch av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return fmt.Sprintf("%T", av)
+}
+
+// Equal returns true if a and b are the same type and have the same value.
+// Numbers are compared numerically and sets are compared regardless of order.
+func Equal(a, b types.AttributeValue) bool {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		y, ok := b.(*types.AttributeValueMemberS)
+		return ok && x.Value == y.Value
+	case *types.AttributeValueMemberN:
+		y, ok := b.(*types.AttributeValueMemberN)
+		return ok && numEqual(x.Value, y.Value)
+	case *types.AttributeValueMemberB:
+		y, ok := b.(*types.AttributeValueMemberB)
+		return ok && bytes.Equal(x.Value, y.Value)
+	case *types.AttributeValueMemberBOOL:
+		y, ok := b.(*types.AttributeValueMemberBOOL)
+		return ok && x.Value == y.Value
+	case *types.AttributeValueMemberNULL:
+		_, ok := b.(*types.AttributeValueMemberNULL)
+		return ok
+	case *types.AttributeValueMemberSS:
+		y, ok := b.(*types.AttributeValueMemberSS)
+		return ok && len(x.Value) == len(y.Value) && containsAll(a, b)
+	case *types.AttributeValueMemberNS:
+		y, ok := b.(*types.AttributeValueMemberNS)
+		return ok && len(x.Value) == len(y.Value) && containsAll(a, b)
+	case *types.AttributeValueMemberBS:
+		y, ok := b.(*types.AttributeValueMemberBS)
+		return ok && len(x.Value) == len(y.Value) && containsAll(a, b)
+	case *types.AttributeValueMemberL:
+		y, ok := b.(*types.AttributeValueMemberL)
+		if !ok || len(x.Value) != len(y.Value) {
+			return false
+		}
+		for i := range x.Value {
+			if !Equal(x.Value[i], y.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		y, ok := b.(*types.AttributeValueMemberM)
+		if !ok || len(x.Value) != len(y.Value) {
+			return false
+		}
+		for k, v := range x.Value {
+			other, ok := y.Value[k]
+			if !ok || !Equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// containsAll returns true if every element of set b is in set a.
+func containsAll(a, b types.AttributeValue) bool {
+	for _, elem := range Elems(b) {
+		if !Contains(a, elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares a and b, returning -1, 0, or 1.
+// Only numbers, strings, and binary values of the same type can be compared;
+// for anything else ok is false.
+func Compare(a, b types.AttributeValue) (cmp int, ok bool) {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(x.Value, y.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if y, ok := b.(*types.AttributeValueMemberN); ok {
+			xn, xok := parseNum(x.Value)
+			yn, yok := parseNum(y.Value)
+			if xok && yok {
+				return xn.Cmp(yn), true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(x.Value, y.Value), true
+		}
+	}
+	return 0, false
+}
+
+// Elems returns the elements of a set as individual scalar values.
+// It returns nil if av is not a set.
+func Elems(av types.AttributeValue) []types.AttributeValue {
+	var elems []types.AttributeValue
+	switch x := av.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range x.Value {
+			elems = append(elems, &types.AttributeValueMemberS{Value: s})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, n := range x.Value {
+			elems = append(elems, &types.AttributeValueMemberN{Value: n})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range x.Value {
+			elems = append(elems, &types.AttributeValueMemberB{Value: b})
+		}
+	}
+	return elems
+}
+
+// Contains returns true if set or list av contains elem.
+func Contains(av, elem types.AttributeValue) bool {
+	if list, ok := av.(*types.AttributeValueMemberL); ok {
+		for _, v := range list.Value {
+			if Equal(v, elem) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range Elems(av) {
+		if Equal(v, elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// Size returns the size of av as defined by the size function:
+// the length of strings, binary values, sets, lists, and maps.
+// ok is false for other types.
+func Size(av types.AttributeValue) (size int, ok bool) {
+	switch x := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(x.Value), true
+	case *types.AttributeValueMemberB:
+		return len(x.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(x.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(x.Value), true
+	case *types.AttributeValueMemberBS:
+		return len(x.Value), true
+	case *types.AttributeValueMemberL:
+		return len(x.Value), true
+	case *types.AttributeValueMemberM:
+		return len(x.Value), true
+	}
+	return 0, false
+}
+
+// AddNumbers returns a + b, or a - b if subtract is true.
+func AddNumbers(a, b string, subtract bool) (string, error) {
+	x, ok := parseNum(a)
+	if !ok {
+		return "", fmt.Errorf("dynamo: invalid number: %q", a)
+	}
+	y, ok := parseNum(b)
+	if !ok {
+		return "", fmt.Errorf("dynamo: invalid number: %q", b)
+	}
+	if subtract {
+		y.Neg(y)
+	}
+	return formatNum(x.Add(x, y)), nil
+}
+
+func parseNum(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(s)
+}
+
+func numEqual(a, b string) bool {
+	x, xok := parseNum(a)
+	y, yok := parseNum(b)
+	if !xok || !yok {
+		return a == b
+	}
+	return x.Cmp(y) == 0
+}
+
+// formatNum formats r as a decimal number.
+// r must be the sum of decimal numbers, so its denominator is a power of 10.
+func formatNum(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	ten := big.NewInt(10)
+	scale := big.NewInt(1)
+	mod := new(big.Int)
+	for digits := 1; digits <= 38; digits++ {
+		scale.Mul(scale, ten)
+		if mod.Mod(scale, r.Denom()).Sign() == 0 {
+			return r.FloatString(digits)
+		}
+	}
+	return r.FloatString(38)
+}
+
+// Union returns a set containing the elements of both a and b, which must be the same type of set.
+func Union(a, b types.AttributeValue) (types.AttributeValue, error) {
+	if TypeName(a) != TypeName(b) {
+		return nil, fmt.Errorf("dynamo: cannot add %s to %s", TypeName(b), TypeName(a))
+	}
+	out := Clone(a)
+	for _, elem := range Elems(b) {
+		if !Contains(out, elem) {
+			out = appendElem(out, elem)
+		}
+	}
+	return out, nil
+}
+
+// Difference returns a set with the elements of b removed from a, which must be the same type of set.
+// ok is false if the result is empty.
+func Difference(a, b types.AttributeValue) (types.AttributeValue, bool, error) {
+	if TypeName(a) != TypeName(b) {
+		return nil, false, fmt.Errorf("dynamo: cannot delete %s from %s", TypeName(b), TypeName(a))
+	}
+	out := emptySet(a)
+	for _, elem := range Elems(a) {
+		if !Contains(b, elem) {
+			out = appendElem(out, elem)
+		}
+	}
+	size, _ := Size(out)
+	return out, size > 0, nil
+}
+
+func emptySet(av types.AttributeValue) types.AttributeValue {
+	switch av.(type) {
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{}
+	case *types.AttributeValueMemberBS:
+		return &types.AttributeValueMemberBS{}
+	}
+	return &types.AttributeValueMemberSS{}
+}
+
+func appendElem(set, elem types.AttributeValue) types.AttributeValue {
+	switch x := set.(type) {
+	case *types.AttributeValueMemberSS:
+		x.Value = append(x.Value, elem.(*types.AttributeValueMemberS).Value)
+	case *types.AttributeValueMemberNS:
+		x.Value = append(x.Value, elem.(*types.AttributeValueMemberN).Value)
+	case *types.AttributeValueMemberBS:
+		x.Value = append(x.Value, elem.(*types.AttributeValueMemberB).Value)
+	}
+	return set
+}
+
+// IsSet returns true if av is a string, number, or binary set.
+func IsSet(av types.AttributeValue) bool {
+	switch av.(type) {
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package docpath
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestAddNumbers(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		subtract bool
+		want     string
+	}{
+		{"1", "2", false, "3"},
+		{"1.5", "0.25", false, "1.75"},
+		{"1.5", "0.5", false, "2"},
+		{"1", "1.1", true, "-0.1"},
+		{"1e3", "1", false, "1001"},
+	}
+	for _, tc := range tests {
+		got, err := AddNumbers(tc.a, tc.b, tc.subtract)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("AddNumbers(%s, %s, %v): want %s, got %s", tc.a, tc.b, tc.subtract, tc.want, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !Equal(&types.AttributeValueMemberN{Value: "1.0"}, &types.AttributeValueMemberN{Value: "1"}) {
+		t.Error("numbers should be compared numerically")
+	}
+	if !Equal(&types.AttributeValueMemberSS{Value: []string{"a", "b"}}, &types.AttributeValueMemberSS{Value: []string{"b", "a"}}) {
+		t.Error("sets should be compared regardless of order")
+	}
+	if Equal(&types.AttributeValueMemberS{Value: "1"}, &types.AttributeValueMemberN{Value: "1"}) {
+		t.Error("different types should not be equal")
+	}
+}
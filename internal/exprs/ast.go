@@ -0,0 +1,325 @@
+package exprs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of expression to parse.
+type Kind int
+
+// Kinds of expressions.
+const (
+	// Condition is a condition or filter expression.
+	Condition Kind = iota
+	// Projection is a comma-separated list of paths, as used in projection expressions and REMOVE actions.
+	Projection
+	// Update is a complete update expression.
+	Update
+	// SetActions is a comma-separated list of actions for the SET clause of an update expression.
+	SetActions
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Condition:
+		return "condition"
+	case Projection:
+		return "projection"
+	case Update:
+		return "update"
+	case SetActions:
+		return "set"
+	}
+	return "unknown"
+}
+
+// Tree is a parsed expression.
+type Tree struct {
+	Input string
+	Kind  Kind
+
+	// Cond is the root of a Condition expression.
+	Cond CondNode
+	// Paths are the paths of a Projection expression.
+	Projection []*Path
+	// Update is the parsed Update or SetActions expression.
+	Update *UpdateExpr
+
+	// Tokens are the scanned tokens of Input, terminated by TokenEOF.
+	Tokens []Token
+
+	err error
+}
+
+// NumArgs returns the number of arguments needed to fill in this expression's placeholders.
+func (t *Tree) NumArgs() int {
+	var n int
+	for _, tok := range t.Tokens {
+		if tok.IsPlaceholder() {
+			n++
+		}
+	}
+	return n
+}
+
+// CheckArgs returns an error if the number of arguments given does not match the number of placeholders.
+func (t *Tree) CheckArgs(given int) error {
+	var n int
+	for _, tok := range t.Tokens {
+		if !tok.IsPlaceholder() {
+			continue
+		}
+		if n == given {
+			return &SyntaxError{Input: t.Input, Pos: tok.Pos, Msg: "missing argument for " + tok.String()}
+		}
+		n++
+	}
+	if given > n {
+		return &SyntaxError{Input: t.Input, Pos: len(t.Input), Msg: "too many arguments: expected " + strconv.Itoa(n) + ", got " + strconv.Itoa(given)}
+	}
+	return nil
+}
+
+// Paths returns every document path referenced by this expression, in order of appearance.
+func (t *Tree) Paths() []*Path {
+	var paths []*Path
+	add := func(p *Path) {
+		paths = append(paths, p)
+	}
+	switch t.Kind {
+	case Condition:
+		walkCond(t.Cond, add)
+	case Projection:
+		paths = append(paths, t.Projection...)
+	case Update, SetActions:
+		for _, set := range t.Update.Set {
+			add(set.Path)
+			walkOperand(set.Value, add)
+		}
+		paths = append(paths, t.Update.Remove...)
+		for _, act := range t.Update.Add {
+			add(act.Path)
+			walkOperand(act.Value, add)
+		}
+		for _, act := range t.Update.Delete {
+			add(act.Path)
+			walkOperand(act.Value, add)
+		}
+	}
+	return paths
+}
+
+func walkCond(node CondNode, fn func(*Path)) {
+	switch x := node.(type) {
+	case *Logical:
+		walkCond(x.Left, fn)
+		walkCond(x.Right, fn)
+	case *Not:
+		walkCond(x.Cond, fn)
+	case *Comparison:
+		walkOperand(x.Left, fn)
+		walkOperand(x.Right, fn)
+	case *Between:
+		walkOperand(x.Operand, fn)
+		walkOperand(x.Lower, fn)
+		walkOperand(x.Upper, fn)
+	case *In:
+		walkOperand(x.Operand, fn)
+		for _, op := range x.List {
+			walkOperand(op, fn)
+		}
+	case *Func:
+		for _, op := range x.Args {
+			walkOperand(op, fn)
+		}
+	}
+}
+
+func walkOperand(op Operand, fn func(*Path)) {
+	switch x := op.(type) {
+	case *Path:
+		fn(x)
+	case *Func:
+		for _, arg := range x.Args {
+			walkOperand(arg, fn)
+		}
+	case *Arithmetic:
+		walkOperand(x.Left, fn)
+		walkOperand(x.Right, fn)
+	}
+}
+
+// Node is an element of the syntax tree.
+type Node interface {
+	// Position returns the byte offset of this node in the input.
+	Position() int
+}
+
+// CondNode is a node that evaluates to true or false.
+type CondNode interface {
+	Node
+	condNode()
+}
+
+// Operand is a node that evaluates to a value.
+type Operand interface {
+	Node
+	operandNode()
+}
+
+// Logical is an AND or OR of two conditions.
+type Logical struct {
+	Pos   int
+	Op    string // "AND" or "OR"
+	Left  CondNode
+	Right CondNode
+}
+
+// Not negates a condition.
+type Not struct {
+	Pos  int
+	Cond CondNode
+}
+
+// Comparison compares two operands with one of =, <>, <, <=, >, or >=.
+type Comparison struct {
+	Pos   int
+	Op    string
+	Left  Operand
+	Right Operand
+}
+
+// Between is a BETWEEN condition.
+type Between struct {
+	Pos     int
+	Operand Operand
+	Lower   Operand
+	Upper   Operand
+}
+
+// In is an IN condition.
+type In struct {
+	Pos     int
+	Operand Operand
+	List    []Operand
+}
+
+// Func is a function call.
+// Condition functions (such as begins_with) are conditions and
+// value functions (such as size and if_not_exists) are operands.
+type Func struct {
+	Pos  int
+	Name string
+	Args []Operand
+}
+
+// Arithmetic is an addition or subtraction of two operands, as used in SET actions.
+type Arithmetic struct {
+	Pos   int
+	Op    string // "+" or "-"
+	Left  Operand
+	Right Operand
+}
+
+// Value is a value operand.
+type Value struct {
+	Pos int
+	// Token is the index of this value's token in Tree.Tokens.
+	// Its type is either TokenValuePlaceholder or TokenValueRef.
+	Token int
+	// Ref is the expression attribute value reference (such as :v) or empty for placeholders.
+	Ref string
+}
+
+// Path is a document path, such as Foo.Bar[2].
+type Path struct {
+	Pos   int
+	Elems []PathElem
+}
+
+// PathElem is one part of a document path.
+type PathElem struct {
+	// Token is the index of this element's token in Tree.Tokens.
+	Token int
+	// Type is the type of this element's token.
+	// Names can be a TokenIdent, TokenNameRef, TokenQuotedName, TokenNamePlaceholder, or TokenMagicLiteral.
+	// Indexes can be a TokenNumber or TokenNamePlaceholder.
+	Type TokenType
+	// Index is true if this element is a list index.
+	Index bool
+	// Name is the text of this element: the name for identifiers and quoted names,
+	// the reference for name references, and the number for list indexes.
+	Name string
+}
+
+// String returns this path as it would appear in an expression,
+// with placeholders given as $ and 🝕.
+func (p *Path) String() string {
+	var sb strings.Builder
+	for i, elem := range p.Elems {
+		if elem.Index {
+			sb.WriteByte('[')
+		} else if i > 0 {
+			sb.WriteByte('.')
+		}
+		switch elem.Type {
+		case TokenQuotedName:
+			sb.WriteString("'" + elem.Name + "'")
+		case TokenNamePlaceholder:
+			sb.WriteByte('$')
+		case TokenMagicLiteral:
+			sb.WriteRune(magic)
+		default:
+			sb.WriteString(elem.Name)
+		}
+		if elem.Index {
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
+
+// UpdateExpr is a parsed update expression.
+type UpdateExpr struct {
+	Set    []*SetAction
+	Remove []*Path
+	Add    []*PathValue
+	Delete []*PathValue
+}
+
+// SetAction is an action in the SET clause, assigning Value to Path.
+type SetAction struct {
+	Pos   int
+	Path  *Path
+	Value Operand
+}
+
+// PathValue is an action in an ADD or DELETE clause.
+type PathValue struct {
+	Pos   int
+	Path  *Path
+	Value Operand
+}
+
+func (n *Logical) Position() int    { return n.Pos }
+func (n *Not) Position() int        { return n.Pos }
+func (n *Comparison) Position() int { return n.Pos }
+func (n *Between) Position() int    { return n.Pos }
+func (n *In) Position() int         { return n.Pos }
+func (n *Func) Position() int       { return n.Pos }
+func (n *Arithmetic) Position() int { return n.Pos }
+func (n *Value) Position() int      { return n.Pos }
+func (n *Path) Position() int       { return n.Pos }
+
+func (*Logical) condNode()    {}
+func (*Not) condNode()        {}
+func (*Comparison) condNode() {}
+func (*Between) condNode()    {}
+func (*In) condNode()         {}
+func (*Func) condNode()       {}
+
+func (*Func) operandNode()       {}
+func (*Arithmetic) operandNode() {}
+func (*Value) operandNode()      {}
+func (*Path) operandNode()       {}
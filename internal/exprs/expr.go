@@ -13,9 +13,8 @@ type Expr struct {
 	err   error
 }
 
-// TODO: actually parse something here.
-
-// Parse returns a lexed but not very parsed expression.
+// Parse returns a lexed expression, suitable for substituting placeholders.
+// See ParseKind for parsing an expression into a syntax tree.
 func Parse(input string) (*Expr, error) {
 	exprCache.RLock()
 	expr := exprCache.m[input]
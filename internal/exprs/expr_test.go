@@ -1,6 +1,7 @@
 package exprs
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -17,3 +18,93 @@ func TestParse(t *testing.T) {
 		t.Error("expected error, got nil")
 	}
 }
+
+func TestParseKinds(t *testing.T) {
+	valid := []struct {
+		kind  Kind
+		input string
+		args  int
+		paths []string
+	}{
+		{Condition, "'Count' > ? AND $ = ?", 3, []string{"'Count'", "$"}},
+		{Condition, "Score <= ? AND begins_with($, ?)", 3, []string{"Score", "$"}},
+		{Condition, "(attribute_exists(A) OR NOT (B.C[2] <> :v)) and size(D) between ? and ?", 2, []string{"A", "B.C[2]", "D"}},
+		{Condition, "#n IN (?, ?, :x)", 2, []string{"#n"}},
+		{Condition, "attribute_type(Tags, ?) AND contains(Tags, Other)", 1, []string{"Tags", "Tags", "Other"}},
+		{Condition, "Map.$[$] >= ?", 3, []string{"Map.$[$]"}},
+		{Projection, "A, B.C, 'D'[0], $", 1, []string{"A", "B.C", "'D'[0]", "$"}},
+		{Update, "SET A = ?, B = B + ?, C = if_not_exists(C, ?), D = list_append(D, ?) REMOVE E[1], F ADD G ? DELETE H :v", 5, []string{"A", "B", "B", "C", "C", "D", "D", "E[1]", "F", "G", "H"}},
+		{Update, "remove A set B = ?", 1, []string{"B", "A"}},
+		{SetActions, "🝕 = if_not_exists(🝕, ?)", 3, []string{"🝕", "🝕"}},
+		{SetActions, "MyMap.$.$ = ?", 3, []string{"MyMap.$.$"}},
+	}
+	for _, tc := range valid {
+		tree, err := ParseKind(tc.kind, tc.input)
+		if err != nil {
+			t.Errorf("%s %q: unexpected error: %v", tc.kind, tc.input, err)
+			continue
+		}
+		if n := tree.NumArgs(); n != tc.args {
+			t.Errorf("%s %q: bad arg count. want: %d got: %d", tc.kind, tc.input, tc.args, n)
+		}
+		if err := tree.CheckArgs(tc.args); err != nil {
+			t.Errorf("%s %q: unexpected CheckArgs error: %v", tc.kind, tc.input, err)
+		}
+		var paths []string
+		for _, p := range tree.Paths() {
+			paths = append(paths, p.String())
+		}
+		if !reflect.DeepEqual(paths, tc.paths) {
+			t.Errorf("%s %q: bad paths. want: %v got: %v", tc.kind, tc.input, tc.paths, paths)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	invalid := []struct {
+		kind  Kind
+		input string
+		pos   int
+	}{
+		{Condition, "'Unclosed", 0},
+		{Condition, "A = ", 4},
+		{Condition, "A ? B", 2},
+		{Condition, "A = ? AND", 9},
+		{Condition, "begins_with(A)", 13},
+		{Condition, "nope(A) = ?", 0},
+		{Condition, "(A = ?", 6},
+		{Condition, "A BETWEEN ? OR ?", 12},
+		{Condition, "A = ? B", 6},
+		{Projection, "A,", 2},
+		{Projection, "A[B]", 2},
+		{Update, "A = ?", 0},
+		{Update, "SET A = ? SET B = ?", 10},
+		{Update, "ADD A B", 6},
+		{SetActions, "A + ?", 2},
+	}
+	for _, tc := range invalid {
+		_, err := ParseKind(tc.kind, tc.input)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%s %q: expected syntax error, got %v", tc.kind, tc.input, err)
+			continue
+		}
+		if serr.Pos != tc.pos {
+			t.Errorf("%s %q: bad error position. want: %d got: %d (%v)", tc.kind, tc.input, tc.pos, serr.Pos, serr)
+		}
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	tree, err := ParseCondition("Score <= ? AND begins_with($, ?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tree.CheckArgs(2)
+	if serr, ok := err.(*SyntaxError); !ok || serr.Pos != 30 {
+		t.Errorf("expected missing argument error at position 30, got: %v", err)
+	}
+	if err := tree.CheckArgs(4); err == nil {
+		t.Error("expected too many arguments error, got nil")
+	}
+}
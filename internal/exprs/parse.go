@@ -0,0 +1,453 @@
+package exprs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SyntaxError is returned when an expression could not be parsed.
+type SyntaxError struct {
+	Input string
+	// Pos is the byte offset in Input where the error was found.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Input == "" {
+		return fmt.Sprintf("dynamo: expression syntax error: %s at position %d", e.Msg, e.Pos)
+	}
+	return fmt.Sprintf("dynamo: expression syntax error: %s at position %d in %q", e.Msg, e.Pos, e.Input)
+}
+
+// ParseKind parses input as the given kind of expression.
+// Parsed expressions are cached, and the returned Tree must not be modified.
+func ParseKind(kind Kind, input string) (*Tree, error) {
+	return parseCached(kind, input)
+}
+
+// ParseCondition parses a condition or filter expression.
+func ParseCondition(input string) (*Tree, error) {
+	return parseCached(Condition, input)
+}
+
+// ParseProjection parses a projection expression or list of paths.
+func ParseProjection(input string) (*Tree, error) {
+	return parseCached(Projection, input)
+}
+
+// ParseUpdate parses a complete update expression.
+func ParseUpdate(input string) (*Tree, error) {
+	return parseCached(Update, input)
+}
+
+// ParseSetActions parses the actions of a SET clause, without the SET keyword.
+func ParseSetActions(input string) (*Tree, error) {
+	return parseCached(SetActions, input)
+}
+
+type treeKey struct {
+	kind  Kind
+	input string
+}
+
+// treeCache holds an in-memory cache of already parsed expressions.
+var treeCache = struct {
+	m map[treeKey]*Tree
+	sync.RWMutex
+}{m: make(map[treeKey]*Tree)}
+
+func parseCached(kind Kind, input string) (*Tree, error) {
+	key := treeKey{kind, input}
+	treeCache.RLock()
+	tree := treeCache.m[key]
+	treeCache.RUnlock()
+	if tree != nil {
+		return tree, tree.err
+	}
+
+	tree = parse(kind, input)
+	treeCache.Lock()
+	treeCache.m[key] = tree
+	treeCache.Unlock()
+	return tree, tree.err
+}
+
+func parse(kind Kind, input string) (tree *Tree) {
+	tree = &Tree{Input: input, Kind: kind}
+	defer func() {
+		if r := recover(); r != nil {
+			serr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			serr.Input = input
+			tree.err = serr
+		}
+	}()
+
+	tokens, err := scan(input)
+	if err != nil {
+		panic(err)
+	}
+	tree.Tokens = tokens
+	p := &parser{tree: tree}
+
+	switch kind {
+	case Condition:
+		tree.Cond = p.parseOr()
+	case Projection:
+		tree.Projection = p.parsePathList()
+	case Update:
+		tree.Update = p.parseUpdate()
+	case SetActions:
+		tree.Update = &UpdateExpr{Set: p.parseSetActions()}
+	}
+	if tok := p.peek(); tok.Type != TokenEOF {
+		p.unexpected(tok)
+	}
+	return tree
+}
+
+type parser struct {
+	tree *Tree
+	idx  int
+}
+
+func (p *parser) peek() Token {
+	return p.tree.Tokens[p.idx]
+}
+
+func (p *parser) peekN(n int) Token {
+	if p.idx+n >= len(p.tree.Tokens) {
+		return p.tree.Tokens[len(p.tree.Tokens)-1]
+	}
+	return p.tree.Tokens[p.idx+n]
+}
+
+func (p *parser) next() Token {
+	tok := p.tree.Tokens[p.idx]
+	if tok.Type != TokenEOF {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *parser) expect(typ TokenType, what string) Token {
+	tok := p.next()
+	if tok.Type != typ {
+		p.errorf(tok.Pos, "expected %s but found %s", what, tok)
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(tok Token, keywords ...string) bool {
+	if tok.Type != TokenIdent {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(tok.Val, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) {
+	panic(&SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) unexpected(tok Token) {
+	p.errorf(tok.Pos, "unexpected %s", tok)
+}
+
+// condition := or
+// or := and (OR and)*
+func (p *parser) parseOr() CondNode {
+	left := p.parseAnd()
+	for p.isKeyword(p.peek(), "OR") {
+		op := p.next()
+		right := p.parseAnd()
+		left = &Logical{Pos: op.Pos, Op: "OR", Left: left, Right: right}
+	}
+	return left
+}
+
+// and := not (AND not)*
+func (p *parser) parseAnd() CondNode {
+	left := p.parseNot()
+	for p.isKeyword(p.peek(), "AND") {
+		op := p.next()
+		right := p.parseNot()
+		left = &Logical{Pos: op.Pos, Op: "AND", Left: left, Right: right}
+	}
+	return left
+}
+
+// not := NOT not | primary
+func (p *parser) parseNot() CondNode {
+	if tok := p.peek(); p.isKeyword(tok, "NOT") {
+		p.next()
+		return &Not{Pos: tok.Pos, Cond: p.parseNot()}
+	}
+	return p.parsePrimary()
+}
+
+// primary := ( condition ) | function | comparison | between | in
+func (p *parser) parsePrimary() CondNode {
+	tok := p.peek()
+	if tok.Type == TokenLParen {
+		p.next()
+		cond := p.parseOr()
+		p.expect(TokenRParen, "closing parenthesis")
+		return cond
+	}
+	if tok.Type == TokenIdent && p.peekN(1).Type == TokenLParen {
+		if arity, ok := condFuncs[strings.ToLower(tok.Val)]; ok {
+			return p.parseFunc(arity)
+		}
+	}
+
+	left := p.parseOperand(Condition)
+	switch next := p.peek(); {
+	case next.Type == TokenOp && next.Val != "+" && next.Val != "-":
+		p.next()
+		right := p.parseOperand(Condition)
+		return &Comparison{Pos: next.Pos, Op: next.Val, Left: left, Right: right}
+	case p.isKeyword(next, "BETWEEN"):
+		p.next()
+		lower := p.parseOperand(Condition)
+		if and := p.next(); !p.isKeyword(and, "AND") {
+			p.errorf(and.Pos, "expected AND in BETWEEN but found %s", and)
+		}
+		upper := p.parseOperand(Condition)
+		return &Between{Pos: next.Pos, Operand: left, Lower: lower, Upper: upper}
+	case p.isKeyword(next, "IN"):
+		p.next()
+		p.expect(TokenLParen, "opening parenthesis after IN")
+		in := &In{Pos: next.Pos, Operand: left}
+		for {
+			in.List = append(in.List, p.parseOperand(Condition))
+			if p.peek().Type != TokenComma {
+				break
+			}
+			p.next()
+		}
+		p.expect(TokenRParen, "closing parenthesis")
+		return in
+	default:
+		p.errorf(next.Pos, "expected comparator, BETWEEN, or IN but found %s", next)
+	}
+	return nil
+}
+
+// the number of arguments for condition functions
+var condFuncs = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+// the number of arguments for value functions, by kind
+var operandFuncs = map[Kind]map[string]int{
+	Condition: {
+		"size": 1,
+	},
+	Update: {
+		"if_not_exists": 2,
+		"list_append":   2,
+	},
+}
+
+func (p *parser) parseFunc(arity int) *Func {
+	name := p.next()
+	p.expect(TokenLParen, "opening parenthesis")
+	fn := &Func{Pos: name.Pos, Name: strings.ToLower(name.Val)}
+	kind := p.tree.Kind
+	if kind == SetActions {
+		kind = Update
+	}
+	for {
+		var arg Operand
+		if len(fn.Args) == 0 && fn.Name != "list_append" {
+			// the first argument of every function except list_append is a path
+			arg = p.parsePath()
+		} else {
+			arg = p.parseOperand(kind)
+		}
+		fn.Args = append(fn.Args, arg)
+		if p.peek().Type != TokenComma {
+			break
+		}
+		p.next()
+	}
+	end := p.expect(TokenRParen, "closing parenthesis")
+	if len(fn.Args) != arity {
+		p.errorf(end.Pos, "%s takes %d argument(s) but was given %d", fn.Name, arity, len(fn.Args))
+	}
+	return fn
+}
+
+// operand := value | function | path
+func (p *parser) parseOperand(kind Kind) Operand {
+	tok := p.peek()
+	switch tok.Type {
+	case TokenValuePlaceholder, TokenValueRef:
+		p.next()
+		v := &Value{Pos: tok.Pos, Token: p.idx - 1}
+		if tok.Type == TokenValueRef {
+			v.Ref = tok.Val
+		}
+		return v
+	case TokenIdent:
+		if p.peekN(1).Type == TokenLParen {
+			fnName := strings.ToLower(tok.Val)
+			if arity, ok := operandFuncs[kind][fnName]; ok {
+				return p.parseFunc(arity)
+			}
+			p.errorf(tok.Pos, "unknown function %s", tok)
+		}
+	}
+	return p.parsePath()
+}
+
+// path := name ( . name | [ index ] )*
+func (p *parser) parsePath() *Path {
+	tok := p.peek()
+	path := &Path{Pos: tok.Pos}
+	switch tok.Type {
+	case TokenIdent:
+		if p.isKeyword(tok, "AND", "OR", "NOT", "BETWEEN", "IN") {
+			p.errorf(tok.Pos, "expected path but found keyword %s", tok)
+		}
+		p.tree.Tokens[p.idx].Name = true
+		path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val})
+	case TokenQuotedName:
+		path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val[1 : len(tok.Val)-1]})
+	case TokenNameRef, TokenNamePlaceholder, TokenMagicLiteral:
+		path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val})
+	default:
+		p.errorf(tok.Pos, "expected path but found %s", tok)
+	}
+	p.next()
+
+	for {
+		switch p.peek().Type {
+		case TokenDot:
+			p.next()
+			tok := p.peek()
+			switch tok.Type {
+			case TokenIdent:
+				p.tree.Tokens[p.idx].Name = true
+				path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val})
+			case TokenQuotedName:
+				path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val[1 : len(tok.Val)-1]})
+			case TokenNameRef, TokenNamePlaceholder, TokenMagicLiteral:
+				path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val})
+			default:
+				p.errorf(tok.Pos, "expected name after . but found %s", tok)
+			}
+			p.next()
+		case TokenLBracket:
+			p.next()
+			tok := p.peek()
+			switch tok.Type {
+			case TokenNumber, TokenNamePlaceholder:
+				path.Elems = append(path.Elems, PathElem{Token: p.idx, Type: tok.Type, Name: tok.Val, Index: true})
+			default:
+				p.errorf(tok.Pos, "expected list index but found %s", tok)
+			}
+			p.next()
+			p.expect(TokenRBracket, "closing bracket")
+		default:
+			return path
+		}
+	}
+}
+
+// paths := path (, path)*
+func (p *parser) parsePathList() []*Path {
+	var paths []*Path
+	for {
+		paths = append(paths, p.parsePath())
+		if p.peek().Type != TokenComma {
+			return paths
+		}
+		p.next()
+	}
+}
+
+// update := (SET actions | REMOVE paths | ADD pathvalues | DELETE pathvalues)+
+func (p *parser) parseUpdate() *UpdateExpr {
+	update := new(UpdateExpr)
+	seen := make(map[string]bool)
+	for {
+		tok := p.next()
+		if tok.Type == TokenEOF && len(seen) > 0 {
+			return update
+		}
+		if !p.isKeyword(tok, "SET", "REMOVE", "ADD", "DELETE") {
+			p.errorf(tok.Pos, "expected SET, REMOVE, ADD, or DELETE but found %s", tok)
+		}
+		clause := strings.ToUpper(tok.Val)
+		if seen[clause] {
+			p.errorf(tok.Pos, "%s clause used more than once", clause)
+		}
+		seen[clause] = true
+		switch clause {
+		case "SET":
+			update.Set = p.parseSetActions()
+		case "REMOVE":
+			update.Remove = p.parsePathList()
+		case "ADD":
+			update.Add = p.parsePathValues()
+		case "DELETE":
+			update.Delete = p.parsePathValues()
+		}
+	}
+}
+
+// actions := path = setvalue (, path = setvalue)*
+// setvalue := operand | operand + operand | operand - operand
+func (p *parser) parseSetActions() []*SetAction {
+	var actions []*SetAction
+	for {
+		path := p.parsePath()
+		eq := p.next()
+		if eq.Type != TokenOp || eq.Val != "=" {
+			p.errorf(eq.Pos, "expected = but found %s", eq)
+		}
+		value := p.parseOperand(Update)
+		if op := p.peek(); op.Type == TokenOp && (op.Val == "+" || op.Val == "-") {
+			p.next()
+			right := p.parseOperand(Update)
+			value = &Arithmetic{Pos: op.Pos, Op: op.Val, Left: value, Right: right}
+		}
+		actions = append(actions, &SetAction{Pos: path.Pos, Path: path, Value: value})
+		if p.peek().Type != TokenComma {
+			return actions
+		}
+		p.next()
+	}
+}
+
+// pathvalues := path value (, path value)*
+func (p *parser) parsePathValues() []*PathValue {
+	var actions []*PathValue
+	for {
+		path := p.parsePath()
+		tok := p.peek()
+		if tok.Type != TokenValuePlaceholder && tok.Type != TokenValueRef {
+			p.errorf(tok.Pos, "expected value but found %s", tok)
+		}
+		value := p.parseOperand(Update)
+		actions = append(actions, &PathValue{Pos: path.Pos, Path: path, Value: value})
+		if p.peek().Type != TokenComma {
+			return actions
+		}
+		p.next()
+	}
+}
@@ -0,0 +1,173 @@
+package exprs
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenType is the type of a scanned token.
+type TokenType int
+
+// Types of scanned tokens.
+const (
+	TokenEOF TokenType = iota
+	// TokenIdent is a bare word: an attribute name, function name, or keyword.
+	TokenIdent
+	// TokenNameRef is an expression attribute name reference such as #name.
+	TokenNameRef
+	// TokenValueRef is an expression attribute value reference such as :value.
+	TokenValueRef
+	// TokenQuotedName is a name in single quotes such as 'Count'.
+	TokenQuotedName
+	// TokenNamePlaceholder is the $ placeholder.
+	TokenNamePlaceholder
+	// TokenValuePlaceholder is the ? placeholder.
+	TokenValuePlaceholder
+	// TokenMagicLiteral is the internal placeholder for pre-escaped text.
+	TokenMagicLiteral
+	// TokenNumber is an unsigned integer, used for list indexes.
+	TokenNumber
+	// TokenOp is a comparator or arithmetic operator.
+	TokenOp
+	TokenLParen
+	TokenRParen
+	TokenLBracket
+	TokenRBracket
+	TokenComma
+	TokenDot
+)
+
+// Token is a scanned token.
+type Token struct {
+	Type TokenType
+	// Pos is the byte offset of the start of this token in the input.
+	Pos int
+	Val string
+	// Name is true for identifiers that the parser determined to be attribute names,
+	// as opposed to keywords or function names.
+	Name bool
+}
+
+// End returns the byte offset of the end of this token.
+func (t Token) End() int {
+	return t.Pos + len(t.Val)
+}
+
+// IsPlaceholder returns true if this token consumes an argument.
+func (t Token) IsPlaceholder() bool {
+	switch t.Type {
+	case TokenNamePlaceholder, TokenValuePlaceholder, TokenMagicLiteral:
+		return true
+	}
+	return false
+}
+
+func (t Token) String() string {
+	switch t.Type {
+	case TokenEOF:
+		return "end of expression"
+	case TokenNamePlaceholder:
+		return "$"
+	case TokenValuePlaceholder:
+		return "?"
+	case TokenMagicLiteral:
+		return "literal"
+	}
+	return fmt.Sprintf("%q", t.Val)
+}
+
+// scan splits input into tokens.
+func scan(input string) ([]Token, error) {
+	var tokens []Token
+	pos := 0
+	emit := func(typ TokenType, end int) {
+		tokens = append(tokens, Token{Type: typ, Pos: pos, Val: input[pos:end]})
+		pos = end
+	}
+	for pos < len(input) {
+		r, w := utf8.DecodeRuneInString(input[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += w
+		case r == '\'':
+			end := pos + 1
+			for end < len(input) && input[end] != '\'' {
+				end++
+			}
+			if end >= len(input) {
+				return nil, &SyntaxError{Pos: pos, Msg: "unterminated quoted name"}
+			}
+			emit(TokenQuotedName, end+1)
+		case r == '$':
+			emit(TokenNamePlaceholder, pos+w)
+		case r == '?':
+			emit(TokenValuePlaceholder, pos+w)
+		case r == magic:
+			emit(TokenMagicLiteral, pos+w)
+		case r == '#' || r == ':':
+			end := scanWord(input, pos+1)
+			if end == pos+1 {
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("expected name after %q", r)}
+			}
+			if r == '#' {
+				emit(TokenNameRef, end)
+			} else {
+				emit(TokenValueRef, end)
+			}
+		case r >= '0' && r <= '9':
+			end := pos
+			for end < len(input) && input[end] >= '0' && input[end] <= '9' {
+				end++
+			}
+			emit(TokenNumber, end)
+		case isWordRune(r):
+			emit(TokenIdent, scanWord(input, pos))
+		case r == '<':
+			if end := pos + 1; end < len(input) && (input[end] == '=' || input[end] == '>') {
+				emit(TokenOp, end+1)
+			} else {
+				emit(TokenOp, end)
+			}
+		case r == '>':
+			if end := pos + 1; end < len(input) && input[end] == '=' {
+				emit(TokenOp, end+1)
+			} else {
+				emit(TokenOp, end)
+			}
+		case r == '=' || r == '+' || r == '-':
+			emit(TokenOp, pos+w)
+		case r == '(':
+			emit(TokenLParen, pos+w)
+		case r == ')':
+			emit(TokenRParen, pos+w)
+		case r == '[':
+			emit(TokenLBracket, pos+w)
+		case r == ']':
+			emit(TokenRBracket, pos+w)
+		case r == ',':
+			emit(TokenComma, pos+w)
+		case r == '.':
+			emit(TokenDot, pos+w)
+		default:
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	tokens = append(tokens, Token{Type: TokenEOF, Pos: len(input)})
+	return tokens, nil
+}
+
+func scanWord(input string, pos int) int {
+	for pos < len(input) {
+		r, w := utf8.DecodeRuneInString(input[pos:])
+		if !isWordRune(r) && !(r >= '0' && r <= '9') {
+			break
+		}
+		pos += w
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || (unicode.IsLetter(r) && r != magic)
+}
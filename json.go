@@ -0,0 +1,361 @@
+package dynamo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ItemToDynamoJSON converts item to DynamoDB's typed JSON format,
+// where each value is an object naming its type, such as {"S": "hello"} or {"N": "42"}.
+// This is the format used by the DynamoDB API and the AWS CLI.
+func ItemToDynamoJSON(item map[string]types.AttributeValue) ([]byte, error) {
+	obj := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		v, err := av2dynamoJSON(av)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: %s: %v", name, err)
+		}
+		obj[name] = v
+	}
+	return json.Marshal(obj)
+}
+
+// ItemFromDynamoJSON converts data in DynamoDB's typed JSON format to an item.
+// See ItemToDynamoJSON.
+func ItemFromDynamoJSON(data []byte) (map[string]types.AttributeValue, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	item := make(map[string]types.AttributeValue, len(obj))
+	for name, raw := range obj {
+		av, err := dynamoJSON2av(raw)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: %s: %v", name, err)
+		}
+		item[name] = av
+	}
+	return item, nil
+}
+
+func av2dynamoJSON(av types.AttributeValue) (interface{}, error) {
+	var v interface{}
+	switch x := av.(type) {
+	case *types.AttributeValueMemberB:
+		v = x.Value
+	case *types.AttributeValueMemberBOOL:
+		v = x.Value
+	case *types.AttributeValueMemberBS:
+		v = x.Value
+	case *types.AttributeValueMemberN:
+		v = x.Value
+	case *types.AttributeValueMemberNS:
+		v = x.Value
+	case *types.AttributeValueMemberNULL:
+		v = x.Value
+	case *types.AttributeValueMemberS:
+		v = x.Value
+	case *types.AttributeValueMemberSS:
+		v = x.Value
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, 0, len(x.Value))
+		for _, elem := range x.Value {
+			ev, err := av2dynamoJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, ev)
+		}
+		v = list
+	case *types.AttributeValueMemberM:
+		m := make(map[string]interface{}, len(x.Value))
+		for k, elem := range x.Value {
+			ev, err := av2dynamoJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = ev
+		}
+		v = m
+	default:
+		return nil, fmt.Errorf("unsupported AV: %#v", av)
+	}
+	return map[string]interface{}{avTypeName(av): v}, nil
+}
+
+func dynamoJSON2av(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("value must have exactly one type, got %d", len(typed))
+	}
+	var typ string
+	var value json.RawMessage
+	for typ, value = range typed {
+	}
+
+	switch typ {
+	case "B":
+		av := new(types.AttributeValueMemberB)
+		return av, json.Unmarshal(value, &av.Value)
+	case "BOOL":
+		av := new(types.AttributeValueMemberBOOL)
+		return av, json.Unmarshal(value, &av.Value)
+	case "BS":
+		av := new(types.AttributeValueMemberBS)
+		return av, json.Unmarshal(value, &av.Value)
+	case "N":
+		av := new(types.AttributeValueMemberN)
+		return av, json.Unmarshal(value, &av.Value)
+	case "NS":
+		av := new(types.AttributeValueMemberNS)
+		return av, json.Unmarshal(value, &av.Value)
+	case "NULL":
+		av := new(types.AttributeValueMemberNULL)
+		return av, json.Unmarshal(value, &av.Value)
+	case "S":
+		av := new(types.AttributeValueMemberS)
+		return av, json.Unmarshal(value, &av.Value)
+	case "SS":
+		av := new(types.AttributeValueMemberSS)
+		return av, json.Unmarshal(value, &av.Value)
+	case "L":
+		var list []json.RawMessage
+		if err := json.Unmarshal(value, &list); err != nil {
+			return nil, err
+		}
+		av := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(list))}
+		for i, elem := range list {
+			ev, err := dynamoJSON2av(elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			av.Value = append(av.Value, ev)
+		}
+		return av, nil
+	case "M":
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(value, &m); err != nil {
+			return nil, err
+		}
+		av := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(m))}
+		for k, elem := range m {
+			ev, err := dynamoJSON2av(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			av.Value[k] = ev
+		}
+		return av, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// JSONOptions control how items are converted to and from plain JSON
+// with ItemToJSON and ItemFromJSON.
+type JSONOptions struct {
+	// FloatNumbers converts numbers to float64 before writing them, which loses precision
+	// for integers beyond 2^53 and decimals with more than about 15 significant digits.
+	// By default, numbers are written exactly as stored.
+	FloatNumbers bool
+	// TagSets writes sets as objects with a single key naming the set type,
+	// such as {"SS": ["a", "b"]}, and reads such objects back as sets.
+	// By default, sets are written as arrays, which are read back as lists.
+	TagSets bool
+	// TagBinary writes binary data as objects like {"B": "aGVsbG8="},
+	// and reads such objects back as binary data.
+	// By default, binary data is written as a base64 string, which is read back as a string.
+	TagBinary bool
+}
+
+// ItemToJSON converts item to plain JSON, such as {"Name": "hello", "Count": 42}.
+// Binary data is encoded as base64. Some type information is lost unless it is kept with opts.
+func ItemToJSON(item map[string]types.AttributeValue, opts JSONOptions) ([]byte, error) {
+	obj := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		v, err := av2ifaceWith(av, opts)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: %s: %v", name, err)
+		}
+		obj[name] = v
+	}
+	return json.Marshal(obj)
+}
+
+// ItemFromJSON converts plain JSON to an item. Numbers keep all of their digits.
+// Objects that look like tagged sets or binary data are converted to them if enabled by opts.
+// See ItemToJSON.
+func ItemFromJSON(data []byte, opts JSONOptions) (map[string]types.AttributeValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	item := make(map[string]types.AttributeValue, len(obj))
+	for name, v := range obj {
+		av, err := iface2av(v, opts)
+		if err != nil {
+			return nil, fmt.Errorf("dynamo: %s: %v", name, err)
+		}
+		item[name] = av
+	}
+	return item, nil
+}
+
+// av2ifaceWith is like av2iface, but follows opts.
+func av2ifaceWith(av types.AttributeValue, opts JSONOptions) (interface{}, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberB:
+		if opts.TagBinary {
+			return map[string]interface{}{"B": v.Value}, nil
+		}
+	case *types.AttributeValueMemberN:
+		if !opts.FloatNumbers {
+			return json.Number(v.Value), nil
+		}
+	case *types.AttributeValueMemberNS:
+		var set interface{}
+		if !opts.FloatNumbers {
+			ns := make([]json.Number, 0, len(v.Value))
+			for _, n := range v.Value {
+				ns = append(ns, json.Number(n))
+			}
+			set = ns
+		} else {
+			iface, err := av2iface(v)
+			if err != nil {
+				return nil, err
+			}
+			set = iface
+		}
+		if opts.TagSets {
+			return map[string]interface{}{"NS": set}, nil
+		}
+		return set, nil
+	case *types.AttributeValueMemberSS:
+		if opts.TagSets {
+			return map[string]interface{}{"SS": v.Value}, nil
+		}
+	case *types.AttributeValueMemberBS:
+		if opts.TagSets {
+			return map[string]interface{}{"BS": v.Value}, nil
+		}
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, 0, len(v.Value))
+		for _, elem := range v.Value {
+			iface, err := av2ifaceWith(elem, opts)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, iface)
+		}
+		return list, nil
+	case *types.AttributeValueMemberM:
+		m := make(map[string]interface{}, len(v.Value))
+		for k, elem := range v.Value {
+			iface, err := av2ifaceWith(elem, opts)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = iface
+		}
+		return m, nil
+	}
+	return av2iface(av)
+}
+
+// iface2av converts a value decoded from JSON (using json.Number) into an AV.
+func iface2av(v interface{}, opts JSONOptions) (types.AttributeValue, error) {
+	switch x := v.(type) {
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: x}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: x.String()}, nil
+	case string:
+		return &types.AttributeValueMemberS{Value: x}, nil
+	case []interface{}:
+		list := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(x))}
+		for i, elem := range x {
+			av, err := iface2av(elem, opts)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			list.Value = append(list.Value, av)
+		}
+		return list, nil
+	case map[string]interface{}:
+		if av, ok, err := taggedJSON(x, opts); ok || err != nil {
+			return av, err
+		}
+		m := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(x))}
+		for k, elem := range x {
+			av, err := iface2av(elem, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			m.Value[k] = av
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported JSON value: %T", v)
+}
+
+// taggedJSON converts objects written with JSONOptions.TagSets or TagBinary back into sets or binary data.
+// It returns false if obj isn't such an object.
+func taggedJSON(obj map[string]interface{}, opts JSONOptions) (types.AttributeValue, bool, error) {
+	if len(obj) != 1 {
+		return nil, false, nil
+	}
+	for tag, v := range obj {
+		switch {
+		case tag == "B" && opts.TagBinary:
+			s, ok := v.(string)
+			if !ok {
+				return nil, false, nil
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			return &types.AttributeValueMemberB{Value: b}, true, err
+		case (tag == "SS" || tag == "NS" || tag == "BS") && opts.TagSets:
+			elems, ok := v.([]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			strs := make([]string, 0, len(elems))
+			for _, elem := range elems {
+				switch x := elem.(type) {
+				case string:
+					strs = append(strs, x)
+				case json.Number:
+					strs = append(strs, x.String())
+				default:
+					return nil, false, nil
+				}
+			}
+			switch tag {
+			case "SS":
+				return &types.AttributeValueMemberSS{Value: strs}, true, nil
+			case "NS":
+				return &types.AttributeValueMemberNS{Value: strs}, true, nil
+			}
+			bs := make([][]byte, 0, len(strs))
+			for _, s := range strs {
+				b, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return nil, true, err
+				}
+				bs = append(bs, b)
+			}
+			return &types.AttributeValueMemberBS{Value: bs}, true, nil
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,163 @@
+package dynamo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func jsonTestItem() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"ID":    &types.AttributeValueMemberN{Value: "12345678901234567890"},
+		"Name":  &types.AttributeValueMemberS{Value: "Alice"},
+		"Data":  &types.AttributeValueMemberB{Value: []byte("hello")},
+		"OK":    &types.AttributeValueMemberBOOL{Value: true},
+		"Nil":   &types.AttributeValueMemberNULL{Value: true},
+		"Tags":  &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"Nums":  &types.AttributeValueMemberNS{Value: []string{"1", "2.5"}},
+		"Blobs": &types.AttributeValueMemberBS{Value: [][]byte{[]byte("x")}},
+		"List": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "one"},
+			&types.AttributeValueMemberN{Value: "2"},
+		}},
+		"Map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"Inner": &types.AttributeValueMemberS{Value: "value"},
+		}},
+	}
+}
+
+func TestDynamoJSON(t *testing.T) {
+	item := jsonTestItem()
+	data, err := ItemToDynamoJSON(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"S": "Alice"}; !reflect.DeepEqual(raw["Name"], want) {
+		t.Errorf("bad typed value. want: %#v got: %#v", want, raw["Name"])
+	}
+	if want := map[string]interface{}{"B": "aGVsbG8="}; !reflect.DeepEqual(raw["Data"], want) {
+		t.Errorf("bad binary value. want: %#v got: %#v", want, raw["Data"])
+	}
+
+	got, err := ItemFromDynamoJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("bad round trip. want: %#v got: %#v", item, got)
+	}
+}
+
+func TestDynamoJSONInvalid(t *testing.T) {
+	tests := []string{
+		`{"A": {"S": "a", "N": "1"}}`,
+		`{"A": {}}`,
+		`{"A": {"X": "a"}}`,
+		`{"A": {"L": [{"Q": 1}]}}`,
+		`{"A": "a"}`,
+	}
+	for _, in := range tests {
+		if _, err := ItemFromDynamoJSON([]byte(in)); err == nil {
+			t.Errorf("%s: expected error, got nil", in)
+		}
+	}
+}
+
+func TestItemToJSON(t *testing.T) {
+	item := jsonTestItem()
+
+	data, err := ItemToJSON(item, JSONOptions{FloatNumbers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"ID":    1.2345678901234567e19,
+		"Name":  "Alice",
+		"Data":  "aGVsbG8=",
+		"OK":    true,
+		"Nil":   nil,
+		"Tags":  []interface{}{"a", "b"},
+		"Nums":  []interface{}{1.0, 2.5},
+		"Blobs": []interface{}{"eA=="},
+		"List":  []interface{}{"one", 2.0},
+		"Map":   map[string]interface{}{"Inner": "value"},
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("bad JSON. want: %#v got: %#v", want, raw)
+	}
+
+	// by default, numbers keep all of their digits
+	exact, err := ItemToJSON(map[string]types.AttributeValue{"ID": item["ID"]}, JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ID":12345678901234567890}`; string(exact) != want {
+		t.Errorf("bad exact number. want: %s got: %s", want, exact)
+	}
+
+	// without options, sets and binary data come back as lists and strings
+	back, err := ItemFromJSON(data, JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := back["Tags"].(*types.AttributeValueMemberL); !ok {
+		t.Errorf("expected list, got %#v", back["Tags"])
+	}
+	if got, want := back["Data"], (&types.AttributeValueMemberS{Value: "aGVsbG8="}); !reflect.DeepEqual(got, want) {
+		t.Errorf("bad binary value. want: %#v got: %#v", want, got)
+	}
+}
+
+func TestItemJSONRoundTrip(t *testing.T) {
+	item := jsonTestItem()
+	opts := JSONOptions{TagSets: true, TagBinary: true}
+
+	data, err := ItemToJSON(item, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ItemFromJSON(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Errorf("bad round trip. want: %#v got: %#v", item, got)
+	}
+}
+
+func TestItemFromJSON(t *testing.T) {
+	got, err := ItemFromJSON([]byte(`{"N": 12345678901234567890.5, "S": {"SS": "not a set"}, "E": {"B": "!"}}`), JSONOptions{TagSets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]types.AttributeValue{
+		"N": &types.AttributeValueMemberN{Value: "12345678901234567890.5"},
+		"S": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"SS": &types.AttributeValueMemberS{Value: "not a set"},
+		}},
+		"E": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"B": &types.AttributeValueMemberS{Value: "!"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, got)
+	}
+
+	if _, err := ItemFromJSON([]byte(`{"E": {"B": "!"}}`), JSONOptions{TagBinary: true}); err == nil {
+		t.Error("expected error for invalid base64, got nil")
+	}
+	if _, err := ItemFromJSON([]byte(`[1, 2]`), JSONOptions{}); err == nil {
+		t.Error("expected error for non-object, got nil")
+	}
+}
@@ -1,5 +1,13 @@
 package dynamo
 
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
 // KeyType is used to specify the type of hash and range keys for tables and indexes.
 type KeyType string
 
@@ -28,3 +36,104 @@ func (k Keys) HashKey() interface{} { return k[0] }
 
 // RangeKey returns the range key's value.
 func (k Keys) RangeKey() interface{} { return k[1] }
+
+// keyDesc describes the primary key of a struct type, as given by its hash and range struct tags.
+type keyDesc struct {
+	hashKey    string
+	hashIdx    []int
+	hashField  *Field
+	rangeKey   string
+	rangeIdx   []int
+	rangeField *Field
+}
+
+// keyDescs caches key descriptors by type.
+var keyDescs sync.Map // reflect.Type → *keyDesc
+
+// keyDescOf returns the key descriptor of rt, which must be a struct or pointer to a struct.
+func keyDescOf(rt reflect.Type) (*keyDesc, error) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if desc, ok := keyDescs.Load(rt); ok {
+		return desc.(*keyDesc), nil
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynamo: item must be a struct with hash key struct tags (got %s)", rt)
+	}
+	desc := new(keyDesc)
+	desc.from(rt)
+	if desc.hashKey == "" {
+		return nil, fmt.Errorf("dynamo: %s has no hash key: tag its hash key field with `dynamo:\",hash\"`", rt)
+	}
+	keyDescs.Store(rt, desc)
+	return desc, nil
+}
+
+func (desc *keyDesc) from(rt reflect.Type) {
+	for _, f := range codecOf(rt).fields {
+		field := rt.FieldByIndex(f.index)
+		switch keyTypeFromTag(field.Tag.Get("dynamo")) {
+		case types.KeyTypeHash:
+			if desc.hashKey == "" {
+				desc.hashKey, desc.hashIdx = f.name, f.index
+				desc.hashField = NewField(field.Name, field.Tag)
+				desc.hashField.name = f.name
+			}
+		case types.KeyTypeRange:
+			if desc.rangeKey == "" {
+				desc.rangeKey, desc.rangeIdx = f.name, f.index
+				desc.rangeField = NewField(field.Name, field.Tag)
+				desc.rangeField.name = f.name
+			}
+		}
+	}
+}
+
+// keys returns the hash and range key values of item, encoded like the fields are when the item is put.
+// rangeValue is nil if this type has no range key.
+func (desc *keyDesc) keys(item interface{}) (hashValue, rangeValue types.AttributeValue, err error) {
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("dynamo: item must not be nil")
+		}
+		rv = rv.Elem()
+	}
+	hashValue, err = encodeKey(rv, desc.hashIdx, desc.hashField)
+	if err != nil || desc.rangeKey == "" {
+		return
+	}
+	rangeValue, err = encodeKey(rv, desc.rangeIdx, desc.rangeField)
+	return
+}
+
+func encodeKey(rv reflect.Value, index []int, field *Field) (types.AttributeValue, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil, fmt.Errorf("dynamo: key field is in nil embedded struct %s", rv.Type())
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	av, err := field.marshal(rv)
+	if err == nil && av == nil {
+		err = fmt.Errorf("dynamo: key %s must not be empty", field.Name())
+	}
+	return av, err
+}
+
+// itemKeys returns the key descriptor and key values of item.
+func itemKeys(item interface{}) (desc *keyDesc, hashValue, rangeValue types.AttributeValue, err error) {
+	if item == nil {
+		return nil, nil, nil, fmt.Errorf("dynamo: item must not be nil")
+	}
+	desc, err = keyDescOf(reflect.TypeOf(item))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hashValue, rangeValue, err = desc.keys(item)
+	return
+}
@@ -0,0 +1,158 @@
+package dynamo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type keyedWidget struct {
+	UserID int       `dynamo:"ID,hash"`
+	Time   time.Time `dynamo:",range"`
+	Msg    string
+}
+
+type embeddedKeyWidget struct {
+	*keyedWidget
+	Extra string
+}
+
+type hashOnlyWidget struct {
+	Name  string `dynamo:",hash"`
+	Value int
+}
+
+type inlineKeyWidget struct {
+	Key   keyedWidget `dynamo:"k_,inline"`
+	Extra string
+}
+
+type unixKeyedWidget struct {
+	Name string    `dynamo:",hash"`
+	Time time.Time `dynamo:",range,unixtime"`
+}
+
+func TestItemKeys(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		item      interface{}
+		hashKey   string
+		hashValue types.AttributeValue
+		rangeKey  string
+		rngValue  types.AttributeValue
+	}{
+		{"struct", keyedWidget{UserID: 42, Time: now}, "ID", &types.AttributeValueMemberN{Value: "42"}, "Time", &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"}},
+		{"pointer", &keyedWidget{UserID: 42, Time: now}, "ID", &types.AttributeValueMemberN{Value: "42"}, "Time", &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"}},
+		{"embedded", embeddedKeyWidget{keyedWidget: &keyedWidget{UserID: 1, Time: now}}, "ID", &types.AttributeValueMemberN{Value: "1"}, "Time", &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"}},
+		{"inline", inlineKeyWidget{Key: keyedWidget{UserID: 7, Time: now}}, "k_ID", &types.AttributeValueMemberN{Value: "7"}, "k_Time", &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"}},
+		{"hash only", hashOnlyWidget{Name: "x"}, "Name", &types.AttributeValueMemberS{Value: "x"}, "", nil},
+		{"time format", unixKeyedWidget{Name: "x", Time: now}, "Name", &types.AttributeValueMemberS{Value: "x"}, "Time", &types.AttributeValueMemberN{Value: "1577934245"}},
+	}
+	for _, tc := range tests {
+		desc, hv, rv, err := itemKeys(tc.item)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if desc.hashKey != tc.hashKey || desc.rangeKey != tc.rangeKey {
+			t.Errorf("%s: bad key names: %s, %s", tc.name, desc.hashKey, desc.rangeKey)
+		}
+		if !reflect.DeepEqual(hv, tc.hashValue) || !reflect.DeepEqual(rv, tc.rngValue) {
+			t.Errorf("%s: bad key values: %v, %v", tc.name, hv, rv)
+		}
+	}
+
+	if _, _, _, err := itemKeys(widget{}); err == nil {
+		t.Error("expected error for item without hash key, got nil")
+	}
+	if _, _, _, err := itemKeys(embeddedKeyWidget{}); err == nil {
+		t.Error("expected error for nil embedded key struct, got nil")
+	}
+	if _, _, _, err := itemKeys(42); err == nil {
+		t.Error("expected error for non-struct item, got nil")
+	}
+	if _, _, _, err := itemKeys(hashOnlyWidget{}); err == nil {
+		t.Error("expected error for empty hash key, got nil")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	client := &fakeClient{
+		item: map[string]types.AttributeValue{
+			"ID":    &types.AttributeValueMemberN{Value: "42"},
+			"Time":  &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"},
+			"Msg":   &types.AttributeValueMemberS{Value: "hello"},
+			"Extra": &types.AttributeValueMemberS{Value: "!"},
+		},
+	}
+	table := NewFromIface(client).Table("Keyed")
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := keyedWidget{UserID: 42, Time: now}
+	if err := table.Load(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Msg != "hello" {
+		t.Errorf("bad loaded item: %#v", item)
+	}
+	wantKey := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberN{Value: "42"},
+		"Time": &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"},
+	}
+	if !reflect.DeepEqual(client.get.Key, wantKey) {
+		t.Errorf("bad key. want: %#v got: %#v", wantKey, client.get.Key)
+	}
+
+	if err := table.Load(item); err == nil {
+		t.Error("expected error for non-pointer item, got nil")
+	}
+
+	client.item = nil
+	if err := table.Load(&item); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRemoveAndBatchItems(t *testing.T) {
+	table := NewFromIface(&fakeClient{}).Table("Keyed")
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := keyedWidget{UserID: 1, Time: now}
+	b := keyedWidget{UserID: 2, Time: now}
+	keyOf := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"ID":   &types.AttributeValueMemberN{Value: id},
+			"Time": &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"},
+		}
+	}
+
+	del := table.Remove(&a)
+	if del.err != nil {
+		t.Fatal(del.err)
+	}
+	if !reflect.DeepEqual(del.key(), keyOf("1")) {
+		t.Errorf("bad delete key: %#v", del.key())
+	}
+
+	bw := table.Batch().Write().DeleteItems(a, &b)
+	if bw.err != nil {
+		t.Fatal(bw.err)
+	}
+	if len(bw.ops) != 2 || !reflect.DeepEqual(bw.ops[0].DeleteRequest.Key, keyOf("1")) || !reflect.DeepEqual(bw.ops[1].DeleteRequest.Key, keyOf("2")) {
+		t.Errorf("bad batch write ops: %#v", bw.ops)
+	}
+
+	bg := table.Batch().Get().Items(a, b)
+	if bg.err != nil {
+		t.Fatal(bg.err)
+	}
+	if len(bg.reqs) != 2 || !reflect.DeepEqual(bg.reqs[1].keys(), keyOf("2")) {
+		t.Errorf("bad batch get requests: %#v", bg.reqs)
+	}
+
+	if err := table.Batch().Write().DeleteItems(widget{}).err; err == nil {
+		t.Error("expected error for item without hash key, got nil")
+	}
+}
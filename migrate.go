@@ -0,0 +1,356 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gofrs/uuid"
+)
+
+// Migration is a change to one or more tables, applied at most once by a Migrator.
+type Migration struct {
+	// ID uniquely identifies this migration, such as "0001-create-orders".
+	ID          string
+	Description string
+	// Schema is the desired state of the tables this migration changes, if any.
+	// Each table is created or updated as in TableSchema.EnsureTable,
+	// and is active again before Backfill is called.
+	Schema *Schema
+	// Backfill is called after the schema changes to migrate data, if not nil.
+	Backfill func(ctx context.Context, db *DB) error
+}
+
+// ErrMigrationLocked is returned by Migrator.Run when another Migrator is applying migrations
+// recorded in the same metadata table.
+var ErrMigrationLocked = errors.New("dynamo: migrations are locked by another runner")
+
+// migrationLockID is the ID of the lock item in the metadata table, which can't be used by a migration.
+const migrationLockID = "#lock"
+
+// defaultMigrationLease is how long a Migrator's lock lasts without being renewed, unless set by Lease.
+const defaultMigrationLease = 5 * time.Minute
+
+// migrationRecord is an applied migration, as stored in the metadata table.
+type migrationRecord struct {
+	ID          string `dynamo:",hash"`
+	Description string `dynamo:",omitempty"`
+	Applied     time.Time
+}
+
+// migrationLock is held by a running Migrator, as stored in the metadata table.
+type migrationLock struct {
+	ID      string `dynamo:",hash"`
+	Owner   string
+	Expires time.Time `dynamo:",unixtime"`
+}
+
+// Migrator applies migrations in order, recording those applied in a metadata table.
+type Migrator struct {
+	db         *DB
+	meta       string
+	migrations []Migration
+	opts       EnsureOptions
+	lease      time.Duration
+}
+
+// Migrator returns a Migrator for the given migrations, which are applied in order.
+// Applied migrations are recorded in the table meta, which has the hash key ID (string)
+// and is created with on-demand billing if it doesn't exist.
+func (db *DB) Migrator(meta string, migrations ...Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		meta:       meta,
+		migrations: migrations,
+	}
+}
+
+// Options sets the DeleteIndexes and PollInterval options used to update tables.
+// Other options are taken from each migration's schema.
+func (m *Migrator) Options(opts EnsureOptions) *Migrator {
+	m.opts = EnsureOptions{
+		DeleteIndexes: opts.DeleteIndexes,
+		PollInterval:  opts.PollInterval,
+	}
+	return m
+}
+
+// Lease sets how long the lock taken by Run lasts if it isn't renewed. The default is 5 minutes.
+// Run renews its lock while migrations are applied, so the lease only matters if a runner dies without releasing it:
+// other runners fail with ErrMigrationLocked until it expires.
+func (m *Migrator) Lease(d time.Duration) *Migrator {
+	m.lease = d
+	return m
+}
+
+// Pending returns the IDs of the migrations that haven't been applied yet.
+func (m *Migrator) Pending() ([]string, error) {
+	ctx, cancel := defaultContext()
+	defer cancel()
+	return m.PendingWithContext(ctx)
+}
+
+// PendingWithContext returns the IDs of the migrations that haven't been applied yet.
+func (m *Migrator) PendingWithContext(ctx context.Context) ([]string, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := m.db.Table(m.meta).Describe().RunWithContext(ctx); err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return m.ids(), nil
+		}
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, mig := range m.migrations {
+		if !applied[mig.ID] {
+			pending = append(pending, mig.ID)
+		}
+	}
+	return pending, nil
+}
+
+// Run applies pending migrations in order, returning the IDs of those applied.
+// It stops at the first migration that fails, which is not recorded and will be retried by the next run.
+// Only one Migrator can run at a time: Run takes a lock in the metadata table first,
+// and returns ErrMigrationLocked if another runner holds it.
+// Schema changes and backfills can take much longer than RetryTimeout, so Run has no deadline;
+// use RunWithContext to limit it.
+func (m *Migrator) Run() ([]string, error) {
+	return m.RunWithContext(context.Background())
+}
+
+// RunWithContext applies pending migrations in order, returning the IDs of those applied.
+// It stops at the first migration that fails, which is not recorded and will be retried by the next run.
+// Only one Migrator can run at a time: RunWithContext takes a lock in the metadata table first,
+// and returns ErrMigrationLocked if another runner holds it.
+func (m *Migrator) RunWithContext(ctx context.Context) (done []string, err error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if err := m.createMeta(ctx); err != nil {
+		return nil, err
+	}
+	lease, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if unlockErr := lease.release(); err == nil {
+			err = unlockErr
+		}
+	}()
+	ctx = lease.ctx
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, lease.cause(err)
+	}
+	for _, mig := range m.migrations {
+		if applied[mig.ID] {
+			continue
+		}
+		if err := m.apply(ctx, mig); err != nil {
+			return done, fmt.Errorf("dynamo: migration %s: %v", mig.ID, lease.cause(err))
+		}
+		done = append(done, mig.ID)
+	}
+	return done, nil
+}
+
+func (m *Migrator) validate() error {
+	seen := make(map[string]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		if mig.ID == "" {
+			return fmt.Errorf("dynamo: migration without an ID")
+		}
+		if mig.ID == migrationLockID {
+			return fmt.Errorf("dynamo: migration ID %s is reserved", mig.ID)
+		}
+		if seen[mig.ID] {
+			return fmt.Errorf("dynamo: duplicate migration %s", mig.ID)
+		}
+		seen[mig.ID] = true
+		if mig.Schema != nil {
+			if err := mig.Schema.Validate(); err != nil {
+				return fmt.Errorf("dynamo: migration %s: %v", mig.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) ids() []string {
+	ids := make([]string, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		ids = append(ids, mig.ID)
+	}
+	return ids
+}
+
+// createMeta creates the metadata table if it doesn't exist and waits for it to become active.
+func (m *Migrator) createMeta(ctx context.Context) error {
+	et := m.db.EnsureTable(m.meta, migrationRecord{}, EnsureOptions{
+		OnDemand:     true,
+		PollInterval: m.opts.PollInterval,
+	})
+	plan, err := et.RunWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !plan.Empty() {
+		return et.wait(ctx)
+	}
+	return nil
+}
+
+// applied returns the IDs of applied migrations.
+func (m *Migrator) applied(ctx context.Context) (map[string]bool, error) {
+	var records []migrationRecord
+	if err := m.db.Table(m.meta).Scan().Consistent(true).AllWithContext(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool, len(records))
+	for _, rec := range records {
+		if rec.ID != migrationLockID {
+			applied[rec.ID] = true
+		}
+	}
+	return applied, nil
+}
+
+// migrationLease is the lock of a running Migrator, renewed in the background until released.
+type migrationLease struct {
+	m     *Migrator
+	owner string
+	// ctx is canceled when the lease is released or lost
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	// lost is why the lease was lost, set before done is closed
+	lost error
+}
+
+// lock takes the lock item in the metadata table, if it doesn't exist or has expired.
+func (m *Migrator) lock(ctx context.Context) (*migrationLease, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	lease := m.lease
+	if lease <= 0 {
+		lease = defaultMigrationLease
+	}
+	now := m.db.now()
+	lock := migrationLock{
+		ID:      migrationLockID,
+		Owner:   id.String(),
+		Expires: now.Add(lease),
+	}
+	err = m.db.Table(m.meta).Put(lock).
+		If("attribute_not_exists($) OR $ < ?", "ID", "Expires", now.Unix()).
+		RunWithContext(ctx)
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil, ErrMigrationLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l := &migrationLease{
+		m:     m,
+		owner: lock.Owner,
+		done:  make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	go l.renew(lease)
+	return l, nil
+}
+
+// renew extends the lease every third of its duration, and cancels its context if that fails.
+func (l *migrationLease) renew(lease time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := l.m.db.Table(l.m.meta).Update("ID", migrationLockID).
+			Set("Expires", l.m.db.now().Add(lease).Unix()).
+			If("$ = ?", "Owner", l.owner).
+			RunWithContext(l.ctx)
+		if err != nil && l.ctx.Err() == nil {
+			l.lost = fmt.Errorf("dynamo: lost migration lock: %v", err)
+			l.cancel()
+			return
+		}
+	}
+}
+
+// cause returns why the lease was lost if it was, or err otherwise.
+func (l *migrationLease) cause(err error) error {
+	select {
+	case <-l.done:
+		if l.lost != nil {
+			return l.lost
+		}
+	default:
+	}
+	return err
+}
+
+// release stops renewing the lease and deletes the lock item, unless another runner has taken it.
+func (l *migrationLease) release() error {
+	l.cancel()
+	<-l.done
+	if l.lost != nil {
+		return nil
+	}
+	ctx, cancel := defaultContext()
+	defer cancel()
+	err := l.m.db.Table(l.m.meta).Delete("ID", migrationLockID).If("$ = ?", "Owner", l.owner).RunWithContext(ctx)
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
+
+func (m *Migrator) apply(ctx context.Context, mig Migration) error {
+	if mig.Schema != nil {
+		for _, ts := range mig.Schema.Tables {
+			et := ts.EnsureTable(m.db, m.opts)
+			plan, err := et.RunWithContext(ctx)
+			if err != nil {
+				return err
+			}
+			if !plan.Empty() {
+				if err := et.wait(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if mig.Backfill != nil {
+		if err := mig.Backfill(ctx, m.db); err != nil {
+			return fmt.Errorf("backfill: %v", err)
+		}
+	}
+	rec := migrationRecord{
+		ID:          mig.ID,
+		Description: mig.Description,
+		Applied:     m.db.now(),
+	}
+	return m.db.Table(m.meta).Put(rec).If("attribute_not_exists($)", "ID").RunWithContext(ctx)
+}
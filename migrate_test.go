@@ -0,0 +1,206 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestMigrator(t *testing.T) {
+	client := newFakeStore()
+	db := NewFromIface(client)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.SetClock(func() time.Time { return now })
+
+	schema, err := ParseSchema([]byte(ordersYAML), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backfilled []string
+	migrations := []Migration{
+		{ID: "0001-orders", Description: "create orders", Schema: schema},
+		{ID: "0002-backfill", Backfill: func(ctx context.Context, db *DB) error {
+			backfilled = append(backfilled, "0002")
+			return nil
+		}},
+	}
+	m := db.Migrator("Migrations", migrations...).Options(EnsureOptions{PollInterval: time.Millisecond})
+
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pending, []string{"0001-orders", "0002-backfill"}) {
+		t.Errorf("bad pending: %v", pending)
+	}
+	if client.tables["Migrations"].created != nil {
+		t.Error("Pending created the metadata table")
+	}
+
+	applied, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, []string{"0001-orders", "0002-backfill"}) {
+		t.Errorf("bad applied: %v", applied)
+	}
+	meta := client.tables["Migrations"].created
+	if len(meta) != 1 || meta[0].BillingMode != types.BillingModePayPerRequest {
+		t.Errorf("bad metadata table creation: %#v", meta)
+	}
+	orders := client.tables["Orders"]
+	if len(orders.created) != 1 || len(orders.ttlUpdate) != 1 {
+		t.Errorf("bad Orders calls: %d creates, %d TTL updates", len(orders.created), len(orders.ttlUpdate))
+	}
+	if len(backfilled) != 1 {
+		t.Errorf("want 1 backfill, got %d", len(backfilled))
+	}
+	var records []migrationRecord
+	if err := db.Table("Migrations").Scan().All(&records); err != nil {
+		t.Fatal(err)
+	}
+	want := []migrationRecord{
+		{ID: "0001-orders", Description: "create orders", Applied: now},
+		{ID: "0002-backfill", Applied: now},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("bad records.\nwant: %#v\ngot:  %#v", want, records)
+	}
+
+	// everything is applied already
+	applied, err = m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || len(backfilled) != 1 {
+		t.Errorf("reapplied migrations: %v", applied)
+	}
+	if pending, err := m.Pending(); err != nil || len(pending) != 0 {
+		t.Errorf("want no pending migrations, got %v, %v", pending, err)
+	}
+}
+
+func TestMigratorFailure(t *testing.T) {
+	client := newFakeStore()
+	db := NewFromIface(client)
+	fail := errors.New("oops")
+	var ran []string
+	m := db.Migrator("Migrations",
+		Migration{ID: "1", Backfill: func(ctx context.Context, db *DB) error {
+			ran = append(ran, "1")
+			return nil
+		}},
+		Migration{ID: "2", Backfill: func(ctx context.Context, db *DB) error {
+			return fail
+		}},
+		Migration{ID: "3", Backfill: func(ctx context.Context, db *DB) error {
+			ran = append(ran, "3")
+			return nil
+		}},
+	).Options(EnsureOptions{PollInterval: time.Millisecond})
+
+	applied, err := m.Run()
+	if err == nil || !strings.Contains(err.Error(), "migration 2: backfill: oops") {
+		t.Errorf("bad error: %v", err)
+	}
+	if !reflect.DeepEqual(applied, []string{"1"}) || !reflect.DeepEqual(ran, []string{"1"}) {
+		t.Errorf("bad applied: %v, ran: %v", applied, ran)
+	}
+	if len(client.items["Migrations"]) != 1 {
+		t.Errorf("want 1 record, got %d", len(client.items["Migrations"]))
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pending, []string{"2", "3"}) {
+		t.Errorf("bad pending: %v", pending)
+	}
+
+	dup := db.Migrator("Migrations", Migration{ID: "1"}, Migration{ID: "1"})
+	if _, err := dup.Run(); err == nil || !strings.Contains(err.Error(), "duplicate migration") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+	if _, err := db.Migrator("Migrations", Migration{}).Pending(); err == nil {
+		t.Error("expected error for migration without an ID")
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	client := newFakeStore()
+	db := NewFromIface(client)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.SetClock(func() time.Time { return now })
+	var ran int
+	m := db.Migrator("Migrations", Migration{ID: "1", Backfill: func(ctx context.Context, db *DB) error {
+		ran++
+		return nil
+	}}).Options(EnsureOptions{PollInterval: time.Millisecond})
+
+	// another runner holds the lock
+	other := migrationLock{ID: migrationLockID, Owner: "other", Expires: now.Add(time.Minute)}
+	if err := db.Table("Migrations").Put(other).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(); err != ErrMigrationLocked {
+		t.Errorf("want ErrMigrationLocked, got %v", err)
+	}
+	if ran != 0 {
+		t.Error("ran a migration without the lock")
+	}
+
+	// its lease expired
+	now = now.Add(2 * time.Minute)
+	applied, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, []string{"1"}) || ran != 1 {
+		t.Errorf("bad applied: %v, ran %d times", applied, ran)
+	}
+	var records []migrationRecord
+	if err := db.Table("Migrations").Scan().All(&records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != "1" {
+		t.Errorf("lock wasn't released: %#v", records)
+	}
+
+	if _, err := db.Migrator("Migrations", Migration{ID: migrationLockID}).Run(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("want reserved ID error, got %v", err)
+	}
+}
+
+func TestMigratorLostLock(t *testing.T) {
+	client := newFakeStore()
+	db := NewFromIface(client)
+	m := db.Migrator("Migrations", Migration{ID: "1", Backfill: func(ctx context.Context, db *DB) error {
+		// another runner takes over the lock
+		stolen := migrationLock{ID: migrationLockID, Owner: "other", Expires: time.Now().Add(time.Minute)}
+		if err := db.Table("Migrations").Put(stolen).Run(); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}}).Options(EnsureOptions{PollInterval: time.Millisecond}).Lease(30 * time.Millisecond)
+
+	applied, err := m.Run()
+	if err == nil || !strings.Contains(err.Error(), "lost migration lock") {
+		t.Errorf("want lost lock error, got %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("bad applied: %v", applied)
+	}
+	var locks []migrationLock
+	if err := db.Table("Migrations").Scan().All(&locks); err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 1 || locks[0].Owner != "other" {
+		t.Errorf("released another runner's lock: %#v", locks)
+	}
+}
@@ -0,0 +1,39 @@
+package dynamo
+
+import (
+	"reflect"
+)
+
+// visitFields calls fn for each encoded field of item, which may be a struct or pointer to a struct,
+// including the fields of embedded structs and inline fields, whose names are prefixed as they are when encoded.
+// Fields are settable if item was given as a pointer.
+// Visiting stops when fn returns false or an error.
+func visitFields(item interface{}, fn func(field reflect.StructField, fv reflect.Value, name string, flags encodeFlags) (bool, error)) error {
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, f := range codecOf(rv.Type()).fields {
+		fv, ok := fieldForEncode(rv, f.index)
+		if !ok || !fv.CanInterface() {
+			continue
+		}
+		more, err := fn(rv.Type().FieldByIndex(f.index), fv, f.name, f.flags)
+		if !more || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// committer is a write operation that updates its original item after it succeeds,
+// such as by incrementing its version.
+type committer interface {
+	commit()
+}
@@ -0,0 +1,288 @@
+package dynamo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Number is a DynamoDB number kept in its string form, such as "3.14".
+// Unlike Go's numeric types, it holds numbers with up to DynamoDB's 38 digits of precision without loss.
+// It is encoded as N (or NS, in sets), and an empty Number is omitted.
+// json.Number is supported in the same way, as are *big.Int, *big.Float and *big.Rat.
+type Number string
+
+// String returns the number as a string.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Uint64 returns the number as a uint64.
+func (n Number) Uint64() (uint64, error) {
+	return strconv.ParseUint(string(n), 10, 64)
+}
+
+// Float64 returns the number as a float64, which may lose precision.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// BigInt returns the number as a *big.Int. It returns an error if the number isn't an integer.
+func (n Number) BigInt() (*big.Int, error) {
+	return parseBigInt(string(n))
+}
+
+// BigFloat returns the number as a *big.Float,
+// with enough precision to hold all of its digits.
+func (n Number) BigFloat() (*big.Float, error) {
+	return parseBigFloat(string(n))
+}
+
+// BigRat returns the number as a *big.Rat.
+func (n Number) BigRat() (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(string(n))
+	if !ok {
+		return nil, fmt.Errorf("dynamo: invalid number %q", string(n))
+	}
+	return r, nil
+}
+
+var (
+	numberType     = reflect.TypeOf(Number(""))
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigFloatType   = reflect.TypeOf(big.Float{})
+	bigRatType     = reflect.TypeOf(big.Rat{})
+)
+
+// isNumberType returns true for the arbitrary-precision number types:
+// Number, json.Number, and big.Int, big.Float and big.Rat or pointers to them.
+func isNumberType(rt reflect.Type) bool {
+	switch rt {
+	case numberType, jsonNumberType:
+		return true
+	}
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch rt {
+	case bigIntType, bigFloatType, bigRatType:
+		return true
+	}
+	return false
+}
+
+// marshalNumber encodes v, which must be of a number type, as N.
+// Nil pointers and empty strings are omitted.
+func marshalNumber(v interface{}, flags encodeFlags) (types.AttributeValue, error) {
+	s, ok, err := formatNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if flags&flagNull != 0 {
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		}
+		return nil, nil
+	}
+	return &types.AttributeValueMemberN{Value: s}, nil
+}
+
+// marshalNumberSet encodes values, which must be of a number type, as NS.
+// Nil pointers and empty strings are skipped.
+func marshalNumberSet(values []reflect.Value) (types.AttributeValue, error) {
+	ns := make([]string, 0, len(values))
+	for _, rv := range values {
+		s, ok, err := formatNumber(rv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ns = append(ns, s)
+		}
+	}
+	if len(ns) == 0 {
+		return nil, nil
+	}
+	return &types.AttributeValueMemberNS{Value: ns}, nil
+}
+
+// formatNumber formats v, which must be of a number type, as a DynamoDB number.
+// It returns false if v is a nil pointer or an empty string.
+func formatNumber(v interface{}) (string, bool, error) {
+	var s string
+	switch x := v.(type) {
+	case Number:
+		s = string(x)
+	case json.Number:
+		s = string(x)
+	case big.Int:
+		return formatNumber(&x)
+	case big.Float:
+		return formatNumber(&x)
+	case big.Rat:
+		return formatNumber(&x)
+	case *big.Int:
+		if x == nil {
+			return "", false, nil
+		}
+		return x.String(), true, nil
+	case *big.Float:
+		if x == nil {
+			return "", false, nil
+		}
+		if x.IsInf() {
+			return "", false, fmt.Errorf("dynamo: cannot marshal %s as a number", x.String())
+		}
+		return x.Text('f', -1), true, nil
+	case *big.Rat:
+		if x == nil {
+			return "", false, nil
+		}
+		return formatRat(x)
+	default:
+		return "", false, fmt.Errorf("dynamo: cannot marshal %T as a number", v)
+	}
+	if s == "" {
+		return "", false, nil
+	}
+	if !validNumber(s) {
+		return "", false, fmt.Errorf("dynamo: cannot marshal %q as a number", s)
+	}
+	return s, true, nil
+}
+
+// formatRat formats r as an exact decimal.
+// Fractions without a finite decimal form, such as 1/3, are an error.
+func formatRat(r *big.Rat) (string, bool, error) {
+	// the decimal form is finite if the denominator only has factors of 2 and 5,
+	// and it needs as many digits as the larger count of either
+	denom := new(big.Int).Set(r.Denom())
+	var twos, fives int
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	for {
+		if q, m := new(big.Int).QuoRem(denom, two, mod); m.Sign() == 0 {
+			denom, twos = q, twos+1
+			continue
+		}
+		if q, m := new(big.Int).QuoRem(denom, five, mod); m.Sign() == 0 {
+			denom, fives = q, fives+1
+			continue
+		}
+		break
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return "", false, fmt.Errorf("dynamo: cannot marshal %s as a number: no exact decimal form", r.String())
+	}
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return r.FloatString(digits), true, nil
+}
+
+// validNumber returns true if s is a decimal number, with an optional sign, fraction and exponent.
+func validNumber(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		exp := 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			exp++
+		}
+		if exp == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// unmarshalNumber decodes av into out if out points to a number type and av is N.
+// It returns false if it didn't handle out.
+func unmarshalNumber(av types.AttributeValue, out interface{}) (bool, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return false, nil
+	}
+	switch x := out.(type) {
+	case *Number:
+		*x = Number(n.Value)
+	case *json.Number:
+		*x = json.Number(n.Value)
+	case *big.Int:
+		v, err := parseBigInt(n.Value)
+		if err != nil {
+			return true, err
+		}
+		x.Set(v)
+	case *big.Float:
+		v, err := parseBigFloat(n.Value)
+		if err != nil {
+			return true, err
+		}
+		// keeps x's precision if it has one
+		x.Set(v)
+	case *big.Rat:
+		if _, ok := x.SetString(n.Value); !ok {
+			return true, fmt.Errorf("dynamo: cannot unmarshal %s into big.Rat", n.Value)
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseBigInt parses s, which may be written with a fraction or exponent as long as it is an integer.
+func parseBigInt(s string) (*big.Int, error) {
+	if i, ok := new(big.Int).SetString(s, 10); ok {
+		return i, nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || !r.IsInt() {
+		return nil, fmt.Errorf("dynamo: cannot unmarshal %s into big.Int", s)
+	}
+	return r.Num(), nil
+}
+
+// parseBigFloat parses s with enough precision to hold all of its digits.
+func parseBigFloat(s string) (*big.Float, error) {
+	// a decimal digit needs less than 4 bits
+	prec := uint(len(s)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: cannot unmarshal %s into big.Float: %v", s, err)
+	}
+	return f, nil
+}
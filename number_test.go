@@ -0,0 +1,173 @@
+package dynamo
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const bigNumber = "12345678901234567890123456789012345678"
+
+type numbers struct {
+	Num    Number
+	JSON   json.Number
+	Int    *big.Int
+	Float  *big.Float
+	Rat    *big.Rat
+	IntVal big.Int
+	Empty  Number
+	Nil    *big.Int `dynamo:",null"`
+
+	NumSet  []Number             `dynamo:",set"`
+	IntSet  []*big.Int           `dynamo:",set"`
+	JSONSet map[json.Number]bool `dynamo:",set"`
+}
+
+func TestNumberEncoding(t *testing.T) {
+	in := numbers{
+		Num:     Number(bigNumber),
+		JSON:    json.Number("-1.5e-130"),
+		Int:     mustBigInt(t, bigNumber),
+		Float:   big.NewFloat(2.5),
+		Rat:     big.NewRat(1, 8),
+		IntVal:  *big.NewInt(7),
+		NumSet:  []Number{"1", bigNumber},
+		IntSet:  []*big.Int{big.NewInt(1), nil, mustBigInt(t, bigNumber)},
+		JSONSet: map[json.Number]bool{"3.25": true, "4": false},
+	}
+	item, err := marshalItem(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]types.AttributeValue{
+		"Num":     &types.AttributeValueMemberN{Value: bigNumber},
+		"JSON":    &types.AttributeValueMemberN{Value: "-1.5e-130"},
+		"Int":     &types.AttributeValueMemberN{Value: bigNumber},
+		"Float":   &types.AttributeValueMemberN{Value: "2.5"},
+		"Rat":     &types.AttributeValueMemberN{Value: "0.125"},
+		"IntVal":  &types.AttributeValueMemberN{Value: "7"},
+		"Nil":     &types.AttributeValueMemberNULL{Value: true},
+		"NumSet":  &types.AttributeValueMemberNS{Value: []string{"1", bigNumber}},
+		"IntSet":  &types.AttributeValueMemberNS{Value: []string{"1", bigNumber}},
+		"JSONSet": &types.AttributeValueMemberNS{Value: []string{"3.25"}},
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, item)
+	}
+
+	var out numbers
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Num != in.Num || out.JSON != in.JSON {
+		t.Errorf("bad strings: %q %q", out.Num, out.JSON)
+	}
+	if out.Int.Cmp(in.Int) != 0 || out.IntVal.Cmp(&in.IntVal) != 0 {
+		t.Errorf("bad ints: %v %v", out.Int, &out.IntVal)
+	}
+	if out.Float.Cmp(in.Float) != 0 || out.Rat.Cmp(in.Rat) != 0 {
+		t.Errorf("bad fractions: %v %v", out.Float, out.Rat)
+	}
+	if out.Nil != nil {
+		t.Errorf("bad nil: %v", out.Nil)
+	}
+	if !reflect.DeepEqual(out.NumSet, in.NumSet) {
+		t.Errorf("bad number set: %v", out.NumSet)
+	}
+	if len(out.IntSet) != 2 || out.IntSet[1].String() != bigNumber {
+		t.Errorf("bad big.Int set: %v", out.IntSet)
+	}
+	if !reflect.DeepEqual(out.JSONSet, map[json.Number]bool{"3.25": true}) {
+		t.Errorf("bad json.Number set: %v", out.JSONSet)
+	}
+}
+
+func TestNumberDecoding(t *testing.T) {
+	// big.Int accepts integers written with a fraction or exponent
+	var i big.Int
+	if err := Unmarshal(&types.AttributeValueMemberN{Value: "1.2e3"}, &i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Int64() != 1200 {
+		t.Errorf("bad big.Int: %v", &i)
+	}
+	if err := Unmarshal(&types.AttributeValueMemberN{Value: "1.5"}, &i); err == nil {
+		t.Error("expected error for fraction into big.Int, got nil")
+	}
+
+	// big.Float keeps all digits
+	f := new(big.Float)
+	if err := Unmarshal(&types.AttributeValueMemberN{Value: bigNumber + ".5"}, f); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Text('f', -1); got != bigNumber+".5" {
+		t.Errorf("bad big.Float. want: %s got: %s", bigNumber+".5", got)
+	}
+
+	// strings, as previously encoded by MarshalText, still work
+	if err := Unmarshal(&types.AttributeValueMemberS{Value: "42"}, &i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Int64() != 42 {
+		t.Errorf("bad big.Int from string: %v", &i)
+	}
+
+	var n Number
+	if err := Unmarshal(&types.AttributeValueMemberN{Value: bigNumber}, &n); err != nil {
+		t.Fatal(err)
+	}
+	if bi, err := n.BigInt(); err != nil || bi.String() != bigNumber {
+		t.Errorf("bad Number.BigInt: %v %v", bi, err)
+	}
+	if _, err := n.Int64(); err == nil {
+		t.Error("expected overflow error from Number.Int64, got nil")
+	}
+}
+
+func TestNumberInvalid(t *testing.T) {
+	tests := []interface{}{
+		Number("abc"),
+		json.Number("1e"),
+		Number("."),
+		big.NewRat(1, 3),
+		new(big.Float).SetInf(false),
+	}
+	for _, in := range tests {
+		if _, err := Marshal(in); err == nil {
+			t.Errorf("%v: expected error, got nil", in)
+		}
+	}
+}
+
+func TestFormatRat(t *testing.T) {
+	tests := []struct {
+		in   *big.Rat
+		want string
+	}{
+		{big.NewRat(3, 1), "3"},
+		{big.NewRat(-1, 4), "-0.25"},
+		{big.NewRat(7, 20), "0.35"},
+		{big.NewRat(1, 1024), "0.0009765625"},
+	}
+	for _, tc := range tests {
+		got, _, err := formatRat(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%v: want %s, got %s", tc.in, tc.want, got)
+		}
+	}
+}
+
+func mustBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad big.Int: %s", s)
+	}
+	return i
+}
@@ -0,0 +1,430 @@
+package dynamo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gofrs/uuid"
+)
+
+// BlobStore stores the values of fields tagged with offload that are too large to keep in their items,
+// such as in S3 or on a filesystem. See DB.SetBlobStore.
+type BlobStore interface {
+	// Put stores data under key, replacing any existing data.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored under key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the data stored under key.
+	// Deleting a key that doesn't exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// OffloadOptions control when fields tagged with offload are moved to the BlobStore.
+type OffloadOptions struct {
+	// Threshold is the size in bytes of an attribute, as calculated by ItemSize,
+	// over which it is moved to the store. Smaller values are kept in the item.
+	// If zero, every value is moved.
+	Threshold int
+	// OnOrphan is called with the key of every blob that a deleted or overwritten item pointed to,
+	// and of every blob found by SweepBlobs. If nil, the blob is deleted from the store.
+	OnOrphan func(ctx context.Context, key string) error
+}
+
+// BlobLister is implemented by BlobStores that can list their keys, as needed by Table.SweepBlobs.
+type BlobLister interface {
+	// List returns the keys of the blobs whose keys start with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ErrNoBlobStore is returned when unmarshaling an offloaded attribute without a BlobStore.
+var ErrNoBlobStore = errors.New("dynamo: offloaded attribute requires a BlobStore (see DB.SetBlobStore)")
+
+// offloadHeader starts every pointer to an offloaded attribute: a magic number and the format version.
+// It is followed by the attribute's key in the store, which holds its value in DynamoDB JSON.
+var offloadHeader = []byte{'d', 'o', 1}
+
+// offloader moves attributes to and from a BlobStore.
+type offloader struct {
+	store BlobStore
+	opts  OffloadOptions
+}
+
+// SetBlobStore sets the BlobStore that holds the values of fields tagged with offload.
+// When a put or update sets an offloaded field to a value over opts.Threshold,
+// the value is written to the store and the item gets a binary pointer attribute in its place.
+// Pointers are resolved when unmarshaling, except into fields of type Blob, which are loaded on demand.
+// Deleting or overwriting an item with Delete, Put or Update calls opts.OnOrphan for the pointers
+// of its offloaded fields that are no longer used, if the fields are known from the item's type,
+// the table's model or registry, or Update.Model.
+// Batch writes, transactions and writes that fail don't clean up blobs;
+// use Table.SweepBlobs to find the blobs they leave behind.
+// Without a BlobStore, offloaded fields are kept in their items.
+func (db *DB) SetBlobStore(store BlobStore, opts OffloadOptions) {
+	if store == nil {
+		db.blobs = nil
+		return
+	}
+	db.blobs = &offloader{store: store, opts: opts}
+}
+
+func (db *DB) offloader() *offloader {
+	if db == nil {
+		return nil
+	}
+	return db.blobs
+}
+
+// blobKey returns the key of av if it points to an offloaded attribute.
+func blobKey(av types.AttributeValue) (string, bool) {
+	b, ok := av.(*types.AttributeValueMemberB)
+	if !ok || !bytes.HasPrefix(b.Value, offloadHeader) {
+		return "", false
+	}
+	return string(b.Value[len(offloadHeader):]), true
+}
+
+func blobPointer(key string) types.AttributeValue {
+	return &types.AttributeValueMemberB{Value: append(append([]byte{}, offloadHeader...), key...)}
+}
+
+// offload writes av to the store if the attribute name is over the threshold,
+// returning a pointer to it, or av if it is kept in the item.
+func (o *offloader) offload(ctx context.Context, table, name string, av types.AttributeValue) (types.AttributeValue, error) {
+	if o == nil || av == nil || len(name)+avSize(av) <= o.opts.Threshold {
+		return av, nil
+	}
+	if _, ok := blobKey(av); ok {
+		return av, nil
+	}
+	obj, err := av2dynamoJSON(av)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	key := table + "/" + id.String()
+	if err := o.store.Put(ctx, key, data); err != nil {
+		return nil, fmt.Errorf("dynamo: offload %s: %v", name, err)
+	}
+	return blobPointer(key), nil
+}
+
+// offloadItem offloads the attributes of item that belong to offloaded fields.
+func (o *offloader) offloadItem(ctx context.Context, table string, names []string, item map[string]types.AttributeValue) error {
+	for _, name := range names {
+		av, ok := item[name]
+		if !ok {
+			continue
+		}
+		var err error
+		if item[name], err = o.offload(ctx, table, name, av); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load returns the offloaded attribute stored under key.
+func (o *offloader) load(ctx context.Context, key string) (types.AttributeValue, error) {
+	if o == nil {
+		return nil, ErrNoBlobStore
+	}
+	data, err := o.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: load offloaded attribute %s: %v", key, err)
+	}
+	return dynamoJSON2av(data)
+}
+
+// orphan releases the blobs that the offloaded attributes names of old point to,
+// unless current, the item that replaced old (if any), still points to them.
+func (o *offloader) orphan(ctx context.Context, names []string, old, current map[string]types.AttributeValue) error {
+	if o == nil {
+		return nil
+	}
+	for _, name := range names {
+		key, ok := blobKey(old[name])
+		if !ok {
+			continue
+		}
+		if kept, ok := blobKey(current[name]); ok && kept == key {
+			continue
+		}
+		if err := o.release(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release calls OnOrphan for key, or deletes it from the store.
+func (o *offloader) release(ctx context.Context, key string) error {
+	var err error
+	if o.opts.OnOrphan != nil {
+		err = o.opts.OnOrphan(ctx, key)
+	} else {
+		err = o.store.Delete(ctx, key)
+	}
+	if err != nil {
+		return fmt.Errorf("dynamo: clean up offloaded attribute %s: %v", key, err)
+	}
+	return nil
+}
+
+// offloadedFields returns the names of the fields of v's type tagged with offload.
+func offloadedFields(v interface{}) []string {
+	rt := reflect.TypeOf(v)
+	if rt == nil {
+		return nil
+	}
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil
+	}
+	return codecOf(rt).offloaded
+}
+
+// Blob is the value of an offloaded field that is loaded on demand.
+// Unmarshaling an item whose Blob field points to the BlobStore only remembers the pointer,
+// and marshaling a Blob that hasn't been loaded writes the same pointer back,
+// so items can be read and rewritten without fetching their blobs.
+// Blob fields can't be tagged with compress or encrypt.
+type Blob struct {
+	data   []byte
+	key    string
+	loaded bool
+	blobs  *offloader
+}
+
+// NewBlob returns a Blob holding data.
+func NewBlob(data []byte) Blob {
+	return Blob{data: data, loaded: true}
+}
+
+// Load returns the blob's data, fetching it from the BlobStore if needed.
+func (b *Blob) Load(ctx context.Context) ([]byte, error) {
+	if b.loaded || b.key == "" {
+		return b.data, nil
+	}
+	av, err := b.blobs.load(ctx, b.key)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := av.(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, fmt.Errorf("dynamo: offloaded attribute %s: Blob requires binary data, got %s", b.key, avTypeName(av))
+	}
+	b.data, b.loaded = v.Value, true
+	return b.data, nil
+}
+
+// Key returns the blob's key in the BlobStore, or "" if it was stored in its item.
+func (b Blob) Key() string {
+	return b.key
+}
+
+// Loaded returns true if the blob's data is available without fetching it.
+func (b Blob) Loaded() bool {
+	return b.loaded || b.key == ""
+}
+
+// IsZero returns true if the blob is empty.
+func (b Blob) IsZero() bool {
+	return b.key == "" && len(b.data) == 0
+}
+
+// MarshalDynamo encodes the blob's data as binary, or as a pointer to the BlobStore if it wasn't loaded.
+func (b Blob) MarshalDynamo() (types.AttributeValue, error) {
+	if !b.Loaded() {
+		return blobPointer(b.key), nil
+	}
+	if len(b.data) == 0 {
+		return nil, nil
+	}
+	return &types.AttributeValueMemberB{Value: b.data}, nil
+}
+
+// UnmarshalDynamo decodes binary data or a pointer to the BlobStore.
+func (b *Blob) UnmarshalDynamo(av types.AttributeValue) error {
+	if key, ok := blobKey(av); ok {
+		*b = Blob{key: key}
+		return nil
+	}
+	v, ok := av.(*types.AttributeValueMemberB)
+	if !ok {
+		return fmt.Errorf("dynamo: cannot unmarshal %s into Blob", avTypeName(av))
+	}
+	*b = NewBlob(v.Value)
+	return nil
+}
+
+var blobType = reflect.TypeOf(Blob{})
+
+// FileBlobStore is a BlobStore that keeps blobs as files in a directory.
+// It is meant for tests and local development.
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore returns a BlobStore that keeps blobs in dir, which is created if needed.
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+func (fs *FileBlobStore) path(key string) (string, error) {
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("dynamo: invalid blob key %q", key)
+		}
+	}
+	return filepath.Join(fs.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes data to the file for key.
+func (fs *FileBlobStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Get reads the file for key.
+func (fs *FileBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+// Delete removes the file for key.
+func (fs *FileBlobStore) Delete(_ context.Context, key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the keys of the files under dir that start with prefix.
+func (fs *FileBlobStore) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(fs.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == fs.dir {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(fs.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+var (
+	_ BlobStore  = (*FileBlobStore)(nil)
+	_ BlobLister = (*FileBlobStore)(nil)
+)
+
+// SweepBlobs is a request to clean up the blobs of a table that none of its items point to,
+// such as those left behind by batch writes and transactions.
+type SweepBlobs struct {
+	table Table
+}
+
+// SweepBlobs creates a new request to clean up this table's unused blobs.
+// The table must have a model or registry with offloaded fields (see Table.WithModel),
+// and the DB's BlobStore must implement BlobLister.
+// Blobs are listed before the table is scanned, but a blob written just before an item
+// that the scan has already passed would still be cleaned up,
+// so sweep while the table isn't being written, or use OnOrphan to delay deletion.
+func (table Table) SweepBlobs() *SweepBlobs {
+	return &SweepBlobs{table: table}
+}
+
+// Run executes this request, returning the number of blobs cleaned up.
+// Sweeping scans the whole table, so Run doesn't time out; use RunWithContext to limit it.
+func (s *SweepBlobs) Run() (int, error) {
+	return s.RunWithContext(context.Background())
+}
+
+// RunWithContext executes this request, returning the number of blobs cleaned up.
+func (s *SweepBlobs) RunWithContext(ctx context.Context) (int, error) {
+	o := s.table.db.offloader()
+	if o == nil {
+		return 0, ErrNoBlobStore
+	}
+	lister, ok := o.store.(BlobLister)
+	if !ok {
+		return 0, fmt.Errorf("dynamo: sweep blobs: %T doesn't implement BlobLister", o.store)
+	}
+	names := s.table.offloaded()
+	if len(names) == 0 {
+		return 0, fmt.Errorf("dynamo: sweep blobs: table %s has no model with offloaded fields (see Table.WithModel)", s.table.name)
+	}
+
+	// list first, so blobs written during the scan are kept
+	keys, err := lister.List(ctx, s.table.name+"/")
+	if err != nil {
+		return 0, fmt.Errorf("dynamo: sweep blobs: %v", err)
+	}
+	used := make(map[string]bool)
+	iter := s.table.Scan().Project(names...).Consistent(true).Iter()
+	var item map[string]types.AttributeValue
+	for iter.NextWithContext(ctx, &item) {
+		for _, name := range names {
+			if key, ok := blobKey(item[name]); ok {
+				used[key] = true
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		if err := o.release(ctx, key); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,353 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type document struct {
+	ID         string `dynamo:",hash"`
+	Title      string
+	Body       string   `dynamo:",offload"`
+	Pages      []string `dynamo:",offload,compress"`
+	Attachment Blob     `dynamo:",offload"`
+}
+
+func testBlobStore(t *testing.T) (*FileBlobStore, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dynamo-blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewFileBlobStore(dir), dir
+}
+
+func TestOffloadPut(t *testing.T) {
+	store, dir := testBlobStore(t)
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetBlobStore(store, OffloadOptions{Threshold: 100})
+	table := db.Table("Docs")
+
+	long := strings.Repeat("lorem ipsum ", 100)
+	in := document{
+		ID:         "d1",
+		Title:      long,
+		Body:       long,
+		Pages:      []string{"short"},
+		Attachment: NewBlob([]byte(long)),
+	}
+	if err := table.Put(in).Run(); err != nil {
+		t.Fatal(err)
+	}
+	item := client.put.Item
+	if !reflect.DeepEqual(item["Title"], &types.AttributeValueMemberS{Value: long}) {
+		t.Error("untagged field was offloaded")
+	}
+	if _, ok := blobKey(item["Pages"]); ok {
+		t.Error("small value was offloaded")
+	}
+	for _, name := range []string{"Body", "Attachment"} {
+		key, ok := blobKey(item[name])
+		if !ok {
+			t.Fatalf("%s: not offloaded: %#v", name, item[name])
+		}
+		if !strings.HasPrefix(key, "Docs/") {
+			t.Errorf("%s: bad key: %s", name, key)
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key))); err != nil {
+			t.Errorf("%s: blob not written: %v", name, err)
+		}
+	}
+
+	// offloaded values are fetched eagerly, except into Blob fields
+	var out document
+	if err := db.decoder().unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Body != long || out.Title != long || !reflect.DeepEqual(out.Pages, in.Pages) {
+		t.Errorf("bad round trip: %#v", out)
+	}
+	if out.Attachment.Loaded() || out.Attachment.Key() == "" {
+		t.Error("Blob field loaded eagerly")
+	}
+	data, err := out.Attachment.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != long || !out.Attachment.Loaded() {
+		t.Errorf("bad blob data: %q", data)
+	}
+
+	// unloaded blobs are written back as the same pointer
+	var lazy document
+	if err := db.decoder().unmarshalItem(item, &lazy); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Put(lazy).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(client.put.Item["Attachment"], item["Attachment"]) {
+		t.Errorf("pointer changed: %#v", client.put.Item["Attachment"])
+	}
+
+	// pointers can't be resolved without a store
+	err = unmarshalItem(item, &out)
+	if !errors.Is(err, ErrNoBlobStore) {
+		t.Errorf("expected ErrNoBlobStore, got %v", err)
+	}
+}
+
+func TestOffloadUpdate(t *testing.T) {
+	store, _ := testBlobStore(t)
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetBlobStore(store, OffloadOptions{})
+
+	u := db.Table("Docs").Update("ID", "d1").
+		Set("Body", "hello").
+		Set("Title", "hi").
+		Model(document{})
+	if err := u.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var ptr types.AttributeValue
+	for _, v := range client.update.ExpressionAttributeValues {
+		switch v.(type) {
+		case *types.AttributeValueMemberS:
+		case *types.AttributeValueMemberB:
+			ptr = v
+		}
+	}
+	key, ok := blobKey(ptr)
+	if !ok {
+		t.Fatal("Body not offloaded")
+	}
+
+	// running again doesn't offload twice
+	if err := u.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range client.update.ExpressionAttributeValues {
+		if k, ok := blobKey(v); ok && k != key {
+			t.Error("value offloaded again")
+		}
+	}
+
+	var out document
+	item := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "d1"}, "Body": ptr}
+	if err := db.decoder().unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Body != "hello" {
+		t.Errorf("bad body: %q", out.Body)
+	}
+}
+
+func TestOffloadDelete(t *testing.T) {
+	store, dir := testBlobStore(t)
+	ctx := context.Background()
+	if err := store.Put(ctx, "Docs/a", []byte(`{"S":"hello"}`)); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{old: map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberS{Value: "d1"},
+		"Body": blobPointer("Docs/a"),
+		// binary data that looks like a pointer, but isn't tagged with offload
+		"Raw": blobPointer("Docs/a"),
+	}}
+	db := NewFromIface(client)
+	db.SetBlobStore(store, OffloadOptions{})
+	table := db.Table("Docs").WithModel(document{})
+
+	var orphans []string
+	db.SetBlobStore(store, OffloadOptions{OnOrphan: func(ctx context.Context, key string) error {
+		orphans = append(orphans, key)
+		return nil
+	}})
+	if err := table.Delete("ID", "d1").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if client.del.ReturnValues != types.ReturnValueAllOld {
+		t.Errorf("old item not requested: %v", client.del.ReturnValues)
+	}
+	if !reflect.DeepEqual(orphans, []string{"Docs/a"}) {
+		t.Errorf("bad orphans: %v", orphans)
+	}
+
+	// the old item isn't requested without offloaded fields
+	orphans = nil
+	if err := db.Table("Docs").Delete("ID", "d1").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if client.del.ReturnValues != types.ReturnValueNone || len(orphans) > 0 {
+		t.Errorf("unexpected clean up without a model: %v %v", client.del.ReturnValues, orphans)
+	}
+
+	// Remove knows the item's fields
+	if err := db.Table("Docs").Remove(document{ID: "d1"}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(orphans, []string{"Docs/a"}) {
+		t.Errorf("bad orphans: %v", orphans)
+	}
+
+	db.SetBlobStore(store, OffloadOptions{})
+	var old document
+	if err := table.Delete("ID", "d1").OldValue(&old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Body != "hello" {
+		t.Errorf("bad old value: %q", old.Body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Docs", "a")); !os.IsNotExist(err) {
+		t.Errorf("orphan not deleted: %v", err)
+	}
+}
+
+func TestOffloadOverwrite(t *testing.T) {
+	store, _ := testBlobStore(t)
+	client := &fakeClient{old: map[string]types.AttributeValue{
+		"ID":         &types.AttributeValueMemberS{Value: "d1"},
+		"Body":       blobPointer("Docs/old-body"),
+		"Attachment": blobPointer("Docs/attachment"),
+	}}
+	db := NewFromIface(client)
+	var orphans []string
+	db.SetBlobStore(store, OffloadOptions{Threshold: 100, OnOrphan: func(ctx context.Context, key string) error {
+		orphans = append(orphans, key)
+		return nil
+	}})
+	table := db.Table("Docs")
+
+	// a put replaces Body, but writes back the same Attachment pointer
+	if err := table.Put(document{ID: "d1", Body: "short", Attachment: Blob{key: "Docs/attachment"}}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if client.put.ReturnValues != types.ReturnValueAllOld {
+		t.Errorf("old item not requested: %v", client.put.ReturnValues)
+	}
+	if !reflect.DeepEqual(orphans, []string{"Docs/old-body"}) {
+		t.Errorf("put: bad orphans: %v", orphans)
+	}
+
+	// updates clean up the fields they set or remove
+	orphans = nil
+	err := table.Update("ID", "d1").Set("Body", "short").Remove("Attachment").Model(document{}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.update.ReturnValues != types.ReturnValueUpdatedOld {
+		t.Errorf("old values not requested: %v", client.update.ReturnValues)
+	}
+	if !reflect.DeepEqual(orphans, []string{"Docs/old-body", "Docs/attachment"}) {
+		t.Errorf("update: bad orphans: %v", orphans)
+	}
+
+	// and so do expressions that replace or remove them
+	orphans = nil
+	err = table.WithModel(document{}).Update("ID", "d1").SetExpr("$ = ?", "Body", "short").RemoveExpr("'Attachment'").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.update.ReturnValues != types.ReturnValueUpdatedOld {
+		t.Errorf("old values not requested for expressions: %v", client.update.ReturnValues)
+	}
+	if !reflect.DeepEqual(orphans, []string{"Docs/old-body", "Docs/attachment"}) {
+		t.Errorf("expressions: bad orphans: %v", orphans)
+	}
+	orphans = nil
+	err = table.WithModel(document{}).Update("ID", "d1").SetExpr("Body = if_not_exists(Body, ?)", "short").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) > 0 {
+		t.Errorf("if_not_exists: bad orphans: %v", orphans)
+	}
+
+	// a value set if it doesn't exist keeps the old blob
+	orphans = nil
+	err = table.WithModel(document{}).Update("ID", "d1").SetIfNotExists("Body", strings.Repeat("x", 200)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0] == "Docs/old-body" {
+		t.Errorf("SetIfNotExists: bad orphans: %v", orphans)
+	}
+
+	// without offloaded fields, nothing changes
+	orphans = nil
+	if err := table.Update("ID", "d1").Set("Body", "short").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if client.update.ReturnValues != types.ReturnValueNone || len(orphans) > 0 {
+		t.Errorf("unexpected clean up without a model: %v %v", client.update.ReturnValues, orphans)
+	}
+}
+
+func TestSweepBlobs(t *testing.T) {
+	store, _ := testBlobStore(t)
+	ctx := context.Background()
+	for _, key := range []string{"Docs/used", "Docs/unused", "Other/x"} {
+		if err := store.Put(ctx, key, []byte(`{"S":"x"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := &fakeClient{pages: [][]map[string]types.AttributeValue{{
+		{"Body": blobPointer("Docs/used"), "Title": blobPointer("Docs/unused")},
+	}}}
+	db := NewFromIface(client)
+	db.SetBlobStore(store, OffloadOptions{})
+
+	if _, err := db.Table("Docs").SweepBlobs().Run(); err == nil {
+		t.Error("expected error without a model, got nil")
+	}
+	n, err := db.Table("Docs").WithModel(document{}).SweepBlobs().Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("bad count: %d", n)
+	}
+	keys, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Docs/used", "Other/x"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("bad blobs left: %v, want %v", keys, want)
+	}
+}
+
+func TestFileBlobStore(t *testing.T) {
+	store, _ := testBlobStore(t)
+	ctx := context.Background()
+	if err := store.Put(ctx, "T/x", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := store.Get(ctx, "T/x"); err != nil || string(data) != "data" {
+		t.Errorf("bad get: %q %v", data, err)
+	}
+	if err := store.Delete(ctx, "T/x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "T/x"); err != nil {
+		t.Errorf("deleting missing key: %v", err)
+	}
+	if _, err := store.Get(ctx, "T/x"); err == nil {
+		t.Error("expected error for deleted key, got nil")
+	}
+	for _, key := range []string{"../escape", "T//x", ""} {
+		if err := store.Put(ctx, key, nil); err == nil {
+			t.Errorf("expected error for key %q, got nil", key)
+		}
+	}
+}
@@ -14,39 +14,96 @@ type Put struct {
 	returnType string
 
 	item map[string]types.AttributeValue
+	// offloaded are the names of the item's fields tagged with offload
+	offloaded []string
 	subber
 	condition string
+	version   *versionInfo
+	stamps    *timestamps
 
 	err error
 	cc  *ConsumedCapacity
 }
 
 // Put creates a new request to create or replace an item.
+// If item is a struct with a field tagged with version, the put will only succeed
+// if the version in the table matches, and the version will be incremented.
+// Fields tagged with created or updated will be set to the current time if they are unset.
+// Pass a pointer to item to have its version and timestamp fields updated after a successful put.
+// If the table has a Registry and item's type is registered, its discriminator attribute will be set.
 func (table Table) Put(item interface{}) *Put {
-	encoded, err := marshalItem(item)
-	return &Put{
-		table: table,
-		item:  encoded,
-		err:   err,
+	encoded, err := encodeItem(item, table.db.crypter())
+	p := &Put{
+		table:     table,
+		item:      encoded,
+		offloaded: offloadedFields(item),
+		err:       err,
 	}
+	if len(p.offloaded) == 0 {
+		p.offloaded = table.offloaded()
+	}
+	if err != nil {
+		return p
+	}
+	table.registry.discriminate(item, p.item)
+	p.version, err = findVersion(item)
+	p.setError(err)
+	if p.version != nil {
+		p.item[p.version.name] = p.version.nextAV()
+		expr, args := p.version.cond()
+		p.If(expr, args...)
+	}
+	p.setError(p.setTimestamps(item))
+	return p
+}
+
+func (p *Put) setTimestamps(item interface{}) error {
+	created, updated, err := findTimestamps(item)
+	if err != nil || (created == nil && updated == nil) {
+		return err
+	}
+	p.stamps = &timestamps{now: p.table.db.now()}
+	for _, ts := range []*timestampField{created, updated} {
+		if ts == nil || !ts.isZero() {
+			continue
+		}
+		av, err := ts.encode(p.stamps.now)
+		if err != nil {
+			return err
+		}
+		p.item[ts.name] = av
+		p.stamps.add(ts)
+	}
+	return nil
 }
 
 // If specifies a conditional expression for this put to succeed.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 // Multiple calls to If will be combined with AND.
 func (p *Put) If(expr string, args ...interface{}) *Put {
-	expr = wrapExpr(expr)
-	expr, err := p.subExprN(expr, args...)
+	cond, err := p.subCond(expr, args)
 	p.setError(err)
+	if cond == "" {
+		return p
+	}
 	if p.condition != "" {
 		p.condition += " AND "
 	}
-	p.condition += expr
+	p.condition += cond
 	return p
 }
 
+// IfExpr is like If, but takes an Expression, such as a condition built with the cond package.
+func (p *Put) IfExpr(expr Expression) *Put {
+	if expr == nil {
+		p.setError(errNilExpression)
+		return p
+	}
+	str, args := expr.Expr()
+	return p.If(str, args...)
+}
+
 // ConsumedCapacity will measure the throughput capacity consumed by this operation and add it to cc.
 func (p *Put) ConsumedCapacity(cc *ConsumedCapacity) *Put {
 	p.cc = cc
@@ -61,10 +118,15 @@ func (p *Put) Run() error {
 }
 
 // Run executes this put.
+// If the DB has a BlobStore, blobs of the replaced item that the new item no longer points to
+// are cleaned up afterwards; see OffloadOptions.OnOrphan.
 func (p *Put) RunWithContext(ctx context.Context) error {
 	p.returnType = "NONE"
-	_, err := p.run(ctx)
-	return err
+	output, err := p.run(ctx)
+	if err != nil {
+		return err
+	}
+	return p.table.db.offloader().orphan(ctx, p.offloaded, output.Attributes, p.item)
 }
 
 // OldValue executes this put, unmarshaling the previous value into out.
@@ -86,15 +148,28 @@ func (p *Put) OldValueWithContext(ctx context.Context, out interface{}) error {
 	case output.Attributes == nil:
 		return ErrNotFound
 	}
-	return unmarshalItem(output.Attributes, out)
+	// resolve offloaded attributes before cleaning them up
+	err = p.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
+	if oerr := p.table.db.offloader().orphan(ctx, p.offloaded, output.Attributes, p.item); err == nil {
+		err = oerr
+	}
+	return err
 }
 
 func (p *Put) run(ctx context.Context) (output *dynamodb.PutItemOutput, err error) {
+	p.setError(p.offload(ctx))
 	if p.err != nil {
 		return nil, p.err
 	}
+	if err := p.table.db.checkSize("item", p.itemSize(), MaxItemSize); err != nil {
+		return nil, err
+	}
 
 	req := p.input()
+	if p.table.db.offloader() != nil && len(p.offloaded) > 0 {
+		// the old item is needed to clean up its offloaded attributes
+		req.ReturnValues = types.ReturnValueAllOld
+	}
 	retry(ctx, func() error {
 		output, err = p.table.db.client.PutItem(ctx, req)
 		return err
@@ -102,9 +177,21 @@ func (p *Put) run(ctx context.Context) (output *dynamodb.PutItemOutput, err erro
 	if p.cc != nil {
 		addConsumedCapacity(p.cc, output.ConsumedCapacity)
 	}
+	if err != nil {
+		return output, versionError(p.version, err)
+	}
+	p.commit()
 	return
 }
 
+// offload moves the item's large offloaded attributes to the BlobStore.
+func (p *Put) offload(ctx context.Context) error {
+	if p.err != nil {
+		return nil
+	}
+	return p.table.db.offloader().offloadItem(ctx, p.table.name, p.offloaded, p.item)
+}
+
 func (p *Put) input() *dynamodb.PutItemInput {
 	input := &dynamodb.PutItemInput{
 		TableName:                 &p.table.name,
@@ -141,6 +228,17 @@ func (p *Put) writeTxItem() (*types.TransactWriteItem, error) {
 	return item, nil
 }
 
+func (p *Put) versionInfo() *versionInfo {
+	return p.version
+}
+
+func (p *Put) commit() {
+	if p.version != nil {
+		p.version.commit()
+	}
+	p.stamps.commit()
+}
+
 func (p *Put) setError(err error) {
 	if p.err == nil {
 		p.err = err
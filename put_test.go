@@ -2,10 +2,13 @@ package dynamo
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/niltonkummer/dynamo/cond"
 )
 
 func TestPut(t *testing.T) {
@@ -129,3 +132,18 @@ func TestPutAndQueryAWSEncoding(t *testing.T) {
 		t.Error("couldn't find awsWidget in All")
 	}
 }
+
+func TestPutIfEmpty(t *testing.T) {
+	table := NewFromIface(nil).Table("Widgets")
+	put := table.Put(widget{UserID: 42}).IfExpr(cond.Cond{}).If("")
+	if put.err != nil || put.condition != "" {
+		t.Errorf("empty conditions: condition %q, err %v", put.condition, put.err)
+	}
+	put.IfExpr(cond.And(cond.Cond{}, cond.Name("Msg").Exists()))
+	if put.err != nil || put.condition == "" || strings.Contains(put.condition, "AND") {
+		t.Errorf("condition %q, err %v", put.condition, put.err)
+	}
+	if put.IfExpr(nil); put.err != errNilExpression {
+		t.Errorf("want errNilExpression, got %v", put.err)
+	}
+}
@@ -3,11 +3,14 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/niltonkummer/dynamo/internal/exprs"
 )
 
 // Query is a request to get one or more items in a table.
@@ -35,8 +38,9 @@ type Query struct {
 
 	subber
 
-	err error
-	cc  *ConsumedCapacity
+	err        error
+	cc         *ConsumedCapacity
+	decodeOpts *DecodeOptions
 }
 
 var (
@@ -84,6 +88,38 @@ func (table Table) Get(name string, value interface{}) *Query {
 	return q
 }
 
+// Load gets the item with the same hash and range keys as item, unmarshaling the result into item.
+// Item must be a pointer to a struct whose key fields are tagged with hash and range, as in CreateTable.
+// Returns ErrNotFound if there is no such item.
+func (table Table) Load(item interface{}) error {
+	ctx, cancel := defaultContext()
+	defer cancel()
+	return table.LoadWithContext(ctx, item)
+}
+
+// LoadWithContext gets the item with the same hash and range keys as item, unmarshaling the result into item.
+// Item must be a pointer to a struct whose key fields are tagged with hash and range, as in CreateTable.
+// Returns ErrNotFound if there is no such item.
+func (table Table) LoadWithContext(ctx context.Context, item interface{}) error {
+	if rv := reflect.ValueOf(item); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("dynamo: Load: item must be a non-nil pointer (got %T)", item)
+	}
+	return table.keyedGet(item).OneWithContext(ctx, item)
+}
+
+// keyedGet creates a new request to get the item with the same hash and range keys as item.
+func (table Table) keyedGet(item interface{}) *Query {
+	desc, hashValue, rangeValue, err := itemKeys(item)
+	if err != nil {
+		return &Query{table: table, err: err}
+	}
+	q := table.Get(desc.hashKey, hashValue)
+	if desc.rangeKey != "" {
+		q.Range(desc.rangeKey, Equal, rangeValue)
+	}
+	return q
+}
+
 // Range specifies the range key (a.k.a. sort key) or keys to get.
 // For single item requests using One, op must be Equal.
 // Name is the name of the range key.
@@ -94,6 +130,7 @@ func (q *Query) Range(name string, op Operator, values ...interface{}) *Query {
 	q.rangeOp = op
 	q.rangeValues, err = marshalSlice(values)
 	q.setError(err)
+	q.checkIndex()
 	return q
 }
 
@@ -105,11 +142,21 @@ func (q *Query) StartFrom(key PagingKey) *Query {
 }
 
 // Index specifies the name of the index that this query will operate on.
+// If the table has a model (see Table.WithModel), the index and the query's keys are checked against it.
 func (q *Query) Index(name string) *Query {
 	q.index = name
+	q.checkIndex()
 	return q
 }
 
+// checkIndex checks the index being queried against the indexes declared by the table's model.
+func (q *Query) checkIndex() {
+	if q.index == "" || q.table.indexes == nil {
+		return
+	}
+	q.setError(q.table.indexes.checkQuery(q.index, q.hashKey, q.rangeKey, q.consistent))
+}
+
 // Project limits the result attributes to the given paths.
 func (q *Query) Project(paths ...string) *Query {
 	var expr string
@@ -128,9 +175,8 @@ func (q *Query) Project(paths ...string) *Query {
 // ProjectExpr limits the result attributes to the given expression.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 func (q *Query) ProjectExpr(expr string, args ...interface{}) *Query {
-	expr, err := q.subExpr(expr, args...)
+	expr, err := q.subKind(flagNone, exprs.Projection, expr, args...)
 	q.setError(err)
 	q.projection = expr
 	return q
@@ -139,21 +185,33 @@ func (q *Query) ProjectExpr(expr string, args ...interface{}) *Query {
 // Filter takes an expression that all results will be evaluated against.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 // Multiple calls to Filter will be combined with AND.
 func (q *Query) Filter(expr string, args ...interface{}) *Query {
-	expr = wrapExpr(expr)
-	expr, err := q.subExprN(expr, args...)
+	filter, err := q.subCond(expr, args)
 	q.setError(err)
-	q.filters = append(q.filters, expr)
+	if filter == "" {
+		return q
+	}
+	q.filters = append(q.filters, filter)
 	return q
 }
 
+// FilterExpr is like Filter, but takes an Expression, such as a condition built with the cond package.
+func (q *Query) FilterExpr(expr Expression) *Query {
+	if expr == nil {
+		q.setError(errNilExpression)
+		return q
+	}
+	str, args := expr.Expr()
+	return q.Filter(str, args...)
+}
+
 // Consistent will, if on is true, make this query a strongly consistent read.
 // Queries are eventually consistent by default.
 // Strongly consistent reads are more resource-heavy than eventually consistent reads.
 func (q *Query) Consistent(on bool) *Query {
 	q.consistent = on
+	q.checkIndex()
 	return q
 }
 
@@ -184,6 +242,21 @@ func (q *Query) ConsumedCapacity(cc *ConsumedCapacity) *Query {
 	return q
 }
 
+// DecodeOptions sets the options used to unmarshal the results of this query,
+// overriding the ones set with DB.SetDecodeOptions.
+func (q *Query) DecodeOptions(opts DecodeOptions) *Query {
+	q.decodeOpts = &opts
+	return q
+}
+
+func (q *Query) decoder() decoder {
+	d := q.table.db.decoder()
+	if q.decodeOpts != nil {
+		d.opts = *q.decodeOpts
+	}
+	return d
+}
+
 // One executes this query and retrieves a single result,
 // unmarshaling the result to out.
 func (q *Query) One(out interface{}) error {
@@ -220,7 +293,7 @@ func (q *Query) OneWithContext(ctx context.Context, out interface{}) error {
 			addConsumedCapacity(q.cc, res.ConsumedCapacity)
 		}
 
-		return unmarshalItem(res.Item, out)
+		return q.decoder().withContext(ctx).unmarshalItem(res.Item, out)
 	}
 
 	// If not, try a Query.
@@ -252,7 +325,7 @@ func (q *Query) OneWithContext(ctx context.Context, out interface{}) error {
 		addConsumedCapacity(q.cc, res.ConsumedCapacity)
 	}
 
-	return unmarshalItem(res.Items[0], out)
+	return q.decoder().withContext(ctx).unmarshalItem(res.Items[0], out)
 }
 
 // Count executes this request, returning the number of results.
@@ -422,7 +495,7 @@ func (q *Query) AllWithLastEvaluatedKey(out interface{}) (PagingKey, error) {
 func (q *Query) AllWithLastEvaluatedKeyContext(ctx context.Context, out interface{}) (PagingKey, error) {
 	iter := &queryIter{
 		query:     q,
-		unmarshal: unmarshalAppend,
+		unmarshal: q.decoder().withContext(ctx).unmarshalAppend,
 		err:       q.err,
 	}
 	for iter.NextWithContext(ctx, out) {
@@ -434,13 +507,57 @@ func (q *Query) AllWithLastEvaluatedKeyContext(ctx context.Context, out interfac
 func (q *Query) Iter() PagingIter {
 	iter := &queryIter{
 		query:     q,
-		unmarshal: unmarshalItem,
+		unmarshal: q.decoder().unmarshalItem,
 		err:       q.err,
 	}
 
 	return iter
 }
 
+// EntityIter returns an iterator that decodes each result into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (q *Query) EntityIter() EntityIter {
+	return entityIter{q.registryIter()}
+}
+
+// AllCollection executes this request and adds all results to coll,
+// decoding each one into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (q *Query) AllCollection(coll *Collection) error {
+	ctx, cancel := defaultContext()
+	defer cancel()
+	return q.AllCollectionWithContext(ctx, coll)
+}
+
+// AllCollectionWithContext executes this request and adds all results to coll,
+// decoding each one into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (q *Query) AllCollectionWithContext(ctx context.Context, coll *Collection) error {
+	iter := q.registryIter()
+	if r := q.table.registry; r != nil {
+		iter.unmarshal = r.unmarshalCollection(q.decoder().withContext(ctx))
+	}
+	for iter.NextWithContext(ctx, coll) {
+	}
+	return iter.Err()
+}
+
+func (q *Query) registryIter() *queryIter {
+	iter := &queryIter{
+		query: q,
+		err:   q.err,
+	}
+	r := q.table.registry
+	if r == nil {
+		if iter.err == nil {
+			iter.err = errNoRegistry
+		}
+		return iter
+	}
+	iter.unmarshal = r.unmarshalEntity(q.decoder())
+	return iter
+}
+
 // can we use the get item API?
 func (q *Query) canGetItem() bool {
 	switch {
@@ -0,0 +1,263 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Registry maps the values of a discriminator attribute to Go types.
+// It is used for single-table designs, where items of many different types
+// are stored in one table and told apart by the discriminator attribute.
+//
+// Use Table.WithRegistry to attach a Registry to a table.
+// Puts to that table will set the discriminator of registered types automatically,
+// and mixed results can be decoded with AllCollection or EntityIter.
+type Registry struct {
+	attr   string
+	types  map[string]reflect.Type
+	values map[reflect.Type]string
+}
+
+// NewRegistry creates a new Registry using attr as the discriminator attribute.
+func NewRegistry(attr string) *Registry {
+	return &Registry{
+		attr:   attr,
+		types:  make(map[string]reflect.Type),
+		values: make(map[reflect.Type]string),
+	}
+}
+
+// Register associates the discriminator value name with the type of example,
+// which should be a struct or a pointer to a struct.
+// It panics if name or the type has already been registered.
+// Registering should be done before the Registry is used, such as in an init function.
+func (r *Registry) Register(name string, example interface{}) *Registry {
+	if name == "" {
+		panic("dynamo: Register: empty discriminator value")
+	}
+	rt := reflect.TypeOf(example)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("dynamo: Register: %T is not a struct", example))
+	}
+	if _, ok := r.types[name]; ok {
+		panic(fmt.Sprintf("dynamo: Register: duplicate discriminator value %q", name))
+	}
+	if prev, ok := r.values[rt]; ok {
+		panic(fmt.Sprintf("dynamo: Register: %s is already registered as %q", rt, prev))
+	}
+	r.types[name] = rt
+	r.values[rt] = name
+	return r
+}
+
+// Attr returns the name of the discriminator attribute.
+func (r *Registry) Attr() string {
+	return r.attr
+}
+
+// discriminate sets the discriminator of encoded if item's type is registered.
+func (r *Registry) discriminate(item interface{}, encoded map[string]types.AttributeValue) {
+	if r == nil || encoded == nil {
+		return
+	}
+	rt := reflect.TypeOf(item)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if name, ok := r.values[rt]; ok {
+		encoded[r.attr] = &types.AttributeValueMemberS{Value: name}
+	}
+}
+
+// models returns the registered types, in order of their names.
+func (r *Registry) models() []reflect.Type {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	models := make([]reflect.Type, 0, len(names))
+	for _, name := range names {
+		models = append(models, r.types[name])
+	}
+	return models
+}
+
+// offloaded returns the names of the fields tagged with offload in any registered type.
+func (r *Registry) offloaded() []string {
+	return r.fields(func(codec *structCodec) []string { return codec.offloaded })
+}
+
+// fields returns the union of the field names selected by of from the codecs of the registered types.
+func (r *Registry) fields(of func(*structCodec) []string) []string {
+	if r == nil {
+		return nil
+	}
+	var names []string
+	for rt := range r.values {
+		names = append(names, of(codecOf(rt))...)
+	}
+	sort.Strings(names)
+	return uniqueStrings(names)
+}
+
+// lookup returns the registered type of item, if any.
+func (r *Registry) lookup(item map[string]types.AttributeValue) (reflect.Type, bool) {
+	av, ok := item[r.attr].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, false
+	}
+	rt, ok := r.types[av.Value]
+	return rt, ok
+}
+
+// decode unmarshals item into a new value of its registered type, returning a pointer to it.
+func (r *Registry) decode(d decoder, item map[string]types.AttributeValue) (interface{}, error) {
+	rt, ok := r.lookup(item)
+	if !ok {
+		return nil, fmt.Errorf("dynamo: item has unregistered %s: %s", r.attr, avString(item[r.attr]))
+	}
+	rv := reflect.New(rt)
+	if err := d.unmarshalItem(item, rv.Interface()); err != nil {
+		return nil, err
+	}
+	return rv.Interface(), nil
+}
+
+// unmarshalEntity returns an unmarshalFunc that decodes items into out, which must be a *interface{}.
+func (r *Registry) unmarshalEntity(d decoder) unmarshalFunc {
+	return func(item map[string]types.AttributeValue, out interface{}) error {
+		ptr, ok := out.(*interface{})
+		if !ok {
+			return fmt.Errorf("dynamo: unmarshal entity: out must be *interface{} (got %T)", out)
+		}
+		v, err := r.decode(d, item)
+		if err != nil {
+			return err
+		}
+		*ptr = v
+		return nil
+	}
+}
+
+// unmarshalCollection returns an unmarshalFunc that adds items to out, which must be a *Collection.
+func (r *Registry) unmarshalCollection(d decoder) unmarshalFunc {
+	return func(item map[string]types.AttributeValue, out interface{}) error {
+		coll, ok := out.(*Collection)
+		if !ok {
+			return fmt.Errorf("dynamo: unmarshal collection: out must be *Collection (got %T)", out)
+		}
+		if _, ok := r.lookup(item); !ok {
+			coll.Unknown = append(coll.Unknown, item)
+			return nil
+		}
+		v, err := r.decode(d, item)
+		if err != nil {
+			return err
+		}
+		coll.entities = append(coll.entities, v)
+		return nil
+	}
+}
+
+func avString(av types.AttributeValue) string {
+	if s, ok := av.(*types.AttributeValueMemberS); ok {
+		return fmt.Sprintf("%q", s.Value)
+	}
+	if av == nil {
+		return "(missing)"
+	}
+	return avTypeName(av)
+}
+
+// Collection holds the items of a mixed result, decoded into their registered types.
+// The zero value is ready to use.
+type Collection struct {
+	// Unknown contains the raw items whose discriminator was missing or not registered.
+	Unknown []map[string]types.AttributeValue
+
+	entities []interface{}
+}
+
+// Entities returns all decoded items in the order they were read,
+// each as a pointer to a value of its registered type.
+func (c *Collection) Entities() []interface{} {
+	return c.entities
+}
+
+// Len returns the number of decoded items.
+func (c *Collection) Len() int {
+	return len(c.entities)
+}
+
+// Get appends all decoded items of out's element type to out,
+// which must be a pointer to a slice of a registered type or of pointers to it.
+func (c *Collection) Get(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dynamo: collection: out must be a slice pointer (got %T)", out)
+	}
+	slicev := rv.Elem()
+	elem := slicev.Type().Elem()
+	ptrs := elem.Kind() == reflect.Ptr
+	want := elem
+	if ptrs {
+		want = elem.Elem()
+	}
+	for _, v := range c.entities {
+		ev := reflect.ValueOf(v)
+		if ev.Type().Elem() != want {
+			continue
+		}
+		if !ptrs {
+			ev = ev.Elem()
+		}
+		slicev = reflect.Append(slicev, ev)
+	}
+	rv.Elem().Set(slicev)
+	return nil
+}
+
+// EntityIter is an iterator of mixed results,
+// which are decoded into the types given by a Registry.
+type EntityIter interface {
+	PagingIter
+	// NextEntity decodes the next result into a new value of its registered type,
+	// and sets out to a pointer to it.
+	// Returns false when it is complete or if it runs into an error,
+	// such as a result whose discriminator isn't registered.
+	NextEntity(out *interface{}) bool
+	// NextEntityWithContext decodes the next result into a new value of its registered type,
+	// and sets out to a pointer to it.
+	// Returns false when it is complete or if it runs into an error,
+	// such as a result whose discriminator isn't registered.
+	NextEntityWithContext(ctx context.Context, out *interface{}) bool
+}
+
+// entityIter is an EntityIter wrapping a query or scan iterator
+// that uses Registry.unmarshalEntity.
+type entityIter struct {
+	PagingIter
+}
+
+func (itr entityIter) NextEntity(out *interface{}) bool {
+	return itr.Next(out)
+}
+
+func (itr entityIter) NextEntityWithContext(ctx context.Context, out *interface{}) bool {
+	return itr.NextWithContext(ctx, out)
+}
+
+// errNoRegistry is returned when decoding entities from a table without a Registry.
+var errNoRegistry = errors.New("dynamo: table has no registry; see Table.WithRegistry")
@@ -0,0 +1,157 @@
+package dynamo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type customer struct {
+	PK   string `dynamo:",hash"`
+	SK   string `dynamo:",range"`
+	Name string
+}
+
+type order struct {
+	PK    string `dynamo:",hash"`
+	SK    string `dynamo:",range"`
+	Total int
+}
+
+func testRegistry() *Registry {
+	return NewRegistry("Type").
+		Register("customer", customer{}).
+		Register("order", &order{})
+}
+
+func entityItem(typ, sk string, attrs ...string) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "C#1"},
+		"SK": &types.AttributeValueMemberS{Value: sk},
+	}
+	if typ != "" {
+		item["Type"] = &types.AttributeValueMemberS{Value: typ}
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		item[attrs[i]] = &types.AttributeValueMemberS{Value: attrs[i+1]}
+	}
+	return item
+}
+
+func TestRegistryRegister(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("duplicate name", func() { testRegistry().Register("order", widget{}) })
+	mustPanic("duplicate type", func() { testRegistry().Register("order2", order{}) })
+	mustPanic("not a struct", func() { NewRegistry("Type").Register("n", 42) })
+	mustPanic("empty name", func() { NewRegistry("Type").Register("", order{}) })
+}
+
+func TestPutDiscriminator(t *testing.T) {
+	client := &fakeClient{}
+	table := NewFromIface(client).Table("Single").WithRegistry(testRegistry())
+
+	if err := table.Put(&order{PK: "C#1", SK: "O#1", Total: 5}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.put.Item["Type"]; !reflect.DeepEqual(got, &types.AttributeValueMemberS{Value: "order"}) {
+		t.Errorf("bad discriminator: %#v", got)
+	}
+
+	if err := table.Put(widget{UserID: 1}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.put.Item["Type"]; ok {
+		t.Error("discriminator set for unregistered type")
+	}
+
+	bw := table.Batch().Write().Put(customer{PK: "C#1", SK: "C#1"})
+	if got := bw.ops[0].PutRequest.Item["Type"]; !reflect.DeepEqual(got, &types.AttributeValueMemberS{Value: "customer"}) {
+		t.Errorf("bad batch discriminator: %#v", got)
+	}
+}
+
+func TestAllCollection(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]types.AttributeValue{
+		{
+			entityItem("customer", "C#1", "Name", "Alice"),
+			entityItem("order", "O#1"),
+		},
+		{
+			entityItem("order", "O#2"),
+			entityItem("invoice", "I#1"),
+		},
+	}}
+	table := NewFromIface(client).Table("Single").WithRegistry(testRegistry())
+
+	var coll Collection
+	if err := table.Get("PK", "C#1").AllCollection(&coll); err != nil {
+		t.Fatal(err)
+	}
+	if coll.Len() != 3 {
+		t.Fatalf("bad number of entities: %d", coll.Len())
+	}
+	if len(coll.Unknown) != 1 || !reflect.DeepEqual(coll.Unknown[0]["SK"], &types.AttributeValueMemberS{Value: "I#1"}) {
+		t.Errorf("bad unknown items: %#v", coll.Unknown)
+	}
+
+	var customers []customer
+	if err := coll.Get(&customers); err != nil {
+		t.Fatal(err)
+	}
+	if want := []customer{{PK: "C#1", SK: "C#1", Name: "Alice"}}; !reflect.DeepEqual(customers, want) {
+		t.Errorf("bad customers. want: %#v got: %#v", want, customers)
+	}
+	var orders []*order
+	if err := coll.Get(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].SK != "O#1" || orders[1].SK != "O#2" {
+		t.Errorf("bad orders: %#v", orders)
+	}
+	if err := coll.Get(orders); err == nil {
+		t.Error("expected error for non-pointer, got nil")
+	}
+
+	// without a registry
+	client.calls = 0
+	if err := table.WithRegistry(nil).Scan().AllCollection(&coll); err != errNoRegistry {
+		t.Errorf("expected errNoRegistry, got %v", err)
+	}
+}
+
+func TestEntityIter(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]types.AttributeValue{
+		{
+			entityItem("order", "O#1"),
+			entityItem("customer", "C#1", "Name", "Alice"),
+			entityItem("", "X#1"),
+		},
+	}}
+	table := NewFromIface(client).Table("Single").WithRegistry(testRegistry())
+
+	iter := table.Scan().EntityIter()
+	var got []interface{}
+	var entity interface{}
+	for iter.NextEntity(&entity) {
+		got = append(got, entity)
+	}
+	want := []interface{}{
+		&order{PK: "C#1", SK: "O#1"},
+		&customer{PK: "C#1", SK: "C#1", Name: "Alice"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad entities. want: %#v got: %#v", want, got)
+	}
+	if iter.Err() == nil {
+		t.Error("expected error for item without discriminator, got nil")
+	}
+}
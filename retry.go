@@ -12,7 +12,8 @@ import (
 // RetryTimeout defines the maximum amount of time that requests will
 // attempt to automatically retry for. In other words, this is the maximum
 // amount of time that dynamo operations will block.
-// RetryTimeout is only considered by methods that do not take a context.
+// RetryTimeout is only considered by methods that do not take a context,
+// except those that wait for long-running changes, such as EnsureTable.Run.
 // Higher values are better when using tables with lower throughput.
 var RetryTimeout = 1 * time.Minute
 
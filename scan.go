@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/niltonkummer/dynamo/internal/exprs"
 )
 
 // Scan is a request to scan all the data in a table.
@@ -24,8 +25,9 @@ type Scan struct {
 
 	subber
 
-	err error
-	cc  *ConsumedCapacity
+	err        error
+	cc         *ConsumedCapacity
+	decodeOpts *DecodeOptions
 }
 
 // Scan creates a new request to scan this table.
@@ -50,7 +52,8 @@ func (s *Scan) Index(name string) *Scan {
 
 // Project limits the result attributes to the given paths.
 func (s *Scan) Project(paths ...string) *Scan {
-	expr, err := s.subExpr(strings.Join(paths, ", "), nil)
+	expr := strings.Join(paths, ", ")
+	expr, err := s.subKind(flagNone, exprs.Projection, expr)
 	s.setError(err)
 	s.projection = expr
 	return s
@@ -59,16 +62,27 @@ func (s *Scan) Project(paths ...string) *Scan {
 // Filter takes an expression that all results will be evaluated against.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
 // Multiple calls to Filter will be combined with AND.
 func (s *Scan) Filter(expr string, args ...interface{}) *Scan {
-	expr = wrapExpr(expr)
-	expr, err := s.subExprN(expr, args...)
+	filter, err := s.subCond(expr, args)
 	s.setError(err)
-	s.filters = append(s.filters, expr)
+	if filter == "" {
+		return s
+	}
+	s.filters = append(s.filters, filter)
 	return s
 }
 
+// FilterExpr is like Filter, but takes an Expression, such as a condition built with the cond package.
+func (s *Scan) FilterExpr(expr Expression) *Scan {
+	if expr == nil {
+		s.setError(errNilExpression)
+		return s
+	}
+	str, args := expr.Expr()
+	return s.Filter(str, args...)
+}
+
 // Consistent will, if on is true, make this scan use a strongly consistent read.
 // Scans are eventually consistent by default.
 // Strongly consistent reads are more resource-heavy than eventually consistent reads.
@@ -97,15 +111,74 @@ func (s *Scan) ConsumedCapacity(cc *ConsumedCapacity) *Scan {
 	return s
 }
 
+// DecodeOptions sets the options used to unmarshal the results of this scan,
+// overriding the ones set with DB.SetDecodeOptions.
+func (s *Scan) DecodeOptions(opts DecodeOptions) *Scan {
+	s.decodeOpts = &opts
+	return s
+}
+
+func (s *Scan) decoder() decoder {
+	d := s.table.db.decoder()
+	if s.decodeOpts != nil {
+		d.opts = *s.decodeOpts
+	}
+	return d
+}
+
 // Iter returns a results iterator for this request.
 func (s *Scan) Iter() PagingIter {
 	return &scanIter{
 		scan:      s,
-		unmarshal: unmarshalItem,
+		unmarshal: s.decoder().unmarshalItem,
 		err:       s.err,
 	}
 }
 
+// EntityIter returns an iterator that decodes each result into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (s *Scan) EntityIter() EntityIter {
+	return entityIter{s.registryIter()}
+}
+
+// AllCollection executes this request and adds all results to coll,
+// decoding each one into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (s *Scan) AllCollection(coll *Collection) error {
+	ctx, cancel := defaultContext()
+	defer cancel()
+	return s.AllCollectionWithContext(ctx, coll)
+}
+
+// AllCollectionWithContext executes this request and adds all results to coll,
+// decoding each one into its registered type.
+// The table must have a Registry; see Table.WithRegistry.
+func (s *Scan) AllCollectionWithContext(ctx context.Context, coll *Collection) error {
+	itr := s.registryIter()
+	if r := s.table.registry; r != nil {
+		itr.unmarshal = r.unmarshalCollection(s.decoder().withContext(ctx))
+	}
+	for itr.NextWithContext(ctx, coll) {
+	}
+	return itr.Err()
+}
+
+func (s *Scan) registryIter() *scanIter {
+	itr := &scanIter{
+		scan: s,
+		err:  s.err,
+	}
+	r := s.table.registry
+	if r == nil {
+		if itr.err == nil {
+			itr.err = errNoRegistry
+		}
+		return itr
+	}
+	itr.unmarshal = r.unmarshalEntity(s.decoder())
+	return itr
+}
+
 // All executes this request and unmarshals all results to out, which must be a pointer to a slice.
 func (s *Scan) All(out interface{}) error {
 	ctx, cancel := defaultContext()
@@ -133,7 +206,7 @@ func (s *Scan) AllWithLastEvaluatedKey(out interface{}) (PagingKey, error) {
 func (s *Scan) AllWithLastEvaluatedKeyContext(ctx context.Context, out interface{}) (PagingKey, error) {
 	itr := &scanIter{
 		scan:      s,
-		unmarshal: unmarshalAppend,
+		unmarshal: s.decoder().withContext(ctx).unmarshalAppend,
 		err:       s.err,
 	}
 	for itr.NextWithContext(ctx, out) {
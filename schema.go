@@ -0,0 +1,345 @@
+package dynamo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v2"
+)
+
+// Schema describes tables declaratively, so they can be kept in version-controlled files.
+// Load schemas with LoadSchema or ParseSchema. A YAML schema looks like:
+//
+//	tables:
+//	- name: Orders
+//	  hashKey: UserID
+//	  hashKeyType: S
+//	  rangeKey: OrderID
+//	  rangeKeyType: S
+//	  onDemand: true
+//	  stream: NEW_IMAGE
+//	  ttl: Expires
+//	  tags:
+//	    team: payments
+//	  indexes:
+//	  - name: Status-index
+//	    hashKey: Status
+//	    hashKeyType: S
+//	    projection: INCLUDE
+//	    attributes: [Total]
+//
+// JSON schemas use the same field names.
+type Schema struct {
+	Tables []TableSchema `json:"tables" yaml:"tables"`
+}
+
+// TableSchema describes a table: its keys, indexes and settings.
+type TableSchema struct {
+	Name string `json:"name" yaml:"name"`
+
+	HashKey      string  `json:"hashKey" yaml:"hashKey"`
+	HashKeyType  KeyType `json:"hashKeyType" yaml:"hashKeyType"`
+	RangeKey     string  `json:"rangeKey,omitempty" yaml:"rangeKey,omitempty"`
+	RangeKeyType KeyType `json:"rangeKeyType,omitempty" yaml:"rangeKeyType,omitempty"`
+
+	// OnDemand selects on-demand billing. Otherwise, the table uses provisioned throughput,
+	// given by Read and Write (1 unit each if unset).
+	OnDemand bool  `json:"onDemand,omitempty" yaml:"onDemand,omitempty"`
+	Read     int64 `json:"read,omitempty" yaml:"read,omitempty"`
+	Write    int64 `json:"write,omitempty" yaml:"write,omitempty"`
+
+	// Stream is the view of the table's stream, if enabled.
+	Stream StreamView `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// TTL is the name of the time to live attribute, if enabled.
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Tags are added to the table, or changed if they differ. Other tags of the table are left as is.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	Indexes []IndexSchema `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+// IndexSchema describes a secondary index of a table.
+type IndexSchema struct {
+	Name string `json:"name" yaml:"name"`
+	// Local is true for local secondary indexes, whose hash key defaults to the table's.
+	Local bool `json:"local,omitempty" yaml:"local,omitempty"`
+
+	HashKey      string  `json:"hashKey,omitempty" yaml:"hashKey,omitempty"`
+	HashKeyType  KeyType `json:"hashKeyType,omitempty" yaml:"hashKeyType,omitempty"`
+	RangeKey     string  `json:"rangeKey,omitempty" yaml:"rangeKey,omitempty"`
+	RangeKeyType KeyType `json:"rangeKeyType,omitempty" yaml:"rangeKeyType,omitempty"`
+
+	// Projection is ALL (the default), KEYS_ONLY or INCLUDE.
+	Projection IndexProjection `json:"projection,omitempty" yaml:"projection,omitempty"`
+	// Attributes are the non-key attributes included in the projection.
+	Attributes []string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+
+	// Read and Write are the provisioned throughput of global indexes.
+	Read  int64 `json:"read,omitempty" yaml:"read,omitempty"`
+	Write int64 `json:"write,omitempty" yaml:"write,omitempty"`
+}
+
+// LoadSchema reads a schema file, decoding it as JSON if its name ends in .json and as YAML otherwise.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	schema, err := ParseSchema(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// ParseSchema decodes and validates a schema in the given format, "json" or "yaml".
+func ParseSchema(data []byte, format string) (*Schema, error) {
+	schema := new(Schema)
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(schema)
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, schema)
+	default:
+		return nil, fmt.Errorf("dynamo: unknown schema format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dynamo: bad schema: %v", err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Table returns the schema of the table name, or nil if there is none.
+func (s *Schema) Table(name string) *TableSchema {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that every table has a name and valid keys, indexes and settings.
+func (s *Schema) Validate() error {
+	seen := make(map[string]bool, len(s.Tables))
+	for _, ts := range s.Tables {
+		if seen[ts.Name] {
+			return fmt.Errorf("dynamo: schema: duplicate table %s", ts.Name)
+		}
+		seen[ts.Name] = true
+		if err := ts.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the table has a name and valid keys, indexes and settings.
+func (ts TableSchema) Validate() error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("dynamo: schema: table %s: %s", ts.Name, fmt.Sprintf(format, args...))
+	}
+	if ts.Name == "" {
+		return fmt.Errorf("dynamo: schema: table without a name")
+	}
+	if err := validKey(ts.HashKey, ts.HashKeyType, true); err != nil {
+		return fail("hash key: %v", err)
+	}
+	if err := validKey(ts.RangeKey, ts.RangeKeyType, false); err != nil {
+		return fail("range key: %v", err)
+	}
+	if ts.OnDemand && (ts.Read != 0 || ts.Write != 0) {
+		return fail("on-demand tables can't have provisioned throughput")
+	}
+	switch ts.Stream {
+	case "", KeysOnlyView, NewImageView, OldImageView, NewAndOldImagesView:
+	default:
+		return fail("unknown stream view %q", ts.Stream)
+	}
+	seen := make(map[string]bool, len(ts.Indexes))
+	for _, idx := range ts.Indexes {
+		if idx.Name == "" {
+			return fail("index without a name")
+		}
+		if seen[idx.Name] {
+			return fail("duplicate index %s", idx.Name)
+		}
+		seen[idx.Name] = true
+		if err := validKey(idx.HashKey, idx.HashKeyType, !idx.Local); err != nil {
+			return fail("index %s: hash key: %v", idx.Name, err)
+		}
+		if err := validKey(idx.RangeKey, idx.RangeKeyType, idx.Local); err != nil {
+			return fail("index %s: range key: %v", idx.Name, err)
+		}
+		switch idx.Projection {
+		case "", AllProjection, KeysOnlyProjection:
+			if len(idx.Attributes) > 0 {
+				return fail("index %s: attributes require the INCLUDE projection", idx.Name)
+			}
+		case IncludeProjection:
+			if len(idx.Attributes) == 0 {
+				return fail("index %s: INCLUDE projection without attributes", idx.Name)
+			}
+		default:
+			return fail("index %s: unknown projection %q", idx.Name, idx.Projection)
+		}
+		if idx.Local && (idx.Read != 0 || idx.Write != 0) {
+			return fail("local index %s can't have its own throughput", idx.Name)
+		}
+	}
+	return nil
+}
+
+func validKey(name string, typ KeyType, required bool) error {
+	switch {
+	case name == "" && required:
+		return fmt.Errorf("missing")
+	case name == "" && typ != "":
+		return fmt.Errorf("type without a name")
+	case name == "":
+		return nil
+	}
+	switch typ {
+	case StringType, NumberType, BinaryType:
+		return nil
+	case "":
+		return fmt.Errorf("%s: missing type", name)
+	}
+	return fmt.Errorf("%s: bad type %q, want S, N or B", name, typ)
+}
+
+// CreateTable returns a request to create this table.
+func (ts TableSchema) CreateTable(db *DB) *CreateTable {
+	ct := db.CreateTable(ts.Name, struct{}{})
+	ct.setError(ts.Validate())
+	ct.key(ts.HashKey, ts.HashKeyType, types.KeyTypeHash)
+	if ts.RangeKey != "" {
+		ct.key(ts.RangeKey, ts.RangeKeyType, types.KeyTypeRange)
+	}
+	ct.OnDemand(ts.OnDemand)
+	if ts.Read != 0 || ts.Write != 0 {
+		ct.Provision(orOne(ts.Read), orOne(ts.Write))
+	}
+	if ts.Stream != "" {
+		ct.Stream(ts.Stream)
+	}
+	for _, idx := range ts.indexes() {
+		ct.Index(idx)
+	}
+	keys := make([]string, 0, len(ts.Tags))
+	for key := range ts.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ct.Tag(key, ts.Tags[key])
+	}
+	return ct
+}
+
+// UpdateTable returns a request to set the billing mode, throughput and stream (if enabled)
+// of the existing table to those of this schema. DynamoDB rejects updates that don't change anything,
+// so prefer EnsureTable, which only sends the changes needed and also handles indexes, time to live and tags.
+func (ts TableSchema) UpdateTable(db *DB) *UpdateTable {
+	ut := db.Table(ts.Name).UpdateTable()
+	if err := ts.Validate(); err != nil {
+		ut.err = err
+		return ut
+	}
+	ut.OnDemand(ts.OnDemand)
+	if !ts.OnDemand {
+		read, write := orOne(ts.Read), orOne(ts.Write)
+		ut.Provision(read, write)
+		for _, idx := range ts.indexes() {
+			if !idx.Local {
+				ut.ProvisionIndex(idx.Name, orOne(idx.Throughput.Read), orOne(idx.Throughput.Write))
+			}
+		}
+	}
+	if ts.Stream != "" {
+		ut.Stream(ts.Stream)
+	}
+	return ut
+}
+
+// EnsureTable returns a request to create or update this table to match this schema, as in DB.EnsureTable.
+// The table's settings are taken from the schema instead of opts,
+// so only the DeleteIndexes, DryRun and PollInterval options are used.
+// Throughput is only changed if the schema gives it.
+// Tags are added or changed, but tags the schema doesn't give are kept.
+func (ts TableSchema) EnsureTable(db *DB, opts EnsureOptions) *EnsureTable {
+	opts.OnDemand = ts.OnDemand
+	opts.Throughput = Throughput{}
+	if ts.Read != 0 || ts.Write != 0 {
+		opts.Throughput = Throughput{Read: orOne(ts.Read), Write: orOne(ts.Write)}
+	}
+	opts.Stream, opts.DisableStream = ts.Stream, ts.Stream == ""
+	opts.TTL, opts.DisableTTL = ts.TTL, ts.TTL == ""
+	declared := make(map[string]bool)
+	for _, idx := range ts.Indexes {
+		if !idx.Local && (idx.Read != 0 || idx.Write != 0) {
+			declared[idx.Name] = true
+		}
+	}
+	return db.ensureTable(ts.CreateTable(db), opts, declared)
+}
+
+// indexes returns the indexes of this schema, with defaults filled in.
+func (ts TableSchema) indexes() []Index {
+	indexes := make([]Index, 0, len(ts.Indexes))
+	for _, is := range ts.Indexes {
+		idx := Index{
+			Name:              is.Name,
+			Local:             is.Local,
+			HashKey:           is.HashKey,
+			HashKeyType:       is.HashKeyType,
+			RangeKey:          is.RangeKey,
+			RangeKeyType:      is.RangeKeyType,
+			ProjectionType:    is.Projection,
+			ProjectionAttribs: is.Attributes,
+		}
+		if is.Read != 0 || is.Write != 0 {
+			idx.Throughput = Throughput{Read: orOne(is.Read), Write: orOne(is.Write)}
+		}
+		if idx.Local && idx.HashKey == "" {
+			idx.HashKey, idx.HashKeyType = ts.HashKey, ts.HashKeyType
+		}
+		if idx.ProjectionType == "" {
+			idx.ProjectionType = AllProjection
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
+
+// key adds name to the primary key of the table.
+func (ct *CreateTable) key(name string, typ KeyType, keyType types.KeyType) {
+	ct.add(name, string(typ))
+	ct.schema = append(ct.schema, types.KeySchemaElement{
+		AttributeName: &name,
+		KeyType:       keyType,
+	})
+}
+
+func orOne(units int64) int64 {
+	if units == 0 {
+		return 1
+	}
+	return units
+}
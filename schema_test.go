@@ -0,0 +1,234 @@
+package dynamo
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/niltonkummer/dynamo/dynamodbiface"
+)
+
+const ordersYAML = `
+tables:
+- name: Orders
+  hashKey: UserID
+  hashKeyType: S
+  rangeKey: OrderID
+  rangeKeyType: S
+  read: 5
+  write: 2
+  stream: NEW_IMAGE
+  ttl: Expires
+  tags:
+    team: payments
+    env: test
+  indexes:
+  - name: Status-index
+    hashKey: Status
+    hashKeyType: S
+    projection: INCLUDE
+    attributes: [Total]
+    read: 3
+  - name: Total-index
+    local: true
+    rangeKey: Total
+    rangeKeyType: N
+    projection: KEYS_ONLY
+`
+
+const ordersJSON = `{"tables": [{
+	"name": "Orders",
+	"hashKey": "UserID", "hashKeyType": "S",
+	"rangeKey": "OrderID", "rangeKeyType": "S",
+	"read": 5, "write": 2,
+	"stream": "NEW_IMAGE",
+	"ttl": "Expires",
+	"tags": {"team": "payments", "env": "test"},
+	"indexes": [
+		{"name": "Status-index", "hashKey": "Status", "hashKeyType": "S", "projection": "INCLUDE", "attributes": ["Total"], "read": 3},
+		{"name": "Total-index", "local": true, "rangeKey": "Total", "rangeKeyType": "N", "projection": "KEYS_ONLY"}
+	]
+}]}`
+
+func TestParseSchema(t *testing.T) {
+	fromYAML, err := ParseSchema([]byte(ordersYAML), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := ParseSchema([]byte(ordersJSON), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML and JSON schemas differ.\nYAML: %#v\nJSON: %#v", fromYAML, fromJSON)
+	}
+	orders := fromYAML.Table("Orders")
+	if orders == nil || orders.TTL != "Expires" || len(orders.Indexes) != 2 {
+		t.Errorf("bad table: %#v", orders)
+	}
+	if fromYAML.Table("Nope") != nil {
+		t.Error("found missing table")
+	}
+
+	dir, err := ioutil.TempDir("", "dynamo-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "schema.json")
+	if err := ioutil.WriteFile(path, []byte(ordersJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSchema(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, fromJSON) {
+		t.Errorf("bad loaded schema: %#v", loaded)
+	}
+}
+
+func TestSchemaCreateTable(t *testing.T) {
+	schema, err := ParseSchema([]byte(ordersYAML), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := schema.Tables[0].CreateTable(testDB)
+	if ct.err != nil {
+		t.Fatal(ct.err)
+	}
+	input := ct.input()
+	if *input.KeySchema[0].AttributeName != "UserID" || *input.KeySchema[1].AttributeName != "OrderID" {
+		t.Errorf("bad key schema: %#v", input.KeySchema)
+	}
+	if *input.ProvisionedThroughput.ReadCapacityUnits != 5 || *input.ProvisionedThroughput.WriteCapacityUnits != 2 {
+		t.Errorf("bad throughput: %#v", input.ProvisionedThroughput)
+	}
+	if input.StreamSpecification.StreamViewType != types.StreamViewTypeNewImage {
+		t.Errorf("bad stream: %#v", input.StreamSpecification)
+	}
+	if len(input.GlobalSecondaryIndexes) != 1 {
+		t.Fatalf("want 1 global index, got %d", len(input.GlobalSecondaryIndexes))
+	}
+	gsi := input.GlobalSecondaryIndexes[0]
+	if !reflect.DeepEqual(gsi.Projection.NonKeyAttributes, []string{"Total"}) ||
+		*gsi.ProvisionedThroughput.ReadCapacityUnits != 3 || *gsi.ProvisionedThroughput.WriteCapacityUnits != 1 {
+		t.Errorf("bad global index: %#v", gsi)
+	}
+	if len(input.LocalSecondaryIndexes) != 1 {
+		t.Fatalf("want 1 local index, got %d", len(input.LocalSecondaryIndexes))
+	}
+	lsi := input.LocalSecondaryIndexes[0]
+	if *lsi.KeySchema[0].AttributeName != "UserID" || *lsi.KeySchema[1].AttributeName != "Total" ||
+		lsi.Projection.ProjectionType != types.ProjectionTypeKeysOnly {
+		t.Errorf("bad local index: %#v", lsi)
+	}
+	if len(input.AttributeDefinitions) != 4 {
+		t.Errorf("want 4 attributes, got %#v", input.AttributeDefinitions)
+	}
+	if len(input.Tags) != 2 || *input.Tags[0].Key != "env" || *input.Tags[1].Key != "team" {
+		t.Errorf("bad tags: %#v", input.Tags)
+	}
+
+	ut := schema.Tables[0].UpdateTable(testDB)
+	if ut.err != nil {
+		t.Fatal(ut.err)
+	}
+	update := ut.input()
+	if update.BillingMode != types.BillingModeProvisioned || *update.ProvisionedThroughput.ReadCapacityUnits != 5 {
+		t.Errorf("bad update: %#v", update)
+	}
+	if len(update.GlobalSecondaryIndexUpdates) != 1 || *update.GlobalSecondaryIndexUpdates[0].Update.IndexName != "Status-index" {
+		t.Errorf("bad index updates: %#v", update.GlobalSecondaryIndexUpdates)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	tests := []struct {
+		yaml string
+		err  string
+	}{
+		{"tables:\n- hashKey: ID\n  hashKeyType: S", "without a name"},
+		{"tables:\n- name: A\n  hashKeyType: S", "hash key: missing"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: X", `bad type "X"`},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n  onDemand: true\n  read: 1", "on-demand"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n  stream: ALL", "unknown stream view"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n- name: A\n  hashKey: ID\n  hashKeyType: S", "duplicate table"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n  indexes:\n  - name: I\n    local: true", "range key: missing"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n  indexes:\n  - name: I\n    hashKey: B\n    hashKeyType: S\n    projection: INCLUDE", "without attributes"},
+		{"tables:\n- name: A\n  hashKey: ID\n  hashKeyType: S\n  hashkey: typo", "field hashkey not found"},
+	}
+	for _, test := range tests {
+		_, err := ParseSchema([]byte(test.yaml), "yaml")
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: want error containing %q, got %v", test.yaml, test.err, err)
+		}
+	}
+	if _, err := ParseSchema([]byte(ordersJSON), "toml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	typo := `{"tables": [{"name": "A", "hashKey": "ID", "hashKeyType": "S", "rangeKeyTyp": "N"}]}`
+	if _, err := ParseSchema([]byte(typo), "json"); err == nil || !strings.Contains(err.Error(), `unknown field "rangeKeyTyp"`) {
+		t.Errorf("want unknown field error, got %v", err)
+	}
+}
+
+func TestSchemaEnsureTags(t *testing.T) {
+	schema, err := ParseSchema([]byte("tables:\n- name: Tagged\n  hashKey: ID\n  hashKeyType: S\n  tags:\n    team: payments\n    env: prod\n    owner: ops\n"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{
+		table: &types.TableDescription{
+			TableName:   aws.String("Tagged"),
+			TableArn:    aws.String("arn:tagged"),
+			TableStatus: types.TableStatusActive,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash},
+			},
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS},
+			},
+		},
+		tags: []types.Tag{
+			{Key: aws.String("team"), Value: aws.String("payments")},
+			{Key: aws.String("env"), Value: aws.String("test")},
+			{Key: aws.String("extra"), Value: aws.String("kept")},
+		},
+	}
+	db := NewFromIface(client)
+	plan, err := schema.Tables[0].EnsureTable(db, EnsureOptions{}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Description != "tag env=prod, owner=ops" {
+		t.Errorf("bad plan: %s", plan)
+	}
+	if len(client.tagged) != 1 {
+		t.Fatalf("want 1 tag request, got %d", len(client.tagged))
+	}
+	tagged := client.tagged[0]
+	if *tagged.ResourceArn != "arn:tagged" || len(tagged.Tags) != 2 {
+		t.Errorf("bad tag request: %#v", tagged)
+	}
+
+	client.tags = append(client.tags[:1],
+		types.Tag{Key: aws.String("env"), Value: aws.String("prod")},
+		types.Tag{Key: aws.String("owner"), Value: aws.String("ops")})
+	plan, err = schema.Tables[0].EnsureTable(db, EnsureOptions{}).Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("want empty plan, got: %s", plan)
+	}
+
+	// clients that only implement DynamoDBAPI can't tag tables
+	untagged := NewFromIface(struct{ dynamodbiface.DynamoDBAPI }{client})
+	if _, err := schema.Tables[0].EnsureTable(untagged, EnsureOptions{}).Plan(); err == nil || !strings.Contains(err.Error(), "can't tag") {
+		t.Errorf("expected error for client without tagging, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package dynamo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Size limits of DynamoDB, in bytes.
+const (
+	// MaxItemSize is the maximum size of an item.
+	MaxItemSize = 400 * 1024
+	// MaxTxSize is the maximum total size of the items in a transaction.
+	MaxTxSize = 4 * 1024 * 1024
+)
+
+// ErrItemTooLarge is returned when size validation is enabled (see DB.SetValidateSizes)
+// and an item or transaction is over DynamoDB's size limits.
+// The error returned is a *SizeError wrapping ErrItemTooLarge.
+var ErrItemTooLarge = errors.New("dynamo: item too large")
+
+// SizeError describes an item or transaction that is too large.
+type SizeError struct {
+	// What is too large, such as "item" or "transaction".
+	What string
+	// Size is the size in bytes, as calculated by ItemSize.
+	// For updates, it is the size of the key and new values, a lower bound of the resulting item's size.
+	Size int
+	// Limit is the maximum size in bytes.
+	Limit int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("dynamo: %s is too large: %d bytes, over the limit of %d bytes", e.What, e.Size, e.Limit)
+}
+
+// Unwrap returns ErrItemTooLarge.
+func (e *SizeError) Unwrap() error {
+	return ErrItemTooLarge
+}
+
+// ItemSize returns the size of item in bytes, as DynamoDB calculates it for its limits.
+// Item can be anything accepted by MarshalItem.
+// The size of an attribute is the length of its name plus the size of its value:
+//
+//	strings and binary: their length in bytes
+//	numbers: 1 byte plus 1 byte for every two significant digits
+//	booleans and nulls: 1 byte
+//	sets: the sum of the sizes of their elements
+//	lists and maps: 3 bytes plus the sizes of their elements, with 1 byte of overhead for each
+//
+// Use DB.ItemSize for items with fields tagged with encrypt.
+func ItemSize(item interface{}) (int, error) {
+	av, err := marshalItem(item)
+	if err != nil {
+		return 0, err
+	}
+	return itemSize(av), nil
+}
+
+// ItemSize is like ItemSize, but counts fields tagged with encrypt
+// by the size of their ciphertext from the DB's Encryptor.
+func (db *DB) ItemSize(item interface{}) (int, error) {
+	av, err := db.MarshalItem(item)
+	if err != nil {
+		return 0, err
+	}
+	return itemSize(av), nil
+}
+
+func itemSize(item map[string]types.AttributeValue) int {
+	size := 0
+	for name, av := range item {
+		size += len(name) + avSize(av)
+	}
+	return size
+}
+
+func avSize(av types.AttributeValue) int {
+	switch x := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(x.Value)
+	case *types.AttributeValueMemberN:
+		return numberSize(x.Value)
+	case *types.AttributeValueMemberB:
+		return len(x.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberSS:
+		size := 0
+		for _, s := range x.Value {
+			size += len(s)
+		}
+		return size
+	case *types.AttributeValueMemberNS:
+		size := 0
+		for _, n := range x.Value {
+			size += numberSize(n)
+		}
+		return size
+	case *types.AttributeValueMemberBS:
+		size := 0
+		for _, b := range x.Value {
+			size += len(b)
+		}
+		return size
+	case *types.AttributeValueMemberL:
+		size := 3
+		for _, elem := range x.Value {
+			size += 1 + avSize(elem)
+		}
+		return size
+	case *types.AttributeValueMemberM:
+		size := 3
+		for k, elem := range x.Value {
+			size += 1 + len(k) + avSize(elem)
+		}
+		return size
+	}
+	return 0
+}
+
+// numberSize returns the size of the number n: 1 byte plus 1 byte for every two significant digits.
+// Leading and trailing zeros aren't significant.
+func numberSize(n string) int {
+	digits := make([]byte, 0, len(n))
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c == 'e' || c == 'E' {
+			break
+		}
+		if c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	start, end := 0, len(digits)
+	for start < end && digits[start] == '0' {
+		start++
+	}
+	for end > start && digits[end-1] == '0' {
+		end--
+	}
+	return (end-start+1)/2 + 1
+}
+
+// SetValidateSizes enables or disables checking the sizes of items before they are written.
+// When enabled, puts, batch writes, updates and write transactions whose items are over
+// DynamoDB's size limits fail with a *SizeError wrapping ErrItemTooLarge instead of being sent.
+// Only the key and new values of updates are known, so updates are only rejected
+// when those alone are too large.
+func (db *DB) SetValidateSizes(validate bool) {
+	db.validateSizes = validate
+}
+
+// checkSize returns an error if validation is enabled and size is over limit.
+func (db *DB) checkSize(what string, size, limit int) error {
+	if db == nil || !db.validateSizes || size <= limit {
+		return nil
+	}
+	return &SizeError{What: what, Size: size, Limit: limit}
+}
+
+// sizedOp is a write whose item size can be calculated, for validating transactions.
+type sizedOp interface {
+	itemSize() int
+}
+
+func (p *Put) itemSize() int {
+	return itemSize(p.item)
+}
+
+// itemSize returns the size of the key and top-level values set by this update,
+// a lower bound of the size of the resulting item.
+// It is called after encrypt and offload, so values are counted as they will be stored.
+func (u *Update) itemSize() int {
+	size := itemSize(u.key())
+	for path, av := range u.values {
+		size += len(path) + avSize(av)
+	}
+	return size
+}
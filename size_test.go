@@ -0,0 +1,131 @@
+package dynamo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestNumberSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"0", 1},
+		{"1", 2},
+		{"12", 2},
+		{"123", 3},
+		{"-123", 3},
+		{"1000", 2},
+		{"0.001", 2},
+		{"123.45", 4},
+		{"1.5e10", 2},
+		{"12345678901234567890123456789012345678", 20},
+	}
+	for _, tc := range tests {
+		if got := numberSize(tc.in); got != tc.want {
+			t.Errorf("%s: want %d, got %d", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestItemSize(t *testing.T) {
+	type sized struct {
+		ID    string
+		Count int
+		OK    bool
+		Tags  []string `dynamo:",set"`
+		List  []int
+		Map   map[string]string
+	}
+	size, err := ItemSize(sized{
+		ID:    "abc",
+		Count: 123,
+		OK:    true,
+		Tags:  []string{"a", "bc"},
+		List:  []int{1, 2},
+		Map:   map[string]string{"k": "vv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := (2 + 3) + // ID
+		(5 + 3) + // Count
+		(2 + 1) + // OK
+		(4 + 3) + // Tags
+		(4 + 3 + 2*(1+2)) + // List
+		(3 + 3 + 1 + 1 + 2) // Map
+	if size != want {
+		t.Errorf("bad size. want: %d got: %d", want, size)
+	}
+
+	if _, err := ItemSize(42); err == nil {
+		t.Error("expected error for non-item, got nil")
+	}
+}
+
+func TestValidateSizes(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	table := db.Table("Sizes")
+	big := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberS{Value: "1"},
+		"Data": &types.AttributeValueMemberS{Value: strings.Repeat("x", MaxItemSize)},
+	}
+
+	// disabled by default
+	if err := table.Put(big).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetValidateSizes(true)
+	client.put = nil
+	err := table.Put(big).Run()
+	var serr *SizeError
+	if !errors.As(err, &serr) || !errors.Is(err, ErrItemTooLarge) {
+		t.Fatalf("expected SizeError, got %v", err)
+	}
+	if serr.Size != itemSize(big) || serr.Limit != MaxItemSize {
+		t.Errorf("bad size error: %+v", serr)
+	}
+	if client.put != nil {
+		t.Error("item was sent")
+	}
+
+	if _, err := table.Batch().Write().Put(big).Run(); !errors.Is(err, ErrItemTooLarge) {
+		t.Errorf("batch write: expected ErrItemTooLarge, got %v", err)
+	}
+	if err := table.Update("ID", "1").Set("Data", strings.Repeat("x", MaxItemSize)).Run(); !errors.Is(err, ErrItemTooLarge) {
+		t.Errorf("update: expected ErrItemTooLarge, got %v", err)
+	}
+	if err := table.Update("ID", "1").Set("Data", "small").Run(); err != nil {
+		t.Errorf("update: unexpected error: %v", err)
+	}
+
+	// encrypted values are counted by the size of their ciphertext
+	db.SetEncryptor(testKeyring(t), EncryptOptions{})
+	u := db.Table("Users").Update("ID", "u1").Set("SSN", strings.Repeat("x", 100)).Model(secretUser{})
+	if err := u.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if size := u.itemSize(); size <= len("ID")+1+len("SSN")+100 {
+		t.Errorf("update size doesn't include the ciphertext overhead: %d", size)
+	}
+	db.SetEncryptor(nil, EncryptOptions{})
+
+	// transactions are limited in total
+	item := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberS{Value: "1"},
+		"Data": &types.AttributeValueMemberS{Value: strings.Repeat("x", MaxItemSize-100)},
+	}
+	tx := db.WriteTx()
+	for i := 0; i < MaxTxSize/MaxItemSize+1; i++ {
+		tx.Put(table.Put(item))
+	}
+	err = tx.Run()
+	if !errors.As(err, &serr) || serr.What != "transaction" {
+		t.Errorf("expected transaction SizeError, got %v", err)
+	}
+}
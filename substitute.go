@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding"
 	"encoding/base32"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -64,6 +65,20 @@ func (s *subber) subExprFlags(flags encodeFlags, expr string, args ...interface{
 		return "", err
 	}
 
+	var placeholders int
+	for _, item := range lexed.Items {
+		switch item.Type {
+		case exprs.ItemNamePlaceholder, exprs.ItemValuePlaceholder, exprs.ItemMagicLiteral:
+			if placeholders == len(args) {
+				return "", fmt.Errorf("dynamo: not enough arguments for expression %q: missing argument for %s at position %d", expr, item, item.Pos-len(item.Val))
+			}
+			placeholders++
+		}
+	}
+	if placeholders < len(args) {
+		return "", fmt.Errorf("dynamo: too many arguments for expression %q: expected %d, got %d", expr, placeholders, len(args))
+	}
+
 	var buf bytes.Buffer
 	var idx int
 	for _, item := range lexed.Items {
@@ -75,23 +90,9 @@ func (s *subber) subExprFlags(flags encodeFlags, expr string, args ...interface{
 			sub := s.subName(item.Val[1 : len(item.Val)-1]) // trim ""
 			_, err = buf.WriteString(sub)
 		case exprs.ItemNamePlaceholder:
-			switch x := args[idx].(type) {
-			case encoding.TextMarshaler:
-				var txt []byte
-				txt, err = x.MarshalText()
-				if err == nil {
-					sub := s.subName(string(txt))
-					_, err = buf.WriteString(sub)
-				}
-			case string:
-				sub := s.subName(x)
+			var sub string
+			if sub, err = s.subNameArg(args[idx]); err == nil {
 				_, err = buf.WriteString(sub)
-			case int:
-				_, err = buf.WriteString(strconv.Itoa(x))
-			case int64:
-				_, err = buf.WriteString(strconv.FormatInt(x, 10))
-			default:
-				err = fmt.Errorf("dynamo: type of argument for $ must be string, int, or int64 (got %T)", x)
 			}
 			idx++
 		case exprs.ItemValuePlaceholder:
@@ -112,6 +113,96 @@ func (s *subber) subExprFlags(flags encodeFlags, expr string, args ...interface{
 	return buf.String(), nil
 }
 
+// subKind parses expr as the given kind of expression and fills in its placeholders with the given args.
+// Unlike subExprFlags, it knows which identifiers are attribute names,
+// so it will automatically substitute names that are reserved words.
+func (s *subber) subKind(flags encodeFlags, kind exprs.Kind, expr string, args ...interface{}) (string, error) {
+	tree, err := exprs.ParseKind(kind, expr)
+	if err != nil {
+		return "", err
+	}
+	if err := tree.CheckArgs(len(args)); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	var idx, prev int
+	for _, tok := range tree.Tokens {
+		// keep whitespace as-is
+		buf.WriteString(expr[prev:tok.Pos])
+		prev = tok.End()
+
+		sub := tok.Val
+		var err error
+		switch tok.Type {
+		case exprs.TokenIdent:
+			if tok.Name && reserved[strings.ToUpper(tok.Val)] {
+				sub = s.subName(tok.Val)
+			}
+		case exprs.TokenQuotedName:
+			sub = s.subName(tok.Val[1 : len(tok.Val)-1]) // trim ''
+		case exprs.TokenNamePlaceholder:
+			sub, err = s.subNameArg(args[idx])
+			idx++
+		case exprs.TokenValuePlaceholder:
+			sub, err = s.subValue(args[idx], flags)
+			idx++
+		case exprs.TokenMagicLiteral:
+			sub = args[idx].(string)
+			idx++
+		}
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(sub)
+	}
+
+	return buf.String(), nil
+}
+
+// subNameArg substitutes the argument for a $ placeholder.
+func (s *subber) subNameArg(arg interface{}) (string, error) {
+	switch x := arg.(type) {
+	case encoding.TextMarshaler:
+		txt, err := x.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return s.subName(string(txt)), nil
+	case string:
+		return s.subName(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	}
+	return "", fmt.Errorf("dynamo: type of argument for $ must be string, int, or int64 (got %T)", arg)
+}
+
+// Expression is implemented by expression builders, such as the ones in the cond package.
+// Expressions can be given to IfExpr and FilterExpr in place of a condition or filter expression string.
+type Expression interface {
+	// Expr returns the expression using the same placeholder syntax as If and Filter,
+	// along with the arguments for its placeholders.
+	Expr() (string, []interface{})
+}
+
+var errNilExpression = errors.New("dynamo: nil expression")
+
+// subCond substitutes a condition or filter expression.
+func (s *subber) subCond(expr string, args []interface{}) (string, error) {
+	// an empty condition, such as the zero cond.Cond, is ignored
+	if strings.TrimSpace(expr) == "" && len(args) == 0 {
+		return "", nil
+	}
+	// parse the expression as given, so that syntax errors point into it, and wrap the result
+	subbed, err := s.subKind(flagAllowEmpty|flagNull, exprs.Condition, expr, args...)
+	if err != nil {
+		return "", err
+	}
+	return wrapExpr(subbed), nil
+}
+
 // encodeName consistently encodes a name.
 // The consistency is important.
 func encodeName(name string) string {
@@ -127,12 +218,76 @@ func (s *subber) escape(name string) (string, error) {
 	}
 	// needs to be parsed
 	if strings.ContainsAny(name, ".[]()'") {
-		return s.subExpr(name, nil)
+		return s.subKind(flagNone, exprs.Projection, name)
 	}
 	// boring
 	return name, nil
 }
 
+// docPath is a document path split into its elements: names, and [] for list indexes of any value.
+type docPath []string
+
+func (p docPath) String() string {
+	var path string
+	for _, elem := range p {
+		path = joinPath(path, elem)
+	}
+	return path
+}
+
+// parseDocPath splits path, as given to Update.Set and the like, into its elements.
+func parseDocPath(path string) (docPath, error) {
+	// like escape, names without special characters are taken as-is
+	if !strings.ContainsAny(path, ".[]'") {
+		return docPath{path}, nil
+	}
+	tree, err := exprs.ParseKind(exprs.Projection, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tree.Projection) != 1 {
+		return nil, fmt.Errorf("dynamo: invalid document path %q", path)
+	}
+	return exprDocPath(tree, tree.Projection[0], nil), nil
+}
+
+// exprDocPath returns path, a document path of tree, with args filled in for its $ placeholders.
+func exprDocPath(tree *exprs.Tree, path *exprs.Path, args []interface{}) docPath {
+	p := make(docPath, 0, len(path.Elems))
+	for _, elem := range path.Elems {
+		name := elem.Name
+		if elem.Type == exprs.TokenNamePlaceholder {
+			name = nameArg(args[argIndex(tree, elem.Token)])
+		}
+		if elem.Index {
+			name = "[]"
+		}
+		p = append(p, name)
+	}
+	return p
+}
+
+// argIndex returns the index of the argument for the placeholder token tok.
+func argIndex(tree *exprs.Tree, tok int) int {
+	var idx int
+	for _, t := range tree.Tokens[:tok] {
+		if t.IsPlaceholder() {
+			idx++
+		}
+	}
+	return idx
+}
+
+// nameArg returns the name given by an argument for a $ placeholder, as subNameArg substitutes it.
+func nameArg(arg interface{}) string {
+	if tm, ok := arg.(encoding.TextMarshaler); ok {
+		if txt, err := tm.MarshalText(); err == nil {
+			return string(txt)
+		}
+	}
+	return fmt.Sprint(arg)
+}
+
 // wrapExpr wraps expr in parens if needed
 func wrapExpr(expr string) string {
 	if len(expr) == 0 {
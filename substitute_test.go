@@ -3,6 +3,9 @@ package dynamo
 import (
 	"fmt"
 	"testing"
+
+	"github.com/niltonkummer/dynamo/cond"
+	"github.com/niltonkummer/dynamo/internal/exprs"
 )
 
 func TestSubExpr(t *testing.T) {
@@ -51,3 +54,121 @@ func BenchmarkSubExpr(b *testing.B) {
 		s.subExpr(expr, 613, "Time", "2015-12-04")
 	}
 }
+
+func TestSubCond(t *testing.T) {
+	s := subber{}
+
+	subbed, err := s.subCond("$ > ? AND begins_with($, ?)", []interface{}{"Count", 1, "Title", "foo"})
+	if err != nil {
+		t.Error(err)
+	}
+	expect := fmt.Sprintf("(%s > :v0 AND begins_with(%s, :v1))", s.subName("Count"), s.subName("Title"))
+	if subbed != expect {
+		t.Errorf("bad subbed expr: %v ≠ %v", subbed, expect)
+	}
+
+	if _, err := s.subCond("$ = ?", []interface{}{"Count"}); err == nil {
+		t.Error("expected error for missing argument, got nil")
+	}
+	if _, err := s.subCond("Score <= ? AND", []interface{}{1}); err == nil {
+		t.Error("expected syntax error, got nil")
+	}
+	// syntax errors refer to the expression as given, not the wrapped one
+	_, err = s.subCond("Foo-Bar = ?", []interface{}{1})
+	if serr, ok := err.(*exprs.SyntaxError); !ok || serr.Input != "Foo-Bar = ?" || serr.Pos != 3 {
+		t.Errorf("bad syntax error: %#v", err)
+	}
+
+	// empty conditions are ignored
+	empty := []string{"", " "}
+	for _, c := range []cond.Cond{{}, cond.And()} {
+		expr, _ := c.Expr()
+		empty = append(empty, expr)
+	}
+	for _, expr := range empty {
+		subbed, err := s.subCond(expr, nil)
+		if err != nil || subbed != "" {
+			t.Errorf("subCond(%q) = %q, %v; want empty", expr, subbed, err)
+		}
+	}
+	if _, err := s.subCond("", []interface{}{1}); err == nil {
+		t.Error("expected error for arguments to an empty expression, got nil")
+	}
+}
+
+func TestSubExprMissingArgs(t *testing.T) {
+	s := subber{}
+	_, err := s.subExpr("Score <= ? AND begins_with($, ?)", 1, "Name")
+	if err == nil {
+		t.Error("expected error for missing argument, got nil")
+	}
+}
+
+func TestSubKindReserved(t *testing.T) {
+	s := subber{}
+	count, date, size := s.subName("Count"), s.subName("Date"), s.subName("size")
+
+	tests := []struct {
+		kind exprs.Kind
+		in   string
+		args []interface{}
+		out  string
+	}{
+		{
+			kind: exprs.Condition,
+			in:   "Count > ? AND begins_with(Title, ?) AND size(Tags) > size",
+			args: []interface{}{1, "foo"},
+			out:  fmt.Sprintf("%s > :v0 AND begins_with(Title, :v1) AND size(Tags) > %s", count, size),
+		},
+		{
+			kind: exprs.Condition,
+			in:   "Info.Date BETWEEN ? AND ? OR NOT attribute_exists('Count')",
+			args: []interface{}{1, 2},
+			out:  fmt.Sprintf("Info.%s BETWEEN :v2 AND :v3 OR NOT attribute_exists(%s)", date, count),
+		},
+		{
+			kind: exprs.Projection,
+			in:   "Count, Data[1].Date,  Title",
+			out:  fmt.Sprintf("%s, %s[1].%s,  Title", count, s.subName("Data"), date),
+		},
+		{
+			kind: exprs.SetActions,
+			in:   "Count = Count + ?, Date = if_not_exists(Date, ?)",
+			args: []interface{}{1, "now"},
+			out:  fmt.Sprintf("%s = %s + :v4, %s = if_not_exists(%s, :v5)", count, count, date, date),
+		},
+	}
+	for _, tc := range tests {
+		got, err := s.subKind(flagNone, tc.kind, tc.in, tc.args...)
+		if err != nil {
+			t.Error(tc.in, err)
+			continue
+		}
+		if got != tc.out {
+			t.Errorf("bad subbed %s expr: %v ≠ %v", tc.kind, got, tc.out)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	s := subber{}
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"Title", "Title"},
+		{"Count", s.subName("Count")},
+		{"Info.Count[2]", "Info." + s.subName("Count") + "[2]"},
+		{"'Count'.Title", s.subName("Count") + ".Title"},
+	}
+	for _, tc := range tests {
+		got, err := s.escape(tc.in)
+		if err != nil {
+			t.Error(tc.in, err)
+			continue
+		}
+		if got != tc.out {
+			t.Errorf("bad escape of %s: %v ≠ %v", tc.in, got, tc.out)
+		}
+	}
+}
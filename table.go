@@ -2,6 +2,7 @@ package dynamo
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -23,8 +24,13 @@ const (
 
 // Table is a DynamoDB table.
 type Table struct {
-	name string
-	db   *DB
+	name     string
+	db       *DB
+	registry *Registry
+	// indexes are the indexes declared by the table's model, if any (see WithModel)
+	indexes *indexSet
+	// model is the struct type of the table's model, if any
+	model reflect.Type
 }
 
 // Table returns a Table handle specified by name.
@@ -40,6 +46,31 @@ func (table Table) Name() string {
 	return table.name
 }
 
+// WithRegistry returns a copy of this table handle that uses r to
+// set and interpret the discriminator attribute of items.
+// See Registry for more information.
+func (table Table) WithRegistry(r *Registry) Table {
+	table.registry = r
+	return table
+}
+
+// models returns the table's model, or the types of its registry if it has no model.
+func (table Table) models() []reflect.Type {
+	if table.model != nil {
+		return []reflect.Type{table.model}
+	}
+	return table.registry.models()
+}
+
+// offloaded returns the names of the fields tagged with offload in the table's model,
+// or in the types of its registry if it has no model.
+func (table Table) offloaded() []string {
+	if table.model != nil {
+		return codecOf(table.model).offloaded
+	}
+	return table.registry.offloaded()
+}
+
 // DeleteTable is a request to delete a table.
 // See: http://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_DeleteTable.html
 type DeleteTable struct {
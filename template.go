@@ -0,0 +1,468 @@
+package dynamo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TableTemplate is the definition of a table, which can be rendered as a
+// CloudFormation template or Terraform configuration to keep deployed infrastructure
+// in sync with tables defined in Go. Get one from CreateTable.Template or Description.Template.
+type TableTemplate struct {
+	// Resource is the name of the table's resource in the template.
+	// If empty, it is derived from the table name.
+	Resource string
+	// Table holds the keys, indexes, billing mode and stream of the table.
+	Table Description
+	// TTL is the name of the time to live attribute, if enabled.
+	TTL string
+	// Tags are the table's metadata tags.
+	Tags map[string]string
+}
+
+// Template returns the definition of the table this request would create, including its tags.
+func (ct *CreateTable) Template() (TableTemplate, error) {
+	if ct.err != nil {
+		return TableTemplate{}, ct.err
+	}
+	tt := TableTemplate{Table: ct.description()}
+	if len(ct.tags) > 0 {
+		tt.Tags = make(map[string]string, len(ct.tags))
+		for _, tag := range ct.tags {
+			tt.Tags[*tag.Key] = *tag.Value
+		}
+	}
+	return tt, nil
+}
+
+// Template returns the definition of this table.
+// Descriptions don't include time to live or tags, so set TTL and Tags of the result to include them.
+func (d Description) Template() TableTemplate {
+	return TableTemplate{Table: d}
+}
+
+// CloudFormationJSON renders this table as an indented CloudFormation template in JSON,
+// with a single AWS::DynamoDB::Table resource.
+func (tt TableTemplate) CloudFormationJSON() ([]byte, error) {
+	tmpl, err := tt.cloudFormation()
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// CloudFormationYAML renders this table as a CloudFormation template in YAML,
+// with a single AWS::DynamoDB::Table resource.
+func (tt TableTemplate) CloudFormationYAML() ([]byte, error) {
+	tmpl, err := tt.cloudFormation()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tmpl)
+}
+
+// Terraform renders this table as an aws_dynamodb_table resource in Terraform's configuration language (HCL).
+func (tt TableTemplate) Terraform() ([]byte, error) {
+	if err := tt.validate(); err != nil {
+		return nil, err
+	}
+	d := tt.Table
+	res := &hclBlock{header: fmt.Sprintf("resource %s %s", hclString("aws_dynamodb_table"), hclString(tt.terraformName()))}
+	res.attr("name", hclString(d.Name))
+	if d.OnDemand {
+		res.attr("billing_mode", hclString("PAY_PER_REQUEST"))
+	} else {
+		res.attr("billing_mode", hclString("PROVISIONED"))
+		res.attr("read_capacity", strconv.FormatInt(d.Throughput.Read, 10))
+		res.attr("write_capacity", strconv.FormatInt(d.Throughput.Write, 10))
+	}
+	res.attr("hash_key", hclString(d.HashKey))
+	if d.RangeKey != "" {
+		res.attr("range_key", hclString(d.RangeKey))
+	}
+	if d.StreamEnabled {
+		res.blank()
+		res.attr("stream_enabled", "true")
+		res.attr("stream_view_type", hclString(string(d.StreamView)))
+	}
+	for _, attr := range tt.attributes() {
+		block := res.block("attribute")
+		block.attr("name", hclString(attr.name))
+		block.attr("type", hclString(string(attr.typ)))
+	}
+	for _, idx := range tt.indexes(false) {
+		block := res.block("global_secondary_index")
+		tt.terraformIndex(block, idx)
+		if !d.OnDemand {
+			block.attr("read_capacity", strconv.FormatInt(idx.Throughput.Read, 10))
+			block.attr("write_capacity", strconv.FormatInt(idx.Throughput.Write, 10))
+		}
+	}
+	for _, idx := range tt.indexes(true) {
+		tt.terraformIndex(res.block("local_secondary_index"), idx)
+	}
+	if tt.TTL != "" {
+		block := res.block("ttl")
+		block.attr("attribute_name", hclString(tt.TTL))
+		block.attr("enabled", "true")
+	}
+	if len(tt.Tags) > 0 {
+		res.blank()
+		tags := &hclBlock{header: "tags ="}
+		for _, key := range tt.tagKeys() {
+			tags.attr(hclKey(key), hclString(tt.Tags[key]))
+		}
+		res.entries = append(res.entries, hclEntry{block: tags})
+	}
+	var buf bytes.Buffer
+	res.write(&buf, 0)
+	return buf.Bytes(), nil
+}
+
+func (tt TableTemplate) terraformIndex(block *hclBlock, idx Index) {
+	block.attr("name", hclString(idx.Name))
+	if !idx.Local {
+		block.attr("hash_key", hclString(idx.HashKey))
+	}
+	if idx.RangeKey != "" {
+		block.attr("range_key", hclString(idx.RangeKey))
+	}
+	block.attr("projection_type", hclString(string(projectionOf(idx))))
+	if len(idx.ProjectionAttribs) > 0 {
+		attribs := make([]string, 0, len(idx.ProjectionAttribs))
+		for _, attr := range idx.ProjectionAttribs {
+			attribs = append(attribs, hclString(attr))
+		}
+		block.attr("non_key_attributes", "["+strings.Join(attribs, ", ")+"]")
+	}
+}
+
+func (tt TableTemplate) validate() error {
+	d := tt.Table
+	if d.Name == "" {
+		return fmt.Errorf("dynamo: template: table without a name")
+	}
+	if d.HashKey == "" {
+		return fmt.Errorf("dynamo: template: table %s has no hash key", d.Name)
+	}
+	for _, attr := range tt.attributes() {
+		if attr.typ == "" {
+			return fmt.Errorf("dynamo: template: table %s: unknown type of key %s", d.Name, attr.name)
+		}
+	}
+	if d.StreamEnabled && d.StreamView == "" {
+		return fmt.Errorf("dynamo: template: table %s: stream without a view type", d.Name)
+	}
+	return nil
+}
+
+type templateAttribute struct {
+	name string
+	typ  KeyType
+}
+
+// attributes returns the key attributes of the table and its indexes, in order of appearance.
+func (tt TableTemplate) attributes() []templateAttribute {
+	var attrs []templateAttribute
+	seen := make(map[string]bool)
+	add := func(name string, typ KeyType) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		attrs = append(attrs, templateAttribute{name: name, typ: typ})
+	}
+	d := tt.Table
+	add(d.HashKey, d.HashKeyType)
+	add(d.RangeKey, d.RangeKeyType)
+	for _, idx := range append(tt.indexes(false), tt.indexes(true)...) {
+		add(idx.HashKey, idx.HashKeyType)
+		add(idx.RangeKey, idx.RangeKeyType)
+	}
+	return attrs
+}
+
+// indexes returns the global or local indexes sorted by name.
+func (tt TableTemplate) indexes(local bool) []Index {
+	src := tt.Table.GSI
+	if local {
+		src = tt.Table.LSI
+	}
+	indexes := append([]Index(nil), src...)
+	sortIndexes(indexes)
+	return indexes
+}
+
+func (tt TableTemplate) tagKeys() []string {
+	keys := make([]string, 0, len(tt.Tags))
+	for key := range tt.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func projectionOf(idx Index) IndexProjection {
+	if idx.ProjectionType == "" {
+		return AllProjection
+	}
+	return idx.ProjectionType
+}
+
+// CloudFormation
+
+type cfnTemplate struct {
+	Version   string                 `json:"AWSTemplateFormatVersion" yaml:"AWSTemplateFormatVersion"`
+	Resources map[string]cfnResource `json:"Resources" yaml:"Resources"`
+}
+
+type cfnResource struct {
+	Type       string   `json:"Type" yaml:"Type"`
+	Properties cfnTable `json:"Properties" yaml:"Properties"`
+}
+
+type cfnTable struct {
+	TableName               string         `json:"TableName" yaml:"TableName"`
+	BillingMode             string         `json:"BillingMode" yaml:"BillingMode"`
+	ProvisionedThroughput   *cfnThroughput `json:"ProvisionedThroughput,omitempty" yaml:"ProvisionedThroughput,omitempty"`
+	AttributeDefinitions    []cfnAttribute `json:"AttributeDefinitions" yaml:"AttributeDefinitions"`
+	KeySchema               []cfnKey       `json:"KeySchema" yaml:"KeySchema"`
+	GlobalSecondaryIndexes  []cfnIndex     `json:"GlobalSecondaryIndexes,omitempty" yaml:"GlobalSecondaryIndexes,omitempty"`
+	LocalSecondaryIndexes   []cfnIndex     `json:"LocalSecondaryIndexes,omitempty" yaml:"LocalSecondaryIndexes,omitempty"`
+	StreamSpecification     *cfnStream     `json:"StreamSpecification,omitempty" yaml:"StreamSpecification,omitempty"`
+	TimeToLiveSpecification *cfnTTL        `json:"TimeToLiveSpecification,omitempty" yaml:"TimeToLiveSpecification,omitempty"`
+	Tags                    []cfnTag       `json:"Tags,omitempty" yaml:"Tags,omitempty"`
+}
+
+type cfnThroughput struct {
+	ReadCapacityUnits  int64 `json:"ReadCapacityUnits" yaml:"ReadCapacityUnits"`
+	WriteCapacityUnits int64 `json:"WriteCapacityUnits" yaml:"WriteCapacityUnits"`
+}
+
+type cfnAttribute struct {
+	AttributeName string `json:"AttributeName" yaml:"AttributeName"`
+	AttributeType string `json:"AttributeType" yaml:"AttributeType"`
+}
+
+type cfnKey struct {
+	AttributeName string `json:"AttributeName" yaml:"AttributeName"`
+	KeyType       string `json:"KeyType" yaml:"KeyType"`
+}
+
+type cfnIndex struct {
+	IndexName             string         `json:"IndexName" yaml:"IndexName"`
+	KeySchema             []cfnKey       `json:"KeySchema" yaml:"KeySchema"`
+	Projection            cfnProjection  `json:"Projection" yaml:"Projection"`
+	ProvisionedThroughput *cfnThroughput `json:"ProvisionedThroughput,omitempty" yaml:"ProvisionedThroughput,omitempty"`
+}
+
+type cfnProjection struct {
+	ProjectionType   string   `json:"ProjectionType" yaml:"ProjectionType"`
+	NonKeyAttributes []string `json:"NonKeyAttributes,omitempty" yaml:"NonKeyAttributes,omitempty"`
+}
+
+type cfnStream struct {
+	StreamViewType string `json:"StreamViewType" yaml:"StreamViewType"`
+}
+
+type cfnTTL struct {
+	AttributeName string `json:"AttributeName" yaml:"AttributeName"`
+	Enabled       bool   `json:"Enabled" yaml:"Enabled"`
+}
+
+type cfnTag struct {
+	Key   string `json:"Key" yaml:"Key"`
+	Value string `json:"Value" yaml:"Value"`
+}
+
+func (tt TableTemplate) cloudFormation() (cfnTemplate, error) {
+	if err := tt.validate(); err != nil {
+		return cfnTemplate{}, err
+	}
+	d := tt.Table
+	table := cfnTable{
+		TableName: d.Name,
+		KeySchema: cfnKeys(d.HashKey, d.RangeKey),
+	}
+	if d.OnDemand {
+		table.BillingMode = "PAY_PER_REQUEST"
+	} else {
+		table.BillingMode = "PROVISIONED"
+		table.ProvisionedThroughput = &cfnThroughput{d.Throughput.Read, d.Throughput.Write}
+	}
+	for _, attr := range tt.attributes() {
+		table.AttributeDefinitions = append(table.AttributeDefinitions, cfnAttribute{attr.name, string(attr.typ)})
+	}
+	for _, idx := range tt.indexes(false) {
+		ci := cfnIndexOf(idx)
+		if !d.OnDemand {
+			ci.ProvisionedThroughput = &cfnThroughput{idx.Throughput.Read, idx.Throughput.Write}
+		}
+		table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, ci)
+	}
+	for _, idx := range tt.indexes(true) {
+		table.LocalSecondaryIndexes = append(table.LocalSecondaryIndexes, cfnIndexOf(idx))
+	}
+	if d.StreamEnabled {
+		table.StreamSpecification = &cfnStream{string(d.StreamView)}
+	}
+	if tt.TTL != "" {
+		table.TimeToLiveSpecification = &cfnTTL{AttributeName: tt.TTL, Enabled: true}
+	}
+	for _, key := range tt.tagKeys() {
+		table.Tags = append(table.Tags, cfnTag{key, tt.Tags[key]})
+	}
+	return cfnTemplate{
+		Version: "2010-09-09",
+		Resources: map[string]cfnResource{
+			tt.cloudFormationName(): {Type: "AWS::DynamoDB::Table", Properties: table},
+		},
+	}, nil
+}
+
+func cfnIndexOf(idx Index) cfnIndex {
+	return cfnIndex{
+		IndexName: idx.Name,
+		KeySchema: cfnKeys(idx.HashKey, idx.RangeKey),
+		Projection: cfnProjection{
+			ProjectionType:   string(projectionOf(idx)),
+			NonKeyAttributes: idx.ProjectionAttribs,
+		},
+	}
+}
+
+func cfnKeys(hashKey, rangeKey string) []cfnKey {
+	keys := []cfnKey{{hashKey, "HASH"}}
+	if rangeKey != "" {
+		keys = append(keys, cfnKey{rangeKey, "RANGE"})
+	}
+	return keys
+}
+
+// cloudFormationName returns the logical ID of the table resource, which must be alphanumeric.
+func (tt TableTemplate) cloudFormationName() string {
+	if tt.Resource != "" {
+		return tt.Resource
+	}
+	var name strings.Builder
+	for _, r := range tt.Table.Name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			name.WriteRune(r)
+		}
+	}
+	if name.Len() == 0 || !unicode.IsLetter(rune(name.String()[0])) {
+		return "Table" + name.String()
+	}
+	return name.String()
+}
+
+// Terraform
+
+// terraformName returns the resource name, which may contain letters, digits, underscores and dashes.
+func (tt TableTemplate) terraformName() string {
+	if tt.Resource != "" {
+		return tt.Resource
+	}
+	var name strings.Builder
+	for _, r := range tt.Table.Name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			name.WriteRune(unicode.ToLower(r))
+		default:
+			name.WriteByte('_')
+		}
+	}
+	if name.Len() == 0 || unicode.IsDigit(rune(name.String()[0])) {
+		return "table_" + name.String()
+	}
+	return name.String()
+}
+
+// hclBlock is a block of HCL, written with aligned attributes like terraform fmt.
+type hclBlock struct {
+	header  string
+	entries []hclEntry
+}
+
+// hclEntry is an attribute, a nested block, or a blank line if both are empty.
+type hclEntry struct {
+	key, value string
+	block      *hclBlock
+}
+
+func (b *hclBlock) attr(key, value string) {
+	b.entries = append(b.entries, hclEntry{key: key, value: value})
+}
+
+func (b *hclBlock) blank() {
+	b.entries = append(b.entries, hclEntry{})
+}
+
+// block adds a nested block, preceded by a blank line.
+func (b *hclBlock) block(name string) *hclBlock {
+	nested := &hclBlock{header: name}
+	b.blank()
+	b.entries = append(b.entries, hclEntry{block: nested})
+	return nested
+}
+
+func (b *hclBlock) write(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	buf.WriteString(b.header + " {\n")
+	for i := 0; i < len(b.entries); {
+		entry := b.entries[i]
+		switch {
+		case entry.block != nil:
+			buf.WriteString(indent + "  ")
+			entry.block.write(buf, depth+1)
+			i++
+		case entry.key == "":
+			buf.WriteString("\n")
+			i++
+		default:
+			// align the values of consecutive attributes
+			j, width := i, 0
+			for ; j < len(b.entries) && b.entries[j].key != ""; j++ {
+				if len(b.entries[j].key) > width {
+					width = len(b.entries[j].key)
+				}
+			}
+			for _, attr := range b.entries[i:j] {
+				fmt.Fprintf(buf, "%s  %-*s = %s\n", indent, width, attr.key, attr.value)
+			}
+			i = j
+		}
+	}
+	buf.WriteString(indent + "}\n")
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences.
+func hclString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.Replace(quoted, "${", "$${", -1)
+	return strings.Replace(quoted, "%{", "%%{", -1)
+}
+
+// hclKey returns key as an HCL map key, quoted unless it's an identifier.
+func hclKey(key string) string {
+	for i, r := range key {
+		if !(r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '-')))) {
+			return hclString(key)
+		}
+	}
+	if key == "" {
+		return hclString(key)
+	}
+	return key
+}
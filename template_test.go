@@ -0,0 +1,110 @@
+package dynamo
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s: output differs from golden file.\nwant:\n%s\ngot:\n%s", name, want, got)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	schema, err := ParseSchema([]byte(ordersYAML), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := schema.Tables[0].CreateTable(testDB).Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.TTL = "Expires"
+
+	live := liveOrders()
+	live.BillingModeSummary = &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest}
+	described := newDescription(live).Template()
+	described.Resource = "LiveOrders"
+	described.Tags = map[string]string{"cost center": "42"}
+
+	tests := []struct {
+		name string
+		tt   TableTemplate
+	}{
+		{"create", created},
+		{"describe", described},
+	}
+	for _, test := range tests {
+		cfnJSON, err := test.tt.CloudFormationJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "template_"+test.name+".cfn.json", cfnJSON)
+
+		cfnYAML, err := test.tt.CloudFormationYAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "template_"+test.name+".cfn.yaml", cfnYAML)
+
+		tf, err := test.tt.Terraform()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "template_"+test.name+".tf", tf)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	if _, err := (Description{HashKey: "ID", HashKeyType: StringType}).Template().Terraform(); err == nil {
+		t.Error("expected error for table without a name")
+	}
+	desc := Description{Name: "A", HashKey: "ID"}
+	if _, err := desc.Template().CloudFormationJSON(); err == nil || !strings.Contains(err.Error(), "unknown type of key ID") {
+		t.Errorf("expected key type error, got %v", err)
+	}
+	if _, err := testDB.CreateTable("Bad", 42).Template(); err == nil {
+		t.Error("expected CreateTable error")
+	}
+}
+
+func TestTemplateNames(t *testing.T) {
+	tests := []struct {
+		table, cfn, tf string
+	}{
+		{"Orders", "Orders", "orders"},
+		{"my-app.orders_v2", "myappordersv2", "my_app_orders_v2"},
+		{"2021", "Table2021", "table_2021"},
+	}
+	for _, test := range tests {
+		tt := Description{Name: test.table}.Template()
+		if got := tt.cloudFormationName(); got != test.cfn {
+			t.Errorf("%s: want CloudFormation name %s, got %s", test.table, test.cfn, got)
+		}
+		if got := tt.terraformName(); got != test.tf {
+			t.Errorf("%s: want Terraform name %s, got %s", test.table, test.tf, got)
+		}
+	}
+	if got := hclString("${var} %{if}"); got != `"$${var} %%{if}"` {
+		t.Errorf("bad escaping: %s", got)
+	}
+}
@@ -0,0 +1,56 @@
+resource "aws_dynamodb_table" "orders" {
+  name           = "Orders"
+  billing_mode   = "PROVISIONED"
+  read_capacity  = 5
+  write_capacity = 2
+  hash_key       = "UserID"
+  range_key      = "OrderID"
+
+  stream_enabled   = true
+  stream_view_type = "NEW_IMAGE"
+
+  attribute {
+    name = "UserID"
+    type = "S"
+  }
+
+  attribute {
+    name = "OrderID"
+    type = "S"
+  }
+
+  attribute {
+    name = "Status"
+    type = "S"
+  }
+
+  attribute {
+    name = "Total"
+    type = "N"
+  }
+
+  global_secondary_index {
+    name               = "Status-index"
+    hash_key           = "Status"
+    projection_type    = "INCLUDE"
+    non_key_attributes = ["Total"]
+    read_capacity      = 3
+    write_capacity     = 1
+  }
+
+  local_secondary_index {
+    name            = "Total-index"
+    range_key       = "Total"
+    projection_type = "KEYS_ONLY"
+  }
+
+  ttl {
+    attribute_name = "Expires"
+    enabled        = true
+  }
+
+  tags = {
+    env  = "test"
+    team = "payments"
+  }
+}
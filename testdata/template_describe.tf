@@ -0,0 +1,34 @@
+resource "aws_dynamodb_table" "LiveOrders" {
+  name         = "Orders"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "UserID"
+  range_key    = "OrderID"
+
+  stream_enabled   = true
+  stream_view_type = "KEYS_ONLY"
+
+  attribute {
+    name = "UserID"
+    type = "S"
+  }
+
+  attribute {
+    name = "OrderID"
+    type = "S"
+  }
+
+  attribute {
+    name = "Legacy"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "Legacy-index"
+    hash_key        = "Legacy"
+    projection_type = "ALL"
+  }
+
+  tags = {
+    "cost center" = "42"
+  }
+}
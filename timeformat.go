@@ -0,0 +1,139 @@
+package dynamo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// timeFormat is the encoding of a time.Time field, as given by its struct tag.
+// Times are either encoded as numbers (counting unit since the Unix epoch)
+// or as strings formatted with layout.
+type timeFormat struct {
+	name   string
+	unit   time.Duration
+	layout string
+}
+
+var (
+	unixTimeFormat    = &timeFormat{name: "unixtime", unit: time.Second}
+	unixMilliFormat   = &timeFormat{name: "unixmilli", unit: time.Millisecond}
+	unixNanoFormat    = &timeFormat{name: "unixnano", unit: time.Nanosecond}
+	rfc3339NanoFormat = &timeFormat{name: "rfc3339nano", layout: time.RFC3339Nano}
+)
+
+// timeFormatFor returns the time format given by flags and the layout of the time= option,
+// or nil if the default encoding (encoding.TextMarshaler) should be used.
+func timeFormatFor(flags encodeFlags, layout string) *timeFormat {
+	switch {
+	case flags&flagUnixTime != 0:
+		return unixTimeFormat
+	case flags&flagUnixMilli != 0:
+		return unixMilliFormat
+	case flags&flagUnixNano != 0:
+		return unixNanoFormat
+	case flags&flagRFC3339Nano != 0:
+		return rfc3339NanoFormat
+	case flags&flagTimeLayout != 0 && layout != "":
+		return &timeFormat{name: "time=" + layout, layout: layout}
+	}
+	return nil
+}
+
+// timeLayout returns the layout given by the time= option of field's struct tag.
+// Because layouts may contain commas, time= must be the last option.
+func timeLayout(field reflect.StructField) string {
+	tag := field.Tag.Get("dynamo")
+	if i := strings.Index(tag, ",time="); i >= 0 {
+		return tag[i+len(",time="):]
+	}
+	return ""
+}
+
+// isTimeType returns true for time.Time and *time.Time.
+func isTimeType(rt reflect.Type) bool {
+	return rt == timeType || rt == reflect.PtrTo(timeType)
+}
+
+// encode encodes t. Zero times are omitted.
+func (tf *timeFormat) encode(t time.Time) types.AttributeValue {
+	if t.IsZero() {
+		return nil
+	}
+	if tf.layout != "" {
+		return &types.AttributeValueMemberS{Value: t.Format(tf.layout)}
+	}
+	var n int64
+	switch tf.unit {
+	case time.Second:
+		n = t.Unix()
+	case time.Millisecond:
+		n = t.Unix()*1e3 + int64(t.Nanosecond())/1e6
+	default:
+		n = t.UnixNano()
+	}
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+// decode decodes av, which must be of the type this format encodes to.
+func (tf *timeFormat) decode(av types.AttributeValue) (time.Time, error) {
+	if tf.layout != "" {
+		s, ok := av.(*types.AttributeValueMemberS)
+		if !ok {
+			return time.Time{}, fmt.Errorf("dynamo: cannot unmarshal %s data into time.Time (%s)", avTypeName(av), tf.name)
+		}
+		return time.Parse(tf.layout, s.Value)
+	}
+
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return time.Time{}, fmt.Errorf("dynamo: cannot unmarshal %s data into time.Time (%s)", avTypeName(av), tf.name)
+	}
+	ts, err := strconv.ParseInt(n.Value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("dynamo: cannot unmarshal %s into time.Time (%s): %v", n.Value, tf.name, err)
+	}
+	switch tf.unit {
+	case time.Second:
+		return time.Unix(ts, 0).UTC(), nil
+	case time.Millisecond:
+		return time.Unix(ts/1e3, (ts%1e3)*1e6).UTC(), nil
+	}
+	return time.Unix(0, ts).UTC(), nil
+}
+
+// encodeField encodes rv, which is a time.Time or *time.Time.
+func (tf *timeFormat) encodeField(rv reflect.Value, flags encodeFlags) types.AttributeValue {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			if flags&flagNull != 0 {
+				return &types.AttributeValueMemberNULL{Value: true}
+			}
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	return tf.encode(rv.Interface().(time.Time))
+}
+
+// decodeField decodes av into rv, which is a settable time.Time or *time.Time.
+func (tf *timeFormat) decodeField(av types.AttributeValue, rv reflect.Value) error {
+	if _, ok := av.(*types.AttributeValueMemberNULL); ok {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	t, err := tf.decode(av)
+	if err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.ValueOf(&t))
+		return nil
+	}
+	rv.Set(reflect.ValueOf(t))
+	return nil
+}
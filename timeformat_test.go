@@ -0,0 +1,117 @@
+package dynamo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestTimeFormats(t *testing.T) {
+	type times struct {
+		Sec    time.Time  `dynamo:",unixtime"`
+		Milli  time.Time  `dynamo:",unixmilli"`
+		Nano   *time.Time `dynamo:",unixnano"`
+		RFC    time.Time  `dynamo:",rfc3339nano"`
+		Day    time.Time  `dynamo:",omitempty,time=2006-01-02"`
+		Commas time.Time  `dynamo:",time=Mon, 02 Jan 2006"`
+	}
+
+	when := time.Date(2020, 1, 2, 3, 4, 5, 678901234, time.UTC)
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	in := times{
+		Sec:    when.Truncate(time.Second),
+		Milli:  when.Truncate(time.Millisecond),
+		Nano:   &when,
+		RFC:    when,
+		Day:    day,
+		Commas: day,
+	}
+	want := map[string]types.AttributeValue{
+		"Sec":    &types.AttributeValueMemberN{Value: "1577934245"},
+		"Milli":  &types.AttributeValueMemberN{Value: "1577934245678"},
+		"Nano":   &types.AttributeValueMemberN{Value: "1577934245678901234"},
+		"RFC":    &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05.678901234Z"},
+		"Day":    &types.AttributeValueMemberS{Value: "2020-01-02"},
+		"Commas": &types.AttributeValueMemberS{Value: "Thu, 02 Jan 2020"},
+	}
+
+	item, err := marshalItem(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, item)
+	}
+
+	var out times
+	if err := unmarshalItem(item, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("bad round trip. want: %#v got: %#v", in, out)
+	}
+
+	// zero times are omitted
+	item, err = marshalItem(times{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item) != 0 {
+		t.Errorf("zero times not omitted: %#v", item)
+	}
+}
+
+func TestTimeFormatMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		out  interface{}
+		item map[string]types.AttributeValue
+	}{
+		{
+			name: "unixmilli from S",
+			out: &struct {
+				T time.Time `dynamo:",unixmilli"`
+			}{},
+			item: map[string]types.AttributeValue{"T": &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"}},
+		},
+		{
+			name: "layout from N",
+			out: &struct {
+				T time.Time `dynamo:",time=2006-01-02"`
+			}{},
+			item: map[string]types.AttributeValue{"T": &types.AttributeValueMemberN{Value: "1577934245"}},
+		},
+		{
+			name: "layout mismatch",
+			out: &struct {
+				T *time.Time `dynamo:",time=2006-01-02"`
+			}{},
+			item: map[string]types.AttributeValue{"T": &types.AttributeValueMemberS{Value: "02/01/2020"}},
+		},
+		{
+			name: "unixtime from fraction",
+			out: &struct {
+				T time.Time `dynamo:",unixtime"`
+			}{},
+			item: map[string]types.AttributeValue{"T": &types.AttributeValueMemberN{Value: "1.5"}},
+		},
+	}
+	for _, tc := range tests {
+		if err := unmarshalItem(tc.item, tc.out); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+
+	// untagged fields still accept Unix seconds
+	var untagged struct {
+		T time.Time
+	}
+	if err := unmarshalItem(map[string]types.AttributeValue{"T": &types.AttributeValueMemberN{Value: "1577934245"}}, &untagged); err != nil {
+		t.Fatal(err)
+	}
+	if !untagged.T.Equal(time.Unix(1577934245, 0)) {
+		t.Errorf("bad untagged time: %v", untagged.T)
+	}
+}
@@ -0,0 +1,88 @@
+package dynamo
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timestampField is a field tagged with created or updated.
+type timestampField struct {
+	name   string
+	flags  encodeFlags
+	format *timeFormat
+	field  reflect.Value // settable if the item was given as a pointer
+}
+
+// findTimestamps returns the fields of item tagged with created and updated, if any.
+func findTimestamps(item interface{}) (created, updated *timestampField, err error) {
+	err = visitFields(item, func(field reflect.StructField, fv reflect.Value, name string, flags encodeFlags) (bool, error) {
+		if flags&(flagCreated|flagUpdated) == 0 {
+			return true, nil
+		}
+		if fv.Type() != timeType && fv.Type() != reflect.PtrTo(timeType) {
+			return false, fmt.Errorf("dynamo: timestamp field %s must be a time.Time or *time.Time (got %s)", field.Name, fv.Type())
+		}
+		ts := &timestampField{name: name, flags: flags, format: timeFormatFor(flags, timeLayout(field)), field: fv}
+		if flags&flagCreated != 0 && created == nil {
+			created = ts
+		}
+		if flags&flagUpdated != 0 && updated == nil {
+			updated = ts
+		}
+		return true, nil
+	})
+	return
+}
+
+// isZero returns true if this field is unset.
+func (ts *timestampField) isZero() bool {
+	if ts.field.Kind() == reflect.Ptr {
+		return ts.field.IsNil() || ts.field.Elem().Interface().(time.Time).IsZero()
+	}
+	return ts.field.Interface().(time.Time).IsZero()
+}
+
+// encode returns now encoded according to this field's flags, such as unixtime.
+func (ts *timestampField) encode(now time.Time) (types.AttributeValue, error) {
+	if ts.format != nil {
+		return ts.format.encode(now), nil
+	}
+	return marshal(now, ts.flags)
+}
+
+// set sets the field of the original item to now.
+func (ts *timestampField) set(now time.Time) {
+	if !ts.field.CanSet() {
+		return
+	}
+	if ts.field.Kind() == reflect.Ptr {
+		ts.field.Set(reflect.ValueOf(&now))
+		return
+	}
+	ts.field.Set(reflect.ValueOf(now))
+}
+
+// timestamps are the timestamp fields that a write operation will set.
+type timestamps struct {
+	now    time.Time
+	fields []*timestampField
+}
+
+func (t *timestamps) add(ts *timestampField) {
+	t.fields = append(t.fields, ts)
+}
+
+// commit sets the timestamp fields of the original item after a successful write.
+func (t *timestamps) commit() {
+	if t == nil {
+		return
+	}
+	for _, ts := range t.fields {
+		ts.set(t.now)
+	}
+}
@@ -0,0 +1,117 @@
+package dynamo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type stampedWidget struct {
+	UserID    int        `dynamo:",hash"`
+	CreatedAt time.Time  `dynamo:",created"`
+	UpdatedAt *time.Time `dynamo:",updated,unixtime"`
+}
+
+func TestPutTimestamps(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetClock(func() time.Time { return now })
+	table := db.Table("Stamped")
+
+	item := stampedWidget{UserID: 42}
+	if err := table.Put(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !item.CreatedAt.Equal(now) || item.UpdatedAt == nil || !item.UpdatedAt.Equal(now) {
+		t.Errorf("bad timestamps: %v, %v", item.CreatedAt, item.UpdatedAt)
+	}
+	want := map[string]types.AttributeValue{
+		"UserID":    &types.AttributeValueMemberN{Value: "42"},
+		"CreatedAt": &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"},
+		"UpdatedAt": &types.AttributeValueMemberN{Value: "1577934245"},
+	}
+	if !reflect.DeepEqual(client.put.Item, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, client.put.Item)
+	}
+
+	// fields that are already set are left alone
+	created := now.Add(-time.Hour)
+	item = stampedWidget{UserID: 42, CreatedAt: created}
+	if err := table.Put(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !item.CreatedAt.Equal(created) {
+		t.Errorf("created timestamp was overwritten: %v", item.CreatedAt)
+	}
+	if got := client.put.Item["CreatedAt"]; !reflect.DeepEqual(got, &types.AttributeValueMemberS{Value: "2020-01-02T02:04:05Z"}) {
+		t.Errorf("bad created timestamp: %#v", got)
+	}
+}
+
+type stampMeta struct {
+	CreatedAt time.Time `dynamo:",created"`
+	UpdatedAt time.Time `dynamo:",updated,unixtime"`
+}
+
+func TestInlineTimestamps(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetClock(func() time.Time { return now })
+
+	item := struct {
+		UserID int       `dynamo:",hash"`
+		Stamps stampMeta `dynamo:"ts_,inline"`
+	}{UserID: 42}
+	if err := db.Table("Stamped").Put(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !item.Stamps.CreatedAt.Equal(now) || !item.Stamps.UpdatedAt.Equal(now) {
+		t.Errorf("bad timestamps: %v, %v", item.Stamps.CreatedAt, item.Stamps.UpdatedAt)
+	}
+	want := map[string]types.AttributeValue{
+		"UserID":       &types.AttributeValueMemberN{Value: "42"},
+		"ts_CreatedAt": &types.AttributeValueMemberS{Value: "2020-01-02T03:04:05Z"},
+		"ts_UpdatedAt": &types.AttributeValueMemberN{Value: "1577934245"},
+	}
+	if !reflect.DeepEqual(client.put.Item, want) {
+		t.Errorf("bad item. want: %#v got: %#v", want, client.put.Item)
+	}
+}
+
+func TestUpdateModelTimestamps(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	db.SetClock(func() time.Time { return now })
+	table := db.Table("Stamped")
+
+	var item stampedWidget
+	if err := table.Update("UserID", 42).Model(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := *client.update.UpdateExpression; got != "SET UpdatedAt = :v0, CreatedAt = if_not_exists(CreatedAt, :v1)" {
+		t.Errorf("bad update expression: %s", got)
+	}
+	if got := client.update.ExpressionAttributeValues[":v0"]; !reflect.DeepEqual(got, &types.AttributeValueMemberN{Value: "1577934245"}) {
+		t.Errorf("bad updated timestamp: %#v", got)
+	}
+	// the stored creation time may have been kept, so it isn't known
+	if !item.CreatedAt.IsZero() || item.UpdatedAt == nil || !item.UpdatedAt.Equal(now) {
+		t.Errorf("bad timestamps: %v, %v", item.CreatedAt, item.UpdatedAt)
+	}
+}
+
+func TestTimestampFieldType(t *testing.T) {
+	type badStamp struct {
+		ID      int
+		Created string `dynamo:",created"`
+	}
+	table := NewFromIface(&fakeClient{}).Table("Stamped")
+	if err := table.Put(badStamp{ID: 1}).Run(); err == nil {
+		t.Error("expected error for non-time timestamp field, got nil")
+	}
+}
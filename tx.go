@@ -90,16 +90,16 @@ func (tx *GetTx) RunWithContext(ctx context.Context) error {
 	if isResponsesEmpty(resp.Responses) {
 		return ErrNotFound
 	}
-	return tx.unmarshal(resp)
+	return tx.unmarshal(ctx, resp)
 }
 
-func (tx *GetTx) unmarshal(resp *dynamodb.TransactGetItemsOutput) error {
+func (tx *GetTx) unmarshal(ctx context.Context, resp *dynamodb.TransactGetItemsOutput) error {
 	for i, item := range resp.Responses {
 		if item.Item == nil {
 			continue
 		}
 		if target := tx.unmarshalers[tx.items[i]]; target != nil {
-			if err := UnmarshalItem(item.Item, target); err != nil {
+			if err := tx.db.decoder().withContext(ctx).unmarshalItem(item.Item, target); err != nil {
 				return err
 			}
 		}
@@ -137,14 +137,14 @@ func (tx *GetTx) AllWithContext(ctx context.Context, out interface{}) error {
 	if isResponsesEmpty(resp.Responses) {
 		return ErrNotFound
 	}
-	if err := tx.unmarshal(resp); err != nil {
+	if err := tx.unmarshal(ctx, resp); err != nil {
 		return err
 	}
 	for _, item := range resp.Responses {
 		if item.Item == nil {
 			continue
 		}
-		if err := unmarshalAppend(item.Item, out); err != nil {
+		if err := tx.db.decoder().withContext(ctx).unmarshalAppend(item.Item, out); err != nil {
 			return err
 		}
 	}
@@ -173,6 +173,11 @@ type writeTxOp interface {
 	writeTxItem() (*types.TransactWriteItem, error)
 }
 
+// offloadOp is a write that may move large attributes to the BlobStore before it is sent.
+type offloadOp interface {
+	offload(ctx context.Context) error
+}
+
 // WriteTx is a transaction to delete, put, update, and check items.
 // It can contain up to 25 operations and works across multiple tables.
 // Two operations cannot target the same item.
@@ -264,6 +269,13 @@ func (tx *WriteTx) RunWithContext(ctx context.Context) error {
 	if tx.err != nil {
 		return tx.err
 	}
+	for _, item := range tx.items {
+		if op, ok := item.(offloadOp); ok {
+			if err := op.offload(ctx); err != nil {
+				return err
+			}
+		}
+	}
 	input, err := tx.input()
 	if err != nil {
 		return err
@@ -277,7 +289,15 @@ func (tx *WriteTx) RunWithContext(ctx context.Context) error {
 		}
 		return err
 	})
-	return err
+	if err != nil {
+		return txVersionError(tx.items, err)
+	}
+	for _, item := range tx.items {
+		if op, ok := item.(committer); ok {
+			op.commit()
+		}
+	}
+	return nil
 }
 
 func (tx *WriteTx) input() (*dynamodb.TransactWriteItemsInput, error) {
@@ -285,13 +305,24 @@ func (tx *WriteTx) input() (*dynamodb.TransactWriteItemsInput, error) {
 		return nil, ErrNoInput
 	}
 	input := &dynamodb.TransactWriteItemsInput{}
+	total := 0
 	for _, item := range tx.items {
 		wti, err := item.writeTxItem()
 		if err != nil {
 			return nil, err
 		}
+		if op, ok := item.(sizedOp); ok {
+			size := op.itemSize()
+			if err := tx.db.checkSize("item", size, MaxItemSize); err != nil {
+				return nil, err
+			}
+			total += size
+		}
 		input.TransactItems = append(input.TransactItems, *wti)
 	}
+	if err := tx.db.checkSize("transaction", total, MaxTxSize); err != nil {
+		return nil, err
+	}
 	if tx.token != "" {
 		input.ClientRequestToken = aws.String(tx.token)
 	}
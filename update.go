@@ -3,11 +3,13 @@ package dynamo
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/niltonkummer/dynamo/internal/exprs"
 )
 
 // Update represents changes to an existing item.
@@ -29,6 +31,27 @@ type Update struct {
 	remove map[string]struct{}
 
 	condition string
+	version   *versionInfo
+	stamps    *timestamps
+
+	// models are the types whose fields may be encrypted: the model, or the table's model or registered types.
+	// values are the values set by Set and its variants, by the document paths in setPaths,
+	// which are encrypted if their field is, or offloaded if their top-level field is.
+	// changed are the paths changed by other actions, which can't change encrypted fields.
+	models   []reflect.Type
+	values   map[string]types.AttributeValue
+	setPaths []docPath
+	changed  []docPath
+	// resign are the values whose encrypted fields were signed with the item's keys,
+	// which are encoded again when the update runs, in case its range key was given after them
+	resign []resignValue
+	// sealed is set once values have been encrypted, so running the update again doesn't encrypt them twice
+	sealed bool
+	// offloaded are the offloaded fields of the model, removed are the paths removed,
+	// and ifNotExists are the paths only set if they don't exist, by SetIfNotExists or if_not_exists in SetExpr
+	offloaded   []string
+	removed     []docPath
+	ifNotExists map[string]bool
 
 	subber
 
@@ -48,6 +71,8 @@ func (table Table) Update(hashKey string, value interface{}) *Update {
 		remove: make(map[string]struct{}),
 	}
 	u.hashValue, u.err = marshal(value, flagNone)
+	u.models = table.models()
+	u.offloaded = table.offloaded()
 	return u
 }
 
@@ -65,12 +90,15 @@ func (u *Update) Range(name string, value interface{}) *Update {
 // Paths that are reserved words are automatically escaped.
 // Use single quotes to escape complex values like 'User'.'Count'.
 func (u *Update) Set(path string, value interface{}) *Update {
-	v, err := marshal(value, flagNone)
+	p, err := parseDocPath(path)
+	u.setError(err)
+	v, err := u.encode(p, value, flagNone)
 	if v == nil && err == nil {
 		// auto-omitted value
 		return u.Remove(path)
 	}
 	u.setError(err)
+	u.setValue(p, v)
 
 	path, err = u.escape(path)
 	u.setError(err)
@@ -86,9 +114,15 @@ func (u *Update) Set(path string, value interface{}) *Update {
 // Paths that are reserved words are automatically escaped.
 // Use single quotes to escape complex values like 'User'.'Count'.
 func (u *Update) SetNullable(path string, value interface{}) *Update {
-	path, err := u.escape(path)
+	p, err := parseDocPath(path)
 	u.setError(err)
-	expr, err := u.subExprN("🝕 = ?", path, value)
+	v, err := u.encode(p, value, flagAllowEmpty|flagNull)
+	u.setError(err)
+	u.setValue(p, v)
+
+	path, err = u.escape(path)
+	u.setError(err)
+	expr, err := u.subExprN("🝕 = ?", path, v)
 	u.setError(err)
 	u.set = append(u.set, expr)
 	return u
@@ -100,12 +134,15 @@ func (u *Update) SetNullable(path string, value interface{}) *Update {
 // Paths that are reserved words are automatically escaped.
 // Use single quotes to escape complex values like 'User'.'Count'.
 func (u *Update) SetSet(path string, value interface{}) *Update {
-	v, err := marshal(value, flagSet)
+	p, err := parseDocPath(path)
+	u.setError(err)
+	v, err := u.encode(p, value, flagSet)
 	if v == nil && err == nil {
 		// empty set
 		return u.Remove(path)
 	}
 	u.setError(err)
+	u.setValue(p, v)
 
 	path, err = u.escape(path)
 	u.setError(err)
@@ -117,51 +154,87 @@ func (u *Update) SetSet(path string, value interface{}) *Update {
 
 // SetIfNotExists changes path to the given value, if it does not already exist.
 func (u *Update) SetIfNotExists(path string, value interface{}) *Update {
-	path, err := u.escape(path)
+	p, err := parseDocPath(path)
 	u.setError(err)
-	expr, err := u.subExprN("🝕 = if_not_exists(🝕, ?)", path, path, value)
+	v, err := u.encode(p, value, flagAllowEmpty|flagNull)
+	u.setError(err)
+	u.setValue(p, v)
+	u.setIfNotExists(p)
+
+	path, err = u.escape(path)
+	u.setError(err)
+	expr, err := u.subExprN("🝕 = if_not_exists(🝕, ?)", path, path, v)
 	u.setError(err)
 	u.set = append(u.set, expr)
 	return u
 }
 
 // SetExpr performs a custom set expression, substituting the args into expr as in filter expressions.
+// Fields tagged with encrypt can't be changed by SetExpr; use Set instead.
 // See: http://docs.aws.amazon.com/amazondynamodb/latest/APIReference/API_UpdateItem.html#DDB-UpdateItem-request-UpdateExpression
 //	SetExpr("MyMap.$.$ = ?", key1, key2, val)
 //	SetExpr("'Counter' = 'Counter' + ?", 1)
 func (u *Update) SetExpr(expr string, args ...interface{}) *Update {
-	expr, err := u.subExprN(expr, args...)
+	sub, err := u.subKind(flagAllowEmpty|flagNull, exprs.SetActions, expr, args...)
 	u.setError(err)
-	u.set = append(u.set, expr)
+	u.set = append(u.set, sub)
+	if err != nil {
+		return u
+	}
+	tree, _ := exprs.ParseKind(exprs.SetActions, expr)
+	for _, set := range tree.Update.Set {
+		path := exprDocPath(tree, set.Path, args)
+		u.changed = append(u.changed, path)
+		if fn, ok := set.Value.(*exprs.Func); ok && fn.Name == "if_not_exists" && len(fn.Args) == 2 {
+			if src, ok := fn.Args[0].(*exprs.Path); ok && exprDocPath(tree, src, args).String() == path.String() {
+				u.setIfNotExists(path)
+			}
+		}
+	}
 	return u
 }
 
 // Append appends value to the end of the list specified by path.
+// The encrypted fields of structs in value are encrypted, but lists tagged with encrypt can't be appended to.
 func (u *Update) Append(path string, value interface{}) *Update {
+	v := u.encodeList(path, value)
 	path, err := u.escape(path)
 	u.setError(err)
-	expr, err := u.subExprN("🝕 = list_append(🝕, ?)", path, path, value)
+	expr, err := u.subExprN("🝕 = list_append(🝕, ?)", path, path, v)
 	u.setError(err)
 	u.set = append(u.set, expr)
 	return u
 }
 
 // Prepend inserts value to the beginning of the list specified by path.
+// The encrypted fields of structs in value are encrypted, but lists tagged with encrypt can't be prepended to.
 func (u *Update) Prepend(path string, value interface{}) *Update {
+	v := u.encodeList(path, value)
 	path, err := u.escape(path)
 	u.setError(err)
-	expr, err := u.subExprN("🝕 = list_append(?, 🝕)", path, value, path)
+	expr, err := u.subExprN("🝕 = list_append(?, 🝕)", path, v, path)
 	u.setError(err)
 	u.set = append(u.set, expr)
 	return u
 }
 
+// encodeList encodes value, the elements to add to the list at path.
+func (u *Update) encodeList(path string, value interface{}) types.AttributeValue {
+	p, err := parseDocPath(path)
+	u.setError(err)
+	u.changed = append(u.changed, p)
+	v, err := u.encode(p, value, flagAllowEmpty|flagNull)
+	u.setError(err)
+	return v
+}
+
 // Add adds value to path.
 // Path can be a number or a set.
 // If path represents a number, value is atomically added to the number.
 // If path represents a set, value must be a slice, a map[*]struct{}, or map[*]bool.
-// Path must be a top-level attribute.
+// Path must be a top-level attribute, and can't be a field tagged with encrypt.
 func (u *Update) Add(path string, value interface{}) *Update {
+	u.change(path)
 	path, err := u.escape(path)
 	u.setError(err)
 	vsub, err := u.subValue(value, flagSet)
@@ -186,6 +259,7 @@ func (u *Update) AddFloatsToSet(path string, values ...float64) *Update {
 }
 
 func (u *Update) delete(path string, value interface{}) *Update {
+	u.change(path)
 	path, err := u.escape(path)
 	u.setError(err)
 	vsub, err := u.subValue(value, flagSet)
@@ -242,7 +316,10 @@ func (u *Update) DeleteFloatsFromSet(path string, values ...float64) *Update {
 // Remove removes the paths from this item, deleting the specified attributes.
 func (u *Update) Remove(paths ...string) *Update {
 	for _, n := range paths {
-		n, err := u.escape(n)
+		p, err := parseDocPath(n)
+		u.setError(err)
+		u.removed = append(u.removed, p)
+		n, err = u.escape(n)
 		u.setError(err)
 		u.remove[n] = struct{}{}
 	}
@@ -252,21 +329,29 @@ func (u *Update) Remove(paths ...string) *Update {
 // RemoveExpr performs a custom remove expression, substituting the args into expr as in filter expressions.
 // 	RemoveExpr("MyList[$]", 5)
 func (u *Update) RemoveExpr(expr string, args ...interface{}) *Update {
-	expr, err := u.subExpr(expr, args...)
+	sub, err := u.subKind(flagNone, exprs.Projection, expr, args...)
 	u.setError(err)
-	u.remove[expr] = struct{}{}
+	u.remove[sub] = struct{}{}
+	if err != nil {
+		return u
+	}
+	tree, _ := exprs.ParseKind(exprs.Projection, expr)
+	for _, path := range tree.Projection {
+		u.removed = append(u.removed, exprDocPath(tree, path, args))
+	}
 	return u
 }
 
 // If specifies a conditional expression for this update to succeed.
 // Use single quotes to specificy reserved names inline (like 'Count').
 // Use the placeholder ? within the expression to substitute values, and use $ for names.
-// You need to use quoted or placeholder names when the name is a reserved word in DynamoDB.
-// Multiple calls to Update will be combined with AND.
+// Multiple calls to If will be combined with AND.
 func (u *Update) If(expr string, args ...interface{}) *Update {
-	expr = wrapExpr(expr)
-	cond, err := u.subExprN(expr, args...)
+	cond, err := u.subCond(expr, args)
 	u.setError(err)
+	if cond == "" {
+		return u
+	}
 	if u.condition != "" {
 		u.condition += " AND "
 	}
@@ -274,6 +359,66 @@ func (u *Update) If(expr string, args ...interface{}) *Update {
 	return u
 }
 
+// IfExpr is like If, but takes an Expression, such as a condition built with the cond package.
+func (u *Update) IfExpr(expr Expression) *Update {
+	if expr == nil {
+		u.setError(errNilExpression)
+		return u
+	}
+	str, args := expr.Expr()
+	return u.If(str, args...)
+}
+
+// Model applies the special struct tags of model to this update.
+// If model has a field tagged with version, the update will only succeed if the version in the table
+// matches model's version, and the version will be incremented.
+// A field tagged with updated will be set to the current time,
+// and a field tagged with created will be set to the current time if it doesn't exist yet.
+// Values set for fields tagged with encrypt will be encrypted, as they are for the table's model (see Table.WithModel),
+// and large values set for fields tagged with offload will be moved to the DB's BlobStore.
+// Pass a pointer to model to have its version and updated fields updated after a successful update.
+// Its created field isn't changed, because the stored creation time may have been kept; use Value to read it.
+func (u *Update) Model(model interface{}) *Update {
+	if rt := reflect.TypeOf(model); rt != nil {
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt.Kind() == reflect.Struct {
+			u.models = []reflect.Type{rt}
+			u.offloaded = codecOf(rt).offloaded
+		}
+	}
+
+	v, err := findVersion(model)
+	u.setError(err)
+	if v != nil {
+		u.version = v
+		u.Add(v.name, 1)
+		expr, args := v.cond()
+		u.If(expr, args...)
+	}
+
+	created, updated, err := findTimestamps(model)
+	u.setError(err)
+	if created == nil && updated == nil {
+		return u
+	}
+	u.stamps = &timestamps{now: u.table.db.now()}
+	if updated != nil {
+		av, err := updated.encode(u.stamps.now)
+		u.setError(err)
+		u.Set(updated.name, av)
+		u.stamps.add(updated)
+	}
+	if created != nil && created != updated {
+		av, err := created.encode(u.stamps.now)
+		u.setError(err)
+		// the stored value may be kept, so model's field is left alone
+		u.SetIfNotExists(created.name, av)
+	}
+	return u
+}
+
 // ConsumedCapacity will measure the throughput capacity consumed by this operation and add it to cc.
 func (u *Update) ConsumedCapacity(cc *ConsumedCapacity) *Update {
 	u.cc = cc
@@ -288,10 +433,16 @@ func (u *Update) Run() error {
 }
 
 // RunWithContext executes this update.
+// If the DB has a BlobStore, blobs that the offloaded fields changed or removed by any of this update's actions,
+// including SetExpr and RemoveExpr, pointed to are cleaned up afterwards; see OffloadOptions.OnOrphan.
+// OldValue and OnlyUpdatedOldValue clean them up too, but Value and OnlyUpdatedValue can't.
 func (u *Update) RunWithContext(ctx context.Context) error {
 	u.returnType = "NONE"
-	_, err := u.run(ctx)
-	return err
+	output, err := u.run(ctx)
+	if err != nil {
+		return err
+	}
+	return u.orphan(ctx, output.Attributes)
 }
 
 // Value executes this update, encoding out with the new value after the update.
@@ -310,7 +461,7 @@ func (u *Update) ValueWithContext(ctx context.Context, out interface{}) error {
 	if err != nil {
 		return err
 	}
-	return unmarshalItem(output.Attributes, out)
+	return u.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
 }
 
 // OldValue executes this update, encoding out with the old value before the update.
@@ -329,7 +480,12 @@ func (u *Update) OldValueWithContext(ctx context.Context, out interface{}) error
 	if err != nil {
 		return err
 	}
-	return unmarshalItem(output.Attributes, out)
+	// resolve offloaded attributes before cleaning them up
+	err = u.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
+	if oerr := u.orphan(ctx, output.Attributes); err == nil {
+		err = oerr
+	}
+	return err
 }
 
 // OnlyUpdatedValue executes this update, encoding out with only with new values of the attributes that were changed.
@@ -348,7 +504,7 @@ func (u *Update) OnlyUpdatedValueWithContext(ctx context.Context, out interface{
 	if err != nil {
 		return err
 	}
-	return unmarshalItem(output.Attributes, out)
+	return u.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
 }
 
 // OnlyUpdatedOldValue executes this update, encoding out with only with old values of the attributes that were changed.
@@ -367,15 +523,29 @@ func (u *Update) OnlyUpdatedOldValueWithContext(ctx context.Context, out interfa
 	if err != nil {
 		return err
 	}
-	return unmarshalItem(output.Attributes, out)
+	// resolve offloaded attributes before cleaning them up
+	err = u.table.db.decoder().withContext(ctx).unmarshalItem(output.Attributes, out)
+	if oerr := u.orphan(ctx, output.Attributes); err == nil {
+		err = oerr
+	}
+	return err
 }
 
 func (u *Update) run(ctx context.Context) (*dynamodb.UpdateItemOutput, error) {
+	u.setError(u.encrypt())
+	u.setError(u.offload(ctx))
 	if u.err != nil {
 		return nil, u.err
 	}
+	if err := u.table.db.checkSize("item", u.itemSize(), MaxItemSize); err != nil {
+		return nil, err
+	}
 
 	input := u.updateInput()
+	if u.returnType == "NONE" && len(u.touchedBlobs()) > 0 {
+		// the old values are needed to clean up offloaded attributes
+		input.ReturnValues = types.ReturnValueUpdatedOld
+	}
 	var output *dynamodb.UpdateItemOutput
 	err := retry(ctx, func() error {
 		var err error
@@ -385,7 +555,11 @@ func (u *Update) run(ctx context.Context) (*dynamodb.UpdateItemOutput, error) {
 	if u.cc != nil {
 		addConsumedCapacity(u.cc, output.ConsumedCapacity)
 	}
-	return output, err
+	if err != nil {
+		return output, versionError(u.version, err)
+	}
+	u.commit()
+	return output, nil
 }
 
 func (u *Update) updateInput() *dynamodb.UpdateItemInput {
@@ -407,6 +581,7 @@ func (u *Update) updateInput() *dynamodb.UpdateItemInput {
 }
 
 func (u *Update) writeTxItem() (*types.TransactWriteItem, error) {
+	u.setError(u.encrypt())
 	if u.err != nil {
 		return nil, u.err
 	}
@@ -470,6 +645,221 @@ func (u *Update) updateExpr() *string {
 	return &joined
 }
 
+// resignValue is a value encoded by encode that signed the item's keys.
+type resignValue struct {
+	path  docPath
+	value interface{}
+	flags encodeFlags
+	av    types.AttributeValue
+}
+
+// encode encodes value, to be set at path, encrypting the encrypted fields of the structs in it.
+func (u *Update) encode(path docPath, value interface{}, flags encodeFlags) (types.AttributeValue, error) {
+	c := u.crypter(path)
+	av, err := encodeValue(value, flags, c)
+	if err == nil && av != nil && c.signed() {
+		u.resign = append(u.resign, resignValue{path: path, value: value, flags: flags, av: av})
+	}
+	return av, err
+}
+
+// crypter returns the DB's crypter for the value at path, which signs this update's keys.
+func (u *Update) crypter(path docPath) *crypter {
+	names := []string{u.hashKey}
+	if u.rangeKey != "" {
+		names = append(names, u.rangeKey)
+	}
+	return u.table.db.crypter().at(path.String()).withKeys(names, u.key())
+}
+
+// setValue remembers the value set for path, in case it needs to be encrypted or offloaded.
+func (u *Update) setValue(path docPath, value types.AttributeValue) {
+	if value == nil || path == nil {
+		return
+	}
+	if u.values == nil {
+		u.values = make(map[string]types.AttributeValue)
+	}
+	name := path.String()
+	if _, ok := u.values[name]; !ok {
+		u.setPaths = append(u.setPaths, path)
+	}
+	u.values[name] = value
+}
+
+// setIfNotExists remembers that path is only set if it doesn't exist yet.
+func (u *Update) setIfNotExists(path docPath) {
+	if u.ifNotExists == nil {
+		u.ifNotExists = make(map[string]bool)
+	}
+	u.ifNotExists[path.String()] = true
+}
+
+// change remembers that path is changed by an action other than Set and its variants.
+func (u *Update) change(path string) {
+	p, err := parseDocPath(path)
+	u.setError(err)
+	u.changed = append(u.changed, p)
+}
+
+// fieldWith returns the path of the field tagged with flag that path is, or is inside of,
+// in any of the update's models.
+func (u *Update) fieldWith(flag encodeFlags, path docPath) (docPath, bool) {
+	for _, rt := range u.models {
+		for i, flags := range flagsAt(rt, path) {
+			if flags&flag != 0 {
+				return path[:i+1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// replaceValue replaces the value av, wherever it is used, with v.
+func (u *Update) replaceValue(av, v types.AttributeValue) {
+	for sub, x := range u.valueExpr {
+		if x == av {
+			u.valueExpr[sub] = v
+		}
+	}
+	for name, x := range u.values {
+		if x == av {
+			u.values[name] = v
+		}
+	}
+}
+
+// encrypt replaces the values set for the encrypted fields of the model, or of the table's model or registry,
+// with their ciphertext, and encodes the values that signed the item's keys again with the final keys.
+// It fails if other actions change encrypted fields.
+func (u *Update) encrypt() error {
+	if u.sealed {
+		return nil
+	}
+	for _, path := range u.changed {
+		if field, ok := u.fieldWith(flagEncrypt, path); ok {
+			return fmt.Errorf("dynamo: update: encrypted field %s can only be changed with Set", field)
+		}
+	}
+	for _, rv := range u.resign {
+		av, err := encodeValue(rv.value, rv.flags, u.crypter(rv.path))
+		if err != nil {
+			return err
+		}
+		u.replaceValue(rv.av, av)
+	}
+	for _, path := range u.setPaths {
+		field, ok := u.fieldWith(flagEncrypt, path)
+		if !ok {
+			continue
+		}
+		if len(field) < len(path) {
+			return fmt.Errorf("dynamo: update: cannot set %s inside of encrypted field %s", path, field)
+		}
+		c := u.crypter(path)
+		aad, err := c.aad("")
+		if err != nil {
+			return err
+		}
+		av := u.values[path.String()]
+		enc, err := c.encrypt(av, aad)
+		if err != nil {
+			return err
+		}
+		u.replaceValue(av, enc)
+	}
+	u.sealed = true
+	return nil
+}
+
+// offload moves the large values set for the model's offloaded fields to the BlobStore.
+func (u *Update) offload(ctx context.Context) error {
+	if len(u.offloaded) == 0 || u.err != nil {
+		return nil
+	}
+	o := u.table.db.offloader()
+	for _, name := range u.offloaded {
+		av, ok := u.values[name]
+		if !ok {
+			continue
+		}
+		ptr, err := o.offload(ctx, u.table.name, name, av)
+		if err != nil {
+			return err
+		}
+		if ptr == av {
+			continue
+		}
+		for sub, v := range u.valueExpr {
+			if v == av {
+				u.valueExpr[sub] = ptr
+			}
+		}
+		u.values[name] = ptr
+	}
+	return nil
+}
+
+// touchedBlobs returns the offloaded fields changed or removed by any action of this update,
+// whose old blobs need to be cleaned up, or nil if the DB has no BlobStore.
+func (u *Update) touchedBlobs() []string {
+	if u.table.db.offloader() == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range u.offloaded {
+		if u.touches(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// touches reports whether an action of this update changes or removes the top-level attribute name,
+// or a path inside of it.
+func (u *Update) touches(name string) bool {
+	for _, paths := range [][]docPath{u.setPaths, u.changed, u.removed} {
+		for _, path := range paths {
+			if len(path) > 0 && path[0] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// orphan cleans up the blobs that old, the old values of this update's item, no longer needs.
+func (u *Update) orphan(ctx context.Context, old map[string]types.AttributeValue) error {
+	o := u.table.db.offloader()
+	for _, name := range u.touchedBlobs() {
+		names := []string{name}
+		if !u.ifNotExists[name] {
+			if err := o.orphan(ctx, names, old, u.values); err != nil {
+				return err
+			}
+			continue
+		}
+		// the stored value was kept if there was one, leaving the new value unused
+		if _, ok := old[name]; ok {
+			if err := o.orphan(ctx, names, u.values, old); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *Update) versionInfo() *versionInfo {
+	return u.version
+}
+
+func (u *Update) commit() {
+	if u.version != nil {
+		u.version.commit()
+	}
+	u.stamps.commit()
+}
+
 func (u *Update) setError(err error) {
 	if u.err == nil {
 		u.err = err
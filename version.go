@@ -0,0 +1,133 @@
+package dynamo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrVersionConflict is returned when writing a versioned item fails because its version
+// in the table doesn't match, meaning it was changed or created by someone else since it was read.
+// The error returned wraps it along with the AWS error, so check for it with errors.Is.
+// See the version struct tag for more information.
+var ErrVersionConflict = errors.New("dynamo: version conflict")
+
+// versionInfo is the version field of an item, as given by the version struct tag.
+type versionInfo struct {
+	name  string
+	field reflect.Value // settable if the item was given as a pointer
+	old   int64
+}
+
+// findVersion returns the version field of item, or nil if it doesn't have one.
+func findVersion(item interface{}) (*versionInfo, error) {
+	var v *versionInfo
+	err := visitFields(item, func(field reflect.StructField, fv reflect.Value, name string, flags encodeFlags) (bool, error) {
+		if flags&flagVersion == 0 {
+			return true, nil
+		}
+		v = &versionInfo{name: name, field: fv}
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.old = fv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.old = int64(fv.Uint())
+		default:
+			return false, fmt.Errorf("dynamo: version field %s must be an integer (got %s)", field.Name, fv.Type())
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// next returns the version that will be written.
+func (v *versionInfo) next() int64 {
+	return v.old + 1
+}
+
+// nextAV returns the encoded version that will be written.
+func (v *versionInfo) nextAV() types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(v.next(), 10)}
+}
+
+// cond returns the condition that checks the stored version.
+// Version zero means the item is new, so it must not already exist.
+func (v *versionInfo) cond() (string, []interface{}) {
+	if v.old == 0 {
+		return "attribute_not_exists($)", []interface{}{v.name}
+	}
+	return "$ = ?", []interface{}{v.name, v.old}
+}
+
+// commit updates the version field of the original item after a successful write.
+func (v *versionInfo) commit() {
+	if !v.field.CanSet() {
+		return
+	}
+	switch v.field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.field.SetInt(v.next())
+	default:
+		v.field.SetUint(uint64(v.next()))
+	}
+}
+
+// versionedOp is a write operation that may be versioned.
+type versionedOp interface {
+	versionInfo() *versionInfo
+}
+
+// versionConflict is ErrVersionConflict along with the error that caused it,
+// which may also be the failure of a condition given with If.
+type versionConflict struct {
+	cause error
+}
+
+func (e versionConflict) Error() string {
+	return ErrVersionConflict.Error() + ": " + e.cause.Error()
+}
+
+func (e versionConflict) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+func (e versionConflict) Unwrap() error {
+	return e.cause
+}
+
+// versionError translates a failed condition check into ErrVersionConflict.
+// Use errors.Is to check for ErrVersionConflict, and errors.As for the underlying AWS error.
+func versionError(v *versionInfo, err error) error {
+	if v == nil {
+		return err
+	}
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return versionConflict{cause: err}
+	}
+	return err
+}
+
+// txVersionError translates a canceled transaction into ErrVersionConflict
+// if the condition check of any of its versioned operations failed.
+func txVersionError(ops []writeTxOp, err error) error {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return err
+	}
+	for i, reason := range canceled.CancellationReasons {
+		if i >= len(ops) || reason.Code == nil || *reason.Code != "ConditionalCheckFailed" {
+			continue
+		}
+		if op, ok := ops[i].(versionedOp); ok && op.versionInfo() != nil {
+			return versionConflict{cause: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package dynamo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type versionedWidget struct {
+	UserID  int `dynamo:",hash"`
+	Msg     string
+	Version int64 `dynamo:",version"`
+}
+
+func TestPutVersion(t *testing.T) {
+	client := &fakeClient{}
+	table := NewFromIface(client).Table("Versioned")
+
+	item := versionedWidget{UserID: 42, Msg: "hello"}
+	if err := table.Put(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 1 {
+		t.Errorf("bad version. want: 1 got: %d", item.Version)
+	}
+	if got := *client.put.ConditionExpression; got != "(attribute_not_exists(#sKZSXE43JN5XA))" {
+		t.Errorf("bad condition: %s", got)
+	}
+	if got := client.put.Item["Version"]; !reflect.DeepEqual(got, &types.AttributeValueMemberN{Value: "1"}) {
+		t.Errorf("bad encoded version: %#v", got)
+	}
+
+	if err := table.Put(&item).If("Msg = ?", "hello").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 2 {
+		t.Errorf("bad version. want: 2 got: %d", item.Version)
+	}
+	if got := *client.put.ConditionExpression; got != "(#sKZSXE43JN5XA = :v0) AND (Msg = :v1)" {
+		t.Errorf("bad condition: %s", got)
+	}
+
+	client.err = &types.ConditionalCheckFailedException{Message: aws.String("failed")}
+	if err := table.Put(&item).Run(); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+	if item.Version != 2 {
+		t.Errorf("version changed after failed put: %d", item.Version)
+	}
+	// the caller's own condition may have failed instead, so the AWS error is kept
+	err := table.Put(&item).If("Msg = ?", "hello").Run()
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.Is(err, ErrVersionConflict) || !errors.As(err, &ccf) {
+		t.Errorf("expected ErrVersionConflict wrapping ConditionalCheckFailedException, got %v", err)
+	}
+
+	// items without a version field are unaffected
+	if err := table.Put(widget{UserID: 42}).Run(); errors.Is(err, ErrVersionConflict) || err == nil {
+		t.Errorf("expected ConditionalCheckFailedException, got %v", err)
+	}
+}
+
+type versionMeta struct {
+	Version int64 `dynamo:",version"`
+}
+
+type inlineVersionWidget struct {
+	UserID int         `dynamo:",hash"`
+	Meta   versionMeta `dynamo:"meta_,inline"`
+}
+
+func TestInlineVersion(t *testing.T) {
+	client := &fakeClient{}
+	table := NewFromIface(client).Table("Versioned")
+
+	item := inlineVersionWidget{UserID: 42, Meta: versionMeta{Version: 3}}
+	if err := table.Put(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if item.Meta.Version != 4 {
+		t.Errorf("bad version. want: 4 got: %d", item.Meta.Version)
+	}
+	if got := client.put.ExpressionAttributeNames; len(got) != 1 || !containsValue(got, "meta_Version") {
+		t.Errorf("bad condition names: %v", got)
+	}
+	if got := client.put.Item["meta_Version"]; !reflect.DeepEqual(got, &types.AttributeValueMemberN{Value: "4"}) {
+		t.Errorf("bad encoded version: %#v", got)
+	}
+
+	if err := table.Update("UserID", 42).Model(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := *client.update.UpdateExpression; got != "ADD meta_Version :v0" {
+		t.Errorf("bad update expression: %s", got)
+	}
+	if item.Meta.Version != 5 {
+		t.Errorf("bad version after update. want: 5 got: %d", item.Meta.Version)
+	}
+}
+
+func containsValue(m map[string]string, v string) bool {
+	for _, x := range m {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUpdateModelVersion(t *testing.T) {
+	client := &fakeClient{}
+	table := NewFromIface(client).Table("Versioned")
+
+	item := versionedWidget{UserID: 42, Version: 3}
+	if err := table.Update("UserID", item.UserID).Set("Msg", "hi").Model(&item).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 4 {
+		t.Errorf("bad version. want: 4 got: %d", item.Version)
+	}
+	if got := *client.update.UpdateExpression; got != "SET Msg = :v0 ADD Version :v1" {
+		t.Errorf("bad update expression: %s", got)
+	}
+	if got := *client.update.ConditionExpression; got != "(#sKZSXE43JN5XA = :v2)" {
+		t.Errorf("bad condition: %s", got)
+	}
+
+	client.err = &types.ConditionalCheckFailedException{Message: aws.String("failed")}
+	if err := table.Update("UserID", item.UserID).Model(&item).Run(); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+}
+
+func TestWriteTxVersion(t *testing.T) {
+	client := &fakeClient{}
+	db := NewFromIface(client)
+	table := db.Table("Versioned")
+
+	a := versionedWidget{UserID: 1}
+	b := versionedWidget{UserID: 2, Version: 1}
+	if err := db.WriteTx().Put(table.Put(&a)).Update(table.Update("UserID", 2).Model(&b)).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 1 || b.Version != 2 {
+		t.Errorf("bad versions: %d, %d", a.Version, b.Version)
+	}
+
+	client.err = &types.TransactionCanceledException{
+		Message: aws.String("canceled"),
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	}
+	err := db.WriteTx().Delete(table.Delete("UserID", 3)).Put(table.Put(&a)).Run()
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+	if a.Version != 1 {
+		t.Errorf("version changed after failed transaction: %d", a.Version)
+	}
+
+	// condition failure of an unversioned operation
+	client.err.(*types.TransactionCanceledException).CancellationReasons = []types.CancellationReason{
+		{Code: aws.String("ConditionalCheckFailed")},
+		{Code: aws.String("None")},
+	}
+	err = db.WriteTx().Delete(table.Delete("UserID", 3)).Put(table.Put(&a)).Run()
+	if errors.Is(err, ErrVersionConflict) || err == nil {
+		t.Errorf("expected TransactionCanceledException, got %v", err)
+	}
+}
+
+func TestVersionFieldType(t *testing.T) {
+	type badVersion struct {
+		ID      int
+		Version string `dynamo:",version"`
+	}
+	table := NewFromIface(&fakeClient{}).Table("Versioned")
+	if err := table.Put(badVersion{ID: 1}).Run(); err == nil {
+		t.Error("expected error for non-integer version field, got nil")
+	}
+}